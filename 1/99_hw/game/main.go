@@ -2,103 +2,321 @@ package main
 
 import (
 	"bufio"
+	"context"
+	_ "embed"
+	"flag"
 	"fmt"
+	"net"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
+	"sync"
 )
 
-type Command func(*World, ...string) string
+type Command func(*World, *Player, ...string) string
 
-type Location struct {
-	items   [][]string
-	places  []string
-	clothes map[string]string
+// Place - место в локации, где лежат предметы, например "на столе"
+type Place struct {
+	name  string
+	items []string
+}
 
-	locations []string
+type Exit struct {
+	name   string
+	to     *Location
+	locked bool
+}
 
-	locks map[string]bool
+type Location struct {
+	name string
 
-	showTasks bool
+	places   []*Place
+	exits    []*Exit
+	fixtures map[string]bool
 
-	hidden         map[string]string
+	showTasks      bool
 	description    string
 	welcome        string
-	triggers       map[string]func(*World) string
 	noItemsMessage string
 }
 
 func (l *Location) getAvailableLocations() string {
-	return fmt.Sprintf("можно пройти - %s", strings.Join(l.locations, ", "))
+	names := make([]string, len(l.exits))
+	for i, e := range l.exits {
+		names[i] = e.name
+	}
+	return fmt.Sprintf("можно пройти - %s", strings.Join(names, ", "))
 }
 
 func (l *Location) getItems() string {
 	res := make([]string, 0)
 
-	// тут не нужно отображать пустые "" записи(удаление реализовано именно так)
-	for i := 0; i < len(l.items); i++ {
-		notEmpty := make([]string, 0)
-		for _, item := range l.items[i] {
-			if item != "" {
-				notEmpty = append(notEmpty, item)
+	for _, p := range l.places {
+		if len(p.items) != 0 {
+			res = append(res, strings.Join([]string{p.name, strings.Join(p.items, ", ")}, ": "))
+		}
+	}
+
+	if len(res) == 0 {
+		return l.noItemsMessage
+	}
+
+	return strings.Join(res, ", ")
+}
+
+func (l *Location) exit(name string) *Exit {
+	for _, e := range l.exits {
+		if e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+func (l *Location) hasItem(item string) bool {
+	for _, p := range l.places {
+		for _, i := range p.items {
+			if i == item {
+				return true
 			}
 		}
+	}
+	return false
+}
 
-		if len(notEmpty) != 0 {
-			res = append(res, strings.Join([]string{l.places[i], strings.Join(notEmpty, ", ")}, ": "))
+func (l *Location) removeItem(item string) {
+	for _, p := range l.places {
+		for i, v := range p.items {
+			if v == item {
+				p.items = append(p.items[:i:i], p.items[i+1:]...)
+				return
+			}
 		}
 	}
+}
 
-	for item, place := range l.clothes {
-		res = append(res, fmt.Sprintf("%s: %s", place, item))
+type Player struct {
+	// name и send нужны только в мультиплеере: send доставляет сообщения от других игроков
+	name string
+	send func(msg string)
+
+	location *Location
+	// items - инвентарь вместе с надетым, в порядке получения
+	items []string
+	worn  []string
+	// tasks - ещё не выполненные задачи
+	tasks []string
+}
+
+func (p *Player) has(item string) bool {
+	for _, i := range p.items {
+		if i == item {
+			return true
+		}
 	}
+	return false
+}
 
-	if len(res) == 0 {
-		return l.noItemsMessage
+func (p *Player) drop(item string) {
+	for i, v := range p.items {
+		if v == item {
+			p.items = append(p.items[:i:i], p.items[i+1:]...)
+			return
+		}
 	}
+}
 
-	return strings.Join(res, ", ")
+func (p *Player) done(task string) bool {
+	for _, t := range p.tasks {
+		if t == task {
+			return false
+		}
+	}
+	return true
 }
 
-func (l *Location) openLocation(name string) {
-	l.locks[name] = true
+func (p *Player) accomplish(task string) {
+	for i, t := range p.tasks {
+		if t == task {
+			p.tasks = append(p.tasks[:i:i], p.tasks[i+1:]...)
+			return
+		}
+	}
 }
 
-type Player struct {
-	items map[string]bool
+func (p *Player) getCurrentTasks() string {
+	return fmt.Sprintf("надо %s", strings.Join(p.tasks, " и "))
 }
 
+// World можно менять только через Handle, Join и Leave - они держат mu
 type World struct {
-	currentLocation *Location
-	locations       map[string]*Location
-	items           map[string]string
-	places          []string
-	accomplished    map[string]bool
-	tasks           []string
-	inventoryOn     bool
+	mu sync.Mutex
+	// players - игроки в игре, по именам
+	players  map[string]*Player
+	commands map[string]Command
+
+	start     *Location
+	locations map[string]*Location
+	items     map[string]ItemSpec
+	rules     []RuleSpec
+	tasks     []string
+}
+
+// NewWorld проверяет описание и строит по нему мир
+func NewWorld(spec *WorldSpec) (*World, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	w := &World{
+		players:   make(map[string]*Player),
+		commands:  initCommands(),
+		locations: make(map[string]*Location, len(spec.Locations)),
+		items:     spec.Items,
+		rules:     spec.Rules,
+		tasks:     spec.Tasks,
+	}
+	for _, ls := range spec.Locations {
+		l := &Location{
+			name:           ls.Name,
+			fixtures:       make(map[string]bool),
+			showTasks:      ls.ShowTasks,
+			description:    ls.Description,
+			welcome:        ls.Welcome,
+			noItemsMessage: ls.Empty,
+		}
+		for _, ps := range ls.Places {
+			l.places = append(l.places, &Place{name: ps.Name, items: append([]string(nil), ps.Items...)})
+		}
+		for _, f := range ls.Fixtures {
+			l.fixtures[f] = true
+		}
+		w.locations[l.name] = l
+	}
+	// выходы - когда все локации уже есть
+	for _, ls := range spec.Locations {
+		l := w.locations[ls.Name]
+		for _, es := range ls.Exits {
+			l.exits = append(l.exits, &Exit{name: es.Name, to: w.locations[exitTarget(es)], locked: es.Locked})
+		}
+	}
+	w.start = w.locations[spec.Start]
+
+	return w, nil
+}
+
+// NewPlayer ставит игрока в начальную локацию со всеми задачами
+func (w *World) NewPlayer() *Player {
+	return &Player{location: w.start, tasks: append([]string(nil), w.tasks...)}
+}
+
+// Handle выполняет команду игрока
+func (w *World) Handle(p *Player, command string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	parts := strings.Split(command, " ")
+	executeCmd, ok := w.commands[parts[0]]
+	if !ok {
+		return "неизвестная команда"
+	}
+
+	return executeCmd(w, p, parts[1:]...)
+}
+
+// Join вводит игрока в мир, остальные в локации видят, что он пришёл
+func (w *World) Join(p *Player) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.players[p.name]; ok {
+		return fmt.Errorf("игрок %s уже в игре", p.name)
+	}
+	w.players[p.name] = p
+	w.notifyLocation(p, fmt.Sprintf("%s пришёл", p.name))
+	return nil
+}
+
+// Leave выводит игрока из мира, его предметы остаются при нём
+func (w *World) Leave(p *Player) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.players, p.name)
+	w.notifyLocation(p, fmt.Sprintf("%s ушёл", p.name))
 }
 
-func (w *World) getCurrentTasks() string {
-	notEmptyTasks := make([]string, 0)
-	for _, t := range w.tasks {
-		if t != "" {
-			notEmptyTasks = append(notEmptyTasks, t)
+// notifyLocation отправляет сообщение всем в локации игрока, кроме него самого
+func (w *World) notifyLocation(from *Player, msg string) {
+	for _, p := range w.players {
+		if p != from && p.location == from.location && p.send != nil {
+			p.send(msg)
 		}
 	}
+}
 
-	return fmt.Sprintf("надо %s", strings.Join(notEmptyTasks, " и "))
+// others - имена других игроков в локации, по алфавиту
+func (w *World) others(from *Player) []string {
+	names := make([]string, 0)
+	for _, p := range w.players {
+		if p != from && p.location == from.location {
+			names = append(names, p.name)
+		}
+	}
+	sort.Strings(names)
+	return names
 }
 
-func (w *World) accomplish(task string) {
-	delete(w.accomplished, task)
-	for i, v := range w.tasks {
-		if v == task {
-			w.tasks[i] = ""
+// canCarry - надет ли рюкзак или что-то ещё, куда можно класть
+func (w *World) canCarry(p *Player) bool {
+	for _, item := range p.worn {
+		if w.items[item].Container {
+			return true
 		}
 	}
+	return false
 }
 
+//go:embed world.yaml
+var defaultWorld []byte
+
 func main() {
-	initGame()
+	worldPath := flag.String("world", "", "world file, yaml or json, the built-in world by default")
+	listen := flag.String("listen", "", "serve the world to many players over tcp at this address instead of stdin")
+	sessions := flag.String("sessions", "", "directory to keep the players between restarts of the server")
+	flag.StringVar(&saveDir, "saves", saveDir, "directory for the saved games")
+	flag.Parse()
+
+	if *worldPath == "" {
+		initGame()
+	} else {
+		spec, err := LoadWorldSpec(*worldPath)
+		if err == nil {
+			err = startGame(spec)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if *listen != "" {
+		ln, err := net.Listen("tcp", *listen)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		fmt.Println("mud server at", ln.Addr())
+		if err := NewServer(world, *sessions).Serve(ctx, ln); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	s := bufio.NewScanner(os.Stdin)
 
@@ -108,243 +326,175 @@ func main() {
 }
 
 var world *World
-var commands map[string]Command
+var player *Player
 
 func initGame() {
-	commands = initCommands()
-
-	room := &Location{
-		items: [][]string{
-			{"ключи", "конспекты"},
-		},
-		places: []string{
-			"на столе",
-		},
-		clothes: map[string]string{
-			"рюкзак": "на стуле",
-		},
-		locks: map[string]bool{
-			"коридор": true,
-		},
-		locations: []string{"коридор"},
-		triggers: map[string]func(*World) string{
-			"взять конспекты": func(w *World) string {
-				w.accomplish("собрать рюкзак")
-				return ""
-			},
-			"надеть рюкзак": func(w *World) string {
-				w.inventoryOn = true
-				return ""
-			},
-		},
-		welcome:        "ты в своей комнате",
-		noItemsMessage: "пустая комната",
-	}
-
-	kitchen := &Location{
-		items: [][]string{
-			{"чай"},
-		},
-		places: []string{"на столе"},
-		locks: map[string]bool{
-			"коридор": true,
-		},
-		locations:   []string{"коридор"},
-		description: "ты находишься на кухне",
-		welcome:     "кухня, ничего интересного",
-		showTasks:   true,
-	}
-
-	corridor := &Location{
-		locks: map[string]bool{
-			"кухня":   true,
-			"комната": true,
-			"улица":   false,
-		},
-		locations: []string{"кухня", "комната", "улица"},
-		hidden: map[string]string{
-			"дверь": "дверь на улицу",
-		},
-		triggers: map[string]func(*World) string{
-			"применить ключи дверь": func(w *World) string {
-				w.currentLocation.openLocation("улица")
-				return "дверь открыта"
-			},
-		},
-		welcome: "ничего интересного",
-	}
-
-	street := &Location{
-		locks: map[string]bool{
-			"домой": true,
-		},
-		locations: []string{"домой"},
-		welcome:   "на улице весна",
-	}
-
-	world = &World{currentLocation: kitchen,
-		locations: map[string]*Location{"комната": room, "кухня": kitchen, "коридор": corridor, "улица": street},
-		items:     make(map[string]string),
-		accomplished: map[string]bool{"собрать рюкзак": false,
-			"идти в универ": false},
-		tasks: []string{"собрать рюкзак", "идти в универ"},
+	spec, err := ParseWorldSpec(defaultWorld, "yaml")
+	if err == nil {
+		err = startGame(spec)
+	}
+	if err != nil {
+		panic(err)
 	}
 }
 
-func handleCommand(command string) string {
-	parts := strings.Split(command, " ")
-	executeCmd, ok := commands[parts[0]]
-	if !ok {
-		return "неизвестная команда"
+func startGame(spec *WorldSpec) error {
+	w, err := NewWorld(spec)
+	if err != nil {
+		return err
 	}
 
-	res := executeCmd(world, parts[1:]...)
+	world = w
+	player = w.NewPlayer()
+	history = nil
+	return nil
+}
 
-	return res
+// withSays дописывает к ответу то, что сказали сработавшие правила
+func withSays(answer string, says []string) string {
+	return strings.Join(append([]string{answer}, says...), ". ")
 }
 
 func initCommands() map[string]Command {
-	walk := func(w *World, args ...string) string {
-		target := args[0]
+	walk := func(w *World, p *Player, args ...string) string {
+		if len(args) < 1 {
+			return "недостаточно аргументов для выполнения команды"
+		}
 
-		isOpen, ok := w.currentLocation.locks[target]
-		if !ok {
+		target := args[0]
+		exit := p.location.exit(target)
+		if exit == nil {
 			return fmt.Sprintf("нет пути в %s", target)
 		}
-
-		if isOpen {
-			next := w.locations[target]
-			w.currentLocation = next
-			return fmt.Sprintf("%s. %s", next.welcome, next.getAvailableLocations())
+		if exit.locked {
+			return "дверь закрыта"
 		}
 
-		return "дверь закрыта"
+		w.notifyLocation(p, fmt.Sprintf("%s ушёл в %s", p.name, exit.name))
+		p.location = exit.to
+		w.notifyLocation(p, fmt.Sprintf("%s пришёл", p.name))
+		says, _ := w.fire(p, "идти", "", target)
+		return withSays(fmt.Sprintf("%s. %s", exit.to.welcome, exit.to.getAvailableLocations()), says)
 	}
 
-	wear := func(w *World, args ...string) string {
+	wear := func(w *World, p *Player, args ...string) string {
 		if len(args) < 1 {
 			return "недостаточно аргументов для выполнения команды"
 		}
 
 		item := args[0]
-		_, ok := w.currentLocation.clothes[item]
-		if !ok {
+		if !p.location.hasItem(item) || !w.items[item].Wearable {
 			return "нет такого"
 		}
 
-		w.items[item] = item
-		delete(w.currentLocation.clothes, item)
-
-		trigger, ok := w.currentLocation.triggers["надеть "+item]
-		if ok {
-			trigger(w)
-		}
+		p.location.removeItem(item)
+		p.items = append(p.items, item)
+		p.worn = append(p.worn, item)
 
-		return fmt.Sprintf("вы надели: %s", item)
+		says, _ := w.fire(p, "надеть", item, "")
+		return withSays(fmt.Sprintf("вы надели: %s", item), says)
 	}
 
-	take := func(w *World, args ...string) string {
+	take := func(w *World, p *Player, args ...string) string {
 		if len(args) < 1 {
 			return "недостаточно аргументов для выполнения команды"
 		}
 
-		if !w.inventoryOn {
+		if !w.canCarry(p) {
 			return "некуда класть"
 		}
 
 		item := args[0]
-
-		// можно создавать один раз и хранить в структуре
-		itemsMap := make(map[string]bool)
-		for _, placeItems := range w.currentLocation.items {
-			for _, j := range placeItems {
-				itemsMap[j] = true
-			}
-		}
-
-		_, ok := itemsMap[item]
-		if !ok {
+		// надеваемое не берут, а надевают
+		if !p.location.hasItem(item) || w.items[item].Wearable {
 			return "нет такого"
 		}
 
-		w.items[item] = item
-		items := w.currentLocation.items
+		p.location.removeItem(item)
+		p.items = append(p.items, item)
 
-		// удаляем путём присваивания пустой строки, которая потом будет не отображена в результате
-		for i := 0; i < len(items); i++ {
-			for j := 0; j < len(items[i]); j++ {
-				if items[i][j] == item {
-					items[i][j] = ""
-				}
-			}
-		}
-
-		trigger, ok := w.currentLocation.triggers["взять "+item]
-		if ok {
-			trigger(w)
-		}
-
-		return fmt.Sprintf("предмет добавлен в инвентарь: %s", item)
+		says, _ := w.fire(p, "взять", item, "")
+		return withSays(fmt.Sprintf("предмет добавлен в инвентарь: %s", item), says)
 	}
 
-	use := func(w *World, args ...string) string {
+	use := func(w *World, p *Player, args ...string) string {
 		if len(args) < 2 {
 			return "недостаточно аргументов для выполнения команды"
 		}
 
 		item1 := args[0]
-		_, ok := w.items[item1]
-		if !ok {
+		if !p.has(item1) {
 			return fmt.Sprintf("нет предмета в инвентаре - %s", item1)
 		}
 
 		item2 := args[1]
-
-		itemsMap := make(map[string]bool)
-		for _, placeItems := range w.currentLocation.items {
-			for _, j := range placeItems {
-				itemsMap[j] = true
-			}
-		}
-
-		_, ok = itemsMap[item2]
-		if !ok {
-			_, ok = w.currentLocation.hidden[item2]
-			if !ok {
-				return "не к чему применить"
-			}
+		if !p.location.hasItem(item2) && !p.location.fixtures[item2] {
+			return "не к чему применить"
 		}
 
-		l := w.currentLocation
-		trigger, ok := l.triggers["применить "+item1+" "+item2]
-		if !ok {
+		says, fired := w.fire(p, "применить", item1, item2)
+		if !fired {
 			return "не к чему применить"
 		}
+		if len(says) == 0 {
+			return "готово"
+		}
 
-		return trigger(w)
+		return strings.Join(says, ". ")
 	}
 
-	lookAround := func(w *World, args ...string) string {
-		description := w.currentLocation.description
-		items := w.currentLocation.getItems()
+	lookAround := func(w *World, p *Player, args ...string) string {
+		description := p.location.description
+		items := p.location.getItems()
 		var tasks string
-		if w.currentLocation.showTasks {
-			tasks = w.getCurrentTasks()
+		if p.location.showTasks && len(p.tasks) != 0 {
+			tasks = p.getCurrentTasks()
 		} else {
 			tasks = ""
 		}
 
-		notEmpty := make([]string, 0, 3)
-		for _, info := range []string{description, items, tasks} {
+		var others string
+		if names := w.others(p); len(names) != 0 {
+			others = fmt.Sprintf("здесь также: %s", strings.Join(names, ", "))
+		}
+
+		notEmpty := make([]string, 0, 4)
+		for _, info := range []string{description, items, tasks, others} {
 			if info != "" {
 				notEmpty = append(notEmpty, info)
 			}
 		}
 
-		return fmt.Sprintf("%s. %s", strings.Join(notEmpty, ", "), w.currentLocation.getAvailableLocations())
+		return fmt.Sprintf("%s. %s", strings.Join(notEmpty, ", "), p.location.getAvailableLocations())
+	}
+
+	say := func(w *World, p *Player, args ...string) string {
+		msg := strings.Join(args, " ")
+		if msg == "" {
+			return "недостаточно аргументов для выполнения команды"
+		}
+
+		w.notifyLocation(p, fmt.Sprintf("%s говорит: %s", p.name, msg))
+		return fmt.Sprintf("вы сказали: %s", msg)
+	}
+
+	shout := func(w *World, p *Player, args ...string) string {
+		msg := strings.Join(args, " ")
+		if msg == "" {
+			return "недостаточно аргументов для выполнения команды"
+		}
+
+		for _, other := range w.players {
+			if other != p && other.send != nil {
+				other.send(fmt.Sprintf("%s кричит: %s", p.name, msg))
+			}
+		}
+		return fmt.Sprintf("вы крикнули: %s", msg)
 	}
 
 	return map[string]Command{
+		"сказать":     say,
+		"крикнуть":    shout,
 		"идти":        walk,
 		"надеть":      wear,
 		"взять":       take,
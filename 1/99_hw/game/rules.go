@@ -0,0 +1,64 @@
+package main
+
+// fire применяет все правила, условия которых выполнены для действия игрока.
+// says - что сказали правила, fired - сработало ли хоть одно
+func (w *World) fire(p *Player, action, item, target string) (says []string, fired bool) {
+	for _, r := range w.rules {
+		if !w.matches(p, r.When, action, item, target) {
+			continue
+		}
+
+		fired = true
+		for _, e := range r.Then {
+			if msg := w.apply(p, e); msg != "" {
+				says = append(says, msg)
+			}
+		}
+	}
+	return says, fired
+}
+
+func (w *World) matches(p *Player, c Condition, action, item, target string) bool {
+	switch {
+	case c.Action != action:
+		return false
+	case c.Item != "" && c.Item != item:
+		return false
+	case c.Target != "" && c.Target != target:
+		return false
+	case c.Location != "" && c.Location != p.location.name:
+		return false
+	}
+
+	for _, i := range c.Has {
+		if !p.has(i) {
+			return false
+		}
+	}
+	for _, t := range c.Done {
+		if !p.done(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *World) apply(p *Player, e Effect) string {
+	switch {
+	case e.Say != "":
+		return e.Say
+	case e.Complete != "":
+		p.accomplish(e.Complete)
+	case e.Unlock != nil:
+		w.locations[e.Unlock.Location].exit(e.Unlock.Exit).locked = false
+	case e.Lock != nil:
+		w.locations[e.Lock.Location].exit(e.Lock.Exit).locked = true
+	case e.Give != "":
+		if !p.has(e.Give) {
+			p.items = append(p.items, e.Give)
+		}
+	case e.Destroy != "":
+		p.drop(e.Destroy)
+	}
+	return ""
+}
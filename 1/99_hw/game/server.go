@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// sessionBuffer - сколько сообщений от других игроков ждут медленного клиента, дальше они теряются
+	sessionBuffer = 64
+	maxNameLen    = 20
+	// worldFile - общий мир рядом с игроками, точки в имени игрока быть не может
+	worldFile = ".world.json"
+)
+
+var validName = regexp.MustCompile(`^[\p{L}\d_-]+$`)
+
+// Server - многопользовательский режим: игроки подключаются по tcp, например telnet-ом,
+// и ходят по общему миру, у каждого своё место и инвентарь.
+// Отключившийся игрок сохраняется и при входе под тем же именем продолжает с того же места.
+// Вместе с ним сохраняется и мир, иначе после перезапуска его вещи лежали бы и на своих местах.
+type Server struct {
+	world *World
+	// dir - где хранить игроков и мир между перезапусками, "" - только в памяти
+	dir string
+	// saveMu - мир пишется в один файл, по одному
+	saveMu sync.Mutex
+
+	mu     sync.Mutex
+	saved  map[string]PlayerState
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewServer(w *World, dir string) *Server {
+	return &Server{
+		world: w,
+		dir:   dir,
+		saved: make(map[string]PlayerState),
+		conns: make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve принимает игроков, пока не отменят ctx, потом отключает всех, сохраняя их
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	if err := s.loadWorld(); err != nil {
+		ln.Close()
+		return err
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		ln.Close()
+		s.closeConns()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.closeConns()
+			s.wg.Wait()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if !s.track(conn) {
+			conn.Close()
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			s.session(conn)
+		}()
+	}
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+func (s *Server) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *Server) session(conn net.Conn) {
+	out := make(chan string, sessionBuffer)
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for msg := range out {
+			if _, err := conn.Write([]byte(msg + "\r\n")); err != nil {
+				conn.Close()
+			}
+		}
+	}()
+	defer func() {
+		close(out)
+		<-written
+		conn.Close()
+	}()
+
+	// ответы на свои команды не теряются, в отличие от сообщений других игроков
+	reply := func(msg string) {
+		out <- msg
+	}
+	notify := func(msg string) {
+		select {
+		case out <- msg:
+		default:
+		}
+	}
+
+	lines := bufio.NewScanner(conn)
+	p, err := s.login(lines, reply)
+	if err != nil {
+		return
+	}
+	p.send = notify
+	// уходит из мира раньше, чем закроется out: после Leave ему никто не пишет
+	defer s.save(p)
+	defer s.world.Leave(p)
+
+	reply(s.world.Handle(p, "осмотреться"))
+	for lines.Scan() {
+		command := strings.TrimSpace(lines.Text())
+		switch command {
+		case "":
+			continue
+		case "выйти":
+			reply("до встречи")
+			return
+		}
+		reply(s.world.Handle(p, command))
+	}
+}
+
+func (s *Server) login(lines *bufio.Scanner, reply func(string)) (*Player, error) {
+	for {
+		reply("как тебя зовут?")
+		if !lines.Scan() {
+			if err := lines.Err(); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("disconnected before login")
+		}
+
+		name := strings.TrimSpace(lines.Text())
+		if !validName.MatchString(name) || utf8.RuneCountInString(name) > maxNameLen {
+			reply(fmt.Sprintf("имя - одно слово из букв и цифр, не длиннее %d", maxNameLen))
+			continue
+		}
+
+		p, err := s.player(name)
+		if err != nil {
+			log.Printf("restore %s: %v", name, err)
+			reply("не получилось восстановить игрока")
+			continue
+		}
+		// до Join сообщения никому не нужны
+		p.send = func(string) {}
+		if err := s.world.Join(p); err != nil {
+			reply(err.Error())
+			continue
+		}
+		return p, nil
+	}
+}
+
+// player - сохранённый игрок или новый в начальной локации
+func (s *Server) player(name string) (*Player, error) {
+	s.mu.Lock()
+	state, ok := s.saved[name]
+	s.mu.Unlock()
+
+	if !ok && s.dir != "" {
+		data, err := os.ReadFile(s.playerFile(name))
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &state); err != nil {
+				return nil, err
+			}
+			ok = true
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+	}
+
+	if !ok {
+		p := s.world.NewPlayer()
+		p.name = name
+		return p, nil
+	}
+	return s.world.restorePlayer(state)
+}
+
+func (s *Server) save(p *Player) {
+	state := p.state()
+
+	s.mu.Lock()
+	s.saved[p.name] = state
+	s.mu.Unlock()
+
+	if s.dir == "" {
+		return
+	}
+	if err := writeFileAtomic(s.playerFile(p.name), state); err != nil {
+		log.Printf("save %s: %v", p.name, err)
+	}
+
+	// снимок берётся под saveMu, чтобы старый не перезаписал более новый
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if err := writeFileAtomic(filepath.Join(s.dir, worldFile), s.world.SharedSnapshot()); err != nil {
+		log.Printf("save world: %v", err)
+	}
+}
+
+// loadWorld возвращает мир, сохранённый прошлым запуском, если он есть
+func (s *Server) loadWorld() error {
+	if s.dir == "" {
+		return nil
+	}
+
+	path := filepath.Join(s.dir, worldFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	state := &SharedState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := s.world.RestoreShared(state); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (s *Server) playerFile(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// writeFileAtomic пишет json во временный файл и переименовывает, чтобы не оставить половину при падении
+func writeFileAtomic(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type client struct {
+	t     *testing.T
+	conn  net.Conn
+	lines *bufio.Scanner
+}
+
+func startServer(t *testing.T, dir string) (addr string, stop func()) {
+	t.Helper()
+	spec, err := ParseWorldSpec(defaultWorld, "yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w, err := NewWorld(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewServer(w, dir).Serve(ctx, ln) }()
+
+	return ln.Addr().String(), func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("serve: %v", err)
+		}
+	}
+}
+
+// connect входит под именем name и ждёт, пока игрок осмотрится
+func connect(t *testing.T, addr, name string) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := &client{t: t, conn: conn, lines: bufio.NewScanner(conn)}
+	c.expect("как тебя зовут?")
+	c.send(name)
+	c.expectPrefix("ты находишься на кухне")
+	return c
+}
+
+func (c *client) send(line string) {
+	c.t.Helper()
+	if _, err := fmt.Fprintf(c.conn, "%s\r\n", line); err != nil {
+		c.t.Fatalf("send %q: %v", line, err)
+	}
+}
+
+// next читает строки, пропуская чужие сообщения, пока не найдёт подходящую
+func (c *client) next(match func(string) bool) string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var seen []string
+	for c.lines.Scan() {
+		line := strings.TrimSuffix(c.lines.Text(), "\r")
+		if match(line) {
+			return line
+		}
+		seen = append(seen, line)
+	}
+	c.t.Fatalf("no expected line, got %q: %v", seen, c.lines.Err())
+	return ""
+}
+
+func (c *client) expect(want string) {
+	c.t.Helper()
+	c.next(func(line string) bool { return line == want })
+}
+
+func (c *client) expectPrefix(prefix string) string {
+	c.t.Helper()
+	return c.next(func(line string) bool { return strings.HasPrefix(line, prefix) })
+}
+
+// do выполняет команду и проверяет ответ
+func (c *client) do(command, want string) {
+	c.t.Helper()
+	c.send(command)
+	c.expect(want)
+}
+
+func TestServerPlayersSeeEachOther(t *testing.T) {
+	addr, stop := startServer(t, "")
+	defer stop()
+
+	anna := connect(t, addr, "анна")
+	bob := connect(t, addr, "боб")
+	anna.expect("боб пришёл")
+
+	anna.do("осмотреться", "ты находишься на кухне, на столе: чай, надо собрать рюкзак и идти в универ, здесь также: боб. можно пройти - коридор")
+	anna.do("сказать доброе утро", "вы сказали: доброе утро")
+	bob.expect("анна говорит: доброе утро")
+
+	bob.do("идти коридор", "ничего интересного. можно пройти - кухня, комната, улица")
+	anna.expect("боб ушёл в коридор")
+	// в другой локации не слышно, как говорят, но слышно, как кричат
+	anna.do("сказать где ты", "вы сказали: где ты")
+	anna.do("крикнуть боб", "вы крикнули: боб")
+	bob.expect("анна кричит: боб")
+
+	// мир общий: взятое одним пропадает у другого
+	bob.do("идти комната", "ты в своей комнате. можно пройти - коридор")
+	bob.do("надеть рюкзак", "вы надели: рюкзак")
+	anna.do("идти коридор", "ничего интересного. можно пройти - кухня, комната, улица")
+	anna.do("идти комната", "ты в своей комнате. можно пройти - коридор")
+	bob.expect("анна пришёл")
+	anna.do("осмотреться", "на столе: ключи, конспекты, здесь также: боб. можно пройти - коридор")
+	anna.do("взять ключи", "некуда класть")
+
+	bob.do("выйти", "до встречи")
+	anna.expect("боб ушёл")
+	anna.do("осмотреться", "на столе: ключи, конспекты. можно пройти - коридор")
+
+	// два игрока с одним именем в игре быть не могут
+	anna2, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dup := &client{t: t, conn: anna2, lines: bufio.NewScanner(anna2)}
+	dup.expect("как тебя зовут?")
+	dup.send("анна")
+	dup.expect("игрок анна уже в игре")
+	dup.send("анна боб")
+	dup.expectPrefix("имя - одно слово")
+	anna2.Close()
+}
+
+func TestServerSessions(t *testing.T) {
+	dir := t.TempDir()
+	addr, stop := startServer(t, dir)
+
+	anna := connect(t, addr, "анна")
+	anna.do("идти коридор", "ничего интересного. можно пройти - кухня, комната, улица")
+	anna.do("идти комната", "ты в своей комнате. можно пройти - коридор")
+	anna.do("надеть рюкзак", "вы надели: рюкзак")
+	anna.do("взять конспекты", "предмет добавлен в инвентарь: конспекты")
+	// обрыв связи, а не выход
+	anna.conn.Close()
+
+	// снова в игре - на том же месте и с теми же вещами
+	anna = connect2(t, addr, "анна", "на столе: ключи.")
+	anna.do("взять ключи", "предмет добавлен в инвентарь: ключи")
+	anna.do("идти коридор", "ничего интересного. можно пройти - кухня, комната, улица")
+	anna.do("идти кухня", "кухня, ничего интересного. можно пройти - коридор")
+	anna.do("осмотреться", "ты находишься на кухне, на столе: чай, надо идти в универ. можно пройти - коридор")
+
+	// при остановке сервера игроки сохраняются на диск
+	stop()
+	if _, err := os.Stat(filepath.Join(dir, "анна.json")); err != nil {
+		t.Fatalf("session is not saved: %v", err)
+	}
+
+	// а новый сервер с новым миром их оттуда берёт
+	addr, stop = startServer(t, dir)
+	anna = connect2(t, addr, "анна", "ты находишься на кухне")
+	anna.do("осмотреться", "ты находишься на кухне, на столе: чай, надо идти в универ. можно пройти - коридор")
+	anna.do("идти коридор", "ничего интересного. можно пройти - кухня, комната, улица")
+	anna.do("применить ключи дверь", "дверь открыта")
+	stop()
+
+	// мир тоже сохраняется: взятое анной не лежит снова на месте, а дверь открыта
+	addr, stop = startServer(t, dir)
+	defer stop()
+	boris := connect(t, addr, "борис")
+	boris.do("идти коридор", "ничего интересного. можно пройти - кухня, комната, улица")
+	boris.do("идти улица", "на улице весна. можно пройти - домой")
+	boris.do("идти домой", "ничего интересного. можно пройти - кухня, комната, улица")
+	boris.do("идти комната", "ты в своей комнате. можно пройти - коридор")
+	boris.do("осмотреться", "пустая комната. можно пройти - коридор")
+}
+
+// connect2 - вход уже известного игрока, который может стоять не на кухне
+func connect2(t *testing.T, addr, name, welcome string) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := &client{t: t, conn: conn, lines: bufio.NewScanner(conn)}
+	for {
+		c.expect("как тебя зовут?")
+		c.send(name)
+		// старое соединение могло ещё не закрыться на сервере
+		line := c.next(func(line string) bool {
+			return strings.HasPrefix(line, welcome) || strings.HasSuffix(line, "уже в игре")
+		})
+		if strings.HasPrefix(line, welcome) {
+			return c
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerConcurrentPlayers(t *testing.T) {
+	addr, stop := startServer(t, "")
+	defer stop()
+
+	const players = 10
+	var wg sync.WaitGroup
+	for i := 0; i < players; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := connect(t, addr, fmt.Sprintf("игрок%d", i))
+			defer c.conn.Close()
+			for j := 0; j < 20; j++ {
+				c.do("идти коридор", "ничего интересного. можно пройти - кухня, комната, улица")
+				c.do("сказать привет", "вы сказали: привет")
+				c.do("крикнуть эй", "вы крикнули: эй")
+				c.do("идти кухня", "кухня, ничего интересного. можно пройти - коридор")
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// historyLimit - сколько команд можно отменить подряд
+const historyLimit = 32
+
+// undoStep - состояние до команды, которая что-то поменяла в мире
+type undoStep struct {
+	command string
+	state   *WorldState
+}
+
+// одиночная игра: история для отмены и папка для сохранений
+var (
+	history []undoStep
+	saveDir = "."
+)
+
+func handleCommand(command string) string {
+	parts := strings.Split(command, " ")
+	switch parts[0] {
+	case "сохранить":
+		return saveGame(parts[1:]...)
+	case "загрузить":
+		return loadGame(parts[1:]...)
+	case "отменить":
+		return undo()
+	}
+
+	before := world.Snapshot(player)
+	answer := world.Handle(player, command)
+	if !reflect.DeepEqual(before, world.Snapshot(player)) {
+		remember(command, before)
+	}
+	return answer
+}
+
+func remember(command string, state *WorldState) {
+	if len(history) == historyLimit {
+		history = append(history[:0:0], history[1:]...)
+	}
+	history = append(history, undoStep{command: command, state: state})
+}
+
+// savePath - файл сохранения по имени, без имени - "игра"
+func savePath(args []string) (name, path string, ok bool) {
+	name = "игра"
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if !validName.MatchString(name) {
+		return name, "", false
+	}
+	return name, filepath.Join(saveDir, name+".json"), true
+}
+
+func saveGame(args ...string) string {
+	name, path, ok := savePath(args)
+	if !ok {
+		return "имя сохранения - одно слово из букв и цифр"
+	}
+
+	if err := SaveState(path, world.Snapshot(player)); err != nil {
+		return fmt.Sprintf("не удалось сохранить: %v", err)
+	}
+	return fmt.Sprintf("игра сохранена: %s", name)
+}
+
+func loadGame(args ...string) string {
+	name, path, ok := savePath(args)
+	if !ok {
+		return "имя сохранения - одно слово из букв и цифр"
+	}
+
+	state, err := LoadState(path)
+	if os.IsNotExist(err) {
+		return fmt.Sprintf("нет сохранения %s", name)
+	}
+	if err != nil {
+		return fmt.Sprintf("не удалось загрузить: %v", err)
+	}
+
+	before := world.Snapshot(player)
+	if err := world.Restore(player, state); err != nil {
+		return fmt.Sprintf("не удалось загрузить: %v", err)
+	}
+	// загрузку тоже можно отменить
+	remember("загрузить "+name, before)
+	return fmt.Sprintf("игра загружена: %s", name)
+}
+
+func undo() string {
+	if len(history) == 0 {
+		return "нечего отменять"
+	}
+
+	last := history[len(history)-1]
+	history = history[:len(history)-1]
+	if err := world.Restore(player, last.state); err != nil {
+		return fmt.Sprintf("не удалось отменить: %v", err)
+	}
+	return fmt.Sprintf("отменено: %s", last.command)
+}
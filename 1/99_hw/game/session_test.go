@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newSession(t *testing.T) {
+	t.Helper()
+	initGame()
+	saveDir = t.TempDir()
+	t.Cleanup(func() { saveDir = "." })
+}
+
+func TestSaveLoad(t *testing.T) {
+	newSession(t)
+
+	runCases(t, "save", []gameCase{
+		{1, "идти коридор", "ничего интересного. можно пройти - кухня, комната, улица"},
+		{2, "идти комната", "ты в своей комнате. можно пройти - коридор"},
+		{3, "надеть рюкзак", "вы надели: рюкзак"},
+		{4, "взять ключи", "предмет добавлен в инвентарь: ключи"},
+		{5, "сохранить", "игра сохранена: игра"},
+		{6, "взять конспекты", "предмет добавлен в инвентарь: конспекты"},
+		{7, "идти коридор", "ничего интересного. можно пройти - кухня, комната, улица"},
+		{8, "применить ключи дверь", "дверь открыта"},
+		{9, "сохранить утро", "игра сохранена: утро"},
+		{10, "идти улица", "на улице весна. можно пройти - домой"},
+		// вернулись в комнату: конспекты снова на столе, задачи не выполнены
+		{11, "загрузить", "игра загружена: игра"},
+		{12, "осмотреться", "на столе: конспекты. можно пройти - коридор"},
+		{13, "идти коридор", "ничего интересного. можно пройти - кухня, комната, улица"},
+		{14, "идти кухня", "кухня, ничего интересного. можно пройти - коридор"},
+		{15, "осмотреться", "ты находишься на кухне, на столе: чай, надо собрать рюкзак и идти в универ. можно пройти - коридор"},
+		// а дверь снова закрыта
+		{16, "идти коридор", "ничего интересного. можно пройти - кухня, комната, улица"},
+		{17, "идти улица", "дверь закрыта"},
+		{18, "загрузить утро", "игра загружена: утро"},
+		{19, "идти улица", "на улице весна. можно пройти - домой"},
+		{20, "загрузить вечер", "нет сохранения вечер"},
+		{21, "сохранить ../игра", "имя сохранения - одно слово из букв и цифр"},
+	})
+
+	// новая игра продолжает сохранённую
+	initGame()
+	runCases(t, "resume", []gameCase{
+		{1, "загрузить утро", "игра загружена: утро"},
+		{2, "идти улица", "на улице весна. можно пройти - домой"},
+		{3, "идти домой", "ничего интересного. можно пройти - кухня, комната, улица"},
+		{4, "идти кухня", "кухня, ничего интересного. можно пройти - коридор"},
+		{5, "осмотреться", "ты находишься на кухне, на столе: чай, надо идти в универ. можно пройти - коридор"},
+	})
+}
+
+func TestUndo(t *testing.T) {
+	newSession(t)
+
+	runCases(t, "undo", []gameCase{
+		{1, "отменить", "нечего отменять"},
+		{2, "идти коридор", "ничего интересного. можно пройти - кухня, комната, улица"},
+		{3, "идти комната", "ты в своей комнате. можно пройти - коридор"},
+		{4, "надеть рюкзак", "вы надели: рюкзак"},
+		{5, "взять конспекты", "предмет добавлен в инвентарь: конспекты"},
+		// осмотреться и неудачные команды ничего не меняют и не отменяются
+		{6, "осмотреться", "на столе: ключи. можно пройти - коридор"},
+		{7, "взять телефон", "нет такого"},
+		{8, "отменить", "отменено: взять конспекты"},
+		{9, "осмотреться", "на столе: ключи, конспекты. можно пройти - коридор"},
+		{10, "взять ключи", "предмет добавлен в инвентарь: ключи"},
+		{11, "идти коридор", "ничего интересного. можно пройти - кухня, комната, улица"},
+		{12, "применить ключи дверь", "дверь открыта"},
+		{13, "отменить", "отменено: применить ключи дверь"},
+		{14, "идти улица", "дверь закрыта"},
+		{15, "сохранить", "игра сохранена: игра"},
+		{16, "идти комната", "ты в своей комнате. можно пройти - коридор"},
+		{17, "загрузить", "игра загружена: игра"},
+		{18, "отменить", "отменено: загрузить игра"},
+		{19, "осмотреться", "на столе: конспекты. можно пройти - коридор"},
+		{20, "отменить", "отменено: идти комната"},
+		{21, "отменить", "отменено: идти коридор"},
+		{22, "отменить", "отменено: взять ключи"},
+		{23, "отменить", "отменено: надеть рюкзак"},
+		{24, "осмотреться", "на столе: ключи, конспекты, на стуле: рюкзак. можно пройти - коридор"},
+		{25, "отменить", "отменено: идти комната"},
+		{26, "отменить", "отменено: идти коридор"},
+		{27, "отменить", "нечего отменять"},
+		{28, "осмотреться", "ты находишься на кухне, на столе: чай, надо собрать рюкзак и идти в универ. можно пройти - коридор"},
+	})
+}
+
+func TestUndoLimit(t *testing.T) {
+	newSession(t)
+
+	for i := 0; i < historyLimit; i++ {
+		handleCommand("идти коридор")
+		handleCommand("идти кухня")
+	}
+	for i := 0; i < historyLimit; i++ {
+		if answer := handleCommand("отменить"); !strings.HasPrefix(answer, "отменено: ") {
+			t.Fatalf("undo %d: %s", i, answer)
+		}
+	}
+	if answer := handleCommand("отменить"); answer != "нечего отменять" {
+		t.Fatalf("history is not bounded: %s", answer)
+	}
+}
+
+func TestLoadStateErrors(t *testing.T) {
+	newSession(t)
+	handleCommand("идти коридор")
+	before := world.Snapshot(player)
+
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(saveDir, name+".json"), []byte(data), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	write("старая", `{"version": 0}`)
+	write("битая", `{"version": 1,`)
+
+	bad := world.Snapshot(player)
+	bad.Locked = append(bad.Locked, ExitRef{Location: "кухня", Exit: "подвал"})
+	if err := SaveState(filepath.Join(saveDir, "чужая.json"), bad); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]string{
+		"старая": "unsupported state version 0, want 1",
+		"битая":  "unexpected end of JSON input",
+		"чужая":  `unknown exit "подвал" of "кухня"`,
+	}
+	for name, want := range cases {
+		answer := handleCommand("загрузить " + name)
+		if !strings.HasPrefix(answer, "не удалось загрузить: ") || !strings.Contains(answer, want) {
+			t.Errorf("%s: got %q, want error %q", name, answer, want)
+		}
+	}
+
+	// неудачная загрузка ничего не меняет
+	if after := world.Snapshot(player); !reflect.DeepEqual(before, after) {
+		t.Fatalf("state changed after failed loads:\n%+v\n%+v", after, before)
+	}
+}
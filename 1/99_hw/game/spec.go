@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WorldSpec - описание мира в файле, см. world.yaml
+type WorldSpec struct {
+	Start string   `yaml:"start" json:"start"`
+	Tasks []string `yaml:"tasks" json:"tasks"`
+	// Items - свойства предметов, у обычного предмета их нет и описывать его не надо
+	Items     map[string]ItemSpec `yaml:"items" json:"items"`
+	Locations []LocationSpec      `yaml:"locations" json:"locations"`
+	Rules     []RuleSpec          `yaml:"rules" json:"rules"`
+}
+
+type ItemSpec struct {
+	Wearable bool `yaml:"wearable" json:"wearable"`
+	// Container - надетый, даёт класть предметы в инвентарь
+	Container bool `yaml:"container" json:"container"`
+}
+
+type LocationSpec struct {
+	Name string `yaml:"name" json:"name"`
+	// Description показывается при осмотре, Welcome - при входе
+	Description string `yaml:"description" json:"description"`
+	Welcome     string `yaml:"welcome" json:"welcome"`
+	// Empty - что сказать при осмотре, когда предметов нет
+	Empty     string      `yaml:"empty" json:"empty"`
+	ShowTasks bool        `yaml:"show_tasks" json:"show_tasks"`
+	Exits     []ExitSpec  `yaml:"exits" json:"exits"`
+	Places    []PlaceSpec `yaml:"places" json:"places"`
+	// Fixtures - то, что нельзя взять, но можно к чему-то применить, например дверь
+	Fixtures []string `yaml:"fixtures" json:"fixtures"`
+}
+
+type ExitSpec struct {
+	Name string `yaml:"name" json:"name"`
+	// To - куда ведёт выход, по умолчанию в локацию Name
+	To     string `yaml:"to" json:"to"`
+	Locked bool   `yaml:"locked" json:"locked"`
+}
+
+type PlaceSpec struct {
+	Name  string   `yaml:"name" json:"name"`
+	Items []string `yaml:"items" json:"items"`
+}
+
+// RuleSpec - когда выполнено условие When, срабатывают эффекты Then по порядку
+type RuleSpec struct {
+	When Condition `yaml:"when" json:"when"`
+	Then []Effect  `yaml:"then" json:"then"`
+}
+
+// Condition - пустые поля не проверяются
+type Condition struct {
+	// Action - команда: идти, взять, надеть или применить
+	Action string `yaml:"action" json:"action"`
+	Item   string `yaml:"item" json:"item"`
+	// Target - к чему применяют или куда идут
+	Target   string `yaml:"target" json:"target"`
+	Location string `yaml:"location" json:"location"`
+	// Has - предметы, которые должны быть в инвентаре, Done - выполненные задачи
+	Has  []string `yaml:"has" json:"has"`
+	Done []string `yaml:"done" json:"done"`
+}
+
+// Effect - у эффекта заполнено ровно одно поле
+type Effect struct {
+	Say      string   `yaml:"say" json:"say"`
+	Complete string   `yaml:"complete" json:"complete"`
+	Unlock   *ExitRef `yaml:"unlock" json:"unlock"`
+	Lock     *ExitRef `yaml:"lock" json:"lock"`
+	// Give кладёт предмет в инвентарь, Destroy убирает его из инвентаря
+	Give    string `yaml:"give" json:"give"`
+	Destroy string `yaml:"destroy" json:"destroy"`
+}
+
+type ExitRef struct {
+	Location string `yaml:"location" json:"location"`
+	Exit     string `yaml:"exit" json:"exit"`
+}
+
+// ParseWorldSpec разбирает yaml или json, format - "yaml" или "json"
+func ParseWorldSpec(data []byte, format string) (*WorldSpec, error) {
+	spec := &WorldSpec{}
+	switch format {
+	case "yaml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(spec); err != nil {
+			return nil, err
+		}
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(spec); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown world format %q", format)
+	}
+	return spec, nil
+}
+
+// LoadWorldSpec читает файл мира, формат по расширению
+func LoadWorldSpec(path string) (*WorldSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := "yaml"
+	if filepath.Ext(path) == ".json" {
+		format = "json"
+	}
+
+	spec, err := ParseWorldSpec(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// stateVersion меняется, когда меняется формат WorldState
+const stateVersion = 1
+
+// PlayerState - игрок между сессиями: где он, что у него и что осталось сделать
+type PlayerState struct {
+	Name     string   `json:"name"`
+	Location string   `json:"location"`
+	Items    []string `json:"items"`
+	Worn     []string `json:"worn"`
+	Tasks    []string `json:"tasks"`
+}
+
+// WorldState - всё, что меняется в одиночной игре: игрок, предметы по местам и закрытые двери
+type WorldState struct {
+	Version int         `json:"version"`
+	Player  PlayerState `json:"player"`
+	// Places - места в локациях с предметами, в том же порядке, что в мире
+	Places map[string][]PlaceSpec `json:"places"`
+	Locked []ExitRef              `json:"locked"`
+}
+
+// SharedState - общий мир сервера без игроков, хранится рядом с ними:
+// иначе после перезапуска взятые игроками предметы снова лежат на местах
+type SharedState struct {
+	Version int                    `json:"version"`
+	Places  map[string][]PlaceSpec `json:"places"`
+	Locked  []ExitRef              `json:"locked"`
+}
+
+func (p *Player) state() PlayerState {
+	return PlayerState{
+		Name:     p.name,
+		Location: p.location.name,
+		Items:    append([]string(nil), p.items...),
+		Worn:     append([]string(nil), p.worn...),
+		Tasks:    append([]string(nil), p.tasks...),
+	}
+}
+
+// restorePlayer возвращает игрока из состояния, локация должна быть в мире
+func (w *World) restorePlayer(s PlayerState) (*Player, error) {
+	loc, ok := w.locations[s.Location]
+	if !ok {
+		return nil, fmt.Errorf("unknown location %q of player %s", s.Location, s.Name)
+	}
+
+	return &Player{
+		name:     s.Name,
+		location: loc,
+		items:    append([]string(nil), s.Items...),
+		worn:     append([]string(nil), s.Worn...),
+		tasks:    append([]string(nil), s.Tasks...),
+	}, nil
+}
+
+// Snapshot запоминает мир вместе с игроком p
+func (w *World) Snapshot(p *Player) *WorldState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	places, locked := w.shared()
+	return &WorldState{
+		Version: stateVersion,
+		Player:  p.state(),
+		Places:  places,
+		Locked:  locked,
+	}
+}
+
+// SharedSnapshot запоминает мир без игроков
+func (w *World) SharedSnapshot() *SharedState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	places, locked := w.shared()
+	return &SharedState{Version: stateVersion, Places: places, Locked: locked}
+}
+
+// RestoreShared возвращает мир к состоянию s, игроки остаются как есть
+func (w *World) RestoreShared(s *SharedState) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if s.Version != stateVersion {
+		return fmt.Errorf("unsupported state version %d, want %d", s.Version, stateVersion)
+	}
+	locked, err := w.checkShared(s.Places, s.Locked)
+	if err != nil {
+		return err
+	}
+	w.applyShared(s.Places, locked)
+	return nil
+}
+
+// shared - предметы по местам и закрытые двери, mu должен быть взят
+func (w *World) shared() (map[string][]PlaceSpec, []ExitRef) {
+	places := make(map[string][]PlaceSpec, len(w.locations))
+	locked := make([]ExitRef, 0)
+	for name, l := range w.locations {
+		lp := make([]PlaceSpec, len(l.places))
+		for i, pl := range l.places {
+			lp[i] = PlaceSpec{Name: pl.name, Items: append([]string{}, pl.items...)}
+		}
+		places[name] = lp
+
+		for _, e := range l.exits {
+			if e.locked {
+				locked = append(locked, ExitRef{Location: name, Exit: e.name})
+			}
+		}
+	}
+	sort.Slice(locked, func(i, j int) bool {
+		if locked[i].Location != locked[j].Location {
+			return locked[i].Location < locked[j].Location
+		}
+		return locked[i].Exit < locked[j].Exit
+	})
+	return places, locked
+}
+
+// checkShared проверяет, что места и двери те же, что в мире, и возвращает закрытые выходы
+func (w *World) checkShared(places map[string][]PlaceSpec, refs []ExitRef) (map[*Exit]bool, error) {
+	for name := range places {
+		if _, ok := w.locations[name]; !ok {
+			return nil, fmt.Errorf("unknown location %q", name)
+		}
+	}
+	for name, l := range w.locations {
+		lp, ok := places[name]
+		if !ok {
+			return nil, fmt.Errorf("no places of location %q", name)
+		}
+		if len(lp) != len(l.places) {
+			return nil, fmt.Errorf("location %q has %d places, not %d", name, len(l.places), len(lp))
+		}
+		for i, pl := range lp {
+			if pl.Name != l.places[i].name {
+				return nil, fmt.Errorf("unknown place %q in %q", pl.Name, name)
+			}
+		}
+	}
+	locked := make(map[*Exit]bool, len(refs))
+	for _, ref := range refs {
+		l, ok := w.locations[ref.Location]
+		if !ok || l.exit(ref.Exit) == nil {
+			return nil, fmt.Errorf("unknown exit %q of %q", ref.Exit, ref.Location)
+		}
+		locked[l.exit(ref.Exit)] = true
+	}
+	return locked, nil
+}
+
+// applyShared раскладывает предметы и закрывает двери, всё уже проверено checkShared
+func (w *World) applyShared(places map[string][]PlaceSpec, locked map[*Exit]bool) {
+	for name, l := range w.locations {
+		for i, pl := range l.places {
+			pl.items = append([]string(nil), places[name][i].Items...)
+		}
+		for _, e := range l.exits {
+			e.locked = locked[e]
+		}
+	}
+}
+
+// Restore возвращает мир и игрока p к состоянию s.
+// Состояние сначала целиком проверяется, так что при ошибке ничего не меняется
+func (w *World) Restore(p *Player, s *WorldState) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if s.Version != stateVersion {
+		return fmt.Errorf("unsupported state version %d, want %d", s.Version, stateVersion)
+	}
+	restored, err := w.restorePlayer(s.Player)
+	if err != nil {
+		return err
+	}
+	locked, err := w.checkShared(s.Places, s.Locked)
+	if err != nil {
+		return err
+	}
+
+	w.applyShared(s.Places, locked)
+	// имя и send остаются свои: это тот же игрок, что и был
+	p.location, p.items, p.worn, p.tasks = restored.location, restored.items, restored.worn, restored.tasks
+	return nil
+}
+
+// SaveState пишет состояние в файл
+func SaveState(path string, s *WorldState) error {
+	return writeFileAtomic(path, s)
+}
+
+// LoadState читает сохранение, версия должна совпадать с текущей
+func LoadState(path string) (*WorldState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &WorldState{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Version != stateVersion {
+		return nil, fmt.Errorf("%s: unsupported state version %d, want %d", path, s.Version, stateVersion)
+	}
+	return s, nil
+}
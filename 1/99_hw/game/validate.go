@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WorldError перечисляет все ошибки в описании мира, чтобы исправить их за раз
+type WorldError struct {
+	Problems []string
+}
+
+func (e *WorldError) Error() string {
+	return "invalid world: " + strings.Join(e.Problems, "; ")
+}
+
+var ruleActions = map[string]bool{
+	"идти":      true,
+	"взять":     true,
+	"надеть":    true,
+	"применить": true,
+}
+
+type validator struct {
+	spec     *WorldSpec
+	problems []string
+
+	locations map[string]*LocationSpec
+	// placed - где лежит предмет, given - какие предметы выдают правила
+	placed map[string]string
+	given  map[string]bool
+	tasks  map[string]bool
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// Validate проверяет ссылки между локациями, предметами, задачами и правилами,
+// а потом - что из начальной локации можно добраться до всех локаций и предметов
+func (s *WorldSpec) Validate() error {
+	v := &validator{
+		spec:      s,
+		locations: make(map[string]*LocationSpec),
+		placed:    make(map[string]string),
+		given:     make(map[string]bool),
+		tasks:     make(map[string]bool),
+	}
+
+	v.checkLocations()
+	v.checkRules()
+	v.checkItems()
+	if len(v.problems) == 0 {
+		v.checkReachability()
+	}
+
+	if len(v.problems) != 0 {
+		sort.Strings(v.problems)
+		return &WorldError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (v *validator) checkLocations() {
+	for _, t := range v.spec.Tasks {
+		if v.tasks[t] {
+			v.addf("task %q is defined twice", t)
+		}
+		v.tasks[t] = true
+	}
+
+	for i := range v.spec.Locations {
+		l := &v.spec.Locations[i]
+		if l.Name == "" {
+			v.addf("location #%d has no name", i)
+			continue
+		}
+		if _, ok := v.locations[l.Name]; ok {
+			v.addf("location %q is defined twice", l.Name)
+		}
+		v.locations[l.Name] = l
+	}
+
+	if _, ok := v.locations[v.spec.Start]; !ok {
+		v.addf("start location %q is unknown", v.spec.Start)
+	}
+
+	for _, l := range v.locations {
+		exits := make(map[string]bool)
+		for _, e := range l.Exits {
+			if exits[e.Name] {
+				v.addf("exit %q of %q is defined twice", e.Name, l.Name)
+			}
+			exits[e.Name] = true
+			if _, ok := v.locations[exitTarget(e)]; !ok {
+				v.addf("exit %q of %q leads to unknown location %q", e.Name, l.Name, exitTarget(e))
+			}
+		}
+
+		for _, p := range l.Places {
+			for _, item := range p.Items {
+				if where, ok := v.placed[item]; ok {
+					v.addf("item %q is placed in %q and %q", item, where, l.Name)
+				}
+				v.placed[item] = l.Name
+			}
+		}
+	}
+}
+
+func (v *validator) checkRules() {
+	for _, r := range v.spec.Rules {
+		for _, e := range r.Then {
+			if e.Give != "" {
+				v.given[e.Give] = true
+			}
+		}
+	}
+
+	for i, r := range v.spec.Rules {
+		name := fmt.Sprintf("rule #%d", i)
+		w := r.When
+
+		if !ruleActions[w.Action] {
+			v.addf("%s: unknown action %q", name, w.Action)
+		}
+		var loc *LocationSpec
+		if w.Location != "" {
+			if loc = v.locations[w.Location]; loc == nil {
+				v.addf("%s: unknown location %q", name, w.Location)
+			}
+		}
+		for _, item := range append([]string{w.Item}, w.Has...) {
+			if item != "" && !v.isItem(item) {
+				v.addf("%s: unknown item %q", name, item)
+			}
+		}
+		if w.Target != "" && !v.isTarget(w.Action, w.Target, loc) {
+			v.addf("%s: unknown target %q", name, w.Target)
+		}
+		for _, t := range w.Done {
+			if !v.tasks[t] {
+				v.addf("%s: unknown task %q", name, t)
+			}
+		}
+
+		if len(r.Then) == 0 {
+			v.addf("%s: no effects", name)
+		}
+		for j, e := range r.Then {
+			v.checkEffect(fmt.Sprintf("%s effect #%d", name, j), e)
+		}
+	}
+}
+
+func (v *validator) checkEffect(name string, e Effect) {
+	set := 0
+	for _, ok := range []bool{e.Say != "", e.Complete != "", e.Unlock != nil, e.Lock != nil, e.Give != "", e.Destroy != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		v.addf("%s: must have exactly one of say, complete, unlock, lock, give, destroy", name)
+	}
+
+	if e.Complete != "" && !v.tasks[e.Complete] {
+		v.addf("%s: unknown task %q", name, e.Complete)
+	}
+	for _, ref := range []*ExitRef{e.Unlock, e.Lock} {
+		if ref != nil && v.exit(ref) == nil {
+			v.addf("%s: unknown exit %q of %q", name, ref.Exit, ref.Location)
+		}
+	}
+	if e.Destroy != "" && !v.isItem(e.Destroy) {
+		v.addf("%s: unknown item %q", name, e.Destroy)
+	}
+}
+
+func (v *validator) checkItems() {
+	for item, props := range v.spec.Items {
+		if _, ok := v.placed[item]; !ok && !v.given[item] {
+			v.addf("item %q is neither placed nor given", item)
+		}
+		if props.Container && !props.Wearable {
+			v.addf("container %q must be wearable", item)
+		}
+	}
+}
+
+// checkReachability обходит мир от начальной локации, открывая двери правилами,
+// которые могут сработать: в их локацию можно попасть, а нужные предметы - достать
+func (v *validator) checkReachability() {
+	reached := map[string]bool{v.spec.Start: true}
+	obtainable := make(map[string]bool)
+	unlocked := make(map[ExitRef]bool)
+	fired := make([]bool, len(v.spec.Rules))
+
+	for changed := true; changed; {
+		changed = false
+
+		for name := range reached {
+			l := v.locations[name]
+			for _, e := range l.Exits {
+				to := exitTarget(e)
+				if !reached[to] && (!e.Locked || unlocked[ExitRef{Location: name, Exit: e.Name}]) {
+					reached[to] = true
+					changed = true
+				}
+			}
+			for _, p := range l.Places {
+				for _, item := range p.Items {
+					obtainable[item] = true
+				}
+			}
+		}
+
+		for i, r := range v.spec.Rules {
+			if fired[i] || !v.canFire(r.When, reached, obtainable) {
+				continue
+			}
+			fired[i], changed = true, true
+			for _, e := range r.Then {
+				if e.Unlock != nil {
+					unlocked[*e.Unlock] = true
+				}
+				if e.Give != "" {
+					obtainable[e.Give] = true
+				}
+			}
+		}
+	}
+
+	for _, l := range v.spec.Locations {
+		if reached[l.Name] {
+			continue
+		}
+		v.addf("location %q is unreachable", l.Name)
+		for _, p := range l.Places {
+			for _, item := range p.Items {
+				v.addf("item %q is in unreachable location %q", item, l.Name)
+			}
+		}
+	}
+	for i, ok := range fired {
+		if !ok {
+			v.addf("rule #%d can never fire", i)
+		}
+	}
+}
+
+func (v *validator) canFire(w Condition, reached, obtainable map[string]bool) bool {
+	if w.Location != "" && !reached[w.Location] {
+		return false
+	}
+	for _, item := range append([]string{w.Item}, w.Has...) {
+		if item != "" && !obtainable[item] {
+			return false
+		}
+	}
+	return w.Action != "идти" || w.Target == "" || reached[v.exitTargetByName(w.Location, w.Target)]
+}
+
+func (v *validator) isItem(item string) bool {
+	_, placed := v.placed[item]
+	return placed || v.given[item]
+}
+
+// isTarget: применяют к предмету или к тому, что есть в локации, идут в выход
+func (v *validator) isTarget(action, target string, loc *LocationSpec) bool {
+	for _, l := range v.spec.Locations {
+		if loc != nil && l.Name != loc.Name {
+			continue
+		}
+		if action == "идти" {
+			for _, e := range l.Exits {
+				if e.Name == target {
+					return true
+				}
+			}
+			continue
+		}
+		for _, f := range l.Fixtures {
+			if f == target {
+				return true
+			}
+		}
+	}
+	return action != "идти" && v.isItem(target)
+}
+
+func (v *validator) exit(ref *ExitRef) *ExitSpec {
+	l, ok := v.locations[ref.Location]
+	if !ok {
+		return nil
+	}
+	for i := range l.Exits {
+		if l.Exits[i].Name == ref.Exit {
+			return &l.Exits[i]
+		}
+	}
+	return nil
+}
+
+// exitTargetByName - куда ведёт выход name, без локации - любой выход с таким именем
+func (v *validator) exitTargetByName(location, name string) string {
+	for _, l := range v.spec.Locations {
+		if location != "" && l.Name != location {
+			continue
+		}
+		for _, e := range l.Exits {
+			if e.Name == name {
+				return exitTarget(e)
+			}
+		}
+	}
+	return ""
+}
+
+func exitTarget(e ExitSpec) string {
+	if e.To != "" {
+		return e.To
+	}
+	return e.Name
+}
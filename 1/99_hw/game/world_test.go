@@ -0,0 +1,196 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func runCases(t *testing.T, name string, cases []gameCase) {
+	t.Helper()
+	for _, item := range cases {
+		answer := handleCommand(item.command)
+		if answer != item.answer {
+			t.Error("world:", name, item.step,
+				"\n\tcmd:", item.command,
+				"\n\tresult:  ", answer,
+				"\n\texpected:", item.answer)
+		}
+	}
+}
+
+// те же сценарии на мире из json
+func TestGameJSONWorld(t *testing.T) {
+	yamlSpec, err := ParseWorldSpec(defaultWorld, "yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	jsonSpec, err := LoadWorldSpec("testdata/world.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(yamlSpec, jsonSpec) {
+		t.Fatalf("json world differs from the default one:\n%+v\n%+v", jsonSpec, yamlSpec)
+	}
+
+	for _, cases := range game0cases {
+		if err := startGame(jsonSpec); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		runCases(t, "json", cases)
+	}
+}
+
+const rulesWorld = `
+start: подвал
+tasks: [выбраться, найти монету]
+locations:
+  - name: подвал
+    welcome: темно
+    exits:
+      - {name: люк, to: чердак, locked: true}
+    places:
+      - {name: на полу, items: [лом, сундук]}
+    fixtures: [люк]
+  - name: чердак
+    welcome: светло
+    exits:
+      - {name: вниз, to: подвал}
+items:
+  рюкзак: {wearable: true, container: true}
+rules:
+  - when: {action: применить, item: монета, target: люк}
+    then:
+      - unlock: {location: подвал, exit: люк}
+      - destroy: монета
+      - say: люк открыт
+  - when: {action: надеть, item: рюкзак}
+    then:
+      - give: монета
+      - complete: найти монету
+      - say: в кармане что-то звякнуло
+  - when: {action: идти, target: люк, done: [найти монету]}
+    then:
+      - complete: выбраться
+      - lock: {location: подвал, exit: люк}
+      - say: люк захлопнулся
+`
+
+func TestRules(t *testing.T) {
+	spec, err := ParseWorldSpec([]byte(strings.Replace(rulesWorld, "[лом, сундук]", "[лом, рюкзак]", 1)), "yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := startGame(spec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runCases(t, "rules", []gameCase{
+		{1, "идти люк", "дверь закрыта"},
+		{2, "применить лом люк", "нет предмета в инвентаре - лом"},
+		{3, "взять лом", "некуда класть"},
+		{4, "надеть рюкзак", "вы надели: рюкзак. в кармане что-то звякнуло"},
+		{5, "применить монета лом", "не к чему применить"},
+		{6, "применить монета люк", "люк открыт"},
+		{7, "применить монета люк", "нет предмета в инвентаре - монета"},
+		{8, "идти люк", "светло. можно пройти - вниз. люк захлопнулся"},
+		{9, "идти вниз", "темно. можно пройти - люк"},
+		{10, "идти люк", "дверь закрыта"},
+		{11, "идти", "недостаточно аргументов для выполнения команды"},
+	})
+	if len(player.tasks) != 0 {
+		t.Errorf("expected all tasks done, got %v", player.tasks)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	_, err := NewWorld(mustSpec(t, rulesWorld))
+	werr, ok := err.(*WorldError)
+	if !ok {
+		t.Fatalf("expected WorldError, got %v", err)
+	}
+	// рюкзака нигде нет - монету не достать, люк не открыть
+	expected := []string{
+		`item "рюкзак" is neither placed nor given`,
+		`rule #1: unknown item "рюкзак"`,
+	}
+	if !reflect.DeepEqual(werr.Problems, expected) {
+		t.Errorf("expected %q, got %q", expected, werr.Problems)
+	}
+
+	_, err = NewWorld(mustSpec(t, strings.Replace(rulesWorld, "[лом, сундук]}", "[лом]}\n      - {name: в углу, items: [рюкзак]}", 1)+`
+  - when: {action: применить, item: лом, target: окно, location: кладовка}
+    then: [{say: a, complete: b}, {unlock: {location: чердак, exit: люк}}]
+`))
+	werr, ok = err.(*WorldError)
+	if !ok {
+		t.Fatalf("expected WorldError, got %v", err)
+	}
+	expected = []string{
+		`rule #3 effect #0: must have exactly one of say, complete, unlock, lock, give, destroy`,
+		`rule #3 effect #0: unknown task "b"`,
+		`rule #3 effect #1: unknown exit "люк" of "чердак"`,
+		`rule #3: unknown location "кладовка"`,
+		`rule #3: unknown target "окно"`,
+	}
+	if !reflect.DeepEqual(werr.Problems, expected) {
+		t.Errorf("expected %q, got %q", expected, werr.Problems)
+	}
+}
+
+func TestValidateReachability(t *testing.T) {
+	// без правила с монетой люк не открыть
+	world := strings.Replace(rulesWorld, "[лом, сундук]", "[лом, рюкзак]", 1)
+	world = strings.Replace(world, "target: люк}\n    then:\n      - unlock", "target: люк}\n    then:\n      - say: заперто\n      - lock", 1)
+
+	_, err := NewWorld(mustSpec(t, world))
+	werr, ok := err.(*WorldError)
+	if !ok {
+		t.Fatalf("expected WorldError, got %v", err)
+	}
+	expected := []string{
+		`location "чердак" is unreachable`,
+		`rule #2 can never fire`,
+	}
+	if !reflect.DeepEqual(werr.Problems, expected) {
+		t.Errorf("expected %q, got %q", expected, werr.Problems)
+	}
+
+	spec := mustSpec(t, `
+start: a
+locations:
+  - {name: a, exits: [{name: b, locked: true}, {name: x}]}
+  - {name: b, places: [{name: на полу, items: [ключ]}]}
+`)
+	_, err = NewWorld(spec)
+	if err == nil || err.Error() != `invalid world: exit "x" of "a" leads to unknown location "x"` {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	spec.Locations[0].Exits = spec.Locations[0].Exits[:1]
+	_, err = NewWorld(spec)
+	if err == nil || err.Error() != `invalid world: item "ключ" is in unreachable location "b"; location "b" is unreachable` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseWorldSpecErrors(t *testing.T) {
+	if _, err := ParseWorldSpec([]byte("start: a\nfoo: 1\n"), "yaml"); err == nil {
+		t.Errorf("expected unknown field error")
+	}
+	if _, err := ParseWorldSpec([]byte(`{"start": "a", "foo": 1}`), "json"); err == nil {
+		t.Errorf("expected unknown field error")
+	}
+	if _, err := ParseWorldSpec(nil, "toml"); err == nil {
+		t.Errorf("expected unknown format error")
+	}
+}
+
+func mustSpec(t *testing.T, src string) *WorldSpec {
+	t.Helper()
+	spec, err := ParseWorldSpec([]byte(src), "yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return spec
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// Args - аргументы команды, уже проверенные по её описанию
+type Args struct {
+	// ID - id задачи, только у команд с Command.ID
+	ID int
+	// Text - всё, что после команды и id
+	Text string
+	// Workspace - пространство, в котором выполняется команда
+	Workspace *Workspace
+}
+
+type cmdHandler func(upd *tgbotapi.Update, args Args) (map[int]string, error)
+
+// argMode - нужен ли команде текст после неё
+type argMode int
+
+const (
+	argNone argMode = iota
+	argOptional
+	argRequired
+)
+
+// Command - команда бота: как её разбирать, как о ней рассказать в /help и кто её выполняет
+type Command struct {
+	Name string
+	// ID - команде нужен id задачи: /assign_5 или /assign 5
+	ID   bool
+	Text argMode
+	// Usage - формат для ошибок и /help, пустой - только имя и id
+	Usage   string
+	Help    string
+	Handler cmdHandler
+}
+
+// Format - как правильно написать команду
+func (c *Command) Format() string {
+	if c.Usage != "" {
+		return c.Usage
+	}
+	if c.ID {
+		return c.Name + "_$ID"
+	}
+	return c.Name
+}
+
+// parseArgs проверяет id и текст по описанию команды, false - команда написана неверно
+func (c *Command) parseArgs(line cmdLine) (Args, bool) {
+	var args Args
+	rest := line.Rest
+	if c.ID {
+		id := line.ID
+		if id == "" {
+			id, rest = cutWord(rest)
+		}
+		n, err := strconv.Atoi(id)
+		if err != nil || n <= 0 {
+			return Args{}, false
+		}
+		args.ID = n
+	} else if line.ID != "" {
+		return Args{}, false
+	}
+
+	switch {
+	case c.Text == argNone && rest != "":
+		return Args{}, false
+	case c.Text == argRequired && rest == "":
+		return Args{}, false
+	}
+	args.Text = rest
+	return args, true
+}
+
+// cmdLine - команда, как её написал пользователь
+type cmdLine struct {
+	// Name - "/assign" в нижнем регистре
+	Name string
+	// ID - то, что было после "_" в самой команде, может быть пустым
+	ID string
+	// Mention - бот из "/assign_5@taskbot", пустой, если бота не упомянули
+	Mention string
+	// Rest - текст после команды без пробелов по краям
+	Rest string
+}
+
+// splitCmd разбирает "/cmd_5 текст", "/cmd 5 текст" и "/cmd_5@bot текст".
+// Имя бота может содержать "_", поэтому упоминание отрезается раньше id
+func splitCmd(text string) (cmdLine, bool) {
+	var line cmdLine
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return line, false
+	}
+
+	head := text
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		head, line.Rest = text[:i], strings.TrimSpace(text[i:])
+	}
+	if i := strings.IndexByte(head, '@'); i >= 0 {
+		head, line.Mention = head[:i], head[i+1:]
+	}
+	if i := strings.IndexByte(head, '_'); i >= 0 {
+		head, line.ID = head[:i], head[i+1:]
+	}
+	if len(head) < 2 {
+		return cmdLine{}, false
+	}
+	line.Name = strings.ToLower(head)
+	return line, true
+}
+
+// cutWord - первое слово и всё остальное
+func cutWord(s string) (string, string) {
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// commandMessage - сообщение с командой: обычное или собранное из нажатия на кнопку,
+// тогда в тексте данные кнопки, а автор - тот, кто нажал
+func commandMessage(upd *tgbotapi.Update) *tgbotapi.Message {
+	if upd.Message != nil {
+		return upd.Message
+	}
+	cq := upd.CallbackQuery
+	if cq == nil || cq.From == nil {
+		return nil
+	}
+
+	chat := &tgbotapi.Chat{ID: int64(cq.From.ID), Type: "private"}
+	if cq.Message != nil && cq.Message.Chat != nil {
+		chat = cq.Message.Chat
+	}
+	return &tgbotapi.Message{From: cq.From, Chat: chat, Text: cq.Data}
+}
+
+func (tb *TaskBot) Help(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	userID := upd.Message.From.ID
+	if args.Text != "" {
+		name := strings.ToLower(args.Text)
+		if !strings.HasPrefix(name, "/") {
+			name = "/" + name
+		}
+		cmd, ok := tb.cmds[name]
+		if !ok {
+			return map[int]string{userID: "Нет такой команды, список команд: /help"}, nil
+		}
+		return map[int]string{userID: fmt.Sprintf("%s\nФормат: %s", cmd.Help, cmd.Format())}, nil
+	}
+
+	var sb strings.Builder
+	for i, cmd := range tb.Cmds {
+		if i != 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(cmd.Format() + " - " + cmd.Help)
+	}
+	return map[int]string{userID: sb.String()}, nil
+}
+
+// usage - ответ на неверно написанную команду
+func (tb *TaskBot) usage(upd *tgbotapi.Update, name string) map[int]string {
+	return map[int]string{upd.Message.From.ID: "Формат: " + tb.cmds[name].Format()}
+}
+
+// taskKeyboard - кнопки для команд /assign_$ID, /unassign_$ID и /resolve_$ID из текста ответа,
+// по ряду на задачу; nil, если таких команд нет
+func taskKeyboard(text string) *tgbotapi.InlineKeyboardMarkup {
+	labels := map[string]string{
+		"/assign":   "Взять",
+		"/unassign": "Отказаться",
+		"/resolve":  "Выполнить",
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	row := -1
+	var rowID string
+	for _, word := range strings.Fields(text) {
+		line, ok := splitCmd(word)
+		label, button := labels[line.Name]
+		if !ok || !button {
+			continue
+		}
+		if _, err := strconv.Atoi(line.ID); err != nil {
+			continue
+		}
+		if row < 0 || line.ID != rowID {
+			rows = append(rows, nil)
+			row, rowID = len(rows)-1, line.ID
+		}
+		rows[row] = append(rows[row], tgbotapi.NewInlineKeyboardButtonData(label+" #"+line.ID, line.Name+"_"+line.ID))
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	kb := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &kb
+}
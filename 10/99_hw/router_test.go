@@ -0,0 +1,221 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+func TestSplitCmd(t *testing.T) {
+	cases := []struct {
+		text string
+		line cmdLine
+		ok   bool
+	}{
+		{"/tasks", cmdLine{Name: "/tasks"}, true},
+		{"  /assign_5  ", cmdLine{Name: "/assign", ID: "5"}, true},
+		{"/assign 5", cmdLine{Name: "/assign", Rest: "5"}, true},
+		{"/new купить\nмолоко ", cmdLine{Name: "/new", Rest: "купить\nмолоко"}, true},
+		{"/Due_3@game_test_bot 2021-03-15 18:00", cmdLine{Name: "/due", ID: "3", Mention: "game_test_bot", Rest: "2021-03-15 18:00"}, true},
+		{"/help@game_test_bot", cmdLine{Name: "/help", Mention: "game_test_bot"}, true},
+		{"/", cmdLine{}, false},
+		{"/_5", cmdLine{}, false},
+		{"привет", cmdLine{}, false},
+		{"", cmdLine{}, false},
+	}
+
+	for _, c := range cases {
+		line, ok := splitCmd(c.text)
+		if ok != c.ok || line != c.line {
+			t.Errorf("splitCmd(%q) = %#v, %v, want %#v, %v", c.text, line, ok, c.line, c.ok)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	tb := newTestBot(NewTasksRepoInMemory())
+
+	runCmds(t, tb, []testCase{
+		{Ivanov, "привет", map[int]string{Ivanov: "Неизвестная команда, список команд: /help"}},
+		{Ivanov, "/nope_1", map[int]string{Ivanov: "Неизвестная команда, список команд: /help"}},
+		{Ivanov, "/new", map[int]string{Ivanov: "Формат: /new текст задачи"}},
+		{Ivanov, "/new   ", map[int]string{Ivanov: "Формат: /new текст задачи"}},
+		{Ivanov, "/new написать бота", map[int]string{Ivanov: `Задача "написать бота" создана, id=1`}},
+		// раньше эти команды роняли бота
+		{Ivanov, "/unassign", map[int]string{Ivanov: "Формат: /unassign_$ID"}},
+		{Ivanov, "/unassign_1", map[int]string{Ivanov: "Задача не на вас"}},
+		{Ivanov, "/unassign_100", map[int]string{Ivanov: "Задача с таким id не найдена"}},
+		{Ivanov, "/resolve_x", map[int]string{Ivanov: "Формат: /resolve_$ID"}},
+		{Ivanov, "/assign_-1", map[int]string{Ivanov: "Формат: /assign_$ID"}},
+		{Ivanov, "/assign_1 2", map[int]string{Ivanov: "Формат: /assign_$ID"}},
+		{Ivanov, "/my please", map[int]string{Ivanov: "Формат: /my"}},
+		{Ivanov, "/tasks_1", map[int]string{Ivanov: "Формат: /tasks или /tasks #тег"}},
+		// id можно и через подчёркивание, и через пробел, и с упоминанием бота
+		{Petrov, "/assign 1", map[int]string{
+			Petrov: `Задача "написать бота" назначена на вас`,
+			Ivanov: `Задача "написать бота" назначена на @ppetrov`,
+		}},
+		{Alexandrov, "/ASSIGN_1@game_test_bot", map[int]string{
+			Alexandrov: `Задача "написать бота" назначена на вас`,
+			Petrov:     `Задача "написать бота" назначена на @aalexandrov`,
+		}},
+		{Alexandrov, "/due 1 2021-03-15", map[int]string{
+			Alexandrov: `Задача "написать бота": срок 2021-03-15 23:59`,
+			Ivanov:     `Задача "написать бота": срок 2021-03-15 23:59 (@aalexandrov)`,
+		}},
+		{Ivanov, "/help assign", map[int]string{Ivanov: "назначить задачу на себя\nФормат: /assign_$ID"}},
+		{Ivanov, "/help /due", map[int]string{
+			Ivanov: "срок задачи\nФормат: /due_$ID 2021-03-15 18:00, /due_$ID 2021-03-15 или /due_$ID - чтобы снять срок",
+		}},
+		{Ivanov, "/help nope", map[int]string{Ivanov: "Нет такой команды, список команд: /help"}},
+	})
+
+	answers, err := tb.ExecuteCmd(newUpdate(Ivanov, "/help"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	help := answers[Ivanov]
+	for _, cmd := range tb.Cmds {
+		if !strings.Contains(help, cmd.Format()+" - "+cmd.Help) {
+			t.Errorf("no %s in help:\n%s", cmd.Name, help)
+		}
+	}
+}
+
+func TestCallback(t *testing.T) {
+	tb := newTestBot(NewTasksRepoInMemory())
+	runCmds(t, tb, []testCase{
+		{Ivanov, "/new написать бота", map[int]string{Ivanov: `Задача "написать бота" создана, id=1`}},
+	})
+
+	// кнопка под сообщением бота: автор команды - тот, кто нажал, а не бот
+	press := func(userID int, data string) map[int]string {
+		answers, err := tb.ExecuteCmd(&tgbotapi.Update{
+			CallbackQuery: &tgbotapi.CallbackQuery{
+				ID:   "1",
+				From: users[userID],
+				Message: &tgbotapi.Message{
+					From: &tgbotapi.User{ID: BotChatID, UserName: "game_test_bot", IsBot: true},
+					Chat: &tgbotapi.Chat{ID: int64(userID), Type: "private"},
+				},
+				Data: data,
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return answers
+	}
+
+	want := map[int]string{
+		Petrov: `Задача "написать бота" назначена на вас`,
+		Ivanov: `Задача "написать бота" назначена на @ppetrov`,
+	}
+	if answers := press(Petrov, "/assign_1"); !reflect.DeepEqual(answers, want) {
+		t.Fatalf("bad answers\n\tgot: %#v\n\twant: %#v", answers, want)
+	}
+	want = map[int]string{
+		Petrov: `Задача "написать бота" выполнена`,
+		Ivanov: `Задача "написать бота" выполнена @ppetrov`,
+	}
+	if answers := press(Petrov, "/resolve_1"); !reflect.DeepEqual(answers, want) {
+		t.Fatalf("bad answers\n\tgot: %#v\n\twant: %#v", answers, want)
+	}
+
+	// обновления без сообщения и без кнопки просто пропускаются
+	if answers, err := tb.ExecuteCmd(&tgbotapi.Update{}); answers != nil || err != nil {
+		t.Fatalf("unexpected answers %#v, %v", answers, err)
+	}
+}
+
+func TestTaskKeyboard(t *testing.T) {
+	if kb := taskKeyboard(`Задача "написать бота" создана, id=1`); kb != nil {
+		t.Fatalf("unexpected keyboard %#v", kb)
+	}
+
+	kb := taskKeyboard(`1. написать бота by @ivanov
+/assign_1
+
+2. сделать ДЗ by @ppetrov
+assignee: я
+/unassign_2 /resolve_2`)
+	if kb == nil {
+		t.Fatalf("no keyboard")
+	}
+
+	var got [][]string
+	for _, row := range kb.InlineKeyboard {
+		var buttons []string
+		for _, b := range row {
+			buttons = append(buttons, b.Text+" "+*b.CallbackData)
+		}
+		got = append(got, buttons)
+	}
+	want := [][]string{
+		{"Взять #1 /assign_1"},
+		{"Отказаться #2 /unassign_2", "Выполнить #2 /resolve_2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad keyboard\n\tgot: %v\n\twant: %v", got, want)
+	}
+}
+
+func FuzzSplitCmd(f *testing.F) {
+	for _, seed := range []string{"/tasks", "/assign_5", "/assign 5", "/due_3@bot_name 2021-03-15 18:00", "/_", "/@", "текст"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		line, ok := splitCmd(text)
+		if !ok {
+			return
+		}
+		if !strings.HasPrefix(line.Name, "/") || strings.ContainsAny(line.Name, "_@") || strings.ContainsAny(line.ID, "@") {
+			t.Fatalf("bad split of %q: %#v", text, line)
+		}
+		if strings.TrimSpace(line.Rest) != line.Rest || strings.TrimSpace(line.Name) != line.Name {
+			t.Fatalf("untrimmed split of %q: %#v", text, line)
+		}
+
+		// собранная обратно команда разбирается так же
+		again := line.Name
+		if line.ID != "" {
+			again += "_" + line.ID
+		}
+		if line.Mention != "" {
+			again += "@" + line.Mention
+		}
+		if line.Rest != "" {
+			again += " " + line.Rest
+		}
+		if line2, ok := splitCmd(again); !ok || line2 != line {
+			t.Fatalf("split of %q is %#v, but %q gives %#v", text, line, again, line2)
+		}
+	})
+}
+
+func FuzzExecuteCmd(f *testing.F) {
+	for _, seed := range []string{"/new задача", "/assign_1", "/assign 1", "/unassign_2", "/resolve_", "/due_1 2021-03-15",
+		"/tag 1 go -bug", "/snooze_1 1d", "/tz", "/help due", "/tasks #go", "/my x", "привет"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		tb := newTestBot(NewTasksRepoInMemory())
+		for _, cmd := range []string{"/new первая", "/new вторая", "/assign_2"} {
+			if _, err := tb.ExecuteCmd(newUpdate(Ivanov, cmd)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		answers, err := tb.ExecuteCmd(newUpdate(Petrov, text))
+		if err != nil {
+			t.Fatalf("%q: unexpected error %v", text, err)
+		}
+		if _, ok := answers[Petrov]; !ok {
+			t.Fatalf("%q: no answer to the author: %#v", text, answers)
+		}
+	})
+}
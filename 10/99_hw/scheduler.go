@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// digestDefault - во сколько приходит дайджест, если пользователь не выбрал сам
+	digestDefault = "09:00"
+	digestLayout  = "15:04"
+	dayLayout     = "2006-01-02"
+)
+
+// RunScheduler на каждый тик из ticks рассылает через send напоминания и дайджесты.
+// Время тика - текущее время для планировщика, так что в тестах часы подменяются каналом
+func (tb *TaskBot) RunScheduler(ctx context.Context, ticks <-chan time.Time, send func(result map[int]string)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticks:
+			result, err := tb.Tick(now)
+			if err != nil {
+				log.Printf("scheduler error: %v", err)
+			}
+			if len(result) != 0 {
+				send(result)
+			}
+		}
+	}
+}
+
+// Tick - сообщения, которые пора отправить в момент now
+func (tb *TaskBot) Tick(now time.Time) (map[int]string, error) {
+	result := make(map[int]string)
+	if err := tb.remind(now, result); err != nil {
+		return result, err
+	}
+	if err := tb.digest(now, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// remind напоминает исполнителю за RemindBefore до срока или когда закончилась отсрочка,
+// каждой задаче не больше одного раза, пока срок или отсрочку не поменяют.
+// Про задачи группы напоминание приходит в группу с упоминанием
+func (tb *TaskBot) remind(now time.Time, result map[int]string) error {
+	spaces, err := tb.Repo.GetWorkspaces()
+	if err != nil {
+		return err
+	}
+	for _, ws := range spaces {
+		if err := tb.remindIn(ws, now, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tb *TaskBot) remindIn(ws *Workspace, now time.Time, result map[int]string) error {
+	tasks, err := tb.Repo.GetAll(ws.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		if t.assignedTo == nil || t.due.IsZero() || t.reminded {
+			continue
+		}
+		at := t.due.Add(-tb.RemindBefore)
+		if t.snoozedUntil.After(at) {
+			at = t.snoozedUntil
+		}
+		if now.Before(at) {
+			continue
+		}
+
+		var fire bool
+		task, err := tb.Repo.Update(ws.ID, t.ID, func(t *Task) {
+			fire = !t.reminded && t.assignedTo != nil
+			t.reminded = true
+		})
+		if err != nil {
+			return err
+		}
+		if task == nil || !fire {
+			continue
+		}
+
+		loc, err := tb.location(task.assignedTo.ID)
+		if err != nil {
+			return err
+		}
+		due := task.due.In(loc).Format(dueLayout)
+		var text string
+		if now.Before(task.due) {
+			text = fmt.Sprintf("Напоминание: задачу \"%s\" нужно сделать до %s", task.info, due)
+		} else {
+			text = fmt.Sprintf("Напоминание: задача \"%s\" просрочена, срок был %s", task.info, due)
+		}
+		text += fmt.Sprintf("\n/snooze_%d /resolve_%d", task.ID, task.ID)
+		if ws.Group() {
+			addMessage(result, int(ws.ChatID), mention(task.assignedTo)+" "+text)
+		} else {
+			addMessage(result, task.assignedTo.ID, text)
+		}
+	}
+	return nil
+}
+
+// digestPart - задачи пользователя в одном пространстве
+type digestPart struct {
+	ws       *Workspace
+	assigned []*Task
+	created  []*Task
+}
+
+// digest раз в день в выбранное время присылает каждому лично его открытые задачи
+// во всех пространствах: назначенные на него и созданные им
+func (tb *TaskBot) digest(now time.Time, result map[int]string) error {
+	spaces, err := tb.Repo.GetWorkspaces()
+	if err != nil {
+		return err
+	}
+
+	parts := make(map[int][]*digestPart)
+	part := func(userID int, ws *Workspace) *digestPart {
+		pp := parts[userID]
+		if len(pp) == 0 || pp[len(pp)-1].ws != ws {
+			pp = append(pp, &digestPart{ws: ws})
+			parts[userID] = pp
+		}
+		return pp[len(pp)-1]
+	}
+	for _, ws := range spaces {
+		tasks, err := tb.Repo.GetAll(ws.ID)
+		if err != nil {
+			return err
+		}
+		for _, t := range tasks {
+			if t.assignedTo != nil {
+				p := part(t.assignedTo.ID, ws)
+				p.assigned = append(p.assigned, t)
+			}
+			p := part(t.createdBy.ID, ws)
+			p.created = append(p.created, t)
+		}
+	}
+
+	userIDs := make([]int, 0, len(parts))
+	for id := range parts {
+		userIDs = append(userIDs, id)
+	}
+	sort.Ints(userIDs)
+
+	for _, userID := range userIDs {
+		settings, err := tb.Repo.GetSettings(userID)
+		if err != nil {
+			return err
+		}
+		loc, err := tb.location(userID)
+		if err != nil {
+			return err
+		}
+		day, ok := digestDue(settings, now.In(loc))
+		if !ok {
+			continue
+		}
+		if _, err := tb.Repo.UpdateSettings(userID, func(s *UserSettings) { s.LastDigest = day }); err != nil {
+			return err
+		}
+
+		var sb strings.Builder
+		sb.WriteString("Задачи на " + day)
+		for _, p := range parts[userID] {
+			// id у задач в каждом пространстве свои, поэтому группы подписаны
+			if p.ws.Group() {
+				sb.WriteString(fmt.Sprintf("\n\n«%s», /workspace %d", p.ws.Name(), p.ws.ID))
+			}
+			writeDigestPart(&sb, "На вас:", p.assigned, userID, false)
+			writeDigestPart(&sb, "Созданы вами:", p.created, userID, true)
+		}
+		addMessage(result, userID, sb.String())
+	}
+	return nil
+}
+
+// digestDue - местная дата, если дайджест за неё ещё не отправлен и его время уже наступило
+func digestDue(s UserSettings, local time.Time) (string, bool) {
+	if s.DigestAt == "-" {
+		return "", false
+	}
+	at := s.DigestAt
+	if at == "" {
+		at = digestDefault
+	}
+
+	day := local.Format(dayLayout)
+	if s.LastDigest == day || local.Format(digestLayout) < at {
+		return "", false
+	}
+	return day, true
+}
+
+func writeDigestPart(sb *strings.Builder, title string, tasks []*Task, userID int, includeAssignee bool) {
+	if len(tasks) == 0 {
+		return
+	}
+	sb.WriteString("\n\n" + title)
+	for _, t := range tasks {
+		sb.WriteString("\n\n" + t.String(userID, includeAssignee))
+	}
+}
+
+// addMessage дописывает text к тому, что пользователь уже получит в этот раз
+func addMessage(result map[int]string, userID int, text string) {
+	if prev, ok := result[userID]; ok {
+		text = prev + "\n\n" + text
+	}
+	result[userID] = text
+}
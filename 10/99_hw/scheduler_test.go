@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+func TestScheduler(t *testing.T) {
+	tds := NewTDS()
+	ts := httptest.NewServer(tds)
+	defer ts.Close()
+	tgbotapi.APIEndpoint = ts.URL + "/bot%s/%s"
+
+	bot, err := tgbotapi.NewBotAPI(BotToken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	tb := NewTaskBot(NewTasksRepoInMemory())
+	tb.Location = time.UTC
+	tb.Now = func() time.Time { return now }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticks := make(chan time.Time)
+	go tb.RunScheduler(ctx, ticks, func(result map[int]string) { sendResult(bot, result) })
+
+	runCmds(t, tb, []testCase{
+		{Ivanov, "/new сделать деплой", map[int]string{Ivanov: `Задача "сделать деплой" создана, id=1`}},
+		{Petrov, "/assign_1", map[int]string{
+			Petrov: `Задача "сделать деплой" назначена на вас`,
+			Ivanov: `Задача "сделать деплой" назначена на @ppetrov`,
+		}},
+		{Ivanov, "/due_1 2021-03-10 15:00", map[int]string{
+			Ivanov: `Задача "сделать деплой": срок 2021-03-10 15:00`,
+			Petrov: `Задача "сделать деплой": срок 2021-03-10 15:00 (@ivanov)`,
+		}},
+		{Ivanov, "/digest 25:00", map[int]string{Ivanov: "Формат: /digest 09:00 или /digest off"}},
+		{Ivanov, "/digest 13:00", map[int]string{Ivanov: "Дайджест каждый день в 13:00 (UTC)"}},
+		{Petrov, "/tz Nowhere/City", map[int]string{Petrov: "Формат: /tz Europe/Moscow"}},
+		{Petrov, "/tz Asia/Vladivostok", map[int]string{Petrov: "Часовой пояс: Asia/Vladivostok"}},
+		{Petrov, "/snooze_1 soon", map[int]string{Petrov: "Формат: /snooze_$ID 30m, 2h или 1d, без срока - на час"}},
+		{Ivanov, "/snooze_1", map[int]string{Ivanov: "Задача не на вас"}},
+	})
+
+	tick := func(at string, want map[int]string) {
+		t.Helper()
+		tds.Lock()
+		tds.Answers = make(map[int]string)
+		tds.Unlock()
+
+		now, err = time.Parse(dueLayout, at)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ticks <- now
+		// give TDS time to process request
+		time.Sleep(10 * time.Millisecond)
+
+		tds.Lock()
+		defer tds.Unlock()
+		if !reflect.DeepEqual(tds.Answers, want) {
+			t.Fatalf("[%s] bad results:\n\tWant: %v\n\tHave: %v", at, want, tds.Answers)
+		}
+	}
+
+	// у Петрова во Владивостоке уже 22:00 - пора дайджест, Иванову в UTC ещё рано
+	tick("2021-03-10 12:00", map[int]string{
+		Petrov: `Задачи на 2021-03-10
+
+На вас:
+
+1. сделать деплой by @ivanov
+due: 2021-03-10 15:00
+/unassign_1 /resolve_1`,
+	})
+	// за час до срока - напоминание, время в поясе исполнителя
+	tick("2021-03-10 14:00", map[int]string{
+		Petrov: `Напоминание: задачу "сделать деплой" нужно сделать до 2021-03-11 01:00
+/snooze_1 /resolve_1`,
+		Ivanov: `Задачи на 2021-03-10
+
+Созданы вами:
+
+1. сделать деплой by @ivanov
+assignee: @ppetrov
+due: 2021-03-10 15:00`,
+	})
+	tick("2021-03-10 14:05", map[int]string{})
+
+	runCmds(t, tb, []testCase{
+		{Petrov, "/snooze_1 30m", map[int]string{Petrov: `Напомню про задачу "сделать деплой" 2021-03-11 00:35`}},
+	})
+	tick("2021-03-10 14:20", map[int]string{})
+	tick("2021-03-10 14:35", map[int]string{
+		Petrov: `Напоминание: задачу "сделать деплой" нужно сделать до 2021-03-11 01:00
+/snooze_1 /resolve_1`,
+	})
+	// новый день во Владивостоке: дайджест, а про просроченную задачу второй раз не напоминаем
+	tick("2021-03-10 23:00", map[int]string{
+		Petrov: `Задачи на 2021-03-11
+
+На вас:
+
+1. сделать деплой by @ivanov
+due: 2021-03-10 15:00
+/unassign_1 /resolve_1`,
+	})
+
+	runCmds(t, tb, []testCase{
+		{Petrov, "/snooze_1 1d", map[int]string{Petrov: `Напомню про задачу "сделать деплой" 2021-03-12 09:00`}},
+		{Ivanov, "/digest off", map[int]string{Ivanov: "Дайджест выключен"}},
+	})
+	tick("2021-03-11 23:00", map[int]string{
+		Petrov: `Напоминание: задача "сделать деплой" просрочена, срок был 2021-03-11 01:00
+/snooze_1 /resolve_1
+
+Задачи на 2021-03-12
+
+На вас:
+
+1. сделать деплой by @ivanov
+due: 2021-03-10 15:00
+/unassign_1 /resolve_1`,
+	})
+}
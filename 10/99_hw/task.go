@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+const dueLayout = "2006-01-02 15:04"
+
+type Priority int
+
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	}
+	return "normal"
+}
+
+func parsePriority(s string) (Priority, bool) {
+	for _, p := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		if strings.EqualFold(s, p.String()) {
+			return p, true
+		}
+	}
+	return PriorityNormal, false
+}
+
+type Comment struct {
+	Author  *tgbotapi.User `json:"author"`
+	Text    string         `json:"text"`
+	Created time.Time      `json:"created"`
+}
+
+type Task struct {
+	ID         int
+	info       string
+	assignedTo *tgbotapi.User
+	createdBy  *tgbotapi.User
+	created    time.Time
+	// due - срок, нулевое время - без срока
+	due      time.Time
+	priority Priority
+	tags     []string
+	comments []Comment
+	watchers []*tgbotapi.User
+	// reminded - исполнителю уже напомнили о сроке, snoozedUntil - раньше этого не напоминать
+	reminded     bool
+	snoozedUntil time.Time
+	// resolvedBy != nil у выполненной задачи, она остаётся в истории
+	resolvedBy *tgbotapi.User
+	resolvedAt time.Time
+}
+
+func (t *Task) Resolved() bool {
+	return t.resolvedBy != nil
+}
+
+func (t *Task) String(currUserID int, includeAssignee bool) string {
+	res := fmt.Sprintf("%d. %s by @%s", t.ID, t.info, t.createdBy.String())
+
+	if includeAssignee && t.assignedTo != nil {
+		var assignee string
+		if t.assignedTo.ID == currUserID {
+			assignee = "я"
+		} else {
+			assignee = fmt.Sprintf("@%s", t.assignedTo.UserName)
+		}
+		res += fmt.Sprintf("\nassignee: %s", assignee)
+	}
+	res += t.details()
+
+	res += t.getAvailableCmds(currUserID)
+	return res
+}
+
+// details - строки про срок, приоритет, теги и комментарии, только если они заданы
+func (t *Task) details() string {
+	var res string
+	if !t.due.IsZero() {
+		res += "\ndue: " + t.due.Format(dueLayout)
+	}
+	if t.priority != PriorityNormal {
+		res += "\npriority: " + t.priority.String()
+	}
+	if len(t.tags) != 0 {
+		res += "\ntags: " + t.tagList()
+	}
+	if len(t.comments) != 0 {
+		res += fmt.Sprintf("\ncomments: %d", len(t.comments))
+	}
+	return res
+}
+
+// Full - задача целиком для /task_$ID: с комментариями и наблюдателями
+func (t *Task) Full(currUserID int) string {
+	if t.Resolved() {
+		return fmt.Sprintf("%d. %s by @%s%s\nresolved by @%s %s",
+			t.ID, t.info, t.createdBy.String(), t.details(), t.resolvedBy.String(), t.resolvedAt.Format(dueLayout))
+	}
+
+	res := t.String(currUserID, true)
+	if len(t.watchers) != 0 {
+		names := make([]string, len(t.watchers))
+		for i, w := range t.watchers {
+			names[i] = "@" + w.String()
+		}
+		res += "\nwatchers: " + strings.Join(names, " ")
+	}
+	for _, c := range t.comments {
+		res += fmt.Sprintf("\n\n@%s %s:\n%s", c.Author.String(), c.Created.Format(dueLayout), c.Text)
+	}
+	return res
+}
+
+func (t *Task) getAvailableCmds(currUserID int) string {
+	if t.assignedTo == nil {
+		return fmt.Sprintf("\n/assign_%d", t.ID)
+	}
+	if t.assignedTo != nil && t.assignedTo.ID == currUserID {
+		return fmt.Sprintf("\n/unassign_%d /resolve_%d", t.ID, t.ID)
+	}
+
+	return ""
+}
+
+func (t *Task) tagList() string {
+	if len(t.tags) == 0 {
+		return "тегов нет"
+	}
+	return "#" + strings.Join(t.tags, " #")
+}
+
+func (t *Task) hasTag(tag string) bool {
+	for _, have := range t.tags {
+		if have == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// setTags добавляет теги, а теги с минусом вида -bug убирает
+func (t *Task) setTags(changes []string) {
+	for _, change := range changes {
+		remove := strings.HasPrefix(change, "-")
+		tag := normalizeTag(strings.TrimPrefix(change, "-"))
+		if tag == "" {
+			continue
+		}
+		if remove {
+			kept := t.tags[:0]
+			for _, have := range t.tags {
+				if have != tag {
+					kept = append(kept, have)
+				}
+			}
+			t.tags = kept
+		} else if !t.hasTag(tag) {
+			t.tags = append(t.tags, tag)
+		}
+	}
+	sort.Strings(t.tags)
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// watchedBy - следит ли пользователь за задачей
+func (t *Task) watchedBy(userID int) bool {
+	for _, w := range t.watchers {
+		if w.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Task) unwatch(userID int) {
+	kept := t.watchers[:0]
+	for _, w := range t.watchers {
+		if w.ID != userID {
+			kept = append(kept, w)
+		}
+	}
+	t.watchers = kept
+}
+
+// clone - копия, которую можно отдать из репозитория и читать без блокировки
+func (t *Task) clone() *Task {
+	c := *t
+	c.tags = append([]string(nil), t.tags...)
+	c.comments = append([]Comment(nil), t.comments...)
+	c.watchers = append([]*tgbotapi.User(nil), t.watchers...)
+	return &c
+}
+
+// taskJSON - Task для хранения в файле, поля у самой задачи не экспортируются
+type taskJSON struct {
+	ID         int              `json:"id"`
+	Info       string           `json:"info"`
+	AssignedTo *tgbotapi.User   `json:"assigned_to,omitempty"`
+	CreatedBy  *tgbotapi.User   `json:"created_by"`
+	Created    time.Time        `json:"created"`
+	Due        *time.Time       `json:"due,omitempty"`
+	Priority   Priority         `json:"priority,omitempty"`
+	Tags       []string         `json:"tags,omitempty"`
+	Comments   []Comment        `json:"comments,omitempty"`
+	Watchers   []*tgbotapi.User `json:"watchers,omitempty"`
+	Reminded   bool             `json:"reminded,omitempty"`
+	Snoozed    *time.Time       `json:"snoozed_until,omitempty"`
+	ResolvedBy *tgbotapi.User   `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time       `json:"resolved_at,omitempty"`
+}
+
+func (t *Task) MarshalJSON() ([]byte, error) {
+	j := taskJSON{
+		ID:         t.ID,
+		Info:       t.info,
+		AssignedTo: t.assignedTo,
+		CreatedBy:  t.createdBy,
+		Created:    t.created,
+		Priority:   t.priority,
+		Tags:       t.tags,
+		Comments:   t.comments,
+		Watchers:   t.watchers,
+		Reminded:   t.reminded,
+		ResolvedBy: t.resolvedBy,
+	}
+	if !t.due.IsZero() {
+		j.Due = &t.due
+	}
+	if !t.snoozedUntil.IsZero() {
+		j.Snoozed = &t.snoozedUntil
+	}
+	if t.Resolved() {
+		j.ResolvedAt = &t.resolvedAt
+	}
+	return json.Marshal(j)
+}
+
+func (t *Task) UnmarshalJSON(data []byte) error {
+	var j taskJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.CreatedBy == nil {
+		return fmt.Errorf("task %d has no author", j.ID)
+	}
+	*t = Task{
+		ID:         j.ID,
+		info:       j.Info,
+		assignedTo: j.AssignedTo,
+		createdBy:  j.CreatedBy,
+		created:    j.Created,
+		priority:   j.Priority,
+		tags:       j.Tags,
+		comments:   j.Comments,
+		watchers:   j.Watchers,
+		reminded:   j.Reminded,
+		resolvedBy: j.ResolvedBy,
+	}
+	if j.Due != nil {
+		t.due = *j.Due
+	}
+	if j.Snoozed != nil {
+		t.snoozedUntil = *j.Snoozed
+	}
+	if j.ResolvedAt != nil {
+		t.resolvedAt = *j.ResolvedAt
+	}
+	return nil
+}
@@ -19,7 +19,8 @@ var WebhookURL = "https://vctrl-taskbot.herokuapp.com/"
 var BotToken = os.Getenv("BOT_TOKEN")
 var Port = ":" + os.Getenv("PORT")
 
-type cmdHandler func(upd *tgbotapi.Update, params ...string) (map[int]string, error)
+// TasksFile - куда сохранять задачи, без него они живут только в памяти
+var TasksFile = os.Getenv("TASKS_FILE")
 
 func main() {
 	err := startTaskBot(context.Background())
@@ -51,17 +52,35 @@ func startTaskBot(ctx context.Context) error {
 
 	fmt.Printf("server started at %s\n", Port)
 
-	tb := NewTaskBot()
+	var repo TasksRepo = NewTasksRepoInMemory()
+	if TasksFile != "" {
+		repo, err = NewTasksRepoFile(TasksFile)
+		if err != nil {
+			return fmt.Errorf("error opening tasks file: %v", err)
+		}
+	}
+
+	tb := NewTaskBot(repo)
+	tb.UserName = bot.Self.UserName
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	go tb.RunScheduler(ctx, ticker.C, func(result map[int]string) { sendResult(bot, result) })
+
 	for update := range updates {
+		if update.CallbackQuery != nil {
+			bot.AnswerCallbackQuery(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+		}
+
 		result, err := tb.ExecuteCmd(&update)
-		chatID := update.Message.Chat.ID
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(chatID, "error happened"))
+			log.Printf("error executing command: %v", err)
+			if msg := commandMessage(&update); msg != nil {
+				bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "error happened"))
+			}
 		}
 
-		for id, text := range result {
-			bot.Send(tgbotapi.NewMessage(int64(id), text))
-		}
+		sendResult(bot, result)
 	}
 
 	quit := make(chan os.Signal, 1)
@@ -81,61 +100,139 @@ func startTaskBot(ctx context.Context) error {
 	return nil
 }
 
+func sendResult(bot *tgbotapi.BotAPI, result map[int]string) {
+	for id, text := range result {
+		msg := tgbotapi.NewMessage(int64(id), text)
+		if kb := taskKeyboard(text); kb != nil {
+			msg.ReplyMarkup = kb
+		}
+		bot.Send(msg)
+	}
+}
+
 type TaskBot struct {
-	Repo *TasksRepoInMemory
-	Cmds map[string]cmdHandler
+	Repo TasksRepo
+	// Cmds - все команды в порядке /help
+	Cmds []*Command
+	cmds map[string]*Command
+	// Now - текущее время для сроков, комментариев и истории
+	Now func() time.Time
+	// Location - часовой пояс тех, кто не выбрал свой через /tz
+	Location *time.Location
+	// RemindBefore - за сколько до срока напоминать исполнителю
+	RemindBefore time.Duration
+	// UserName - имя бота, в группе команды с упоминанием другого бота пропускаются
+	UserName string
 }
 
-func NewTaskBot() *TaskBot {
+func NewTaskBot(repo TasksRepo) *TaskBot {
 	tb := &TaskBot{
-		Repo: NewTasksRepoInMemory(),
-	}
-
-	tb.Cmds = map[string]cmdHandler{
-		"/tasks":    tb.GetTasks,
-		"/new":      tb.CreateTask,
-		"/assign":   tb.AssignTask,
-		"/unassign": tb.UnassignTask,
-		"/resolve":  tb.ResolveTask,
-		"/my":       tb.GetAssignedToUser,
-		"/owner":    tb.GetCreatedByUser,
+		Repo:         repo,
+		Now:          time.Now,
+		Location:     time.Local,
+		RemindBefore: time.Hour,
+	}
+
+	tb.Cmds = []*Command{
+		{Name: "/tasks", Text: argOptional, Usage: "/tasks или /tasks #тег", Help: "открытые задачи", Handler: tb.GetTasks},
+		{Name: "/new", Text: argRequired, Usage: "/new текст задачи", Help: "создать задачу", Handler: tb.CreateTask},
+		{Name: "/assign", ID: true, Help: "назначить задачу на себя", Handler: tb.AssignTask},
+		{Name: "/unassign", ID: true, Help: "снять задачу с себя", Handler: tb.UnassignTask},
+		{Name: "/resolve", ID: true, Help: "выполнить задачу", Handler: tb.ResolveTask},
+		{Name: "/my", Help: "задачи, назначенные на меня", Handler: tb.GetAssignedToUser},
+		{Name: "/owner", Help: "задачи, созданные мной", Handler: tb.GetCreatedByUser},
+		{Name: "/task", ID: true, Help: "задача целиком, с комментариями", Handler: tb.ShowTask},
+		{Name: "/done", Help: "последние выполненные задачи", Handler: tb.GetResolved},
+		{Name: "/due", ID: true, Text: argRequired,
+			Usage: "/due_$ID 2021-03-15 18:00, /due_$ID 2021-03-15 или /due_$ID - чтобы снять срок",
+			Help:  "срок задачи", Handler: tb.SetDue},
+		{Name: "/tag", ID: true, Text: argRequired, Usage: "/tag_$ID тег другой_тег, -тег убирает тег",
+			Help: "теги задачи", Handler: tb.SetTags},
+		{Name: "/priority", ID: true, Text: argRequired, Usage: "/priority_$ID high, normal или low",
+			Help: "приоритет задачи", Handler: tb.SetPriority},
+		{Name: "/comment", ID: true, Text: argRequired, Usage: "/comment_$ID текст комментария",
+			Help: "прокомментировать задачу", Handler: tb.AddComment},
+		{Name: "/watch", ID: true, Help: "следить за задачей", Handler: tb.WatchTask},
+		{Name: "/unwatch", ID: true, Help: "не следить за задачей", Handler: tb.UnwatchTask},
+		{Name: "/snooze", ID: true, Text: argOptional, Usage: "/snooze_$ID 30m, 2h или 1d, без срока - на час",
+			Help: "отложить напоминание", Handler: tb.SnoozeTask},
+		{Name: "/tz", Text: argOptional, Usage: "/tz Europe/Moscow", Help: "часовой пояс", Handler: tb.SetTimeZone},
+		{Name: "/digest", Text: argRequired, Usage: "/digest 09:00 или /digest off",
+			Help: "время ежедневного дайджеста", Handler: tb.SetDigest},
+		{Name: "/workspace", Text: argOptional, Usage: "/workspace или /workspace $N",
+			Help: "рабочие пространства, в личных сообщениях - выбрать пространство", Handler: tb.SelectWorkspace},
+		{Name: "/members", Help: "участники пространства и их роли", Handler: tb.GetMembers},
+		{Name: "/role", Text: argRequired, Usage: "/role @username admin или member",
+			Help: "роль участника группы, меняет владелец", Handler: tb.SetRole},
+		{Name: "/help", Text: argOptional, Usage: "/help или /help команда", Help: "список команд", Handler: tb.Help},
+	}
+
+	tb.cmds = make(map[string]*Command, len(tb.Cmds))
+	for _, cmd := range tb.Cmds {
+		tb.cmds[cmd.Name] = cmd
 	}
 
 	return tb
 }
 
+// ExecuteCmd выполняет команду из сообщения или из нажатой кнопки в пространстве её чата.
+// Неизвестные и неверно написанные команды получают подсказку, а не ошибку.
+// В группе бот молчит в ответ на обычные сообщения и на команды, адресованные другим ботам
 func (tb *TaskBot) ExecuteCmd(upd *tgbotapi.Update) (map[int]string, error) {
-	var cmd, arg string
-	text := upd.Message.Text
-	// неуклюжий роутер
-	if strings.HasPrefix(text, "/new ") {
-		cmd = "/new"
-		arg = strings.TrimPrefix(text, "/new ")
-	} else {
-		parts := strings.Split(text, "_")
-		cmd = parts[0]
-		if len(parts) > 1 {
-			arg = parts[1]
+	msg := commandMessage(upd)
+	if msg == nil || msg.From == nil {
+		return nil, nil
+	}
+	upd = &tgbotapi.Update{UpdateID: upd.UpdateID, Message: msg}
+
+	line, ok := splitCmd(msg.Text)
+	cmd := tb.cmds[line.Name]
+	if msg.Chat != nil && !msg.Chat.IsPrivate() {
+		toOtherBot := line.Mention != "" && !strings.EqualFold(line.Mention, tb.UserName)
+		if !ok || toOtherBot || (cmd == nil && line.Mention == "") {
+			return nil, nil
 		}
 	}
 
-	cmdHandler, ok := tb.Cmds[cmd]
-	if !ok {
-		return nil, fmt.Errorf("command is not supported")
+	ws, err := tb.workspace(msg)
+	if err != nil {
+		return nil, err
 	}
 
-	return cmdHandler(upd, arg)
+	var result map[int]string
+	if !ok || cmd == nil {
+		result = map[int]string{msg.From.ID: "Неизвестная команда, список команд: /help"}
+	} else if args, ok := cmd.parseArgs(line); !ok {
+		result = tb.usage(upd, cmd.Name)
+	} else {
+		args.Workspace = ws
+		if result, err = cmd.Handler(upd, args); err != nil {
+			return nil, err
+		}
+	}
+	return route(msg, ws, result), nil
 }
 
-func (tb *TaskBot) GetTasks(upd *tgbotapi.Update, args ...string) (map[int]string, error) {
+func (tb *TaskBot) GetTasks(upd *tgbotapi.Update, args Args) (map[int]string, error) {
 	userID := upd.Message.From.ID
 
 	var result = make(map[int]string)
-	tasks, err := tb.Repo.GetAll()
+	tasks, err := tb.Repo.GetAll(args.Workspace.ID)
 
 	if err != nil {
 		return nil, err
 	}
+	// /tasks #tag - только задачи с тегом
+	if args.Text != "" {
+		tag := normalizeTag(args.Text)
+		filtered := tasks[:0]
+		for _, t := range tasks {
+			if t.hasTag(tag) {
+				filtered = append(filtered, t)
+			}
+		}
+		tasks = filtered
+	}
 	if len(tasks) == 0 {
 		result[userID] = "Нет задач"
 		return result, nil
@@ -153,38 +250,31 @@ func (tb *TaskBot) GetTasks(upd *tgbotapi.Update, args ...string) (map[int]strin
 	return result, nil
 }
 
-func (tb *TaskBot) CreateTask(upd *tgbotapi.Update, args ...string) (map[int]string, error) {
-	info := args[0]
-	task := &Task{info: info, createdBy: upd.Message.From}
-	id, err := tb.Repo.Add(task)
+func (tb *TaskBot) CreateTask(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	userID := upd.Message.From.ID
+	info := args.Text
+	task := &Task{info: info, createdBy: upd.Message.From, created: tb.Now()}
+	id, err := tb.Repo.Add(args.Workspace.ID, task)
 	if err != nil {
 		return nil, err
 	}
-	userID := upd.Message.From.ID
 	result := map[int]string{userID: fmt.Sprintf("Задача \"%s\" создана, id=%d", info, id)}
 	return result, nil
 }
 
-func (tb *TaskBot) AssignTask(upd *tgbotapi.Update, args ...string) (map[int]string, error) {
-	if len(args) == 0 {
-		return nil, fmt.Errorf("invalid args, no id")
-	}
-	id, err := strconv.Atoi(args[0])
-	if err != nil {
-		return nil, fmt.Errorf("error parsing task id: %s", err.Error())
-	}
-
-	task, err := tb.Repo.GetByID(id)
+func (tb *TaskBot) AssignTask(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	id := args.ID
+	task, err := tb.Repo.GetByID(args.Workspace.ID, id)
 	if err != nil {
 		return nil, err
 	}
 
 	userID := upd.Message.From.ID
-	if task == nil {
+	if task == nil || task.Resolved() {
 		return map[int]string{userID: "Задача с таким id не найдена"}, nil
 	}
 	oldUsr := task.assignedTo
-	ok, err := tb.Repo.AssignTo(id, upd.Message.From)
+	ok, err := tb.Repo.AssignTo(args.Workspace.ID, id, upd.Message.From)
 	if err != nil {
 		return nil, err
 	}
@@ -203,66 +293,73 @@ func (tb *TaskBot) AssignTask(upd *tgbotapi.Update, args ...string) (map[int]str
 		result[task.createdBy.ID] =
 			fmt.Sprintf("Задача \"%s\" назначена на @%s", task.info, upd.Message.From)
 	}
+	notifyWatchers(result, task, userID,
+		fmt.Sprintf("Задача \"%s\" назначена на @%s", task.info, upd.Message.From))
 
 	return result, nil
 }
 
-func (tb *TaskBot) UnassignTask(upd *tgbotapi.Update, args ...string) (map[int]string, error) {
-	id, err := strconv.Atoi(args[0])
+func (tb *TaskBot) UnassignTask(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	id := args.ID
+	task, err := tb.Repo.GetByID(args.Workspace.ID, id)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing task id: %s", err.Error())
+		return nil, err
 	}
-
-	task, err := tb.Repo.GetByID(id)
 	user := upd.Message.From
-	if task.assignedTo.ID != user.ID {
+	if task == nil || task.Resolved() {
+		return map[int]string{user.ID: "Задача с таким id не найдена"}, nil
+	}
+	if task.assignedTo == nil || task.assignedTo.ID != user.ID {
 		return map[int]string{user.ID: "Задача не на вас"}, nil
 	}
 
-	ok, err := tb.Repo.Unassign(id, upd.Message.From)
+	ok, err := tb.Repo.Unassign(args.Workspace.ID, id, upd.Message.From)
 	if err != nil {
 		return nil, err
 	}
 	if !ok {
 		return map[int]string{user.ID: "Задача с таким id не найдена"}, nil
 	}
-	return map[int]string{
+	result := map[int]string{
 		user.ID:           "Принято",
 		task.createdBy.ID: fmt.Sprintf("Задача \"%s\" осталась без исполнителя", task.info),
-	}, nil
-}
-
-func (tb *TaskBot) ResolveTask(upd *tgbotapi.Update, args ...string) (map[int]string, error) {
-	id, err := strconv.Atoi(args[0])
-	if err != nil {
-		return nil, fmt.Errorf("error parsing task id: %s", err.Error())
 	}
+	notifyWatchers(result, task, user.ID, fmt.Sprintf("Задача \"%s\" осталась без исполнителя", task.info))
+	return result, nil
+}
 
-	t, err := tb.Repo.GetByID(id)
+func (tb *TaskBot) ResolveTask(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	id := args.ID
+	t, err := tb.Repo.GetByID(args.Workspace.ID, id)
 	if err != nil {
 		return nil, err
 	}
 	userID := upd.Message.From.ID
-	if t == nil {
+	if t == nil || t.Resolved() {
 		return map[int]string{userID: "Задача с таким id не найдена"}, nil
 	}
-	ok, err := tb.Repo.Delete(id)
+	if !args.Workspace.canManage(t, userID) {
+		return map[int]string{userID: noRights}, nil
+	}
+	ok, err := tb.Repo.Resolve(args.Workspace.ID, id, upd.Message.From, tb.Now())
 	if err != nil {
 		return nil, err
 	}
 
 	if !ok {
-		return map[int]string{userID: "Упс! Задача уже удалена кем-то другим"}, nil
+		return map[int]string{userID: "Упс! Задача уже выполнена кем-то другим"}, nil
 	}
 
-	return map[int]string{
+	result := map[int]string{
 		userID:         fmt.Sprintf("Задача \"%s\" выполнена", t.info),
 		t.createdBy.ID: fmt.Sprintf("Задача \"%s\" выполнена @%s", t.info, upd.Message.From),
-	}, nil
+	}
+	notifyWatchers(result, t, userID, fmt.Sprintf("Задача \"%s\" выполнена @%s", t.info, upd.Message.From))
+	return result, nil
 }
 
-func (tb *TaskBot) GetAssignedToUser(upd *tgbotapi.Update, args ...string) (map[int]string, error) {
-	tasks, err := tb.Repo.GetByAssigneeID(upd.Message.From.ID)
+func (tb *TaskBot) GetAssignedToUser(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	tasks, err := tb.Repo.GetByAssigneeID(args.Workspace.ID, upd.Message.From.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -278,8 +375,8 @@ func (tb *TaskBot) GetAssignedToUser(upd *tgbotapi.Update, args ...string) (map[
 	return map[int]string{upd.Message.From.ID: sb.String()}, nil
 }
 
-func (tb *TaskBot) GetCreatedByUser(upd *tgbotapi.Update, args ...string) (map[int]string, error) {
-	tasks, err := tb.Repo.GetByAuthorID(upd.Message.From.ID)
+func (tb *TaskBot) GetCreatedByUser(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	tasks, err := tb.Repo.GetByAuthorID(args.Workspace.ID, upd.Message.From.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -294,3 +391,317 @@ func (tb *TaskBot) GetCreatedByUser(upd *tgbotapi.Update, args ...string) (map[i
 
 	return map[int]string{upd.Message.From.ID: sb.String()}, nil
 }
+
+func (tb *TaskBot) ShowTask(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	id := args.ID
+	task, err := tb.Repo.GetByID(args.Workspace.ID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := upd.Message.From.ID
+	if task == nil {
+		return map[int]string{userID: "Задача с таким id не найдена"}, nil
+	}
+	return map[int]string{userID: task.Full(userID)}, nil
+}
+
+// doneLimit - сколько последних выполненных задач показывает /done
+const doneLimit = 10
+
+func (tb *TaskBot) GetResolved(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	tasks, err := tb.Repo.GetResolved(args.Workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := upd.Message.From.ID
+	if len(tasks) == 0 {
+		return map[int]string{userID: "Нет выполненных задач"}, nil
+	}
+	if len(tasks) > doneLimit {
+		tasks = tasks[:doneLimit]
+	}
+
+	var sb strings.Builder
+	for i, t := range tasks {
+		if i != 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(t.Full(userID))
+	}
+	return map[int]string{userID: sb.String()}, nil
+}
+
+func (tb *TaskBot) SetDue(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	var due time.Time
+	if args.Text != "-" {
+		loc, err := tb.location(upd.Message.From.ID)
+		if err != nil {
+			return nil, err
+		}
+		if due, err = parseDue(args.Text, loc); err != nil {
+			return tb.usage(upd, "/due"), nil
+		}
+	}
+
+	// новый срок - новое напоминание
+	update := func(t *Task) {
+		t.due = due
+		t.reminded = false
+		t.snoozedUntil = time.Time{}
+	}
+	return tb.manageTask(upd, args, update, func(t *Task) string {
+		if t.due.IsZero() {
+			return "срок снят"
+		}
+		return "срок " + t.due.Format(dueLayout)
+	})
+}
+
+func (tb *TaskBot) SetTags(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	changes := strings.Fields(args.Text)
+	return tb.manageTask(upd, args, func(t *Task) { t.setTags(changes) }, func(t *Task) string {
+		return "теги " + t.tagList()
+	})
+}
+
+func (tb *TaskBot) SetPriority(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	priority, ok := parsePriority(args.Text)
+	if !ok {
+		return tb.usage(upd, "/priority"), nil
+	}
+
+	return tb.manageTask(upd, args, func(t *Task) { t.priority = priority }, func(t *Task) string {
+		return "приоритет " + t.priority.String()
+	})
+}
+
+func (tb *TaskBot) AddComment(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	comment := Comment{Author: upd.Message.From, Text: args.Text, Created: tb.Now()}
+	return tb.updateTask(upd, args, func(t *Task) { t.comments = append(t.comments, comment) }, func(t *Task) string {
+		return fmt.Sprintf("комментарий \"%s\"", comment.Text)
+	})
+}
+
+func (tb *TaskBot) WatchTask(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	id := args.ID
+
+	user := upd.Message.From
+	task, err := tb.Repo.Update(args.Workspace.ID, id, func(t *Task) {
+		if !t.watchedBy(user.ID) {
+			t.watchers = append(t.watchers, user)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return map[int]string{user.ID: "Задача с таким id не найдена"}, nil
+	}
+	return map[int]string{user.ID: fmt.Sprintf("Вы следите за задачей \"%s\"", task.info)}, nil
+}
+
+func (tb *TaskBot) UnwatchTask(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	id := args.ID
+
+	user := upd.Message.From
+	task, err := tb.Repo.Update(args.Workspace.ID, id, func(t *Task) { t.unwatch(user.ID) })
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return map[int]string{user.ID: "Задача с таким id не найдена"}, nil
+	}
+	return map[int]string{user.ID: fmt.Sprintf("Вы больше не следите за задачей \"%s\"", task.info)}, nil
+}
+
+func (tb *TaskBot) SnoozeTask(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	id := args.ID
+	d := time.Hour
+	if args.Text != "" {
+		var err error
+		if d, err = parseSnooze(args.Text); err != nil {
+			return tb.usage(upd, "/snooze"), nil
+		}
+	}
+
+	userID := upd.Message.From.ID
+	task, err := tb.Repo.GetByID(args.Workspace.ID, id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil || task.Resolved() {
+		return map[int]string{userID: "Задача с таким id не найдена"}, nil
+	}
+	if task.assignedTo == nil || task.assignedTo.ID != userID {
+		return map[int]string{userID: "Задача не на вас"}, nil
+	}
+	if task.due.IsZero() {
+		return map[int]string{userID: fmt.Sprintf("У задачи \"%s\" нет срока", task.info)}, nil
+	}
+
+	until := tb.Now().Add(d)
+	task, err = tb.Repo.Update(args.Workspace.ID, id, func(t *Task) {
+		t.reminded = false
+		t.snoozedUntil = until
+	})
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return map[int]string{userID: "Задача с таким id не найдена"}, nil
+	}
+
+	loc, err := tb.location(userID)
+	if err != nil {
+		return nil, err
+	}
+	return map[int]string{
+		userID: fmt.Sprintf("Напомню про задачу \"%s\" %s", task.info, until.In(loc).Format(dueLayout)),
+	}, nil
+}
+
+func (tb *TaskBot) SetTimeZone(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	userID := upd.Message.From.ID
+	if args.Text == "" {
+		loc, err := tb.location(userID)
+		if err != nil {
+			return nil, err
+		}
+		return map[int]string{userID: "Часовой пояс: " + loc.String()}, nil
+	}
+
+	loc, err := time.LoadLocation(args.Text)
+	if err != nil || strings.EqualFold(args.Text, "local") {
+		return tb.usage(upd, "/tz"), nil
+	}
+	_, err = tb.Repo.UpdateSettings(userID, func(s *UserSettings) { s.TimeZone = loc.String() })
+	if err != nil {
+		return nil, err
+	}
+	return map[int]string{userID: "Часовой пояс: " + loc.String()}, nil
+}
+
+func (tb *TaskBot) SetDigest(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	userID := upd.Message.From.ID
+	at := args.Text
+	if strings.EqualFold(at, "off") {
+		at = "-"
+	} else if _, err := time.Parse(digestLayout, at); err != nil || len(at) != len(digestLayout) {
+		return tb.usage(upd, "/digest"), nil
+	}
+
+	settings, err := tb.Repo.UpdateSettings(userID, func(s *UserSettings) { s.DigestAt = at })
+	if err != nil {
+		return nil, err
+	}
+	if settings.DigestAt == "-" {
+		return map[int]string{userID: "Дайджест выключен"}, nil
+	}
+	loc, err := tb.location(userID)
+	if err != nil {
+		return nil, err
+	}
+	return map[int]string{userID: fmt.Sprintf("Дайджест каждый день в %s (%s)", at, loc)}, nil
+}
+
+// location - часовой пояс пользователя из /tz или пояс бота
+func (tb *TaskBot) location(userID int) (*time.Location, error) {
+	settings, err := tb.Repo.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.TimeZone == "" {
+		return tb.Location, nil
+	}
+	return time.LoadLocation(settings.TimeZone)
+}
+
+// updateTask меняет открытую задачу и сообщает об изменении автору, исполнителю и наблюдателям.
+// Сам пользователь получает тот же текст без подписи
+func (tb *TaskBot) updateTask(upd *tgbotapi.Update, args Args, update func(t *Task), change func(t *Task) string) (map[int]string, error) {
+	task, err := tb.Repo.Update(args.Workspace.ID, args.ID, update)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := upd.Message.From.ID
+	if task == nil {
+		return map[int]string{userID: "Задача с таким id не найдена"}, nil
+	}
+
+	text := fmt.Sprintf("Задача \"%s\": %s", task.info, change(task))
+	result := map[int]string{userID: text}
+	notifyAll(result, task, userID, fmt.Sprintf("%s (@%s)", text, upd.Message.From))
+	return result, nil
+}
+
+// manageTask - updateTask для того, что в группе меняют только автор, исполнитель и админы
+func (tb *TaskBot) manageTask(upd *tgbotapi.Update, args Args, update func(t *Task), change func(t *Task) string) (map[int]string, error) {
+	task, err := tb.Repo.GetByID(args.Workspace.ID, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	userID := upd.Message.From.ID
+	if task != nil && !task.Resolved() && !args.Workspace.canManage(task, userID) {
+		return map[int]string{userID: noRights}, nil
+	}
+	return tb.updateTask(upd, args, update, change)
+}
+
+const noRights = "Недостаточно прав: это могут автор задачи, исполнитель или админ"
+
+// notifyWatchers дописывает text наблюдателям, кроме автора действия и тех, кому уже что-то отправляется
+func notifyWatchers(result map[int]string, task *Task, actorID int, text string) {
+	for _, w := range task.watchers {
+		if _, ok := result[w.ID]; !ok && w.ID != actorID {
+			result[w.ID] = text
+		}
+	}
+}
+
+// notifyAll - как notifyWatchers, но ещё автору и исполнителю задачи
+func notifyAll(result map[int]string, task *Task, actorID int, text string) {
+	users := append([]*tgbotapi.User{task.createdBy, task.assignedTo}, task.watchers...)
+	for _, u := range users {
+		if u == nil || u.ID == actorID {
+			continue
+		}
+		if _, ok := result[u.ID]; !ok {
+			result[u.ID] = text
+		}
+	}
+}
+
+// parseSnooze - длительность как в time.ParseDuration, плюс дни вида 2d
+func parseSnooze(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("snooze must be positive, got %s", s)
+	}
+	return d, nil
+}
+
+// parseDue понимает "2021-03-15 18:00" и "2021-03-15" - это конец дня
+func parseDue(s string, loc *time.Location) (time.Time, error) {
+	if due, err := time.ParseInLocation(dueLayout, s, loc); err == nil {
+		return due, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.Add(23*time.Hour + 59*time.Minute), nil
+}
@@ -1,179 +1,498 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	tgbotapi "gopkg.in/telegram-bot-api.v4"
 )
 
-type TasksRepoInMemory struct {
-	lastID int
-	tasks  []*Task
-	mu     *sync.Mutex
+// TasksRepo - хранилище задач по рабочим пространствам, ws - Workspace.ID.
+// Все методы отдают копии, менять задачу можно только через методы репозитория
+type TasksRepo interface {
+	// GetAll - открытые задачи, сначала с большим приоритетом, дальше по id
+	GetAll(ws int) ([]*Task, error)
+	// GetResolved - выполненные задачи, последние выполненные первыми
+	GetResolved(ws int) ([]*Task, error)
+	// GetByID - задача, в том числе выполненная, или nil
+	GetByID(ws, id int) (*Task, error)
+	GetByAssigneeID(ws, id int) ([]*Task, error)
+	GetByAuthorID(ws, id int) ([]*Task, error)
+	Add(ws int, task *Task) (int, error)
+	AssignTo(ws, taskID int, usr *tgbotapi.User) (bool, error)
+	Unassign(ws, taskID int, usr *tgbotapi.User) (bool, error)
+	// Resolve переносит задачу в историю, false - если её нет или она уже выполнена
+	Resolve(ws, taskID int, usr *tgbotapi.User, at time.Time) (bool, error)
+	// Update меняет открытую задачу в update и возвращает её новую копию, nil - если задачи нет
+	Update(ws, taskID int, update func(t *Task)) (*Task, error)
+
+	// Join - пространство чата, пользователь становится его участником.
+	// Личные чаты все ведут в defaultWorkspace, группа получает своё пространство
+	// при первой команде, а её первый участник становится владельцем
+	Join(chat *tgbotapi.Chat, usr *tgbotapi.User) (*Workspace, error)
+	// GetWorkspace - пространство или nil
+	GetWorkspace(ws int) (*Workspace, error)
+	// GetWorkspaces - все пространства по id, defaultWorkspace первым
+	GetWorkspaces() ([]*Workspace, error)
+	// SetRole меняет роль участника, false - если такого участника нет
+	SetRole(ws, userID int, role Role) (bool, error)
+
+	// GetSettings - настройки пользователя, нулевые, если он ничего не менял
+	GetSettings(userID int) (UserSettings, error)
+	UpdateSettings(userID int, update func(s *UserSettings)) (UserSettings, error)
 }
 
-func NewTasksRepoInMemory() *TasksRepoInMemory {
-	return &TasksRepoInMemory{tasks: make([]*Task, 0, 10), mu: &sync.Mutex{}}
+// UserSettings - часовой пояс, дайджест и выбранное пространство пользователя
+type UserSettings struct {
+	// TimeZone - название из базы часовых поясов, пустое - пояс бота
+	TimeZone string `json:"time_zone,omitempty"`
+	// DigestAt - во сколько по местному времени присылать дайджест, пустое - в digestDefault, "-" - не присылать
+	DigestAt string `json:"digest_at,omitempty"`
+	// LastDigest - местная дата последнего дайджеста, чтобы не прислать два за день
+	LastDigest string `json:"last_digest,omitempty"`
+	// Workspace - пространство для команд в личных сообщениях
+	Workspace int `json:"workspace,omitempty"`
 }
 
-type Task struct {
-	ID         int
-	info       string
-	assignedTo *tgbotapi.User
-	createdBy  *tgbotapi.User
+// space - пространство вместе с его задачами, id задач у каждого свои
+type space struct {
+	*Workspace
+	LastID int     `json:"last_id"`
+	Tasks  []*Task `json:"tasks"`
 }
 
-func (t *Task) String(currUserID int, includeAssignee bool) string {
-	res := fmt.Sprintf("%d. %s by @%s", t.ID, t.info, t.createdBy.String())
-
-	if includeAssignee && t.assignedTo != nil {
-		var assignee string
-		if t.assignedTo.ID == currUserID {
-			assignee = "я"
-		} else {
-			assignee = fmt.Sprintf("@%s", t.assignedTo.UserName)
-		}
-		res += fmt.Sprintf("\nassignee: %s", assignee)
-	}
-
-	res += t.getAvailableCmds(currUserID)
-	return res
+type TasksRepoInMemory struct {
+	spaces   map[int]*space
+	lastWS   int
+	settings map[int]UserSettings
+	mu       *sync.Mutex
+	// onChange вызывается под mu после каждого изменения
+	onChange func() error
 }
 
-func (t *Task) getAvailableCmds(currUserID int) string {
-	if t.assignedTo == nil {
-		return fmt.Sprintf("\n/assign_%d", t.ID)
-	}
-	if t.assignedTo != nil && t.assignedTo.ID == currUserID {
-		return fmt.Sprintf("\n/unassign_%d /resolve_%d", t.ID, t.ID)
+func NewTasksRepoInMemory() *TasksRepoInMemory {
+	return &TasksRepoInMemory{
+		spaces: map[int]*space{
+			defaultWorkspace: {Workspace: &Workspace{ID: defaultWorkspace}, Tasks: make([]*Task, 0, 10)},
+		},
+		settings: make(map[int]UserSettings),
+		mu:       &sync.Mutex{},
 	}
-
-	return ""
 }
 
-func (r *TasksRepoInMemory) GetAll() ([]*Task, error) {
+func (r *TasksRepoInMemory) GetAll(ws int) ([]*Task, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	return r.tasks, nil
+	return r.open(ws, func(t *Task) bool { return true }), nil
 }
 
-func (r *TasksRepoInMemory) GetByUserID(id int) ([]*Task, error) {
+func (r *TasksRepoInMemory) GetResolved(ws int) ([]*Task, error) {
 	r.mu.Lock()
+	defer r.mu.Unlock()
 	res := make([]*Task, 0)
-	for _, t := range r.tasks {
-		if t.createdBy.ID == id {
-			res = append(res, t)
+	if s := r.spaces[ws]; s != nil {
+		for _, t := range s.Tasks {
+			if t.Resolved() {
+				res = append(res, t.clone())
+			}
 		}
 	}
-
-	r.mu.Unlock()
+	sort.SliceStable(res, func(i, j int) bool { return res[i].resolvedAt.After(res[j].resolvedAt) })
 	return res, nil
 }
 
-func (r *TasksRepoInMemory) Add(task *Task) (int, error) {
+func (r *TasksRepoInMemory) Add(ws int, task *Task) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	task.ID = r.lastID + 1
-	r.tasks = append(r.tasks, task)
-	r.lastID++
 
-	return task.ID, nil
+	s := r.spaces[ws]
+	if s == nil {
+		return 0, fmt.Errorf("no workspace %d", ws)
+	}
+	before := r.snapshot()
+	task.ID = s.LastID + 1
+	s.Tasks = append(s.Tasks, task.clone())
+	s.LastID++
+
+	return task.ID, r.changed(before)
 }
 
-func (r *TasksRepoInMemory) AssignTo(taskID int, usr *tgbotapi.User) (bool, error) {
+func (r *TasksRepoInMemory) AssignTo(ws, taskID int, usr *tgbotapi.User) (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	var task *Task
-	for _, t := range r.tasks {
-		if t.ID == taskID {
-			task = t
-		}
-	}
-
+	task := r.get(ws, taskID)
 	if task == nil {
 		return false, nil
 	}
 
+	before := r.snapshot()
 	task.assignedTo = usr
-	return true, nil
+	return true, r.changed(before)
 }
 
-func (r *TasksRepoInMemory) Unassign(taskID int, usr *tgbotapi.User) (bool, error) {
+func (r *TasksRepoInMemory) Unassign(ws, taskID int, usr *tgbotapi.User) (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	task := r.get(taskID)
+	task := r.get(ws, taskID)
 
 	if task == nil {
 		return false, nil
 	}
 
+	before := r.snapshot()
 	task.assignedTo = nil
-	return true, nil
+	return true, r.changed(before)
 }
 
-func (r *TasksRepoInMemory) Delete(id int) (bool, error) {
+func (r *TasksRepoInMemory) Resolve(ws, taskID int, usr *tgbotapi.User, at time.Time) (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for i, t := range r.tasks {
-		if t.ID == id {
-			l := len(r.tasks)
-			r.tasks[i] = r.tasks[l-1]
-			r.tasks = r.tasks[:l-1]
-			return true, nil
-		}
+	task := r.get(ws, taskID)
+	if task == nil {
+		return false, nil
+	}
+
+	before := r.snapshot()
+	task.resolvedBy = usr
+	task.resolvedAt = at
+	return true, r.changed(before)
+}
+
+func (r *TasksRepoInMemory) Update(ws, taskID int, update func(t *Task)) (*Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task := r.get(ws, taskID)
+	if task == nil {
+		return nil, nil
 	}
 
-	return false, nil
+	before := r.snapshot()
+	update(task)
+	if err := r.changed(before); err != nil {
+		return nil, err
+	}
+	return task.clone(), nil
 }
 
-func (r *TasksRepoInMemory) GetByID(id int) (*Task, error) {
+func (r *TasksRepoInMemory) Join(chat *tgbotapi.Chat, usr *tgbotapi.User) (*Workspace, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	for _, t := range r.tasks {
-		if t.ID == id {
-			return t, nil
+
+	// файл переписываем, только если что-то поменялось, а не на каждую команду,
+	// и копию для отката снимаем перед первым изменением
+	var before *repoState
+	changed := false
+	change := func() {
+		if !changed {
+			before = r.snapshot()
+			changed = true
+		}
+	}
+
+	s := r.spaces[defaultWorkspace]
+	if chat != nil && !chat.IsPrivate() {
+		s = r.byChat(chat.ID)
+		if s == nil {
+			change()
+			r.lastWS++
+			s = &space{Workspace: &Workspace{ID: r.lastWS, ChatID: chat.ID}, Tasks: make([]*Task, 0, 10)}
+			r.spaces[s.ID] = s
+		}
+		if s.Title != chat.Title {
+			change()
+			s.Title = chat.Title
+		}
+	}
+
+	if m := s.Member(usr.ID); m != nil {
+		if *m.User != *usr {
+			change()
+			m.User = usr
+		}
+	} else {
+		change()
+		role := RoleMember
+		if s.Group() && len(s.Members) == 0 {
+			role = RoleOwner
+		}
+		s.Members = append(s.Members, &Member{User: usr, Role: role})
+	}
+
+	if changed {
+		if err := r.changed(before); err != nil {
+			return nil, err
 		}
 	}
+	return s.clone(), nil
+}
 
+func (r *TasksRepoInMemory) GetWorkspace(ws int) (*Workspace, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s := r.spaces[ws]; s != nil {
+		return s.clone(), nil
+	}
 	return nil, nil
 }
 
-func (r *TasksRepoInMemory) GetByAssigneeID(id int) ([]*Task, error) {
+func (r *TasksRepoInMemory) GetWorkspaces() ([]*Workspace, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	res := make([]*Task, 0)
-	for _, t := range r.tasks {
-		if t.assignedTo != nil && t.assignedTo.ID == id {
-			res = append(res, t)
+	res := make([]*Workspace, 0, len(r.spaces))
+	for _, s := range r.spaces {
+		res = append(res, s.clone())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+func (r *TasksRepoInMemory) SetRole(ws, userID int, role Role) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.spaces[ws]
+	if s == nil {
+		return false, nil
+	}
+	m := s.Member(userID)
+	if m == nil {
+		return false, nil
+	}
+	before := r.snapshot()
+	m.Role = role
+	return true, r.changed(before)
+}
+
+func (r *TasksRepoInMemory) GetSettings(userID int) (UserSettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.settings[userID], nil
+}
+
+func (r *TasksRepoInMemory) UpdateSettings(userID int, update func(s *UserSettings)) (UserSettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before := r.snapshot()
+	s := r.settings[userID]
+	update(&s)
+	r.settings[userID] = s
+	if err := r.changed(before); err != nil {
+		return UserSettings{}, err
+	}
+	return s, nil
+}
+
+func (r *TasksRepoInMemory) GetByID(ws, id int) (*Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s := r.spaces[ws]; s != nil {
+		for _, t := range s.Tasks {
+			if t.ID == id {
+				return t.clone(), nil
+			}
 		}
 	}
 
-	return res, nil
+	return nil, nil
+}
+
+func (r *TasksRepoInMemory) GetByAssigneeID(ws, id int) ([]*Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.open(ws, func(t *Task) bool { return t.assignedTo != nil && t.assignedTo.ID == id }), nil
 }
 
-func (r *TasksRepoInMemory) GetByAuthorID(id int) ([]*Task, error) {
+func (r *TasksRepoInMemory) GetByAuthorID(ws, id int) ([]*Task, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	return r.open(ws, func(t *Task) bool { return t.createdBy.ID == id }), nil
+}
+
+// open - копии открытых задач пространства под фильтром в порядке GetAll
+func (r *TasksRepoInMemory) open(ws int, filter func(t *Task) bool) []*Task {
 	res := make([]*Task, 0)
-	for _, t := range r.tasks {
-		if t.createdBy.ID == id {
-			res = append(res, t)
+	s := r.spaces[ws]
+	if s == nil {
+		return res
+	}
+	for _, t := range s.Tasks {
+		if !t.Resolved() && filter(t) {
+			res = append(res, t.clone())
 		}
 	}
-
-	return res, nil
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].priority != res[j].priority {
+			return res[i].priority > res[j].priority
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res
 }
 
-func (r *TasksRepoInMemory) get(id int) *Task {
-	for _, t := range r.tasks {
-		if t.ID == id {
+// get - открытая задача пространства
+func (r *TasksRepoInMemory) get(ws, id int) *Task {
+	s := r.spaces[ws]
+	if s == nil {
+		return nil
+	}
+	for _, t := range s.Tasks {
+		if t.ID == id && !t.Resolved() {
 			return t
 		}
 	}
 
 	return nil
 }
+
+func (r *TasksRepoInMemory) byChat(chatID int64) *space {
+	for _, s := range r.spaces {
+		if s.ChatID == chatID {
+			return s
+		}
+	}
+	return nil
+}
+
+// repoState - копия всего, что сохраняет onChange
+type repoState struct {
+	spaces   map[int]*space
+	lastWS   int
+	settings map[int]UserSettings
+}
+
+// snapshot снимается перед изменением, чтобы откатить его, если сохранить не получилось.
+// Без onChange откатывать нечего и копия не нужна
+func (r *TasksRepoInMemory) snapshot() *repoState {
+	if r.onChange == nil {
+		return nil
+	}
+
+	st := &repoState{
+		spaces:   make(map[int]*space, len(r.spaces)),
+		lastWS:   r.lastWS,
+		settings: make(map[int]UserSettings, len(r.settings)),
+	}
+	for id, s := range r.spaces {
+		c := &space{Workspace: s.Workspace.clone(), LastID: s.LastID, Tasks: make([]*Task, len(s.Tasks))}
+		for i, t := range s.Tasks {
+			c.Tasks[i] = t.clone()
+		}
+		st.spaces[id] = c
+	}
+	for id, us := range r.settings {
+		st.settings[id] = us
+	}
+	return st
+}
+
+// changed сохраняет изменение, а если не вышло - возвращает состояние before,
+// чтобы в памяти не осталось того, что пропадёт после перезапуска
+func (r *TasksRepoInMemory) changed(before *repoState) error {
+	if r.onChange == nil {
+		return nil
+	}
+	if err := r.onChange(); err != nil {
+		r.spaces, r.lastWS, r.settings = before.spaces, before.lastWS, before.settings
+		return err
+	}
+	return nil
+}
+
+// TasksRepoFile держит задачи в памяти и после каждого изменения
+// целиком переписывает json-файл, так что перезапуск бота ничего не теряет
+type TasksRepoFile struct {
+	*TasksRepoInMemory
+	path string
+}
+
+type tasksFile struct {
+	Workspaces []*space             `json:"workspaces"`
+	Users      map[int]UserSettings `json:"users,omitempty"`
+
+	// LastID и Tasks - из файла, записанного до рабочих пространств, это задачи defaultWorkspace
+	LastID int     `json:"last_id,omitempty"`
+	Tasks  []*Task `json:"tasks,omitempty"`
+}
+
+func NewTasksRepoFile(path string) (*TasksRepoFile, error) {
+	r := &TasksRepoFile{TasksRepoInMemory: NewTasksRepoInMemory(), path: path}
+
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		var f tasksFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("bad tasks file %s: %v", path, err)
+		}
+		if f.Tasks != nil {
+			f.Workspaces = append(f.Workspaces, &space{
+				Workspace: &Workspace{ID: defaultWorkspace},
+				LastID:    f.LastID,
+				Tasks:     f.Tasks,
+			})
+		}
+		for _, s := range f.Workspaces {
+			if s.Workspace == nil {
+				return nil, fmt.Errorf("bad tasks file %s: workspace without id", path)
+			}
+			for _, t := range s.Tasks {
+				if t.ID > s.LastID {
+					s.LastID = t.ID
+				}
+			}
+			r.spaces[s.ID] = s
+			if s.ID > r.lastWS {
+				r.lastWS = s.ID
+			}
+		}
+		if f.Users != nil {
+			r.settings = f.Users
+		}
+	}
+
+	r.onChange = r.save
+	return r, nil
+}
+
+// save пишет во временный файл и переименовывает его, чтобы при падении не остался огрызок
+func (r *TasksRepoFile) save() error {
+	f := tasksFile{Users: r.settings}
+	for _, s := range r.spaces {
+		f.Workspaces = append(f.Workspaces, s)
+	}
+	sort.Slice(f.Workspaces, func(i, j int) bool { return f.Workspaces[i].ID < f.Workspaces[j].ID })
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// newUpdate - сообщение пользователя боту, как его присылает телеграм в личном чате
+func newUpdate(userID int, text string) *tgbotapi.Update {
+	user := users[userID]
+	return &tgbotapi.Update{
+		Message: &tgbotapi.Message{
+			From: user,
+			Chat: &tgbotapi.Chat{ID: int64(user.ID), Type: "private"},
+			Text: text,
+		},
+	}
+}
+
+// runCmds прогоняет команды прямо через ExecuteCmd, без вебхука и фейкового телеграма
+func runCmds(t *testing.T, tb *TaskBot, cases []testCase) {
+	t.Helper()
+	for idx, item := range cases {
+		answers, err := tb.ExecuteCmd(newUpdate(item.user, item.command))
+		if err != nil {
+			t.Fatalf("[%d] %s: unexpected error %v", idx, item.command, err)
+		}
+		if !reflect.DeepEqual(answers, item.answers) {
+			t.Fatalf("[%d] %s: bad answers\n\tgot: %#v\n\twant: %#v", idx, item.command, answers, item.answers)
+		}
+	}
+}
+
+func newTestBot(repo TasksRepo) *TaskBot {
+	tb := NewTaskBot(repo)
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	tb.Now = func() time.Time { return now }
+	return tb
+}
+
+func TestTaskDetails(t *testing.T) {
+	tb := newTestBot(NewTasksRepoInMemory())
+
+	runCmds(t, tb, []testCase{
+		{Ivanov, "/new", map[int]string{Ivanov: "Формат: /new текст задачи"}},
+		{Ivanov, "/new сделать деплой", map[int]string{Ivanov: `Задача "сделать деплой" создана, id=1`}},
+		{Ivanov, "/new написать тесты", map[int]string{Ivanov: `Задача "написать тесты" создана, id=2`}},
+		{Petrov, "/watch_1", map[int]string{Petrov: `Вы следите за задачей "сделать деплой"`}},
+		{Alexandrov, "/assign_1", map[int]string{
+			Alexandrov: `Задача "сделать деплой" назначена на вас`,
+			Ivanov:     `Задача "сделать деплой" назначена на @aalexandrov`,
+			Petrov:     `Задача "сделать деплой" назначена на @aalexandrov`,
+		}},
+		{Ivanov, "/due_1 2021-03-15 18:00", map[int]string{
+			Ivanov:     `Задача "сделать деплой": срок 2021-03-15 18:00`,
+			Alexandrov: `Задача "сделать деплой": срок 2021-03-15 18:00 (@ivanov)`,
+			Petrov:     `Задача "сделать деплой": срок 2021-03-15 18:00 (@ivanov)`,
+		}},
+		{Ivanov, "/due_1 завтра", map[int]string{
+			Ivanov: "Формат: /due_$ID 2021-03-15 18:00, /due_$ID 2021-03-15 или /due_$ID - чтобы снять срок",
+		}},
+		{Ivanov, "/due_2 2021-03-20", map[int]string{Ivanov: `Задача "написать тесты": срок 2021-03-20 23:59`}},
+		{Ivanov, "/due_2 -", map[int]string{Ivanov: `Задача "написать тесты": срок снят`}},
+		{Petrov, "/tag_2 #Go bug", map[int]string{
+			Petrov: `Задача "написать тесты": теги #bug #go`,
+			Ivanov: `Задача "написать тесты": теги #bug #go (@ppetrov)`,
+		}},
+		{Petrov, "/tag_2 -bug", map[int]string{
+			Petrov: `Задача "написать тесты": теги #go`,
+			Ivanov: `Задача "написать тесты": теги #go (@ppetrov)`,
+		}},
+		{Ivanov, "/priority_2 urgent", map[int]string{Ivanov: "Формат: /priority_$ID high, normal или low"}},
+		{Ivanov, "/priority_2 high", map[int]string{Ivanov: `Задача "написать тесты": приоритет high`}},
+		// задачи с большим приоритетом идут первыми
+		{Alexandrov, "/tasks", map[int]string{
+			Alexandrov: `2. написать тесты by @ivanov
+priority: high
+tags: #go
+/assign_2
+
+1. сделать деплой by @ivanov
+assignee: я
+due: 2021-03-15 18:00
+/unassign_1 /resolve_1`,
+		}},
+		{Alexandrov, "/tasks #go", map[int]string{
+			Alexandrov: `2. написать тесты by @ivanov
+priority: high
+tags: #go
+/assign_2`,
+		}},
+		{Alexandrov, "/comment_1 выкатил на стейдж", map[int]string{
+			Alexandrov: `Задача "сделать деплой": комментарий "выкатил на стейдж"`,
+			Ivanov:     `Задача "сделать деплой": комментарий "выкатил на стейдж" (@aalexandrov)`,
+			Petrov:     `Задача "сделать деплой": комментарий "выкатил на стейдж" (@aalexandrov)`,
+		}},
+		{Ivanov, "/task_1", map[int]string{
+			Ivanov: `1. сделать деплой by @ivanov
+assignee: @aalexandrov
+due: 2021-03-15 18:00
+comments: 1
+watchers: @ppetrov
+
+@aalexandrov 2021-03-10 12:00:
+выкатил на стейдж`,
+		}},
+		{Petrov, "/unwatch_1", map[int]string{Petrov: `Вы больше не следите за задачей "сделать деплой"`}},
+		{Ivanov, "/done", map[int]string{Ivanov: "Нет выполненных задач"}},
+		{Alexandrov, "/resolve_1", map[int]string{
+			Alexandrov: `Задача "сделать деплой" выполнена`,
+			Ivanov:     `Задача "сделать деплой" выполнена @aalexandrov`,
+		}},
+		{Alexandrov, "/assign_1", map[int]string{Alexandrov: "Задача с таким id не найдена"}},
+		{Alexandrov, "/due_1 -", map[int]string{Alexandrov: "Задача с таким id не найдена"}},
+		{Ivanov, "/done", map[int]string{
+			Ivanov: `1. сделать деплой by @ivanov
+due: 2021-03-15 18:00
+comments: 1
+resolved by @aalexandrov 2021-03-10 12:00`,
+		}},
+		{Ivanov, "/task_1", map[int]string{
+			Ivanov: `1. сделать деплой by @ivanov
+due: 2021-03-15 18:00
+comments: 1
+resolved by @aalexandrov 2021-03-10 12:00`,
+		}},
+	})
+}
+
+func TestTasksRepoFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	repo, err := NewTasksRepoFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runCmds(t, newTestBot(repo), []testCase{
+		{Ivanov, "/new сделать деплой", map[int]string{Ivanov: `Задача "сделать деплой" создана, id=1`}},
+		{Ivanov, "/new написать тесты", map[int]string{Ivanov: `Задача "написать тесты" создана, id=2`}},
+		{Petrov, "/assign_2", map[int]string{
+			Petrov: `Задача "написать тесты" назначена на вас`,
+			Ivanov: `Задача "написать тесты" назначена на @ppetrov`,
+		}},
+		{Petrov, "/tag_2 go", map[int]string{
+			Petrov: `Задача "написать тесты": теги #go`,
+			Ivanov: `Задача "написать тесты": теги #go (@ppetrov)`,
+		}},
+		{Petrov, "/resolve_1", map[int]string{
+			Petrov: `Задача "сделать деплой" выполнена`,
+			Ivanov: `Задача "сделать деплой" выполнена @ppetrov`,
+		}},
+		{Petrov, "/tz Europe/Moscow", map[int]string{Petrov: "Часовой пояс: Europe/Moscow"}},
+	})
+
+	// после перезапуска всё на месте, а id продолжаются
+	repo, err = NewTasksRepoFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runCmds(t, newTestBot(repo), []testCase{
+		{Petrov, "/my", map[int]string{
+			Petrov: `2. написать тесты by @ivanov
+tags: #go
+/unassign_2 /resolve_2`,
+		}},
+		{Ivanov, "/done", map[int]string{
+			Ivanov: `1. сделать деплой by @ivanov
+resolved by @ppetrov 2021-03-10 12:00`,
+		}},
+		{Alexandrov, "/new третья", map[int]string{Alexandrov: `Задача "третья" создана, id=3`}},
+		{Petrov, "/tz", map[int]string{Petrov: "Часовой пояс: Europe/Moscow"}},
+	})
+
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil || len(matches) != 0 {
+		t.Fatalf("temporary files left: %v %v", matches, err)
+	}
+}
+
+func TestTasksRepoFileSaveError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	repo, err := NewTasksRepoFile(filepath.Join(dir, "tasks.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ivanov := users[Ivanov]
+	if _, err := repo.Add(defaultWorkspace, &Task{info: "сделать деплой", createdBy: ivanov}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// писать больше некуда: изменения не должны остаться только в памяти
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.Add(defaultWorkspace, &Task{info: "написать тесты", createdBy: ivanov}); err == nil {
+		t.Fatalf("expected save error on Add")
+	}
+	if _, err := repo.AssignTo(defaultWorkspace, 1, ivanov); err == nil {
+		t.Fatalf("expected save error on AssignTo")
+	}
+	if _, err := repo.Join(&tgbotapi.Chat{ID: -100, Type: "group", Title: "ops"}, ivanov); err == nil {
+		t.Fatalf("expected save error on Join")
+	}
+
+	tasks, _ := repo.GetAll(defaultWorkspace)
+	if len(tasks) != 1 || tasks[0].assignedTo != nil {
+		t.Fatalf("expected the only unassigned task, got %+v", tasks)
+	}
+	spaces, _ := repo.GetWorkspaces()
+	if len(spaces) != 1 {
+		t.Fatalf("expected no new workspace, got %d", len(spaces))
+	}
+
+	// когда файл снова пишется, id продолжаются с сохранённого
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id, err := repo.Add(defaultWorkspace, &Task{info: "написать тесты", createdBy: ivanov}); err != nil || id != 2 {
+		t.Fatalf("expected id 2, got %d %v", id, err)
+	}
+}
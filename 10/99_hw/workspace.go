@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// defaultWorkspace - общее пространство для всех личных сообщений, как было до групп
+const defaultWorkspace = 0
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+)
+
+type Member struct {
+	User *tgbotapi.User `json:"user"`
+	Role Role           `json:"role"`
+}
+
+// Workspace - свой список задач и участников у каждой группы, к которой подключили бота
+type Workspace struct {
+	ID int `json:"id"`
+	// ChatID - группа пространства, у defaultWorkspace 0
+	ChatID  int64     `json:"chat_id,omitempty"`
+	Title   string    `json:"title,omitempty"`
+	Members []*Member `json:"members,omitempty"`
+}
+
+func (w *Workspace) Group() bool {
+	return w.ChatID != 0
+}
+
+// Name - название для списков и заголовков
+func (w *Workspace) Name() string {
+	if !w.Group() {
+		return "личные сообщения"
+	}
+	if w.Title == "" {
+		return "группа"
+	}
+	return w.Title
+}
+
+// Member - участник пространства или nil
+func (w *Workspace) Member(userID int) *Member {
+	for _, m := range w.Members {
+		if m.User.ID == userID {
+			return m
+		}
+	}
+	return nil
+}
+
+// MemberByName ищет участника по @username без учёта регистра
+func (w *Workspace) MemberByName(name string) *Member {
+	name = strings.TrimPrefix(name, "@")
+	for _, m := range w.Members {
+		if strings.EqualFold(m.User.UserName, name) {
+			return m
+		}
+	}
+	return nil
+}
+
+// Admin - может ли пользователь менять и выполнять чужие задачи.
+// В личных сообщениях ролей нет и можно всем
+func (w *Workspace) Admin(userID int) bool {
+	if !w.Group() {
+		return true
+	}
+	m := w.Member(userID)
+	return m != nil && (m.Role == RoleOwner || m.Role == RoleAdmin)
+}
+
+// canManage - менять срок, теги, приоритет и выполнять задачу могут автор, исполнитель и админы
+func (w *Workspace) canManage(t *Task, userID int) bool {
+	if t.createdBy.ID == userID || (t.assignedTo != nil && t.assignedTo.ID == userID) {
+		return true
+	}
+	return w.Admin(userID)
+}
+
+func (w *Workspace) clone() *Workspace {
+	c := *w
+	c.Members = make([]*Member, len(w.Members))
+	for i, m := range w.Members {
+		mc := *m
+		c.Members[i] = &mc
+	}
+	return &c
+}
+
+// workspace - пространство, в котором выполняется команда из msg: группа чата
+// или для личных сообщений то, что пользователь выбрал через /workspace
+func (tb *TaskBot) workspace(msg *tgbotapi.Message) (*Workspace, error) {
+	ws, err := tb.Repo.Join(msg.Chat, msg.From)
+	if err != nil || ws.Group() {
+		return ws, err
+	}
+
+	settings, err := tb.Repo.GetSettings(msg.From.ID)
+	if err != nil || settings.Workspace == defaultWorkspace {
+		return ws, err
+	}
+	selected, err := tb.Repo.GetWorkspace(settings.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	// из группы могли уйти или её могли потерять - тогда снова общее пространство
+	if selected == nil || selected.Member(msg.From.ID) == nil {
+		return ws, nil
+	}
+	return selected, nil
+}
+
+// route раскладывает ответы по чатам. В общем пространстве каждый получает своё лично,
+// а ответы про задачи группы уходят в группу одним сообщением с упоминаниями.
+// Автору команды из личных сообщений отвечаем туда же, где он спросил
+func route(msg *tgbotapi.Message, ws *Workspace, result map[int]string) map[int]string {
+	if !ws.Group() || len(result) == 0 {
+		return result
+	}
+
+	actorID := msg.From.ID
+	ids := make([]int, 0, len(result))
+	for id := range result {
+		if id != actorID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	if _, ok := result[actorID]; ok {
+		ids = append([]int{actorID}, ids...)
+	}
+
+	routed := make(map[int]string)
+	inGroup := msg.Chat != nil && msg.Chat.ID == ws.ChatID
+	for _, id := range ids {
+		m := ws.Member(id)
+		if (id == actorID && !inGroup) || m == nil {
+			addMessage(routed, id, result[id])
+			continue
+		}
+		addMessage(routed, int(ws.ChatID), mention(m.User)+" "+result[id])
+	}
+	return routed
+}
+
+// mention - @username, по которому телеграм уведомит участника. Без username упомянуть
+// можно только ссылкой с разметкой, а ответы уходят простым текстом, поэтому тогда просто имя
+func mention(u *tgbotapi.User) string {
+	if u.UserName == "" {
+		return u.String()
+	}
+	return "@" + u.UserName
+}
+
+func (tb *TaskBot) SelectWorkspace(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	user := upd.Message.From
+	if upd.Message.Chat != nil && !upd.Message.Chat.IsPrivate() {
+		return map[int]string{user.ID: fmt.Sprintf("Это пространство «%s», выбрать его в личных сообщениях: /workspace %d",
+			args.Workspace.Name(), args.Workspace.ID)}, nil
+	}
+
+	if args.Text == "" {
+		spaces, err := tb.Repo.GetWorkspaces()
+		if err != nil {
+			return nil, err
+		}
+		var sb strings.Builder
+		sb.WriteString("Рабочие пространства:")
+		for _, ws := range spaces {
+			if ws.Group() && ws.Member(user.ID) == nil {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("\n%d. %s", ws.ID, ws.Name()))
+			if ws.ID == args.Workspace.ID {
+				sb.WriteString(" - выбрано")
+			}
+		}
+		sb.WriteString("\nВыбрать: /workspace $N")
+		return map[int]string{user.ID: sb.String()}, nil
+	}
+
+	id, err := strconv.Atoi(args.Text)
+	if err != nil {
+		return tb.usage(upd, "/workspace"), nil
+	}
+	ws, err := tb.Repo.GetWorkspace(id)
+	if err != nil {
+		return nil, err
+	}
+	if ws == nil || (ws.Group() && ws.Member(user.ID) == nil) {
+		return map[int]string{user.ID: "Нет такого пространства, список: /workspace"}, nil
+	}
+	if _, err := tb.Repo.UpdateSettings(user.ID, func(s *UserSettings) { s.Workspace = ws.ID }); err != nil {
+		return nil, err
+	}
+	return map[int]string{user.ID: fmt.Sprintf("Команды в личных сообщениях теперь про «%s»", ws.Name())}, nil
+}
+
+func (tb *TaskBot) GetMembers(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	ws := args.Workspace
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Участники «%s»:", ws.Name()))
+	for _, m := range ws.Members {
+		sb.WriteString(fmt.Sprintf("\n%s - %s", mention(m.User), m.Role))
+	}
+	return map[int]string{upd.Message.From.ID: sb.String()}, nil
+}
+
+func (tb *TaskBot) SetRole(upd *tgbotapi.Update, args Args) (map[int]string, error) {
+	ws := args.Workspace
+	userID := upd.Message.From.ID
+	if !ws.Group() {
+		return map[int]string{userID: "Роли есть только в группах"}, nil
+	}
+	if m := ws.Member(userID); m == nil || m.Role != RoleOwner {
+		return map[int]string{userID: "Менять роли может только владелец"}, nil
+	}
+
+	fields := strings.Fields(args.Text)
+	if len(fields) != 2 || (Role(fields[1]) != RoleAdmin && Role(fields[1]) != RoleMember) {
+		return tb.usage(upd, "/role"), nil
+	}
+	target := ws.MemberByName(fields[0])
+	if target == nil {
+		return map[int]string{userID: fmt.Sprintf("%s ещё не участник, пусть сначала напишет боту в группе", fields[0])}, nil
+	}
+	if target.Role == RoleOwner {
+		return map[int]string{userID: "Роль владельца не меняется"}, nil
+	}
+
+	role := Role(fields[1])
+	ok, err := tb.Repo.SetRole(ws.ID, target.User.ID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return map[int]string{userID: fmt.Sprintf("%s ещё не участник, пусть сначала напишет боту в группе", fields[0])}, nil
+	}
+	return map[int]string{userID: fmt.Sprintf("%s теперь %s", mention(target.User), role)}, nil
+}
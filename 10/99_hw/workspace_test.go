@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+const TeamChatID = -1001
+
+var teamChat = &tgbotapi.Chat{ID: TeamChatID, Type: "group", Title: "Команда"}
+
+type chatCase struct {
+	user int
+	// chat - группа, nil - личные сообщения
+	chat    *tgbotapi.Chat
+	command string
+	answers map[int]string
+}
+
+func runChatCmds(t *testing.T, tb *TaskBot, cases []chatCase) {
+	t.Helper()
+	for idx, item := range cases {
+		upd := newUpdate(item.user, item.command)
+		if item.chat != nil {
+			upd.Message.Chat = item.chat
+		}
+		answers, err := tb.ExecuteCmd(upd)
+		if err != nil {
+			t.Fatalf("[%d] %s: unexpected error %v", idx, item.command, err)
+		}
+		if !reflect.DeepEqual(answers, item.answers) {
+			t.Fatalf("[%d] %s: bad answers\n\tgot: %#v\n\twant: %#v", idx, item.command, answers, item.answers)
+		}
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	tb := newTestBot(NewTasksRepoInMemory())
+	tb.UserName = "game_test_bot"
+
+	runChatCmds(t, tb, []chatCase{
+		// в группе бот не отвечает на обычные сообщения
+		{Ivanov, teamChat, "привет", nil},
+		// первый, кто написал боту в группе, - её владелец
+		{Ivanov, teamChat, "/new написать бота", map[int]string{
+			TeamChatID: `@ivanov Задача "написать бота" создана, id=1`,
+		}},
+		// у личных сообщений свои задачи и свои id
+		{Ivanov, nil, "/tasks", map[int]string{Ivanov: "Нет задач"}},
+		{Ivanov, nil, "/new личная", map[int]string{Ivanov: `Задача "личная" создана, id=1`}},
+		{Petrov, teamChat, "/assign_1@game_test_bot", map[int]string{
+			TeamChatID: `@ppetrov Задача "написать бота" назначена на вас
+
+@ivanov Задача "написать бота" назначена на @ppetrov`,
+		}},
+		{Petrov, teamChat, "/assign_1@other_bot", nil},
+		{Petrov, teamChat, "/nope", nil},
+		{Petrov, teamChat, "/nope@game_test_bot", map[int]string{
+			TeamChatID: "@ppetrov Неизвестная команда, список команд: /help",
+		}},
+		{Petrov, teamChat, "/assign", map[int]string{TeamChatID: "@ppetrov Формат: /assign_$ID"}},
+		// чужие задачи в группе меняют только админы
+		{Alexandrov, teamChat, "/due_1 2021-03-15", map[int]string{TeamChatID: "@aalexandrov " + noRights}},
+		{Alexandrov, teamChat, "/resolve_1", map[int]string{TeamChatID: "@aalexandrov " + noRights}},
+		{Alexandrov, teamChat, "/role @aalexandrov admin", map[int]string{
+			TeamChatID: "@aalexandrov Менять роли может только владелец",
+		}},
+		{Ivanov, teamChat, "/role @nobody admin", map[int]string{
+			TeamChatID: "@ivanov @nobody ещё не участник, пусть сначала напишет боту в группе",
+		}},
+		{Ivanov, teamChat, "/role @AAlexandrov boss", map[int]string{TeamChatID: "@ivanov Формат: /role @username admin или member"}},
+		{Ivanov, teamChat, "/role @AAlexandrov admin", map[int]string{TeamChatID: "@ivanov @aalexandrov теперь admin"}},
+		{Ivanov, teamChat, "/members", map[int]string{
+			TeamChatID: `@ivanov Участники «Команда»:
+@ivanov - owner
+@ppetrov - member
+@aalexandrov - admin`,
+		}},
+		{Alexandrov, teamChat, "/due_1 2021-03-15", map[int]string{
+			TeamChatID: `@aalexandrov Задача "написать бота": срок 2021-03-15 23:59
+
+@ivanov Задача "написать бота": срок 2021-03-15 23:59 (@aalexandrov)
+
+@ppetrov Задача "написать бота": срок 2021-03-15 23:59 (@aalexandrov)`,
+		}},
+		{Petrov, teamChat, "/workspace", map[int]string{
+			TeamChatID: "@ppetrov Это пространство «Команда», выбрать его в личных сообщениях: /workspace 1",
+		}},
+
+		// из личных сообщений можно работать с задачами группы:
+		// ответ приходит лично, а остальным - в группу
+		{Petrov, nil, "/workspace", map[int]string{Petrov: `Рабочие пространства:
+0. личные сообщения - выбрано
+1. Команда
+Выбрать: /workspace $N`}},
+		{Petrov, nil, "/workspace 5", map[int]string{Petrov: "Нет такого пространства, список: /workspace"}},
+		{Petrov, nil, "/workspace 1", map[int]string{Petrov: "Команды в личных сообщениях теперь про «Команда»"}},
+		{Petrov, nil, "/my", map[int]string{Petrov: `1. написать бота by @ivanov
+due: 2021-03-15 23:59
+/unassign_1 /resolve_1`}},
+		{Petrov, nil, "/resolve_1", map[int]string{
+			Petrov:     `Задача "написать бота" выполнена`,
+			TeamChatID: `@ivanov Задача "написать бота" выполнена @ppetrov`,
+		}},
+		{Petrov, nil, "/workspace 0", map[int]string{Petrov: "Команды в личных сообщениях теперь про «личные сообщения»"}},
+		{Petrov, nil, "/tasks", map[int]string{Petrov: `1. личная by @ivanov
+/assign_1`}},
+		{Petrov, nil, "/role @ivanov member", map[int]string{Petrov: "Роли есть только в группах"}},
+		// кто не состоит в группе, её не видит
+		{Alexandrov, nil, "/workspace 2", map[int]string{Alexandrov: "Нет такого пространства, список: /workspace"}},
+	})
+}
+
+func TestMention(t *testing.T) {
+	cases := []struct {
+		user     *tgbotapi.User
+		expected string
+	}{
+		{&tgbotapi.User{ID: 1, FirstName: "Ivan", UserName: "ivanov"}, "@ivanov"},
+		// без username телеграм не свяжет "@Ivan Ivanov" с пользователем
+		{&tgbotapi.User{ID: 2, FirstName: "Ivan", LastName: "Ivanov"}, "Ivan Ivanov"},
+	}
+	for _, c := range cases {
+		if got := mention(c.user); got != c.expected {
+			t.Errorf("mention(%d): expected %q, got %q", c.user.ID, c.expected, got)
+		}
+	}
+}
+
+func TestWorkspacesFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// файл из времён до рабочих пространств - всё это задачи личных сообщений
+	old := filepath.Join(dir, "old.json")
+	err := ioutil.WriteFile(old, []byte(`{
+  "last_id": 2,
+  "tasks": [
+    {"id": 2, "info": "старая", "created_by": {"id": 256, "username": "ivanov"}, "created": "2021-03-01T10:00:00Z"}
+  ]
+}`), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	repo, err := NewTasksRepoFile(old)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runChatCmds(t, newTestBot(repo), []chatCase{
+		{Petrov, nil, "/tasks", map[int]string{Petrov: "2. старая by @ivanov\n/assign_2"}},
+		{Petrov, nil, "/new новая", map[int]string{Petrov: `Задача "новая" создана, id=3`}},
+	})
+
+	path := filepath.Join(dir, "tasks.json")
+	repo, err = NewTasksRepoFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runChatCmds(t, newTestBot(repo), []chatCase{
+		{Ivanov, teamChat, "/new написать бота", map[int]string{TeamChatID: `@ivanov Задача "написать бота" создана, id=1`}},
+		{Petrov, teamChat, "/tasks", map[int]string{TeamChatID: "@ppetrov 1. написать бота by @ivanov\n/assign_1"}},
+		{Ivanov, teamChat, "/role @ppetrov admin", map[int]string{TeamChatID: "@ivanov @ppetrov теперь admin"}},
+		{Petrov, nil, "/workspace 1", map[int]string{Petrov: "Команды в личных сообщениях теперь про «Команда»"}},
+	})
+
+	// после перезапуска на месте и группа с ролями, и выбор пространства
+	repo, err = NewTasksRepoFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runChatCmds(t, newTestBot(repo), []chatCase{
+		{Petrov, nil, "/members", map[int]string{Petrov: "Участники «Команда»:\n@ivanov - owner\n@ppetrov - admin"}},
+		{Petrov, nil, "/new вторая", map[int]string{Petrov: `Задача "вторая" создана, id=2`}},
+		{Alexandrov, teamChat, "/new третья", map[int]string{TeamChatID: `@aalexandrov Задача "третья" создана, id=3`}},
+	})
+}
+
+func TestGroupReminder(t *testing.T) {
+	tds := NewTDS()
+	ts := httptest.NewServer(tds)
+	defer ts.Close()
+	tgbotapi.APIEndpoint = ts.URL + "/bot%s/%s"
+
+	bot, err := tgbotapi.NewBotAPI(BotToken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tb := newTestBot(NewTasksRepoInMemory())
+	tb.Location = time.UTC
+	tb.UserName = bot.Self.UserName
+	runChatCmds(t, tb, []chatCase{
+		{Ivanov, teamChat, "/new сделать деплой", map[int]string{TeamChatID: `@ivanov Задача "сделать деплой" создана, id=1`}},
+		{Petrov, teamChat, "/assign_1@game_test_bot", map[int]string{
+			TeamChatID: `@ppetrov Задача "сделать деплой" назначена на вас
+
+@ivanov Задача "сделать деплой" назначена на @ppetrov`,
+		}},
+		{Ivanov, teamChat, "/due_1 2021-03-10 15:00", map[int]string{
+			TeamChatID: `@ivanov Задача "сделать деплой": срок 2021-03-10 15:00
+
+@ppetrov Задача "сделать деплой": срок 2021-03-10 15:00 (@ivanov)`,
+		}},
+		{Ivanov, nil, "/digest off", map[int]string{Ivanov: "Дайджест выключен"}},
+		{Petrov, nil, "/digest off", map[int]string{Petrov: "Дайджест выключен"}},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticks := make(chan time.Time)
+	go tb.RunScheduler(ctx, ticks, func(result map[int]string) { sendResult(bot, result) })
+
+	ticks <- time.Date(2021, 3, 10, 14, 0, 0, 0, time.UTC)
+	// give TDS time to process request
+	time.Sleep(10 * time.Millisecond)
+
+	want := map[int]string{
+		TeamChatID: `@ppetrov Напоминание: задачу "сделать деплой" нужно сделать до 2021-03-10 15:00
+/snooze_1 /resolve_1`,
+	}
+	tds.Lock()
+	defer tds.Unlock()
+	if !reflect.DeepEqual(tds.Answers, want) {
+		t.Fatalf("bad results:\n\tWant: %v\n\tHave: %v", want, tds.Answers)
+	}
+}
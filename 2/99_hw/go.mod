@@ -0,0 +1,3 @@
+module hw2
+
+go 1.18
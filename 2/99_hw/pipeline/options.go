@@ -0,0 +1,44 @@
+package pipeline
+
+// Option configures a stage
+type Option func(*options)
+
+type options struct {
+	workers int
+	ordered bool
+	buffer  int
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{workers: 1}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Workers sets how many values a stage handles at once, 1 by default
+func Workers(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.workers = n
+		}
+	}
+}
+
+// Ordered keeps the order of the input values in the output of Map,
+// a slow value holds the faster ones after it
+func Ordered() Option {
+	return func(o *options) {
+		o.ordered = true
+	}
+}
+
+// Buffer sets the capacity of the output channel, 0 by default
+func Buffer(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.buffer = n
+		}
+	}
+}
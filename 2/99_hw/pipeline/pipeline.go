@@ -0,0 +1,391 @@
+// Package pipeline connects typed stages with channels.
+// Every stage stops when the pipeline context is done, and the first error or panic
+// of any stage cancels the rest and is returned by Wait.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pipeline runs the stages started on it
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+
+	errOnce *sync.Once
+	err     error
+
+	mu     *sync.Mutex
+	stages []*stageMetrics
+}
+
+func New(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pipeline{
+		ctx:     ctx,
+		cancel:  cancel,
+		wg:      &sync.WaitGroup{},
+		errOnce: &sync.Once{},
+		mu:      &sync.Mutex{},
+	}
+}
+
+// Context is done when the parent context is or when a stage has failed
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Wait waits for all the stages and returns the first error
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	canceled := p.ctx.Err()
+	p.cancel()
+
+	if p.err != nil {
+		return p.err
+	}
+	// canceled from the outside
+	return canceled
+}
+
+func (p *Pipeline) fail(err error) {
+	p.errOnce.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// Go runs fn as a stage without typed channels, e.g. to adapt an existing function
+func (p *Pipeline) Go(name string, fn func(ctx context.Context) error) {
+	m := p.addStage(name)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		atomic.AddInt64(&m.in, 1)
+		if err := m.call(func() error { return fn(p.ctx) }); err != nil {
+			p.fail(fmt.Errorf("stage %s: %w", name, err))
+			return
+		}
+		atomic.AddInt64(&m.out, 1)
+	}()
+}
+
+// Stats returns the metrics of the stages in the order they were added
+func (p *Pipeline) Stats() []StageStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	res := make([]StageStats, 0, len(p.stages))
+	for _, m := range p.stages {
+		res = append(res, m.stats())
+	}
+	return res
+}
+
+func (p *Pipeline) addStage(name string) *stageMetrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m := &stageMetrics{name: name}
+	p.stages = append(p.stages, m)
+	return m
+}
+
+// send blocks until out takes v or the pipeline is canceled
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Source starts the pipeline with the values passed to emit by gen.
+// emit returns false when the pipeline is canceled, gen should return then.
+func Source[T any](p *Pipeline, name string, gen func(ctx context.Context, emit func(T) bool) error, opts ...Option) <-chan T {
+	o := newOptions(opts)
+	out := make(chan T, o.buffer)
+	m := p.addStage(name)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(out)
+
+		emit := func(v T) bool {
+			if !send(p.ctx, out, v) {
+				return false
+			}
+			atomic.AddInt64(&m.out, 1)
+			return true
+		}
+		if err := m.call(func() error { return gen(p.ctx, emit) }); err != nil {
+			p.fail(fmt.Errorf("stage %s: %w", name, err))
+		}
+	}()
+
+	return out
+}
+
+// FromSlice is a Source of the values
+func FromSlice[T any](p *Pipeline, name string, values []T, opts ...Option) <-chan T {
+	return Source(p, name, func(_ context.Context, emit func(T) bool) error {
+		for _, v := range values {
+			if !emit(v) {
+				return nil
+			}
+		}
+		return nil
+	}, opts...)
+}
+
+// Map calls fn for every value of in on Workers goroutines
+func Map[In, Out any](p *Pipeline, name string, in <-chan In, fn func(ctx context.Context, v In) (Out, error), opts ...Option) <-chan Out {
+	o := newOptions(opts)
+	out := make(chan Out, o.buffer)
+	m := p.addStage(name)
+
+	run := func(v In) (Out, bool) {
+		atomic.AddInt64(&m.in, 1)
+		var res Out
+		err := m.call(func() error {
+			var err error
+			res, err = fn(p.ctx, v)
+			return err
+		})
+		if err != nil {
+			p.fail(fmt.Errorf("stage %s: %w", name, err))
+			return res, false
+		}
+		return res, true
+	}
+
+	emit := func(v Out) bool {
+		if !send(p.ctx, out, v) {
+			return false
+		}
+		atomic.AddInt64(&m.out, 1)
+		return true
+	}
+
+	p.wg.Add(1)
+	if o.ordered {
+		go mapOrdered(p, in, o.workers, run, emit, func() { close(out); p.wg.Done() })
+	} else {
+		go mapUnordered(p, in, o.workers, run, emit, func() { close(out); p.wg.Done() })
+	}
+
+	return out
+}
+
+func mapUnordered[In, Out any](p *Pipeline, in <-chan In, workers int, run func(In) (Out, bool), emit func(Out) bool, done func()) {
+	defer done()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				v, ok := recv(p.ctx, in)
+				if !ok {
+					return
+				}
+				res, ok := run(v)
+				if !ok || !emit(res) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type result[T any] struct {
+	v  T
+	ok bool
+}
+
+// mapOrdered keeps at most workers values in flight, the results wait for the older ones
+func mapOrdered[In, Out any](p *Pipeline, in <-chan In, workers int, run func(In) (Out, bool), emit func(Out) bool, done func()) {
+	defer done()
+
+	pending := make(chan chan result[Out], workers)
+	sem := make(chan struct{}, workers)
+	wg := &sync.WaitGroup{}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(pending)
+		for {
+			v, ok := recv(p.ctx, in)
+			if !ok {
+				return
+			}
+
+			if !send(p.ctx, sem, struct{}{}) {
+				return
+			}
+			resCh := make(chan result[Out], 1)
+			if !send(p.ctx, pending, resCh) {
+				<-sem
+				return
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, ok := run(v)
+				<-sem
+				resCh <- result[Out]{v: res, ok: ok}
+			}()
+		}
+	}()
+
+	for resCh := range pending {
+		r := <-resCh
+		if !r.ok || !emit(r.v) {
+			// the dispatcher is canceled too and closes pending
+			for range pending {
+			}
+			break
+		}
+	}
+	wg.Wait()
+}
+
+// Sink calls fn for every value of in, the pipeline ends there
+func Sink[T any](p *Pipeline, name string, in <-chan T, fn func(ctx context.Context, v T) error, opts ...Option) {
+	o := newOptions(opts)
+	m := p.addStage(name)
+
+	p.wg.Add(o.workers)
+	for i := 0; i < o.workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for {
+				v, ok := recv(p.ctx, in)
+				if !ok {
+					return
+				}
+				atomic.AddInt64(&m.in, 1)
+				if err := m.call(func() error { return fn(p.ctx, v) }); err != nil {
+					p.fail(fmt.Errorf("stage %s: %w", name, err))
+					return
+				}
+				atomic.AddInt64(&m.out, 1)
+			}
+		}()
+	}
+}
+
+// Collect reads in to a slice, it has to be called before Wait
+func Collect[T any](p *Pipeline, name string, in <-chan T) func() []T {
+	var res []T
+	Sink(p, name, in, func(_ context.Context, v T) error {
+		res = append(res, v)
+		return nil
+	})
+	// the sink has one worker and Wait happens after it
+	return func() []T { return res }
+}
+
+func recv[T any](ctx context.Context, in <-chan T) (T, bool) {
+	select {
+	case v, ok := <-in:
+		return v, ok
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	}
+}
+
+// All calls fn for 0..n-1 concurrently and returns the results by index,
+// it's for the fan-out inside a single value
+func All[T any](ctx context.Context, n int, fn func(ctx context.Context, i int) (T, error)) ([]T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	res := make([]T, n)
+	once := &sync.Once{}
+	var firstErr error
+	wg := &sync.WaitGroup{}
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			err := protect(func() error {
+				var err error
+				res[i], err = fn(ctx, i)
+				return err
+			})
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return res, nil
+}
+
+// protect turns a panic of fn into an error
+func protect(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// StageStats is a snapshot of the counters of a stage
+type StageStats struct {
+	Name   string
+	In     int64 // values taken
+	Out    int64 // values passed on, or handled by a sink
+	Failed int64
+	// Busy is the time spent in the stage function summed over the workers
+	Busy time.Duration
+}
+
+type stageMetrics struct {
+	name   string
+	in     int64
+	out    int64
+	failed int64
+	busy   int64
+}
+
+func (m *stageMetrics) call(fn func() error) error {
+	start := time.Now()
+	err := protect(fn)
+	atomic.AddInt64(&m.busy, int64(time.Since(start)))
+	if err != nil {
+		atomic.AddInt64(&m.failed, 1)
+	}
+	return err
+}
+
+func (m *stageMetrics) stats() StageStats {
+	return StageStats{
+		Name:   m.name,
+		In:     atomic.LoadInt64(&m.in),
+		Out:    atomic.LoadInt64(&m.out),
+		Failed: atomic.LoadInt64(&m.failed),
+		Busy:   time.Duration(atomic.LoadInt64(&m.busy)),
+	}
+}
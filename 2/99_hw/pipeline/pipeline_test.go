@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMapOrdered(t *testing.T) {
+	p := New(context.Background())
+	src := FromSlice(p, "src", []int{1, 2, 3, 4, 5, 6})
+
+	var running, maxRunning int32
+	squares := Map(p, "square", src, func(_ context.Context, n int) (int, error) {
+		cur := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if cur <= max || atomic.CompareAndSwapInt32(&maxRunning, max, cur) {
+				break
+			}
+		}
+		// the first values are the slowest, the order must hold anyway
+		time.Sleep(time.Duration(7-n) * 5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return n * n, nil
+	}, Workers(3), Ordered())
+	res := Collect(p, "collect", squares)
+
+	if err := p.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(res(), []int{1, 4, 9, 16, 25, 36}) {
+		t.Errorf("expected squares in order, got %v", res())
+	}
+	if maxRunning != 3 {
+		t.Errorf("expected 3 workers at most, got %d", maxRunning)
+	}
+
+	stats := p.Stats()
+	if len(stats) != 3 || stats[1].Name != "square" || stats[1].In != 6 || stats[1].Out != 6 || stats[1].Busy <= 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestMapUnorderedParallel(t *testing.T) {
+	p := New(context.Background())
+	src := FromSlice(p, "src", []int{1, 2, 3, 4, 5})
+	out := Map(p, "sleep", src, func(_ context.Context, n int) (int, error) {
+		time.Sleep(50 * time.Millisecond)
+		return n, nil
+	}, Workers(5))
+
+	sum := 0
+	Sink(p, "sum", out, func(_ context.Context, n int) error {
+		sum += n
+		return nil
+	})
+
+	start := time.Now()
+	if err := p.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("expected the values to be handled at once, took %v", elapsed)
+	}
+	if sum != 15 {
+		t.Errorf("expected sum 15, got %d", sum)
+	}
+}
+
+func TestFirstError(t *testing.T) {
+	goroutinesStart := runtime.NumGoroutine()
+	errBad := errors.New("bad value")
+
+	p := New(context.Background())
+	// an endless source stops only by cancellation
+	src := Source(p, "naturals", func(_ context.Context, emit func(int) bool) error {
+		for n := 0; ; n++ {
+			if !emit(n) {
+				return nil
+			}
+		}
+	})
+	out := Map(p, "check", src, func(_ context.Context, n int) (int, error) {
+		if n == 100 {
+			return 0, errBad
+		}
+		return n, nil
+	}, Workers(4), Ordered())
+	Sink(p, "drop", out, func(context.Context, int) error { return nil })
+
+	err := p.Wait()
+	if !errors.Is(err, errBad) || !strings.Contains(err.Error(), "stage check") {
+		t.Fatalf("expected the error of stage check, got %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if n := runtime.NumGoroutine() - goroutinesStart; n > 0 {
+		t.Errorf("looks like %d goroutines leaked", n)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	p := New(context.Background())
+	src := FromSlice(p, "src", []int{1})
+	Sink(p, "panic", src, func(context.Context, int) error {
+		panic("boom")
+	})
+
+	err := p.Wait()
+	if err == nil || !strings.Contains(err.Error(), "panic: boom") {
+		t.Fatalf("expected panic error, got %v", err)
+	}
+	if stats := p.Stats(); stats[1].Failed != 1 {
+		t.Errorf("expected a failed call, got %+v", stats[1])
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx)
+	src := Source(p, "naturals", func(_ context.Context, emit func(int) bool) error {
+		for n := 0; ; n++ {
+			if !emit(n) {
+				return nil
+			}
+		}
+	})
+	Sink(p, "slow", src, func(context.Context, int) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if err := p.Wait(); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestAll(t *testing.T) {
+	res, err := All(context.Background(), 4, func(_ context.Context, i int) (string, error) {
+		time.Sleep(time.Duration(4-i) * time.Millisecond)
+		return strings.Repeat("x", i), nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(res, []string{"", "x", "xx", "xxx"}) {
+		t.Errorf("expected results by index, got %q", res)
+	}
+
+	errBad := errors.New("bad index")
+	_, err = All(context.Background(), 3, func(ctx context.Context, i int) (int, error) {
+		if i == 1 {
+			return 0, errBad
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if err != errBad {
+		t.Errorf("expected %v, got %v", errBad, err)
+	}
+}
@@ -21,7 +21,7 @@ func TestPipeline(t *testing.T) {
 	var ok = true
 	var recieved uint32
 	freeFlowJobs := []job{
-		func(in, out chan interface{}, a int) {
+		job(func(in, out chan interface{}) {
 			out <- 1
 			time.Sleep(10 * time.Millisecond)
 			currRecieved := atomic.LoadUint32(&recieved)
@@ -32,7 +32,7 @@ func TestPipeline(t *testing.T) {
 			if currRecieved == 0 {
 				ok = false
 			}
-		},
+		}),
 		job(func(in, out chan interface{}) {
 			for _ = range in {
 				atomic.AddUint32(&recieved, 1)
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
+
+	"hw2/pipeline"
 )
 
 const (
@@ -12,96 +15,73 @@ const (
 )
 
 // ExecutePipeline тащит движ
+// паника в любой джобе всплывает здесь, а не роняет процесс из чужой горутины
 func ExecutePipeline(jobs ...job) {
-	wg := &sync.WaitGroup{}
-	wg.Add(len(jobs))
+	p := pipeline.New(context.Background())
 
+	// у первой джобы входа нет
 	in := make(chan interface{})
-	for i := 0; i < len(jobs); i++ {
+	close(in)
+	for i, j := range jobs {
 		out := make(chan interface{})
-		go func(i int, in, out chan interface{}) {
-			jobs[i](in, out)
-			close(out)
-			wg.Done()
-		}(i, in, out)
+		j, jobIn := j, in
+		p.Go("job "+strconv.Itoa(i), func(context.Context) error {
+			// если джоба упала, не дочитав вход, предыдущая висит на отправке - дочитываем за неё
+			defer func() {
+				for range jobIn {
+				}
+			}()
+			defer close(out)
+			j(jobIn, out)
+			return nil
+		})
 
 		in = out
 	}
 
-	wg.Wait()
+	if err := p.Wait(); err != nil {
+		panic(err)
+	}
 }
 
-// SingleHash считает один хэш
-func SingleHash(in, out chan interface{}) {
-	mu := &sync.Mutex{}
-	wg := &sync.WaitGroup{}
-	for v := range in {
-		wg.Add(1)
-		go func(v interface{}) {
-			n := v.(int)
-			s := strconv.Itoa(n)
-
-			crc32md5ch := make(chan string)
-			go func(n int, c chan string) {
-				mu.Lock()
-				md5val := DataSignerMd5(s)
-				mu.Unlock()
-
-				c <- DataSignerCrc32(md5val)
-			}(n, crc32md5ch)
-
-			out <- DataSignerCrc32(s) + "~" + <-crc32md5ch
-			wg.Done()
-		}(v)
+// Sign считает подпись данных целиком: SingleHash, MultiHash и CombineResults на одном конвейере
+func Sign(ctx context.Context, data []int) (string, error) {
+	p := pipeline.New(ctx)
+	src := pipeline.FromSlice(p, "data", data)
+	results := pipeline.Collect(p, "combine", multiHash(p, singleHash(p, src)))
+	if err := p.Wait(); err != nil {
+		return "", err
 	}
 
-	wg.Wait()
+	return combine(results()), nil
+}
+
+// SingleHash считает один хэш
+func SingleHash(in, out chan interface{}) {
+	p := pipeline.New(context.Background())
+	data := pipeline.Map(p, "int", in, func(_ context.Context, v interface{}) (int, error) {
+		n, ok := v.(int)
+		if !ok {
+			return 0, fmt.Errorf("expected int, got %T", v)
+		}
+		return n, nil
+	})
+	forward(p, singleHash(p, data), out)
+	mustWait(p)
 }
 
 // MultiHash считает много хэшей
 func MultiHash(in, out chan interface{}) {
-	wg1 := &sync.WaitGroup{}
-	for v := range in {
-		wg1.Add(1)
-		data := v.(string)
-		go func(data string) {
-			crc32ch := make([]chan string, thCnt)
-			for i := range crc32ch {
-				crc32ch[i] = make(chan string)
-			}
-
-			wg := &sync.WaitGroup{}
-
-			for th := 0; th < thCnt; th++ {
-				wg.Add(1)
-				th := th
-				go func() {
-					crc32ch[th] <- DataSignerCrc32(strconv.Itoa(th) + data)
-				}()
-			}
-
-			mu := &sync.Mutex{}
-			res := make([]string, thCnt)
-			go func() {
-				for i := 0; i < thCnt; i++ {
-					i := i
-					go func() {
-						mu.Lock()
-						res[i] = <-crc32ch[i]
-						mu.Unlock()
-						wg.Done()
-					}()
-				}
-			}()
-
-			wg.Wait()
-			out <- strings.Join(res, "")
-			wg1.Done()
-		}(data)
-
-	}
-
-	wg1.Wait()
+	p := pipeline.New(context.Background())
+	data := pipeline.Map(p, "string", in, func(_ context.Context, v interface{}) (string, error) {
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("expected string, got %T", v)
+		}
+		return s, nil
+	})
+	forward(p, multiHash(p, data), out)
+	mustWait(p)
 }
 
 // CombineResults комбинирует
@@ -113,6 +93,69 @@ func CombineResults(in, out chan interface{}) {
 		res = append(res, v)
 	}
 
+	out <- combine(res)
+}
+
+type md5Hash struct {
+	data string
+	md5  string
+}
+
+// singleHash: crc32(data)+"~"+crc32(md5(data)),
+// md5 нельзя считать параллельно (перегрев), поэтому у него отдельная стадия с одним воркером
+func singleHash(p *pipeline.Pipeline, in <-chan int) <-chan string {
+	hashed := pipeline.Map(p, "md5", in, func(_ context.Context, n int) (md5Hash, error) {
+		data := strconv.Itoa(n)
+		return md5Hash{data: data, md5: DataSignerMd5(data)}, nil
+	}, pipeline.Workers(1))
+
+	return pipeline.Map(p, "single hash", hashed, func(ctx context.Context, h md5Hash) (string, error) {
+		crc, err := pipeline.All(ctx, 2, func(_ context.Context, i int) (string, error) {
+			if i == 0 {
+				return DataSignerCrc32(h.data), nil
+			}
+			return DataSignerCrc32(h.md5), nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return crc[0] + "~" + crc[1], nil
+	}, pipeline.Workers(MaxInputDataLen))
+}
+
+// multiHash: конкатенация crc32(th+data) для th=0..5 в порядке th
+func multiHash(p *pipeline.Pipeline, in <-chan string) <-chan string {
+	return pipeline.Map(p, "multi hash", in, func(ctx context.Context, data string) (string, error) {
+		crc, err := pipeline.All(ctx, thCnt, func(_ context.Context, th int) (string, error) {
+			return DataSignerCrc32(strconv.Itoa(th) + data), nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(crc, ""), nil
+	}, pipeline.Workers(MaxInputDataLen))
+}
+
+func combine(res []string) string {
 	sort.Strings(res)
-	out <- strings.Join(res, "_")
+	return strings.Join(res, "_")
+}
+
+// forward отдаёт результаты типизированной стадии в старый канал джобы
+func forward(p *pipeline.Pipeline, in <-chan string, out chan interface{}) {
+	pipeline.Sink(p, "out", in, func(ctx context.Context, v string) error {
+		select {
+		case out <- v:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// mustWait: у джобы нет способа вернуть ошибку, а неверный тип на входе - ошибка программиста
+func mustWait(p *pipeline.Pipeline) {
+	if err := p.Wait(); err != nil {
+		panic(err)
+	}
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// паника в середине конвейера не должна вешать ExecutePipeline на соседних джобах
+func TestPipelineMiddlePanic(t *testing.T) {
+	jobs := []job{
+		job(func(in, out chan interface{}) {
+			for i := 0; i < 10; i++ {
+				out <- i
+			}
+		}),
+		job(func(in, out chan interface{}) {
+			for range in {
+				panic("boom")
+			}
+		}),
+		job(func(in, out chan interface{}) {
+			for range in {
+			}
+		}),
+	}
+
+	done := make(chan interface{})
+	go func() {
+		defer func() {
+			done <- recover()
+		}()
+		ExecutePipeline(jobs...)
+	}()
+
+	select {
+	case r := <-done:
+		if r == nil || !strings.Contains(fmt.Sprint(r), "panic: boom") {
+			t.Errorf("expected panic: boom, got %v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("pipeline hangs after a panic in the middle job")
+	}
+}
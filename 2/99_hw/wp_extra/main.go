@@ -1,40 +1,87 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 func main() {
 
 }
 
+var (
+	ErrStopping = errors.New("worker pool is stopping now")
+	// ErrDropped is the result of a queued task that was not run because Shutdown gave up waiting
+	ErrDropped = errors.New("task dropped on shutdown")
+)
+
+// PanicError is the result of a task that panicked, the worker survives it
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// Result is what Submit reports back
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
 type WorkerPool struct {
 	WorkerCount int32
 	Wg          *sync.WaitGroup
 	WorkerWg    *sync.WaitGroup
-	Stopping    bool
 	Input       chan func()
 	Remove      chan bool
+
+	// mu guards stopping: a task is counted in Wg and sending under RLock,
+	// so once Shutdown has set stopping no new sends start
+	mu       *sync.RWMutex
+	stopping bool
+	sending  *sync.WaitGroup
+	quit     chan struct{}
+	quitOnce *sync.Once
+	dropped  int32
+
+	busy      int32
+	completed int64
+	panics    int64
+	// latency of the tasks finished since the last takeLatency, queueing included
+	latencySum   int64
+	latencyCount int64
 }
 
 func StartWorkerPool(cnt int, input chan func(), remove chan bool, tasks []func()) (*WorkerPool, error) {
 	if cnt < 0 {
 		return nil, fmt.Errorf("Worker count must not be negative")
 	}
+	// without workers the queued tasks would never run
+	if cnt == 0 {
+		cnt = 1
+	}
 
-	wg := &sync.WaitGroup{}
-	wg.Add(len(tasks))
 	workerWg := &sync.WaitGroup{}
 	workerWg.Add(cnt)
 
 	wp := &WorkerPool{
 		WorkerWg: workerWg,
-		Wg:       wg,
-		Stopping: false,
+		Wg:       &sync.WaitGroup{},
 		Input:    input,
-		Remove:   remove}
+		Remove:   remove,
+		mu:       &sync.RWMutex{},
+		sending:  &sync.WaitGroup{},
+		quit:     make(chan struct{}),
+		quitOnce: &sync.Once{},
+	}
 
 	wp.WorkerCount = int32(cnt)
 
@@ -43,15 +90,19 @@ func StartWorkerPool(cnt int, input chan func(), remove chan bool, tasks []func(
 	}
 
 	for _, t := range tasks {
-		input <- t
+		if err := wp.AddTask(t); err != nil {
+			return nil, err
+		}
 	}
 
 	return wp, nil
 }
 
 func (w *WorkerPool) AddWorker() error {
-	if w.Stopping {
-		return fmt.Errorf("Worker pool is stopping now")
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.stopping {
+		return ErrStopping
 	}
 
 	atomic.AddInt32(&w.WorkerCount, 1)
@@ -60,71 +111,241 @@ func (w *WorkerPool) AddWorker() error {
 	return nil
 }
 
+// RemoveWorker waits until a worker is free and stops it
 func (w *WorkerPool) RemoveWorker() error {
-	if w.Stopping {
-		return fmt.Errorf("Worker pool is stopping now")
+	return w.removeWorker(context.Background())
+}
+
+func (w *WorkerPool) removeWorker(ctx context.Context) error {
+	if w.isStopping() {
+		return ErrStopping
 	}
 
-	if atomic.LoadInt32(&w.WorkerCount) == 1 {
-		return fmt.Errorf("Worker count cannot be less than 1")
+	// the decrement is reserved before the send, so concurrent removals can't take the last worker;
+	// the worker that gets the signal leaves the count as is
+	for {
+		n := atomic.LoadInt32(&w.WorkerCount)
+		if n <= 1 {
+			return fmt.Errorf("Worker count cannot be less than 1")
+		}
+		if atomic.CompareAndSwapInt32(&w.WorkerCount, n, n-1) {
+			break
+		}
 	}
 
-	w.Remove <- true
-	return nil
+	select {
+	case w.Remove <- true:
+		return nil
+	case <-w.quit:
+		atomic.AddInt32(&w.WorkerCount, 1)
+		return ErrStopping
+	case <-ctx.Done():
+		atomic.AddInt32(&w.WorkerCount, 1)
+		return ctx.Err()
+	}
 }
 
+// AddTask queues a task, its panic is recovered and counted in Stats
 func (w *WorkerPool) AddTask(task func()) error {
-	if w.Stopping {
-		return fmt.Errorf("Worker pool is stopping now")
+	return w.enqueue(func() error {
+		task()
+		return nil
+	}, nil)
+}
+
+// Submit queues a task and returns a channel with its result,
+// a task whose ctx is done before a worker takes it is not run
+func (w *WorkerPool) Submit(ctx context.Context, task func(ctx context.Context) (interface{}, error)) (<-chan Result, error) {
+	res := make(chan Result, 1)
+	var value interface{}
+	err := w.enqueue(func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		value, err = task(ctx)
+		return err
+	}, func(err error) {
+		res <- Result{Value: value, Err: err}
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	return res, nil
+}
+
+// enqueue sends without holding mu, so a full queue never holds up Shutdown.
+// If Shutdown gives up while the send is blocked, the task is not queued and ErrStopping is returned
+func (w *WorkerPool) enqueue(task func() error, report func(error)) error {
+	w.mu.RLock()
+	if w.stopping {
+		w.mu.RUnlock()
+		return ErrStopping
+	}
 	w.Wg.Add(1)
-	w.Input <- task
+	w.sending.Add(1)
+	w.mu.RUnlock()
+	defer w.sending.Done()
 
-	return nil
+	queued := time.Now()
+	wrapped := func() {
+		defer w.Wg.Done()
+
+		var err error
+		if atomic.LoadInt32(&w.dropped) == 1 {
+			err = ErrDropped
+		} else {
+			err = w.run(task)
+			atomic.AddInt64(&w.latencySum, int64(time.Since(queued)))
+			atomic.AddInt64(&w.latencyCount, 1)
+		}
+
+		if report != nil {
+			report(err)
+		}
+	}
+
+	select {
+	case w.Input <- wrapped:
+		return nil
+	case <-w.quit:
+		w.Wg.Done()
+		return ErrStopping
+	}
+}
+
+func (w *WorkerPool) run(task func() error) (err error) {
+	atomic.AddInt32(&w.busy, 1)
+	defer func() {
+		if r := recover(); r != nil {
+			atomic.AddInt64(&w.panics, 1)
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		atomic.AddInt32(&w.busy, -1)
+		atomic.AddInt64(&w.completed, 1)
+	}()
+
+	return task()
 }
 
 func (w *WorkerPool) Wait() {
 	w.Wg.Wait()
 }
 
+// Stop waits for all the queued tasks and the workers
 func (w *WorkerPool) Stop() error {
-	// надо остаток запросов на старт принять
-	if w.Stopping {
-		return fmt.Errorf("Already stopping")
-	}
+	return w.Shutdown(context.Background())
+}
 
-	w.Stopping = true
+// Shutdown stops taking tasks and waits for the queued ones until ctx is done.
+// Then the tasks left in the queue get ErrDropped, the running ones are not waited for
+// and their workers exit when they finish. A ctx done before the call leaves the pool running.
+func (w *WorkerPool) Shutdown(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
 
-	// сначала ждём выполнения всех тасков
-	w.Wg.Wait()
+	w.mu.Lock()
+	if w.stopping {
+		w.mu.Unlock()
+		return fmt.Errorf("Already stopping")
+	}
+	w.stopping = true
+	w.mu.Unlock()
 
-	// потом ждём завершения воркеров
+	drained := make(chan struct{})
+	go func() {
+		w.Wg.Wait()
+		close(drained)
+	}()
 
-	cnt := int(atomic.LoadInt32(&w.WorkerCount))
-	for i := 0; i < cnt; i++ {
-		w.Remove <- true
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		atomic.StoreInt32(&w.dropped, 1)
+		w.stopWorkers()
+		// blocked senders give up on quit, after them nobody sends anymore
+		// and what is left in the queue only reports ErrDropped
+		w.sending.Wait()
+		for {
+			select {
+			case task := <-w.Input:
+				task()
+				continue
+			default:
+			}
+			break
+		}
+		return ctx.Err()
 	}
 
+	w.stopWorkers()
 	w.WorkerWg.Wait()
 
 	// и закрываем каналы
+	w.mu.Lock()
 	close(w.Input)
 	close(w.Remove)
+	w.mu.Unlock()
 
 	return nil
 }
 
+func (w *WorkerPool) stopWorkers() {
+	w.quitOnce.Do(func() { close(w.quit) })
+}
+
+func (w *WorkerPool) isStopping() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.stopping
+}
+
 func (w *WorkerPool) StartWorker() {
+	defer w.WorkerWg.Done()
+
 	for {
 		select {
 		case executeJob := <-w.Input:
 			executeJob()
-			w.Wg.Done()
 		case <-w.Remove:
+			// removeWorker has already taken it off the count
+			return
+		case <-w.quit:
 			atomic.AddInt32(&w.WorkerCount, -1)
-			defer w.WorkerWg.Done()
 			return
 		}
 	}
 }
+
+// Stats is a snapshot of the pool state
+type Stats struct {
+	Workers   int
+	Busy      int
+	Queue     int
+	Completed int64
+	Panics    int64
+}
+
+func (w *WorkerPool) Stats() Stats {
+	return Stats{
+		Workers:   int(atomic.LoadInt32(&w.WorkerCount)),
+		Busy:      int(atomic.LoadInt32(&w.busy)),
+		Queue:     len(w.Input),
+		Completed: atomic.LoadInt64(&w.completed),
+		Panics:    atomic.LoadInt64(&w.panics),
+	}
+}
+
+// takeLatency returns the mean latency since the previous call, 0 if nothing finished
+func (w *WorkerPool) takeLatency() time.Duration {
+	sum := atomic.SwapInt64(&w.latencySum, 0)
+	count := atomic.SwapInt64(&w.latencyCount, 0)
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(sum / count)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Load is what the signals see at every check of the scaler
+type Load struct {
+	Workers int
+	Busy    int
+	Queue   int
+	// Latency is the mean time from AddTask to the end of the task since the previous check
+	Latency time.Duration
+}
+
+// Signal votes for scaling: >0 for one more worker, <0 for one less, 0 to keep them
+type Signal func(Load) int
+
+// QueueLength asks for a worker when more than high tasks wait and lets one go when at most low do
+func QueueLength(high, low int) Signal {
+	return func(l Load) int {
+		switch {
+		case l.Queue > high:
+			return 1
+		case l.Queue <= low:
+			return -1
+		}
+		return 0
+	}
+}
+
+// TaskLatency scales up above high and down below low, a check without finished tasks keeps the pool
+func TaskLatency(high, low time.Duration) Signal {
+	return func(l Load) int {
+		switch {
+		case l.Latency == 0:
+			return 0
+		case l.Latency > high:
+			return 1
+		case l.Latency < low:
+			return -1
+		}
+		return 0
+	}
+}
+
+// Sensor scales by an outside value, e.g. cpu usage: up above high, down below low
+func Sensor(read func() float64, high, low float64) Signal {
+	return func(Load) int {
+		v := read()
+		switch {
+		case v > high:
+			return 1
+		case v < low:
+			return -1
+		}
+		return 0
+	}
+}
+
+// ScalerConfig: one vote up is enough to add a worker, a worker is removed only when all the signals agree
+type ScalerConfig struct {
+	Min, Max int
+	Interval time.Duration
+	// no scaling in the same direction for the cooldown after a change
+	UpCooldown, DownCooldown time.Duration
+	Signals                  []Signal
+}
+
+func (c *ScalerConfig) validate() error {
+	switch {
+	case c.Min < 1:
+		return fmt.Errorf("min workers must be at least 1, got %d", c.Min)
+	case c.Max < c.Min:
+		return fmt.Errorf("max workers %d is less than min %d", c.Max, c.Min)
+	case c.Interval <= 0:
+		return fmt.Errorf("interval must be positive, got %v", c.Interval)
+	case len(c.Signals) == 0:
+		return fmt.Errorf("no signals")
+	}
+	return nil
+}
+
+// Autoscale starts a controller that keeps the workers between Min and Max by the signals,
+// it stops with ctx or when the pool stops
+func (w *WorkerPool) Autoscale(ctx context.Context, cfg ScalerConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	if w.isStopping() {
+		return ErrStopping
+	}
+
+	go w.scale(ctx, cfg)
+	return nil
+}
+
+func (w *WorkerPool) scale(ctx context.Context, cfg ScalerConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	var lastUp, lastDown time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.quit:
+			return
+		case now := <-ticker.C:
+			stats := w.Stats()
+			load := Load{Workers: stats.Workers, Busy: stats.Busy, Queue: stats.Queue, Latency: w.takeLatency()}
+
+			switch {
+			case load.Workers < cfg.Min:
+				w.AddWorker()
+			case load.Workers > cfg.Max:
+				w.removeWorker(ctx)
+			default:
+				switch vote(cfg.Signals, load) {
+				case 1:
+					if load.Workers < cfg.Max && now.Sub(lastUp) >= cfg.UpCooldown {
+						if w.AddWorker() == nil {
+							lastUp = now
+						}
+					}
+				case -1:
+					if load.Workers > cfg.Min && now.Sub(lastDown) >= cfg.DownCooldown {
+						if w.removeWorker(ctx) == nil {
+							lastDown = now
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func vote(signals []Signal, load Load) int {
+	down := 0
+	for _, s := range signals {
+		switch v := s(load); {
+		case v > 0:
+			return 1
+		case v < 0:
+			down++
+		}
+	}
+
+	if down == len(signals) {
+		return -1
+	}
+	return 0
+}
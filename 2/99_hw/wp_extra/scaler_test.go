@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newPool(t *testing.T, workers int) *WorkerPool {
+	wp, err := StartWorkerPool(workers, make(chan func(), 100), make(chan bool), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return wp
+}
+
+func waitWorkers(t *testing.T, wp *WorkerPool, expected int, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if wp.Stats().Workers == expected {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d workers, got %d", expected, wp.Stats().Workers)
+}
+
+func TestAutoscaleByQueue(t *testing.T) {
+	wp := newPool(t, 1)
+	defer wp.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := wp.Autoscale(ctx, ScalerConfig{
+		Min:      1,
+		Max:      4,
+		Interval: 5 * time.Millisecond,
+		Signals:  []Signal{QueueLength(2, 0)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 40; i++ {
+		wp.AddTask(func() { time.Sleep(20 * time.Millisecond) })
+	}
+	waitWorkers(t, wp, 4, time.Second)
+
+	wp.Wait()
+	waitWorkers(t, wp, 1, time.Second)
+}
+
+func TestAutoscaleCooldown(t *testing.T) {
+	wp := newPool(t, 1)
+	defer wp.Stop()
+
+	var sensor int64 = 100
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Autoscale(ctx, ScalerConfig{
+		Min:        1,
+		Max:        10,
+		Interval:   5 * time.Millisecond,
+		UpCooldown: time.Hour,
+		Signals:    []Signal{Sensor(func() float64 { return float64(atomic.LoadInt64(&sensor)) }, 80, 20)},
+	})
+
+	waitWorkers(t, wp, 2, time.Second)
+	time.Sleep(50 * time.Millisecond)
+	if n := wp.Stats().Workers; n != 2 {
+		t.Fatalf("expected no scaling in the cooldown, got %d workers", n)
+	}
+
+	// no cooldown down, but never below min
+	atomic.StoreInt64(&sensor, 0)
+	waitWorkers(t, wp, 1, time.Second)
+	time.Sleep(20 * time.Millisecond)
+	if n := wp.Stats().Workers; n != 1 {
+		t.Fatalf("expected min 1 worker, got %d", n)
+	}
+}
+
+func TestVote(t *testing.T) {
+	up := func(Load) int { return 1 }
+	keep := func(Load) int { return 0 }
+	down := func(Load) int { return -1 }
+
+	cases := []struct {
+		signals  []Signal
+		expected int
+	}{
+		{[]Signal{down, up}, 1},
+		{[]Signal{down, keep}, 0},
+		{[]Signal{down, down}, -1},
+		{[]Signal{TaskLatency(time.Second, time.Millisecond)}, 0},
+	}
+	for i, tc := range cases {
+		if got := vote(tc.signals, Load{}); got != tc.expected {
+			t.Errorf("case #%d: expected %d, got %d", i, tc.expected, got)
+		}
+	}
+
+	if err := newPool(t, 1).Autoscale(context.Background(), ScalerConfig{Min: 2, Max: 1, Interval: time.Second}); err == nil {
+		t.Errorf("expected error on max < min, got nil")
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	wp := newPool(t, 2)
+	defer wp.Stop()
+
+	errBad := errors.New("bad task")
+	ok, _ := wp.Submit(context.Background(), func(context.Context) (interface{}, error) { return 42, nil })
+	failed, _ := wp.Submit(context.Background(), func(context.Context) (interface{}, error) { return nil, errBad })
+	panicked, _ := wp.Submit(context.Background(), func(context.Context) (interface{}, error) { panic("boom") })
+
+	if res := <-ok; res.Value != 42 || res.Err != nil {
+		t.Errorf("expected 42, got %+v", res)
+	}
+	if res := <-failed; res.Err != errBad {
+		t.Errorf("expected %v, got %+v", errBad, res)
+	}
+	res := <-panicked
+	var panicErr *PanicError
+	if !errors.As(res.Err, &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Errorf("expected PanicError, got %+v", res)
+	}
+
+	// the workers survived the panic
+	wp.AddTask(func() { panic("again") })
+	wp.Wait()
+	if stats := wp.Stats(); stats.Workers != 2 || stats.Panics != 2 {
+		t.Errorf("expected 2 workers and 2 panics, got %+v", stats)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceled, _ := wp.Submit(ctx, func(context.Context) (interface{}, error) { return 1, nil })
+	if res := <-canceled; res.Err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %+v", res)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	wp := newPool(t, 1)
+	release := make(chan struct{})
+	wp.AddTask(func() { <-release })
+	queued, _ := wp.Submit(context.Background(), func(context.Context) (interface{}, error) { return 1, nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := wp.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if res := <-queued; res.Err != ErrDropped {
+		t.Errorf("expected ErrDropped, got %+v", res)
+	}
+	if err := wp.AddTask(func() {}); err != ErrStopping {
+		t.Errorf("expected ErrStopping, got %v", err)
+	}
+
+	close(release)
+	wp.WorkerWg.Wait()
+}
+
+func TestShutdownBlockedSender(t *testing.T) {
+	wp, err := StartWorkerPool(1, make(chan func()), make(chan bool), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release := make(chan struct{})
+	wp.AddTask(func() { <-release })
+
+	// the only worker is busy and the queue has no room, so this send blocks
+	sent := make(chan error, 1)
+	go func() { sent <- wp.AddTask(func() {}) }()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := wp.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Errorf("shutdown ignored the deadline, took %s", d)
+	}
+	if err := <-sent; err != ErrStopping {
+		t.Errorf("expected ErrStopping for the blocked sender, got %v", err)
+	}
+
+	close(release)
+	wp.Wait()
+}
+
+func TestZeroWorkers(t *testing.T) {
+	wp, err := StartWorkerPool(0, make(chan func()), make(chan bool), createTasks(2, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wp.Wait()
+	if workers := wp.Stats().Workers; workers != 1 {
+		t.Errorf("expected 1 worker, got %d", workers)
+	}
+	wp.Stop()
+}
+
+func TestTaskLatency(t *testing.T) {
+	wp := newPool(t, 1)
+	defer wp.Stop()
+
+	wp.AddTask(func() { time.Sleep(10 * time.Millisecond) })
+	wp.AddTask(func() { time.Sleep(10 * time.Millisecond) })
+	wp.Wait()
+
+	// the second task waited for the first one: (10 + 20) / 2
+	if l := wp.takeLatency(); math.Abs(float64(l-15*time.Millisecond)) > float64(5*time.Millisecond) {
+		t.Errorf("expected about 15ms, got %v", l)
+	}
+	if l := wp.takeLatency(); l != 0 {
+		t.Errorf("expected 0 after take, got %v", l)
+	}
+}
+
+func TestConcurrentRemove(t *testing.T) {
+	wp := newPool(t, 2)
+	defer wp.Stop()
+
+	var removed int32
+	done := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		go func() {
+			if wp.RemoveWorker() == nil {
+				atomic.AddInt32(&removed, 1)
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+
+	if removed != 1 {
+		t.Errorf("expected 1 removal, got %d", removed)
+	}
+	waitWorkers(t, wp, 1, time.Second)
+
+	ran := make(chan struct{})
+	wp.AddTask(func() { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatalf("the last worker was removed")
+	}
+}
+
+func TestRemoveAbandoned(t *testing.T) {
+	wp := newPool(t, 2)
+	release := make(chan struct{})
+	wp.AddTask(func() { <-release })
+	wp.AddTask(func() { <-release })
+	time.Sleep(10 * time.Millisecond)
+
+	// both workers are busy, nobody takes the signal
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := wp.removeWorker(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if workers := wp.Stats().Workers; workers != 2 {
+		t.Errorf("expected the reservation to be given back, got %d workers", workers)
+	}
+
+	close(release)
+	wp.Stop()
+}
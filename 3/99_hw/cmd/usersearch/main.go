@@ -0,0 +1,96 @@
+// usersearch streams the users of a log that match a query:
+//
+//	usersearch -q 'browser:Android AND browser:MSIE' data/users.txt
+//	cat users.txt | usersearch -q 'country="Dominican Republic" AND NOT email~"\.edu$"' -format json
+//
+// The log is read in chunks, so memory does not grow with the file.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/mailru/easyjson/jwriter"
+
+	"hw3/search"
+)
+
+func main() {
+	query := flag.String("q", "browser:Android AND browser:MSIE", "query, see the search package for the syntax")
+	format := flag.String("format", "text", "output format: text as FastSearch prints or json, a record per line")
+	workers := flag.Int("workers", 0, "parallel decoders, GOMAXPROCS by default")
+	chunk := flag.Int("chunk", 0, "chunk size in bytes, 64KB by default")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [file]\nreads stdin without a file\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*query, *format, search.Options{Workers: *workers, ChunkSize: *chunk}, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "usersearch:", err)
+		os.Exit(1)
+	}
+}
+
+func run(query, format string, opts search.Options, args []string) error {
+	q, err := search.Parse(query)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	switch {
+	case len(args) > 1:
+		return fmt.Errorf("expected one file, got %d", len(args))
+	case len(args) == 1 && args[0] != "-":
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch format {
+	case "text":
+		_, err = search.Report(ctx, os.Stdout, in, q, opts)
+	case "json":
+		err = writeJSON(ctx, os.Stdout, in, q, opts)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	return err
+}
+
+// writeJSON prints {"line":N,"user":{...}} for every match
+func writeJSON(ctx context.Context, out io.Writer, in io.Reader, q *search.Query, opts search.Options) error {
+	bw := bufio.NewWriter(out)
+	w := &jwriter.Writer{}
+
+	_, err := search.Scan(ctx, in, q, opts, func(m search.Match) error {
+		w.RawString(`{"line":`)
+		w.RawString(strconv.Itoa(m.Line))
+		w.RawString(`,"user":`)
+		m.Record.MarshalEasyJSON(w)
+		w.RawString("}\n")
+		if w.Error != nil {
+			return w.Error
+		}
+
+		_, err := w.DumpTo(bw)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
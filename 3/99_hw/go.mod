@@ -0,0 +1,7 @@
+module hw3
+
+go 1.18
+
+require github.com/mailru/easyjson v0.7.7
+
+require github.com/josharian/intern v1.0.0 // indirect
@@ -30,6 +30,18 @@ func TestSearch(t *testing.T) {
 	}
 }
 
+func TestParallelSearch(t *testing.T) {
+	slowOut := new(bytes.Buffer)
+	SlowSearch(slowOut)
+
+	parallelOut := new(bytes.Buffer)
+	ParallelSearch(parallelOut)
+
+	if slowOut.String() != parallelOut.String() {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", parallelOut.String(), slowOut.String())
+	}
+}
+
 // -----
 // go test -bench . -benchmem
 
@@ -44,3 +56,9 @@ func BenchmarkFast(b *testing.B) {
 		FastSearch(ioutil.Discard)
 	}
 }
+
+func BenchmarkParallel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParallelSearch(ioutil.Discard)
+	}
+}
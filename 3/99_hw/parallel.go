@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"hw3/search"
+)
+
+// androidAndMSIE - запрос FastSearch на языке движка поиска
+var androidAndMSIE = search.MustParse("browser:Android AND browser:MSIE")
+
+// ParallelSearch делает то же, что FastSearch, но через движок search:
+// файл режется на куски, которые разбираются параллельно, вывод в порядке файла
+func ParallelSearch(out io.Writer) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	if _, err := search.Report(context.Background(), out, file, androidAndMSIE, search.Options{}); err != nil {
+		panic(err)
+	}
+}
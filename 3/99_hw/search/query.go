@@ -0,0 +1,416 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a compiled filter over the records.
+//
+// A predicate is field, operator and value:
+//
+//	name:Sharon                  contains
+//	country="Dominican Republic" equals
+//	email~"\.edu$"               regular expression
+//
+// The fields are name, email, phone, company, country, job and browser.
+// Values with spaces or operator characters are double quoted.
+// A browser predicate holds when any of the browsers of the user matches.
+// Predicates are combined with NOT, AND and OR (also !, && and ||) in that
+// order of precedence, parentheses group them.
+type Query struct {
+	src  string
+	root node
+	// browsers are the browser predicates in the query, see EachBrowser
+	browsers []*predicate
+}
+
+// SyntaxError points to the place in the query where parsing failed
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query syntax error at %d: %s", e.Pos, e.Msg)
+}
+
+// Parse compiles the query
+func Parse(src string) (*Query, error) {
+	p := &parser{lex: lexer{src: src}}
+	p.next()
+
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	return &Query{src: src, root: root, browsers: p.browsers}, nil
+}
+
+// MustParse is Parse for queries known at compile time
+func MustParse(src string) *Query {
+	q, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func (q *Query) String() string {
+	return q.src
+}
+
+// Match tells if the record satisfies the query
+func (q *Query) Match(r *Record) bool {
+	return q.root.match(r)
+}
+
+// EachBrowser calls fn for every browser of the record that satisfies
+// at least one browser predicate of the query, whether the record matches or not
+func (q *Query) EachBrowser(r *Record, fn func(browser string)) {
+	for _, b := range r.Browsers {
+		for _, p := range q.browsers {
+			if p.test(b) {
+				fn(b)
+				break
+			}
+		}
+	}
+}
+
+type field int
+
+const (
+	fieldName field = iota
+	fieldEmail
+	fieldPhone
+	fieldCompany
+	fieldCountry
+	fieldJob
+	fieldBrowser
+)
+
+var fields = map[string]field{
+	"name":    fieldName,
+	"email":   fieldEmail,
+	"phone":   fieldPhone,
+	"company": fieldCompany,
+	"country": fieldCountry,
+	"job":     fieldJob,
+	"browser": fieldBrowser,
+}
+
+type node interface {
+	match(r *Record) bool
+}
+
+type predicate struct {
+	field field
+	op    tokenKind
+	value string
+	re    *regexp.Regexp
+}
+
+func (p *predicate) match(r *Record) bool {
+	if p.field != fieldBrowser {
+		return p.test(r.field(p.field))
+	}
+
+	for _, b := range r.Browsers {
+		if p.test(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *predicate) test(s string) bool {
+	switch p.op {
+	case tokContains:
+		return strings.Contains(s, p.value)
+	case tokEquals:
+		return s == p.value
+	default:
+		return p.re.MatchString(s)
+	}
+}
+
+type and []node
+
+func (a and) match(r *Record) bool {
+	for _, n := range a {
+		if !n.match(r) {
+			return false
+		}
+	}
+	return true
+}
+
+type or []node
+
+func (o or) match(r *Record) bool {
+	for _, n := range o {
+		if n.match(r) {
+			return true
+		}
+	}
+	return false
+}
+
+type not struct {
+	node
+}
+
+func (n not) match(r *Record) bool {
+	return !n.node.match(r)
+}
+
+// parser is a recursive descent over
+//
+//	or   = and { OR and }
+//	and  = not { AND not }
+//	not  = NOT not | "(" or ")" | word op value
+type parser struct {
+	lex      lexer
+	tok      token
+	browsers []*predicate
+}
+
+func (p *parser) next() {
+	p.tok = p.lex.next()
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.tok.kind == tokError {
+		return &SyntaxError{Pos: p.tok.pos, Msg: p.tok.text}
+	}
+	return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := or{left}
+	for p.tok.kind == tokOr {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, right)
+	}
+
+	if len(nodes) == 1 {
+		return left, nil
+	}
+	return nodes, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := and{left}
+	for p.tok.kind == tokAnd {
+		p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, right)
+	}
+
+	if len(nodes) == 1 {
+		return left, nil
+	}
+	return nodes, nil
+}
+
+func (p *parser) not() (node, error) {
+	switch p.tok.kind {
+	case tokNot:
+		p.next()
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return not{n}, nil
+
+	case tokLParen:
+		p.next()
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ), got %s", p.tok)
+		}
+		p.next()
+		return n, nil
+
+	case tokWord:
+		return p.predicate()
+	}
+
+	return nil, p.errorf("expected predicate, got %s", p.tok)
+}
+
+func (p *parser) predicate() (node, error) {
+	f, ok := fields[strings.ToLower(p.tok.text)]
+	if !ok {
+		return nil, p.errorf("unknown field %q", p.tok.text)
+	}
+	p.next()
+
+	op := p.tok.kind
+	if op != tokContains && op != tokEquals && op != tokMatch {
+		return nil, p.errorf("expected :, = or ~ after field, got %s", p.tok)
+	}
+	p.next()
+
+	if p.tok.kind != tokWord && p.tok.kind != tokString {
+		return nil, p.errorf("expected value, got %s", p.tok)
+	}
+	pred := &predicate{field: f, op: op, value: p.tok.text}
+	if op == tokMatch {
+		re, err := regexp.Compile(pred.value)
+		if err != nil {
+			return nil, p.errorf("bad regexp: %v", err)
+		}
+		pred.re = re
+	}
+	p.next()
+
+	if f == fieldBrowser {
+		p.browsers = append(p.browsers, pred)
+	}
+	return pred, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokError
+	tokWord
+	tokString
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+	tokContains
+	tokEquals
+	tokMatch
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return "'" + t.text + "'"
+}
+
+var keywords = map[string]tokenKind{
+	"AND": tokAnd,
+	"OR":  tokOr,
+	"NOT": tokNot,
+}
+
+var operators = map[string]tokenKind{
+	"&&": tokAnd,
+	"||": tokOr,
+	"!":  tokNot,
+	"(":  tokLParen,
+	")":  tokRParen,
+	":":  tokContains,
+	"=":  tokEquals,
+	"~":  tokMatch,
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() token {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if start == len(l.src) {
+		return token{kind: tokEOF, pos: start}
+	}
+
+	for _, n := range []int{2, 1} {
+		if start+n > len(l.src) {
+			continue
+		}
+		if kind, ok := operators[l.src[start:start+n]]; ok {
+			l.pos += n
+			return token{kind: kind, text: l.src[start:l.pos], pos: start}
+		}
+	}
+
+	if l.src[start] == '"' {
+		return l.quoted()
+	}
+
+	for l.pos < len(l.src) && !isDelimiter(l.src[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++
+		return token{kind: tokError, text: fmt.Sprintf("unexpected %q", l.src[start]), pos: start}
+	}
+	text := l.src[start:l.pos]
+	if kind, ok := keywords[strings.ToUpper(text)]; ok {
+		return token{kind: kind, text: text, pos: start}
+	}
+	return token{kind: tokWord, text: text, pos: start}
+}
+
+// quoted reads a double quoted string, \" and \\ are the only escapes
+// so that regexps need no doubled backslashes
+func (l *lexer) quoted() token {
+	start := l.pos
+	var sb strings.Builder
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\' && l.pos+1 < len(l.src) && (l.src[l.pos+1] == '"' || l.src[l.pos+1] == '\\'):
+			l.pos++
+			c = l.src[l.pos]
+		case c == '"':
+			l.pos++
+			return token{kind: tokString, text: sb.String(), pos: start}
+		}
+		sb.WriteByte(c)
+	}
+	return token{kind: tokError, text: "unterminated string", pos: start}
+}
+
+func isDelimiter(c byte) bool {
+	return unicode.IsSpace(rune(c)) || strings.IndexByte(`()":=~!&|`, c) >= 0
+}
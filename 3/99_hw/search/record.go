@@ -0,0 +1,37 @@
+package search
+
+// Record is one line of the users log
+//
+//easyjson:json
+type Record struct {
+	Name     string   `json:"name"`
+	Email    string   `json:"email"`
+	Phone    string   `json:"phone"`
+	Company  string   `json:"company"`
+	Country  string   `json:"country"`
+	Job      string   `json:"job"`
+	Browsers []string `json:"browsers"`
+}
+
+// reset clears the record for the next line and keeps the browsers buffer
+func (r *Record) reset() {
+	*r = Record{Browsers: r.Browsers[:0]}
+}
+
+func (r *Record) field(f field) string {
+	switch f {
+	case fieldName:
+		return r.Name
+	case fieldEmail:
+		return r.Email
+	case fieldPhone:
+		return r.Phone
+	case fieldCompany:
+		return r.Company
+	case fieldCountry:
+		return r.Country
+	case fieldJob:
+		return r.Job
+	}
+	return ""
+}
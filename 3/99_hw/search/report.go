@@ -0,0 +1,41 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Report streams the matches in the format of FastSearch:
+//
+//	found users:
+//	[0] Name <user [at] host>
+//
+//	Total unique browsers 42
+func Report(ctx context.Context, w io.Writer, r io.Reader, q *Query, opts Options) (Stats, error) {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("found users:\n")
+
+	var line []byte
+	stats, err := Scan(ctx, r, q, opts, func(m Match) error {
+		line = append(line[:0], '[')
+		line = strconv.AppendInt(line, int64(m.Line), 10)
+		line = append(line, "] "...)
+		line = append(line, m.Record.Name...)
+		line = append(line, " <"...)
+		line = append(line, strings.Replace(m.Record.Email, "@", " [at] ", 1)...)
+		line = append(line, ">\n"...)
+		_, err := bw.Write(line)
+		return err
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	bw.WriteString("\nTotal unique browsers ")
+	bw.WriteString(strconv.Itoa(stats.Browsers))
+	bw.WriteString("\n")
+	return stats, bw.Flush()
+}
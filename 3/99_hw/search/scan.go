@@ -0,0 +1,311 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/mailru/easyjson/jlexer"
+)
+
+// Options tune the scanner, zero values take the defaults
+type Options struct {
+	// Workers decode and match the chunks, GOMAXPROCS by default
+	Workers int
+	// ChunkSize is the size in bytes a chunk of lines is cut at, 64KB by default.
+	// At most 2*Workers chunks are in memory at once, a line longer than the chunk
+	// makes its chunk grow.
+	ChunkSize int
+}
+
+const defaultChunkSize = 64 << 10
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = defaultChunkSize
+	}
+	return o
+}
+
+// Match is a found record, Record is reused after emit returns
+type Match struct {
+	// Line is the zero based number of the line in the input
+	Line   int
+	Record *Record
+}
+
+// Stats sums the scan up
+type Stats struct {
+	Lines   int
+	Matches int
+	// Browsers are the unique browsers that satisfied a browser predicate, see Query.EachBrowser
+	Browsers int
+}
+
+// Scan reads the users log line by line, matches the records in parallel
+// and calls emit for the matches in the order of the input.
+// The first decode error, emit error or ctx cancellation stops the scan.
+func Scan(ctx context.Context, r io.Reader, q *Query, opts Options, emit func(Match) error) (Stats, error) {
+	opts = opts.withDefaults()
+	if err := ctx.Err(); err != nil {
+		return Stats{}, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &scanner{
+		q:       q,
+		opts:    opts,
+		cancel:  cancel,
+		window:  make(chan struct{}, 2*opts.Workers),
+		chunks:  make(chan *chunk, opts.Workers),
+		results: make(chan *chunk, opts.Workers),
+	}
+	s.chunkPool.New = func() interface{} {
+		return &chunk{data: make([]byte, 0, opts.ChunkSize+opts.ChunkSize/4)}
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1 + opts.Workers)
+	go func() {
+		defer wg.Done()
+		defer close(s.chunks)
+		s.fail(s.read(ctx, r))
+	}()
+
+	workers := &sync.WaitGroup{}
+	workers.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			defer workers.Done()
+			s.fail(s.work(ctx))
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(s.results)
+	}()
+
+	stats, err := s.collect(ctx, emit)
+	s.fail(err)
+	// the results are no longer read, let the workers exit
+	for range s.results {
+	}
+	wg.Wait()
+
+	return stats, s.err
+}
+
+// chunk is a batch of lines, the workers fill it in with the matches
+type chunk struct {
+	seq       int
+	firstLine int
+	data      []byte
+	// ends are the offsets of the ends of the lines in data
+	ends []int
+
+	// records are the matched records and lines their numbers
+	records  []Record
+	lines    []int
+	browsers []string
+}
+
+func (c *chunk) reset() {
+	c.data = c.data[:0]
+	c.ends = c.ends[:0]
+	c.records = c.records[:0]
+	c.lines = c.lines[:0]
+	c.browsers = c.browsers[:0]
+}
+
+// slot returns the next record to decode into, the buffers of the records
+// from the previous use of the chunk are kept
+func (c *chunk) slot() *Record {
+	if len(c.records) < cap(c.records) {
+		c.records = c.records[:len(c.records)+1]
+	} else {
+		c.records = append(c.records, Record{})
+	}
+
+	rec := &c.records[len(c.records)-1]
+	rec.reset()
+	return rec
+}
+
+type scanner struct {
+	q    *Query
+	opts Options
+
+	// window bounds the chunks in memory, collect frees a place after emitting a chunk
+	window    chan struct{}
+	chunks    chan *chunk
+	results   chan *chunk
+	chunkPool sync.Pool
+
+	// the first error stops the others
+	cancel  context.CancelFunc
+	errOnce sync.Once
+	err     error
+}
+
+func (s *scanner) fail(err error) {
+	if err != nil && !errors.Is(err, errStopped) {
+		s.errOnce.Do(func() { s.err = err })
+		s.cancel()
+	}
+}
+
+// errStopped is how the goroutines leave when another one has failed
+var errStopped = errors.New("scan stopped")
+
+func (s *scanner) send(ctx context.Context, ch chan<- *chunk, c *chunk) error {
+	select {
+	case ch <- c:
+		return nil
+	case <-ctx.Done():
+		return errStopped
+	}
+}
+
+func (s *scanner) read(ctx context.Context, r io.Reader) error {
+	br := bufio.NewReaderSize(r, s.opts.ChunkSize)
+	seq, line := 0, 0
+
+	var c *chunk
+	for {
+		if c == nil {
+			select {
+			case s.window <- struct{}{}:
+			case <-ctx.Done():
+				return errStopped
+			}
+			c = s.chunkPool.Get().(*chunk)
+			c.reset()
+			c.seq, c.firstLine = seq, line
+			seq++
+		}
+
+		// a line longer than the reader buffer comes in parts
+		var err error
+		for {
+			var part []byte
+			part, err = br.ReadSlice('\n')
+			c.data = append(c.data, part...)
+			if err != bufio.ErrBufferFull {
+				break
+			}
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if len(c.data) > 0 && (len(c.ends) == 0 || c.ends[len(c.ends)-1] != len(c.data)) {
+			c.ends = append(c.ends, len(c.data))
+			line++
+		}
+
+		if err == io.EOF || len(c.data) >= s.opts.ChunkSize {
+			if sendErr := s.send(ctx, s.chunks, c); sendErr != nil {
+				return sendErr
+			}
+			c = nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func (s *scanner) work(ctx context.Context) error {
+	for c := range s.chunks {
+		if ctx.Err() != nil {
+			return errStopped
+		}
+		if err := s.match(c); err != nil {
+			return err
+		}
+		if err := s.send(ctx, s.results, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *scanner) match(c *chunk) error {
+	start := 0
+	for i, end := range c.ends {
+		line := c.data[start:end]
+		start = end
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			line = line[:len(line)-1]
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		rec := c.slot()
+		l := jlexer.Lexer{Data: line}
+		rec.UnmarshalEasyJSON(&l)
+		if err := l.Error(); err != nil {
+			return fmt.Errorf("line %d: %w", c.firstLine+i, err)
+		}
+
+		s.q.EachBrowser(rec, func(b string) {
+			c.browsers = append(c.browsers, b)
+		})
+		if !s.q.Match(rec) {
+			c.records = c.records[:len(c.records)-1]
+			continue
+		}
+		c.lines = append(c.lines, c.firstLine+i)
+	}
+	return nil
+}
+
+// collect puts the chunks back in order and emits their matches
+func (s *scanner) collect(ctx context.Context, emit func(Match) error) (Stats, error) {
+	stats := Stats{}
+	browsers := make(map[string]struct{})
+	pending := make(map[int]*chunk)
+	next := 0
+
+	for c := range s.results {
+		pending[c.seq] = c
+		for {
+			c, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			stats.Lines = c.firstLine + len(c.ends)
+			for _, b := range c.browsers {
+				browsers[b] = struct{}{}
+			}
+			for i := range c.records {
+				stats.Matches++
+				if err := emit(Match{Line: c.lines[i], Record: &c.records[i]}); err != nil {
+					return stats, err
+				}
+			}
+
+			s.chunkPool.Put(c)
+			<-s.window
+		}
+		stats.Browsers = len(browsers)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
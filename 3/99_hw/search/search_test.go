@@ -0,0 +1,195 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testUser = &Record{
+	Name:     "Sharon Crawford",
+	Email:    "JonathanMorris@Muxo.edu",
+	Country:  "Dominican Republic",
+	Job:      "Programmer Analyst #{N}",
+	Browsers: []string{"Mozilla/5.0 (Android; Linux armv7l)", "Opera/9.80 (J2ME/MIDP; Opera Mini/9.80)"},
+}
+
+func TestQuery(t *testing.T) {
+	cases := []struct {
+		query    string
+		expected bool
+	}{
+		{`name:Sharon`, true},
+		{`NAME:sharon`, false},
+		{`country="Dominican Republic"`, true},
+		{`country=Dominican`, false},
+		{`email~"\.edu$"`, true},
+		{`browser:Android`, true},
+		{`browser:Android AND browser:MSIE`, false},
+		{`browser:Android && browser:Opera`, true},
+		{`browser:MSIE OR job:"#{N}"`, true},
+		{`NOT browser:MSIE`, true},
+		{`!browser:Android || phone:1`, false},
+		// NOT binds tighter than AND, AND tighter than OR
+		{`name:Bob AND name:Sharon OR country:Dominican`, true},
+		{`name:Bob AND (name:Sharon OR country:Dominican)`, false},
+		{`NOT (name:Bob OR name:Alice) AND NOT NOT company=""`, true},
+	}
+	for _, tc := range cases {
+		q, err := Parse(tc.query)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.query, err)
+			continue
+		}
+		if got := q.Match(testUser); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.query, tc.expected, got)
+		}
+	}
+}
+
+func TestQuerySyntaxError(t *testing.T) {
+	cases := []struct {
+		query string
+		pos   int
+	}{
+		{``, 0},
+		{`name`, 4},
+		{`age:10`, 0},
+		{`name:Bob AND`, 12},
+		{`(name:Bob`, 9},
+		{`name:Bob)`, 8},
+		{`email~"("`, 6},
+		{`name:"Bob`, 5},
+		{`name:Bob & name:Alice`, 9},
+	}
+	for _, tc := range cases {
+		_, err := Parse(tc.query)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%s: expected SyntaxError, got %v", tc.query, err)
+			continue
+		}
+		if syntaxErr.Pos != tc.pos {
+			t.Errorf("%s: expected error at %d, got %v", tc.query, tc.pos, err)
+		}
+	}
+}
+
+func TestEachBrowser(t *testing.T) {
+	var got []string
+	MustParse(`name:Bob AND NOT browser:Opera OR browser~"^Mozilla"`).EachBrowser(testUser, func(b string) {
+		got = append(got, b)
+	})
+	if !reflect.DeepEqual(got, testUser.Browsers) {
+		t.Errorf("expected all the browsers, got %q", got)
+	}
+}
+
+// scanAll returns the matches as "line:name"
+func scanAll(t *testing.T, input, query string, opts Options) ([]string, Stats, error) {
+	t.Helper()
+	var res []string
+	stats, err := Scan(context.Background(), strings.NewReader(input), MustParse(query), opts, func(m Match) error {
+		res = append(res, fmt.Sprintf("%d:%s", m.Line, m.Record.Name))
+		return nil
+	})
+	return res, stats, err
+}
+
+func TestScanOrder(t *testing.T) {
+	lines := make([]string, 0, 1000)
+	expected := []string{}
+	for i := 0; i < 1000; i++ {
+		browser := "Opera"
+		if i%7 == 0 {
+			browser = "Android " + fmt.Sprint(i%5)
+			expected = append(expected, fmt.Sprintf("%d:user%d", i, i))
+		}
+		lines = append(lines, fmt.Sprintf(`{"name":"user%d","browsers":[%q]}`, i, browser))
+	}
+	// an empty line is counted but not decoded
+	lines[10] = ""
+	input := strings.Join(lines, "\n")
+
+	for _, opts := range []Options{{Workers: 1}, {Workers: 8, ChunkSize: 100}, {Workers: 3, ChunkSize: 1}} {
+		got, stats, err := scanAll(t, input, "browser:Android", opts)
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %v", opts, err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%+v: expected %d matches in order, got %v", opts, len(expected), got)
+		}
+		if stats != (Stats{Lines: 1000, Matches: len(expected), Browsers: 5}) {
+			t.Errorf("%+v: unexpected stats %+v", opts, stats)
+		}
+	}
+}
+
+func TestScanReusedRecords(t *testing.T) {
+	// the second record has no browsers and the third no name, nothing must be left from the previous ones
+	input := `{"name":"a","browsers":["x","y"]}` + "\n" + `{"name":"b"}` + "\n" + `{"browsers":["z"]}` + "\n"
+	var got []string
+	_, err := Scan(context.Background(), strings.NewReader(input), MustParse(`name~""`), Options{Workers: 1}, func(m Match) error {
+		got = append(got, fmt.Sprintf("%s%v", m.Record.Name, m.Record.Browsers))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"a[x y]", "b[]", "[z]"}) {
+		t.Errorf("unexpected records %q", got)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	input := strings.Repeat(`{"name":"ok"}`+"\n", 100) + `{"name":` + "\n" + `{"name":"ok"}`
+	_, _, err := scanAll(t, input, "name:ok", Options{Workers: 4, ChunkSize: 64})
+	if err == nil || !strings.HasPrefix(err.Error(), "line 100:") {
+		t.Errorf("expected the error on line 100, got %v", err)
+	}
+
+	errEnough := errors.New("enough")
+	n := 0
+	_, err = Scan(context.Background(), strings.NewReader(input), MustParse("name:ok"), Options{ChunkSize: 64}, func(Match) error {
+		if n++; n == 10 {
+			return errEnough
+		}
+		return nil
+	})
+	if err != errEnough || n != 10 {
+		t.Errorf("expected to stop on the 10th match with %v, got %v after %d", errEnough, err, n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Scan(ctx, strings.NewReader(input), MustParse("name:ok"), Options{}, func(Match) error { return nil })
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	data, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	q := MustParse("browser:Android AND browser:MSIE")
+
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprint("workers=", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				_, err := Scan(context.Background(), bytes.NewReader(data), q, Options{Workers: workers}, func(Match) error { return nil })
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,6 +18,29 @@ var (
 	client  = &http.Client{Timeout: time.Second}
 )
 
+// ошибки FindUsers, проверяются через errors.Is
+var (
+	ErrBadToken      = errors.New("Bad AccessToken")
+	ErrBadOrderField = errors.New(ErrorBadOrderField)
+	// ErrTimeout и ErrServer возвращаются, когда повторы не помогли
+	ErrTimeout = errors.New("search timeout")
+	ErrServer  = errors.New("SearchServer fatal error")
+)
+
+// searchError сохраняет прежний текст ошибки и даёт сравнить её с одной из Err*
+type searchError struct {
+	msg  string
+	kind error
+}
+
+func (e *searchError) Error() string {
+	return e.msg
+}
+
+func (e *searchError) Unwrap() error {
+	return e.kind
+}
+
 type User struct {
 	Id     int
 	Name   string
@@ -40,6 +64,8 @@ const (
 	OrderByDesc = 1
 
 	ErrorBadOrderField = `OrderField invalid`
+
+	maxLimit = 25
 )
 
 type SearchRequest struct {
@@ -55,18 +81,47 @@ type SearchClient struct {
 	AccessToken string
 	// урл внешней системы, куда идти
 	URL string
+	// HTTPClient - клиент для запросов, если nil - общий с таймаутом в секунду
+	HTTPClient *http.Client
+	// Retry - повторы на таймаутах и 5xx, по умолчанию не повторяем
+	Retry RetryPolicy
+}
+
+// RetryPolicy - экспоненциальная задержка между повторами: BaseDelay, 2*BaseDelay, ... но не больше MaxDelay
+type RetryPolicy struct {
+	// Attempts - сколько раз повторить после первой попытки
+	Attempts  int
+	BaseDelay time.Duration
+	// MaxDelay - 0 значит без ограничения
+	MaxDelay time.Duration
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 0; i < attempt && (p.MaxDelay == 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
 }
 
 // FindUsers отправляет запрос во внешнюю систему, которая непосредственно ищет пользоваталей
 func (srv *SearchClient) FindUsers(req SearchRequest) (*SearchResponse, error) {
+	return srv.FindUsersContext(context.Background(), req)
+}
+
+// FindUsersContext - FindUsers, который прерывается вместе с ctx, в том числе во время ожидания повтора
+func (srv *SearchClient) FindUsersContext(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
 
 	searcherParams := url.Values{}
 
 	if req.Limit < 0 {
 		return nil, fmt.Errorf("limit must be > 0")
 	}
-	if req.Limit > 25 {
-		req.Limit = 25
+	if req.Limit > maxLimit {
+		req.Limit = maxLimit
 	}
 	if req.Offset < 0 {
 		return nil, fmt.Errorf("offset must be > 0")
@@ -81,32 +136,45 @@ func (srv *SearchClient) FindUsers(req SearchRequest) (*SearchResponse, error) {
 	searcherParams.Add("order_field", req.OrderField)
 	searcherParams.Add("order_by", strconv.Itoa(req.OrderBy))
 
-	searcherReq, err := http.NewRequest("GET", srv.URL+"?"+searcherParams.Encode(), nil)
-	searcherReq.Header.Add("AccessToken", srv.AccessToken)
+	var (
+		status int
+		body   []byte
+		err    error
+	)
+	for attempt := 0; ; attempt++ {
+		status, body, err = srv.do(ctx, searcherParams)
+		retry := isTimeout(err) || err == nil && status >= 500
+		if !retry || attempt == srv.Retry.Attempts || ctx.Err() != nil {
+			break
+		}
+		if err = sleep(ctx, srv.Retry.delay(attempt)); err != nil {
+			break
+		}
+	}
 
-	resp, err := client.Do(searcherReq)
 	if err != nil {
-		if err, ok := err.(net.Error); ok && err.Timeout() {
-			return nil, fmt.Errorf("timeout for %s", searcherParams.Encode())
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("search for %s: %w", searcherParams.Encode(), ctxErr)
+		}
+		if isTimeout(err) {
+			return nil, &searchError{msg: fmt.Sprintf("timeout for %s", searcherParams.Encode()), kind: ErrTimeout}
 		}
 		return nil, fmt.Errorf("unknown error %s", err)
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
 
-	switch resp.StatusCode {
-	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("Bad AccessToken")
-	case http.StatusInternalServerError:
-		return nil, fmt.Errorf("SearchServer fatal error")
-	case http.StatusBadRequest:
+	switch {
+	case status == http.StatusUnauthorized:
+		return nil, ErrBadToken
+	case status >= 500:
+		return nil, ErrServer
+	case status == http.StatusBadRequest:
 		errResp := SearchErrorResponse{}
 		err = json.Unmarshal(body, &errResp)
 		if err != nil {
 			return nil, fmt.Errorf("cant unpack error json: %s", err)
 		}
 		if errResp.Error == "ErrorBadOrderField" {
-			return nil, fmt.Errorf("OrderFeld %s invalid", req.OrderField)
+			return nil, &searchError{msg: fmt.Sprintf("OrderFeld %s invalid", req.OrderField), kind: ErrBadOrderField}
 		}
 		return nil, fmt.Errorf("unknown bad request error: %s", errResp.Error)
 	}
@@ -127,3 +195,110 @@ func (srv *SearchClient) FindUsers(req SearchRequest) (*SearchResponse, error) {
 
 	return &result, err
 }
+
+// do - одна попытка запроса, err только на ошибках транспорта
+func (srv *SearchClient) do(ctx context.Context, params url.Values) (int, []byte, error) {
+	searcherReq, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?"+params.Encode(), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	searcherReq.Header.Add("AccessToken", srv.AccessToken)
+
+	httpClient := srv.HTTPClient
+	if httpClient == nil {
+		httpClient = client
+	}
+	resp, err := httpClient.Do(searcherReq)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// UserIterator лениво идёт по всем страницам поиска:
+//
+//	it := srv.Users(ctx, req)
+//	for it.Next() {
+//		u := it.User()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Следующая страница запрашивается, только когда кончилась текущая.
+type UserIterator struct {
+	ctx  context.Context
+	srv  *SearchClient
+	req  SearchRequest
+	page []User
+	pos  int
+	last bool
+	err  error
+}
+
+// Users возвращает итератор по найденным начиная с req.Offset, req.Limit - размер страницы (0 - максимальный)
+func (srv *SearchClient) Users(ctx context.Context, req SearchRequest) *UserIterator {
+	if req.Limit == 0 || req.Limit > maxLimit {
+		req.Limit = maxLimit
+	}
+	return &UserIterator{ctx: ctx, srv: srv, req: req, pos: -1}
+}
+
+// Next переходит к следующему пользователю, false - пользователи кончились или случилась ошибка
+func (it *UserIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.pos+1 < len(it.page) {
+		it.pos++
+		return true
+	}
+	if it.last {
+		return false
+	}
+
+	resp, err := it.srv.FindUsersContext(it.ctx, it.req)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.page, it.pos = resp.Users, 0
+	it.req.Offset += len(resp.Users)
+	// пустая страница с NextPage не даст сдвинуться дальше
+	it.last = !resp.NextPage || len(resp.Users) == 0
+
+	return len(it.page) > 0
+}
+
+// User - текущий пользователь, после Next вернувшего true
+func (it *UserIterator) User() User {
+	return it.page[it.pos]
+}
+
+// Err - ошибка, на которой остановился итератор
+func (it *UserIterator) Err() error {
+	return it.err
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -11,8 +13,11 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"hw4/searchserver"
 )
 
 const (
@@ -555,3 +560,263 @@ func SendTestRequestInternal(handlerFunc http.HandlerFunc, limit, offset, orderB
 
 	return result, nil
 }
+
+// Тесты повторов, контекста и итератора
+
+func newCountingServer(handler http.HandlerFunc) (*httptest.Server, *int32) {
+	calls := new(int32)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		handler(w, r)
+	}))
+	return ts, calls
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	failures := int32(2)
+	ts, calls := newCountingServer(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		SearchServer(w, r)
+	})
+	defer ts.Close()
+
+	sc := &SearchClient{URL: ts.URL, AccessToken: goodToken, Retry: RetryPolicy{Attempts: 2, BaseDelay: time.Millisecond}}
+	result, err := sc.FindUsers(SearchRequest{Limit: 1, Query: "Boyd"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Users) != 1 || result.Users[0].Name != "Boyd Wolf" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 calls, got %d", *calls)
+	}
+
+	// повторы кончились
+	atomic.StoreInt32(&failures, 10)
+	atomic.StoreInt32(calls, 0)
+	_, err = sc.FindUsers(SearchRequest{Limit: 1})
+	if !errors.Is(err, ErrServer) || err.Error() != "SearchServer fatal error" {
+		t.Errorf("expected ErrServer, got %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 calls, got %d", *calls)
+	}
+}
+
+func TestRetryOnTimeout(t *testing.T) {
+	slow := int32(1)
+	ts, calls := newCountingServer(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&slow, -1) >= 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		SearchServer(w, r)
+	})
+	defer ts.Close()
+
+	sc := &SearchClient{
+		URL:         ts.URL,
+		AccessToken: goodToken,
+		HTTPClient:  &http.Client{Timeout: 50 * time.Millisecond},
+	}
+	_, err := sc.FindUsers(SearchRequest{Limit: 1})
+	if !errors.Is(err, ErrTimeout) || err.Error() != "timeout for limit=2&offset=0&order_by=0&order_field=&query=" {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	atomic.StoreInt32(&slow, 1)
+	atomic.StoreInt32(calls, 0)
+	sc.Retry = RetryPolicy{Attempts: 1, BaseDelay: time.Millisecond}
+	if _, err := sc.FindUsers(SearchRequest{Limit: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("expected 2 calls, got %d", *calls)
+	}
+}
+
+func TestNoRetryOnClientErrors(t *testing.T) {
+	ts, calls := newCountingServer(SearchServer)
+	defer ts.Close()
+
+	sc := &SearchClient{URL: ts.URL, AccessToken: badToken, Retry: RetryPolicy{Attempts: 3}}
+	if _, err := sc.FindUsers(SearchRequest{}); !errors.Is(err, ErrBadToken) {
+		t.Errorf("expected ErrBadToken, got %v", err)
+	}
+
+	sc.AccessToken = goodToken
+	_, err := sc.FindUsers(SearchRequest{OrderField: "About"})
+	if !errors.Is(err, ErrBadOrderField) || err.Error() != "OrderFeld About invalid" {
+		t.Errorf("expected ErrBadOrderField, got %v", err)
+	}
+
+	if *calls != 2 {
+		t.Errorf("expected 2 calls, got %d", *calls)
+	}
+}
+
+func TestContextCancelDuringBackoff(t *testing.T) {
+	ts, calls := newCountingServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	sc := &SearchClient{URL: ts.URL, AccessToken: goodToken, Retry: RetryPolicy{Attempts: 5, BaseDelay: time.Hour}}
+	start := time.Now()
+	_, err := sc.FindUsersContext(ctx, SearchRequest{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to return on cancel, took %v", elapsed)
+	}
+	if *calls != 1 {
+		t.Errorf("expected 1 call, got %d", *calls)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	expected := []time.Duration{10, 20, 40, 50, 50}
+	for attempt, d := range expected {
+		if got := p.delay(attempt); got != d*time.Millisecond {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, d*time.Millisecond, got)
+		}
+	}
+
+	if got := (RetryPolicy{BaseDelay: time.Second}).delay(10); got != 1024*time.Second {
+		t.Errorf("expected no limit without MaxDelay, got %v", got)
+	}
+	if got := (RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Millisecond}).delay(0); got != time.Millisecond {
+		t.Errorf("expected MaxDelay, got %v", got)
+	}
+}
+
+func TestTransportErrors(t *testing.T) {
+	_, err := (&SearchClient{URL: ":"}).FindUsers(SearchRequest{})
+	if err == nil || !strings.Contains(err.Error(), "missing protocol scheme") {
+		t.Errorf("expected bad url error, got %v", err)
+	}
+
+	// тело короче заявленного
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10")
+		fmt.Fprint(w, "[")
+	}))
+	defer ts.Close()
+
+	_, err = (&SearchClient{URL: ts.URL, Retry: RetryPolicy{Attempts: 3}}).FindUsers(SearchRequest{})
+	if err == nil || err.Error() != "unknown error unexpected EOF" {
+		t.Errorf("expected unexpected EOF, got %v", err)
+	}
+}
+
+func TestUsersIterator(t *testing.T) {
+	ts, calls := newCountingServer(SearchServer)
+	defer ts.Close()
+
+	sc := &SearchClient{URL: ts.URL, AccessToken: goodToken}
+	it := sc.Users(context.Background(), SearchRequest{Limit: 4, Offset: 1, OrderField: "Id", OrderBy: OrderByAsc})
+
+	ids := []int{}
+	for it.Next() {
+		ids = append(ids, it.User().Id)
+		// страницы запрашиваются по мере надобности
+		if len(ids) == 4 && *calls != 1 {
+			t.Errorf("expected 1 call after the first page, got %d", *calls)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(ids) != 34 {
+		t.Fatalf("expected 34 users, got %d: %v", len(ids), ids)
+	}
+	for i, id := range ids {
+		if id != i+1 {
+			t.Fatalf("expected id %d at %d, got %d", i+1, i, id)
+		}
+	}
+	// 34 = 8 * 4 + 2
+	if *calls != 9 {
+		t.Errorf("expected 9 calls, got %d", *calls)
+	}
+	if it.Next() {
+		t.Errorf("expected Next to stay false")
+	}
+}
+
+func TestUsersIteratorError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(SearchServer))
+	defer ts.Close()
+
+	it := (&SearchClient{URL: ts.URL, AccessToken: badToken}).Users(context.Background(), SearchRequest{})
+	if it.Next() {
+		t.Fatalf("expected no users, got %+v", it.User())
+	}
+	if !errors.Is(it.Err(), ErrBadToken) {
+		t.Errorf("expected ErrBadToken, got %v", it.Err())
+	}
+	if it.Next() {
+		t.Errorf("expected Next to stay false after error")
+	}
+}
+
+// SearchClient и настоящий сервер из searchserver дают то же, что тестовый SearchServer
+func TestRealSearchServer(t *testing.T) {
+	users, err := searchserver.LoadDatasetFile("dataset.xml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prod := httptest.NewServer(searchserver.NewWithIndex(searchserver.NewIndex(users), []string{goodToken}))
+	defer prod.Close()
+	double := httptest.NewServer(http.HandlerFunc(SearchServer))
+	defer double.Close()
+
+	requests := []SearchRequest{
+		{Limit: 5, Query: "Stark"},
+		{Limit: 5, Query: "nulla", OrderField: "Id", OrderBy: OrderByDesc},
+		{Limit: 7, Offset: 3, OrderField: "Id", OrderBy: OrderByAsc},
+		{Limit: 25, Query: "zzz"},
+	}
+	for _, req := range requests {
+		var got, expected []User
+		for _, dst := range []struct {
+			url   string
+			users *[]User
+		}{{prod.URL, &got}, {double.URL, &expected}} {
+			it := (&SearchClient{URL: dst.url, AccessToken: goodToken}).Users(context.Background(), req)
+			for it.Next() {
+				*dst.users = append(*dst.users, it.User())
+			}
+			if err := it.Err(); err != nil {
+				t.Fatalf("%+v: unexpected error: %v", req, err)
+			}
+		}
+
+		if len(got) != len(expected) {
+			t.Fatalf("%+v: expected %d users, got %d", req, len(expected), len(got))
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Errorf("%+v: results not match on index %d\nGot: %v\nExpected: %v", req, i, got[i], expected[i])
+			}
+		}
+	}
+
+	_, err = (&SearchClient{URL: prod.URL, AccessToken: badToken}).FindUsers(SearchRequest{})
+	if !errors.Is(err, ErrBadToken) {
+		t.Errorf("expected ErrBadToken, got %v", err)
+	}
+	_, err = (&SearchClient{URL: prod.URL, AccessToken: goodToken}).FindUsers(SearchRequest{OrderField: "About"})
+	if !errors.Is(err, ErrBadOrderField) {
+		t.Errorf("expected ErrBadOrderField, got %v", err)
+	}
+}
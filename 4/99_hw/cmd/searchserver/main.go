@@ -0,0 +1,56 @@
+// searchserver serves dataset.xml for SearchClient:
+//
+//	searchserver -config searchserver.json
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"hw4/searchserver"
+)
+
+func main() {
+	configPath := flag.String("config", "searchserver.json", "config file")
+	addr := flag.String("addr", "", "listen address, overrides the config")
+	flag.Parse()
+
+	cfg, err := searchserver.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *addr != "" {
+		cfg.Addr = *addr
+	}
+
+	srv, err := searchserver.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      srv,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Println("starting server at", cfg.Addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,3 @@
+module hw4
+
+go 1.18
@@ -0,0 +1,59 @@
+package searchserver
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+// User is what the server answers with, the same fields as User of SearchClient
+type User struct {
+	Id     int
+	Name   string
+	Age    int
+	About  string
+	Gender string
+}
+
+type xmlRow struct {
+	ID        int    `xml:"id"`
+	FirstName string `xml:"first_name"`
+	LastName  string `xml:"last_name"`
+	Age       int    `xml:"age"`
+	About     string `xml:"about"`
+	Gender    string `xml:"gender"`
+}
+
+// LoadDataset reads the rows of dataset.xml, Name is first_name + " " + last_name
+func LoadDataset(r io.Reader) ([]User, error) {
+	var root struct {
+		Rows []xmlRow `xml:"row"`
+	}
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("bad dataset: %w", err)
+	}
+
+	users := make([]User, len(root.Rows))
+	for i, row := range root.Rows {
+		users[i] = User{
+			Id:     row.ID,
+			Name:   row.FirstName + " " + row.LastName,
+			Age:    row.Age,
+			About:  row.About,
+			Gender: row.Gender,
+		}
+	}
+	return users, nil
+}
+
+// LoadDatasetFile is LoadDataset for a file
+func LoadDatasetFile(path string) ([]User, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return LoadDataset(file)
+}
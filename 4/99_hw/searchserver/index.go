@@ -0,0 +1,264 @@
+package searchserver
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// order_by values, the same as in SearchClient
+const (
+	OrderByAsc  = -1
+	OrderByAsIs = 0
+	OrderByDesc = 1
+)
+
+// the errors go to SearchErrorResponse.Error as is
+var (
+	ErrBadOrderField = errors.New("ErrorBadOrderField")
+	ErrBadOrderBy    = errors.New("ErrorBadOrderBy")
+	ErrBadLimit      = errors.New("ErrorBadLimit")
+	ErrBadOffset     = errors.New("ErrorBadOffset")
+)
+
+// Query is one search: users whose Name or About contain Query, ordered and paged
+type Query struct {
+	Query string
+	// OrderField is Id, Age or Name, empty means Name
+	OrderField string
+	OrderBy    int
+	Offset     int
+	Limit      int
+}
+
+// gramSize is the longest n-gram of the words index, longer query words are checked on the found words
+const gramSize = 3
+
+// Index keeps the users in memory with a word index over Name and About,
+// an n-gram index over the words for substring queries
+// and the users presorted by every order field
+type Index struct {
+	users []User
+	// postings are the positions of the users by the lower case words of Name and About
+	postings map[string][]int
+	words    []string
+	// grams are the ascending positions in words by every substring of up to gramSize runes
+	grams map[string][]int
+	// orders are the positions of the users sorted by the field, [0] ascending and [1] descending
+	orders map[string][2][]int
+}
+
+var orderFields = map[string]func(a, b *User) int{
+	"Id":   func(a, b *User) int { return a.Id - b.Id },
+	"Age":  func(a, b *User) int { return a.Age - b.Age },
+	"Name": func(a, b *User) int { return strings.Compare(a.Name, b.Name) },
+}
+
+// NewIndex builds the index, users must not change after it
+func NewIndex(users []User) *Index {
+	ix := &Index{
+		users:    users,
+		postings: make(map[string][]int),
+		grams:    make(map[string][]int),
+		orders:   make(map[string][2][]int, len(orderFields)),
+	}
+
+	for i := range users {
+		seen := make(map[string]struct{})
+		for _, w := range append(tokenize(users[i].Name), tokenize(users[i].About)...) {
+			if _, ok := seen[w]; ok {
+				continue
+			}
+			seen[w] = struct{}{}
+			ix.postings[w] = append(ix.postings[w], i)
+		}
+	}
+	for w := range ix.postings {
+		ix.words = append(ix.words, w)
+	}
+	sort.Strings(ix.words)
+	for i, w := range ix.words {
+		for n := 1; n <= gramSize; n++ {
+			for _, g := range grams(w, n) {
+				ix.grams[g] = append(ix.grams[g], i)
+			}
+		}
+	}
+
+	for field, cmp := range orderFields {
+		var orders [2][]int
+		for dir, sign := range []int{1, -1} {
+			order := make([]int, len(users))
+			for i := range order {
+				order[i] = i
+			}
+			// equal values keep the ascending Id order in both directions
+			sort.SliceStable(order, func(i, j int) bool {
+				a, b := &users[order[i]], &users[order[j]]
+				if c := sign * cmp(a, b); c != 0 {
+					return c < 0
+				}
+				return a.Id < b.Id
+			})
+			orders[dir] = order
+		}
+		ix.orders[field] = orders
+	}
+
+	return ix
+}
+
+// Len is the number of the users
+func (ix *Index) Len() int {
+	return len(ix.users)
+}
+
+// Search finds the users, the match is a case sensitive substring of Name or About,
+// an empty query matches everybody
+func (ix *Index) Search(q Query) ([]User, error) {
+	if q.OrderField == "" {
+		q.OrderField = "Name"
+	}
+	orders, ok := ix.orders[q.OrderField]
+	if !ok {
+		return nil, ErrBadOrderField
+	}
+	switch {
+	case q.OrderBy < OrderByAsc || q.OrderBy > OrderByDesc:
+		return nil, ErrBadOrderBy
+	case q.Limit < 0:
+		return nil, ErrBadLimit
+	case q.Offset < 0:
+		return nil, ErrBadOffset
+	}
+
+	var order []int
+	switch q.OrderBy {
+	case OrderByAsc:
+		order = orders[0]
+	case OrderByDesc:
+		order = orders[1]
+	}
+
+	candidates := ix.candidates(q.Query)
+	res := make([]User, 0, q.Limit)
+	skip := q.Offset
+	for i := 0; i < len(ix.users) && len(res) < q.Limit; i++ {
+		pos := i
+		if order != nil {
+			pos = order[i]
+		}
+		if candidates != nil && !candidates[pos] {
+			continue
+		}
+
+		u := &ix.users[pos]
+		if !strings.Contains(u.Name, q.Query) && !strings.Contains(u.About, q.Query) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		res = append(res, *u)
+	}
+
+	return res, nil
+}
+
+// candidates narrows the search down by the words of the query: a user must have
+// for every word of the query a word containing it. nil means no narrowing.
+func (ix *Index) candidates(query string) []bool {
+	var res []bool
+	for _, qw := range tokenize(query) {
+		found := make([]bool, len(ix.users))
+		for _, i := range ix.wordsContaining(qw) {
+			for _, pos := range ix.postings[ix.words[i]] {
+				found[pos] = true
+			}
+		}
+
+		if res == nil {
+			res = found
+			continue
+		}
+		for i := range res {
+			res[i] = res[i] && found[i]
+		}
+	}
+	return res
+}
+
+// wordsContaining returns the positions in words of the words with the substring:
+// the words having all its n-grams, checked when it's longer than an n-gram
+func (ix *Index) wordsContaining(sub string) []int {
+	n := utf8.RuneCountInString(sub)
+	if n > gramSize {
+		n = gramSize
+	}
+
+	var res []int
+	for i, g := range grams(sub, n) {
+		if i == 0 {
+			res = ix.grams[g]
+		} else {
+			res = intersect(res, ix.grams[g])
+		}
+		if len(res) == 0 {
+			return nil
+		}
+	}
+	if utf8.RuneCountInString(sub) <= gramSize {
+		return res
+	}
+
+	checked := make([]int, 0, len(res))
+	for _, i := range res {
+		if strings.Contains(ix.words[i], sub) {
+			checked = append(checked, i)
+		}
+	}
+	return checked
+}
+
+// grams returns the distinct substrings of n runes of the word
+func grams(w string, n int) []string {
+	runes := []rune(w)
+	seen := make(map[string]struct{})
+	var res []string
+	for i := 0; i+n <= len(runes); i++ {
+		g := string(runes[i : i+n])
+		if _, ok := seen[g]; ok {
+			continue
+		}
+		seen[g] = struct{}{}
+		res = append(res, g)
+	}
+	return res
+}
+
+// intersect returns the common elements of two ascending slices
+func intersect(a, b []int) []int {
+	res := make([]int, 0, len(a))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			res = append(res, a[i])
+			i++
+			j++
+		}
+	}
+	return res
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,182 @@
+package searchserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadIndex(t *testing.T) *Index {
+	t.Helper()
+	users, err := LoadDatasetFile("../dataset.xml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return NewIndex(users)
+}
+
+func ids(users []User) []int {
+	res := make([]int, len(users))
+	for i, u := range users {
+		res[i] = u.Id
+	}
+	return res
+}
+
+func TestSearchMatchesLinearScan(t *testing.T) {
+	ix := loadIndex(t)
+	if ix.Len() != 35 {
+		t.Fatalf("expected 35 users, got %d", ix.Len())
+	}
+
+	queries := []string{"", "Stark", "Boyd Wolf", "oyd Wo", "labore.", "Labore", "reprehenderit nulla", " ", "nothing like this", "a", "Ex", "llamc"}
+	for _, query := range queries {
+		expected := []int{}
+		for _, u := range ix.users {
+			if strings.Contains(u.Name, query) || strings.Contains(u.About, query) {
+				expected = append(expected, u.Id)
+			}
+		}
+
+		res, err := ix.Search(Query{Query: query, Limit: 100})
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", query, err)
+		}
+		if !reflect.DeepEqual(ids(res), expected) {
+			t.Errorf("%q: expected %v, got %v", query, expected, ids(res))
+		}
+	}
+}
+
+func TestWordsContaining(t *testing.T) {
+	ix := loadIndex(t)
+
+	for _, sub := range []string{"e", "qu", "sse", "ollit", "llamco", "zzz"} {
+		expected := []int{}
+		for i, w := range ix.words {
+			if strings.Contains(w, sub) {
+				expected = append(expected, i)
+			}
+		}
+		got := append([]int{}, ix.wordsContaining(sub)...)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%q: expected %v, got %v", sub, expected, got)
+		}
+	}
+}
+
+func TestSearchOrder(t *testing.T) {
+	ix := loadIndex(t)
+
+	for _, field := range []string{"Id", "Age", "Name", ""} {
+		for _, orderBy := range []int{OrderByAsc, OrderByDesc} {
+			res, err := ix.Search(Query{OrderField: field, OrderBy: orderBy, Limit: 100})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(res) != ix.Len() {
+				t.Fatalf("expected all the users, got %d", len(res))
+			}
+
+			for i := 1; i < len(res); i++ {
+				a, b := &res[i-1], &res[i]
+				c := orderFields["Name"](a, b)
+				if field != "" {
+					c = orderFields[field](a, b)
+				}
+				// equal values go by Id in both directions
+				if c*-orderBy > 0 || c == 0 && a.Id > b.Id {
+					t.Fatalf("%q %d: not sorted at %d: %+v, %+v", field, orderBy, i, *a, *b)
+				}
+			}
+		}
+	}
+}
+
+func TestSearchPaging(t *testing.T) {
+	ix := loadIndex(t)
+
+	res, _ := ix.Search(Query{Query: "e", OrderField: "Id", OrderBy: OrderByDesc, Offset: 2, Limit: 3})
+	if !reflect.DeepEqual(ids(res), []int{32, 31, 30}) {
+		t.Errorf("expected [32 31 30], got %v", ids(res))
+	}
+
+	res, _ = ix.Search(Query{Offset: 100, Limit: 10})
+	if len(res) != 0 {
+		t.Errorf("expected nothing after the end, got %v", ids(res))
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	ix := loadIndex(t)
+	cases := []struct {
+		q   Query
+		err error
+	}{
+		{Query{OrderField: "About"}, ErrBadOrderField},
+		{Query{OrderBy: 2}, ErrBadOrderBy},
+		{Query{Limit: -1}, ErrBadLimit},
+		{Query{Offset: -1}, ErrBadOffset},
+	}
+	for _, tc := range cases {
+		if _, err := ix.Search(tc.q); err != tc.err {
+			t.Errorf("%+v: expected %v, got %v", tc.q, tc.err, err)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	srv := NewWithIndex(loadIndex(t), []string{"first", "second"})
+	cases := []struct {
+		method, url, token string
+		status             int
+		body               string
+	}{
+		{"GET", "/?limit=1&query=Stark", "second", http.StatusOK, `[{"Id":5,"Name":"Beulah Stark"`},
+		{"GET", "/?limit=1", "", http.StatusUnauthorized, ""},
+		{"GET", "/?limit=1", "firs", http.StatusUnauthorized, ""},
+		{"POST", "/?limit=1", "first", http.StatusMethodNotAllowed, ""},
+		{"GET", "/?limit=x", "first", http.StatusBadRequest, `{"Error":"ErrorBadLimit"}`},
+		{"GET", "/?order_field=Gender", "first", http.StatusBadRequest, `{"Error":"ErrorBadOrderField"}`},
+		{"GET", "/", "first", http.StatusOK, `[]`},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.url, nil)
+		req.Header.Set("AccessToken", tc.token)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.url, tc.status, w.Code)
+		}
+		if !strings.HasPrefix(w.Body.String(), tc.body) {
+			t.Errorf("%s %s: expected %s, got %s", tc.method, tc.url, tc.body, w.Body.String())
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig("../searchserver.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Dataset != "dataset.xml" || len(cfg.Tokens) == 0 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	cfg.Dataset = "../" + cfg.Dataset
+	if _, err := New(cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := New(&Config{Dataset: cfg.Dataset}); err == nil {
+		t.Errorf("expected error without tokens")
+	}
+
+	var body []User
+	if err := json.Unmarshal([]byte(`[{"Id":1}]`), &body); err != nil || body[0].Id != 1 {
+		t.Errorf("expected User to decode from the answer, got %v %v", body, err)
+	}
+}
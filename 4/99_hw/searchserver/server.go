@@ -0,0 +1,141 @@
+package searchserver
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// Config of the server, usually read with LoadConfig from json:
+//
+//	{"addr": ":8080", "dataset": "dataset.xml", "tokens": ["secret"]}
+type Config struct {
+	Addr    string `json:"addr"`
+	Dataset string `json:"dataset"`
+	// Tokens are the accepted AccessToken headers
+	Tokens []string `json:"tokens"`
+}
+
+// LoadConfig reads the config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("bad config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// SearchErrorResponse is the body of 400, the same as in SearchClient
+type SearchErrorResponse struct {
+	Error string
+}
+
+// Server answers the requests of SearchClient:
+// GET ?query=&order_field=&order_by=&limit=&offset= with the AccessToken header
+type Server struct {
+	index  *Index
+	tokens [][]byte
+}
+
+// New loads the dataset of the config once and builds the index
+func New(cfg *Config) (*Server, error) {
+	if len(cfg.Tokens) == 0 {
+		return nil, fmt.Errorf("no access tokens in the config")
+	}
+
+	users, err := LoadDatasetFile(cfg.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithIndex(NewIndex(users), cfg.Tokens), nil
+}
+
+// NewWithIndex serves an already built index
+func NewWithIndex(index *Index, tokens []string) *Server {
+	srv := &Server{index: index}
+	for _, t := range tokens {
+		srv.tokens = append(srv.tokens, []byte(t))
+	}
+	return srv
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !srv.authorized(r.Header.Get("AccessToken")) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	q, err := parseQuery(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	users, err := srv.index.Search(q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+// authorized compares with every token in constant time not to leak the prefix
+func (srv *Server) authorized(token string) bool {
+	ok := 0
+	for _, t := range srv.tokens {
+		ok |= subtle.ConstantTimeCompare(t, []byte(token))
+	}
+	return ok == 1
+}
+
+func parseQuery(r *http.Request) (Query, error) {
+	q := Query{
+		Query:      r.FormValue("query"),
+		OrderField: r.FormValue("order_field"),
+	}
+
+	params := []struct {
+		name string
+		dst  *int
+		err  error
+	}{
+		{"order_by", &q.OrderBy, ErrBadOrderBy},
+		{"limit", &q.Limit, ErrBadLimit},
+		{"offset", &q.Offset, ErrBadOffset},
+	}
+	for _, p := range params {
+		v := r.FormValue(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return q, p.err
+		}
+		*p.dst = n
+	}
+
+	return q, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(SearchErrorResponse{Error: err.Error()})
+}
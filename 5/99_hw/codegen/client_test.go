@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"codegen/apiclient"
+)
+
+func TestClient(t *testing.T) {
+	ts := httptest.NewServer(NewMyApi())
+	defer ts.Close()
+	ctx := context.Background()
+
+	c := apiclient.NewMyApiClient(ts.URL, "100500")
+	user, err := c.Profile(ctx, apiclient.ProfileParams{Login: "rvasily"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &apiclient.User{ID: 42, Login: "rvasily", FullName: "Vasily Romanov", Status: 20}
+	if !reflect.DeepEqual(user, expected) {
+		t.Errorf("results not match\nGot: %#v\nExpected: %#v", user, expected)
+	}
+
+	// status не передан - сервер подставил default
+	created, err := c.Create(ctx, apiclient.CreateParams{Login: "new_moderator", Name: "Ivan", Age: 30})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.ID != 43 {
+		t.Errorf("expected id 43, got %d", created.ID)
+	}
+
+	cases := []struct {
+		call func() error
+		err  error
+		msg  string
+	}{
+		{func() error { _, err := c.Profile(ctx, apiclient.ProfileParams{}); return err }, apiclient.ErrBadRequest, "login must me not empty"},
+		{func() error { _, err := c.Profile(ctx, apiclient.ProfileParams{Login: "nobody"}); return err }, apiclient.ErrNotFound, "user not exist"},
+		{func() error { _, err := c.Profile(ctx, apiclient.ProfileParams{Login: "bad_user"}); return err }, apiclient.ErrInternal, "bad user"},
+		{func() error {
+			_, err := c.Create(ctx, apiclient.CreateParams{Login: "new_moderator"})
+			return err
+		}, apiclient.ErrConflict, "user new_moderator exist"},
+		{func() error {
+			_, err := c.Create(ctx, apiclient.CreateParams{Login: "new_moderator2", Status: "root"})
+			return err
+		}, apiclient.ErrBadRequest, "status must be one of [user, moderator, admin]"},
+		{func() error {
+			_, err := apiclient.NewMyApiClient(ts.URL, "").Create(ctx, apiclient.CreateParams{Login: "new_moderator2"})
+			return err
+		}, apiclient.ErrUnauthorized, "unauthorized"},
+	}
+	for idx, item := range cases {
+		err := item.call()
+		var apiErr apiclient.ApiError
+		if !errors.Is(err, item.err) || !errors.As(err, &apiErr) || err.Error() != item.msg {
+			t.Errorf("[%d] expected %v with %q, got %v", idx, item.err, item.msg, err)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	data, err := os.ReadFile("docs/OtherApi.openapi.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc struct {
+		Paths map[string]map[string]struct {
+			RequestBody struct {
+				Content map[string]struct {
+					Schema struct {
+						Properties map[string]map[string]interface{} `json:"properties"`
+						Required   []string                          `json:"required"`
+					} `json:"schema"`
+				} `json:"content"`
+			} `json:"requestBody"`
+			Security []map[string][]string `json:"security"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	create := doc.Paths["/user/create"]["post"]
+	if len(create.Security) != 1 {
+		t.Errorf("create must require auth: %v", create.Security)
+	}
+	form := create.RequestBody.Content["application/x-www-form-urlencoded"].Schema
+	expected := map[string]map[string]interface{}{
+		"username":     {"type": "string", "minLength": 3.0},
+		"account_name": {"type": "string"},
+		"class":        {"type": "string", "enum": []interface{}{"warrior", "sorcerer", "rouge"}, "default": "warrior"},
+		"level":        {"type": "integer", "minimum": 1.0, "maximum": 50.0},
+	}
+	if !reflect.DeepEqual(form.Properties, expected) {
+		t.Errorf("results not match\nGot: %#v\nExpected: %#v", form.Properties, expected)
+	}
+	if !reflect.DeepEqual(form.Required, []string{"username"}) {
+		t.Errorf("expected username to be required, got %v", form.Required)
+	}
+}
@@ -0,0 +1,5 @@
+package main
+
+// api_handlers.go, описания OpenAPI в docs и клиент в apiclient собираются кодогенератором
+// из handlers_gen по меткам apigen:api в api.go
+//go:generate go run ./handlers_gen -openapi docs -client apiclient api.go api_handlers.go
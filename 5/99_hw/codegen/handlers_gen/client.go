@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+)
+
+// apiClient - то, из чего собирается пакет клиента
+type apiClient struct {
+	*apiFile
+	Package string
+	// Types - объявления типов параметров и результатов, скопированные из файла
+	Types []string
+}
+
+// writeClient пишет пакет клиента в dir/client.go, имя пакета - имя папки
+func writeClient(file *apiFile, dir string) error {
+	client := &apiClient{apiFile: file, Package: filepath.Base(dir)}
+	if !token.IsIdentifier(client.Package) {
+		return fmt.Errorf("client package name %q is not an identifier", client.Package)
+	}
+
+	types, err := clientTypes(file)
+	if err != nil {
+		return err
+	}
+	client.Types = types
+
+	var buf bytes.Buffer
+	if err := clientTpl.Execute(&buf, client); err != nil {
+		return err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated client is invalid: %v\n%s", err, buf.Bytes())
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "client.go"), code, 0644)
+}
+
+// clientTypes - типы параметров и результатов вместе со всеми типами, на которые они ссылаются,
+// в порядке объявления в файле
+func clientTypes(file *apiFile) ([]string, error) {
+	need := make(map[string]bool)
+	var visit func(e ast.Expr) error
+	visit = func(e ast.Expr) error {
+		var err error
+		ast.Inspect(e, func(n ast.Node) bool {
+			if err != nil {
+				return false
+			}
+			switch t := n.(type) {
+			case *ast.Field:
+				// имена полей - не типы
+				err = visit(t.Type)
+				return false
+			case *ast.SelectorExpr:
+				err = fmt.Errorf("type %s from another package is not supported in the client", exprString(t))
+			case *ast.Ident:
+				ts, ok := file.types[t.Name]
+				if !ok || need[t.Name] {
+					return true
+				}
+				need[t.Name] = true
+				err = visit(ts.Type)
+			}
+			return err == nil
+		})
+		return err
+	}
+
+	for _, p := range file.Params {
+		if err := visit(ast.NewIdent(p.Type)); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range file.Recvs {
+		for _, m := range r.Methods {
+			if err := visit(m.result); err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", r.Name, m.Name, err)
+			}
+		}
+	}
+
+	var decls []string
+	for _, ts := range sortedTypes(file, need) {
+		var buf bytes.Buffer
+		decl := &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{ts}}
+		if err := printer.Fprint(&buf, file.fset, decl); err != nil {
+			return nil, err
+		}
+		decls = append(decls, buf.String())
+	}
+	return decls, nil
+}
+
+func sortedTypes(file *apiFile, need map[string]bool) []*ast.TypeSpec {
+	var specs []*ast.TypeSpec
+	for name := range need {
+		specs = append(specs, file.types[name])
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Pos() < specs[j].Pos() })
+	return specs
+}
+
+// HTTPMethod - каким методом ходит клиент, если в метке метод не указан
+func (m *apiMethod) HTTPMethod() string {
+	if m.Method == "" {
+		return "GET"
+	}
+	return m.Method
+}
+
+var clientTpl = template.Must(template.New("client").Parse(`// Code generated by apigen from {{.Source}}; DO NOT EDIT.
+
+// Package {{.Package}} - клиент к api из {{.Source}}
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+{{- if .NeedStrconv}}
+	"strconv"
+{{- end}}
+	"strings"
+)
+
+// ApiError - ошибка, которую вернул сервер, со статусом ответа.
+// errors.Is(err, ErrNotFound) и подобные сравнивают только статус
+type ApiError struct {
+	HTTPStatus int
+	Err        error
+}
+
+func (ae ApiError) Error() string {
+	return ae.Err.Error()
+}
+
+func (ae ApiError) Is(target error) bool {
+	t, ok := target.(ApiError)
+	return ok && t.HTTPStatus == ae.HTTPStatus
+}
+
+var (
+	ErrBadRequest   = ApiError{http.StatusBadRequest, errors.New("bad request")}
+	ErrUnauthorized = ApiError{http.StatusForbidden, errors.New("unauthorized")}
+	ErrNotFound     = ApiError{http.StatusNotFound, errors.New("not found")}
+	ErrBadMethod    = ApiError{http.StatusNotAcceptable, errors.New("bad method")}
+	ErrConflict     = ApiError{http.StatusConflict, errors.New("conflict")}
+	ErrInternal     = ApiError{http.StatusInternalServerError, errors.New("internal error")}
+)
+{{range .Types}}
+{{.}}
+{{end}}
+{{- range .Params}}
+// values - параметры запроса, пустые не передаются и сервер подставит default
+func (in {{.Type}}) values() url.Values {
+	v := url.Values{}
+{{- range .Fields}}
+{{- if eq .Type "int"}}
+	if in.{{.Field}} != 0 {
+		v.Set({{printf "%q" .Name}}, strconv.Itoa(in.{{.Field}}))
+	}
+{{- else}}
+	if in.{{.Field}} != "" {
+		v.Set({{printf "%q" .Name}}, in.{{.Field}})
+	}
+{{- end}}
+{{- end}}
+	return v
+}
+{{end}}
+// call делает запрос и разбирает ответ вида {"error": "", "response": ...} в out
+func call(ctx context.Context, client *http.Client, method, endpoint, auth string, params url.Values, out interface{}) error {
+	var body io.Reader
+	if method == http.MethodGet {
+		endpoint += "?" + params.Encode()
+	} else {
+		body = strings.NewReader(params.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if auth != "" {
+		req.Header.Set("X-Auth", auth)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Error    string          ` + "`json:\"error\"`" + `
+		Response json.RawMessage ` + "`json:\"response\"`" + `
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return ApiError{resp.StatusCode, errors.New(http.StatusText(resp.StatusCode))}
+		}
+		return fmt.Errorf("cannot decode response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ApiError{resp.StatusCode, errors.New(envelope.Error)}
+	}
+	if err := json.Unmarshal(envelope.Response, out); err != nil {
+		return fmt.Errorf("cannot decode response: %w", err)
+	}
+	return nil
+}
+{{range .Recvs}}{{$recv := .Name}}
+// {{$recv}}Client - клиент к {{$recv}}
+type {{$recv}}Client struct {
+	// URL - адрес сервера без пути, например http://127.0.0.1:8080
+	URL string
+	// Auth уходит в X-Auth у методов, которым нужна авторизация
+	Auth       string
+	HTTPClient *http.Client
+}
+
+func New{{$recv}}Client(baseURL, auth string) *{{$recv}}Client {
+	return &{{$recv}}Client{URL: strings.TrimRight(baseURL, "/"), Auth: auth}
+}
+{{range .Methods}}
+func (c *{{$recv}}Client) {{.Name}}(ctx context.Context, in {{.Params.Type}}) ({{.Result}}, error) {
+	var res {{.Result}}
+	err := call(ctx, c.HTTPClient, {{printf "%q" .HTTPMethod}}, c.URL+{{printf "%q" .URL}}, {{if .Auth}}c.Auth{{else}}""{{end}}, in.values(), &res)
+	return res, err
+}
+{{end}}{{end}}`))
@@ -1,3 +1,500 @@
 package main
 
-// код писать тут
\ No newline at end of file
+// go build handlers_gen/* && ./codegen api.go api_handlers.go
+// -openapi и -client дополнительно пишут описания OpenAPI и пакет с клиентом:
+// ./codegen -openapi docs -client apiclient api.go api_handlers.go
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const apiMark = "// apigen:api "
+
+// apiSpec - json из метки apigen:api
+type apiSpec struct {
+	URL    string `json:"url"`
+	Auth   bool   `json:"auth"`
+	Method string `json:"method"`
+}
+
+// apiParam - поле структуры с параметрами и правила из тега apivalidator
+type apiParam struct {
+	Field string
+	Type  string // int или string
+	Name  string // имя параметра в запросе
+
+	Required bool
+	Default  string
+	Enum     []string
+	// Min и Max действуют, только если заданы HasMin и HasMax
+	Min, Max       int
+	HasMin, HasMax bool
+}
+
+type apiParams struct {
+	Type   string
+	Fields []*apiParam
+}
+
+// apiMethod - помеченный метод: Recv.Name(ctx, Params) (Result, error)
+type apiMethod struct {
+	Recv   string
+	Name   string
+	Params *apiParams
+	Result string
+	apiSpec
+
+	result ast.Expr
+}
+
+// apiRecv - структура и её методы в порядке объявления, из них собирается ServeHTTP
+type apiRecv struct {
+	Name    string
+	Methods []*apiMethod
+}
+
+type apiFile struct {
+	Package string
+	Source  string
+	Recvs   []*apiRecv
+	// Params - все структуры с параметрами, по одной функции разбора на каждую
+	Params []*apiParams
+
+	// types - все типы файла, для описания результатов в OpenAPI и клиенте
+	types map[string]*ast.TypeSpec
+	fset  *token.FileSet
+}
+
+func main() {
+	openapiDir := flag.String("openapi", "", "directory to write an OpenAPI 3 document for each api struct to")
+	clientDir := flag.String("client", "", "directory of the generated client package")
+	flag.Parse()
+	if flag.NArg() != 2 {
+		log.Fatalf("usage: %s [-openapi dir] [-client dir] api.go api_handlers.go", os.Args[0])
+	}
+
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, flag.Arg(0), nil, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := collect(fset, node)
+	if err != nil {
+		log.Fatal(err)
+	}
+	file.Source = filepath.Base(flag.Arg(0))
+
+	code, err := generate(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(flag.Arg(1), code, 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	if *openapiDir != "" {
+		if err := writeOpenAPI(file, *openapiDir); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *clientDir != "" {
+		if err := writeClient(file, *clientDir); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// collect - первый проход: структуры и помеченные методы
+func collect(fset *token.FileSet, node *ast.File) (*apiFile, error) {
+	structs := make(map[string]*ast.StructType)
+	types := make(map[string]*ast.TypeSpec)
+	for _, decl := range node.Decls {
+		g, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range g.Specs {
+			currType, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			types[currType.Name.Name] = currType
+			if currStruct, ok := currType.Type.(*ast.StructType); ok {
+				structs[currType.Name.Name] = currStruct
+			}
+		}
+	}
+
+	file := &apiFile{Package: node.Name.Name, types: types, fset: fset}
+	recvs := make(map[string]*apiRecv)
+	params := make(map[string]*apiParams)
+
+	for _, decl := range node.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || fn.Doc == nil {
+			continue
+		}
+
+		var spec *apiSpec
+		for _, comment := range fn.Doc.List {
+			if !strings.HasPrefix(comment.Text, apiMark) {
+				continue
+			}
+			spec = &apiSpec{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(comment.Text, apiMark)), spec); err != nil {
+				return nil, fmt.Errorf("%s: bad apigen mark: %v", fset.Position(comment.Pos()), err)
+			}
+		}
+		if spec == nil {
+			continue
+		}
+
+		m, err := method(fn, *spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fset.Position(fn.Pos()), err)
+		}
+
+		p, ok := params[m.Params.Type]
+		if !ok {
+			st, ok := structs[m.Params.Type]
+			if !ok {
+				return nil, fmt.Errorf("%s: params struct %s is not in the file", fset.Position(fn.Pos()), m.Params.Type)
+			}
+			p, err = paramsOf(m.Params.Type, st)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", fset.Position(st.Pos()), err)
+			}
+			params[p.Type] = p
+			file.Params = append(file.Params, p)
+		}
+		m.Params = p
+
+		r, ok := recvs[m.Recv]
+		if !ok {
+			r = &apiRecv{Name: m.Recv}
+			recvs[m.Recv] = r
+			file.Recvs = append(file.Recvs, r)
+		}
+		for _, other := range r.Methods {
+			if other.URL == m.URL {
+				return nil, fmt.Errorf("%s: %s and %s both serve %s", fset.Position(fn.Pos()), other.Name, m.Name, m.URL)
+			}
+		}
+		r.Methods = append(r.Methods, m)
+	}
+
+	return file, nil
+}
+
+// method проверяет сигнатуру: (ctx context.Context, in Params) (*Result, error)
+func method(fn *ast.FuncDecl, spec apiSpec) (*apiMethod, error) {
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	recvName, ok := recv.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("unsupported receiver of %s", fn.Name.Name)
+	}
+
+	in := fn.Type.Params.List
+	if len(in) != 2 || len(in[1].Names) > 1 {
+		return nil, fmt.Errorf("%s must take (ctx, params)", fn.Name.Name)
+	}
+	paramsType, ok := in[1].Type.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("params of %s must be a struct from this file", fn.Name.Name)
+	}
+
+	out := fn.Type.Results
+	if out == nil || len(out.List) != 2 {
+		return nil, fmt.Errorf("%s must return (result, error)", fn.Name.Name)
+	}
+
+	return &apiMethod{
+		Recv:    recvName.Name,
+		Name:    fn.Name.Name,
+		Params:  &apiParams{Type: paramsType.Name},
+		Result:  exprString(out.List[0].Type),
+		apiSpec: spec,
+		result:  out.List[0].Type,
+	}, nil
+}
+
+func exprString(e ast.Expr) string {
+	var buf bytes.Buffer
+	format.Node(&buf, token.NewFileSet(), e)
+	return buf.String()
+}
+
+// paramsOf разбирает теги apivalidator полей структуры
+func paramsOf(name string, st *ast.StructType) (*apiParams, error) {
+	p := &apiParams{Type: name}
+	for _, field := range st.Fields.List {
+		ident, ok := field.Type.(*ast.Ident)
+		if !ok || (ident.Name != "int" && ident.Name != "string") {
+			return nil, fmt.Errorf("%s: only int and string fields are supported", name)
+		}
+
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			tag = reflect.StructTag(field.Tag.Value[1 : len(field.Tag.Value)-1])
+		}
+
+		for _, fieldName := range field.Names {
+			param, err := paramOf(fieldName.Name, ident.Name, tag.Get("apivalidator"))
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", name, fieldName.Name, err)
+			}
+			p.Fields = append(p.Fields, param)
+		}
+	}
+	return p, nil
+}
+
+func paramOf(field, typ, tag string) (*apiParam, error) {
+	p := &apiParam{Field: field, Type: typ, Name: strings.ToLower(field)}
+	if tag == "" {
+		return p, nil
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			p.Required = true
+		case "paramname":
+			p.Name = value
+		case "default":
+			p.Default = value
+		case "enum":
+			p.Enum = strings.Split(value, "|")
+		case "min", "max":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be int, got %q", key, value)
+			}
+			if key == "min" {
+				p.Min, p.HasMin = n, true
+			} else {
+				p.Max, p.HasMax = n, true
+			}
+		default:
+			return nil, fmt.Errorf("unknown apivalidator rule %q", key)
+		}
+	}
+
+	// default и enum подставляются в код как значения поля, они должны подходить по типу
+	if typ == "int" {
+		for _, v := range append(append([]string(nil), p.Enum...), p.Default) {
+			if _, err := strconv.Atoi(v); v != "" && err != nil {
+				return nil, fmt.Errorf("%q is not int", v)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Zero - пустое значение поля
+func (p *apiParam) Zero() string {
+	if p.Type == "int" {
+		return "0"
+	}
+	return `""`
+}
+
+// Literal - значение из тега в виде go-кода
+func (p *apiParam) Literal(v string) string {
+	if p.Type == "int" {
+		return v
+	}
+	return strconv.Quote(v)
+}
+
+// Length - то, что сравнивается с min и max: само число или длина строки
+func (p *apiParam) Length() string {
+	if p.Type == "int" {
+		return "params." + p.Field
+	}
+	return "len(params." + p.Field + ")"
+}
+
+// LengthName - как это называется в ошибке
+func (p *apiParam) LengthName() string {
+	if p.Type == "int" {
+		return p.Name
+	}
+	return p.Name + " len"
+}
+
+func (p *apiParam) EnumText() string {
+	return "[" + strings.Join(p.Enum, ", ") + "]"
+}
+
+func (f *apiFile) NeedStrconv() bool {
+	for _, p := range f.Params {
+		for _, field := range p.Fields {
+			if field.Type == "int" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// generate - второй проход: код по собранному
+func generate(file *apiFile) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := handlersTpl.Execute(&buf, file); err != nil {
+		return nil, err
+	}
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code is invalid: %v\n%s", err, buf.Bytes())
+	}
+	return code, nil
+}
+
+var handlersTpl = template.Must(template.New("handlers").Parse(`// Code generated by apigen from {{.Source}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+{{- if .NeedStrconv}}
+	"strconv"
+{{- end}}
+)
+
+// apiResponse - ответ любого метода: ошибка или результат
+type apiResponse struct {
+	Error    string      ` + "`json:\"error\"`" + `
+	Response interface{} ` + "`json:\"response,omitempty\"`" + `
+}
+
+func apiWrite(w http.ResponseWriter, status int, resp apiResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// apiWriteError берёт статус из ApiError, у остальных ошибок - 500
+func apiWriteError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr ApiError
+	if errors.As(err, &apiErr) {
+		status = apiErr.HTTPStatus
+	}
+	apiWrite(w, status, apiResponse{Error: err.Error()})
+}
+
+func apiBadRequest(msg string) error {
+	return ApiError{http.StatusBadRequest, errors.New(msg)}
+}
+{{range .Recvs}}{{$recv := .Name}}
+func (srv *{{$recv}}) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+{{- range .Methods}}
+	case {{printf "%q" .URL}}:
+		srv.handler{{.Name}}(w, r)
+{{- end}}
+	default:
+		apiWriteError(w, ApiError{http.StatusNotFound, errors.New("unknown method")})
+	}
+}
+{{range .Methods}}
+func (srv *{{$recv}}) handler{{.Name}}(w http.ResponseWriter, r *http.Request) {
+{{- if .Method}}
+	if r.Method != {{printf "%q" .Method}} {
+		apiWriteError(w, ApiError{http.StatusNotAcceptable, errors.New("bad method")})
+		return
+	}
+{{- end}}
+{{- if .Auth}}
+	if r.Header.Get("X-Auth") != "100500" {
+		apiWriteError(w, ApiError{http.StatusForbidden, errors.New("unauthorized")})
+		return
+	}
+{{- end}}
+{{- if or .Method .Auth}}
+{{end}}
+	params, err := apiParams{{.Params.Type}}(r)
+	if err != nil {
+		apiWriteError(w, err)
+		return
+	}
+
+	res, err := srv.{{.Name}}(r.Context(), params)
+	if err != nil {
+		apiWriteError(w, err)
+		return
+	}
+	apiWrite(w, http.StatusOK, apiResponse{Response: res})
+}
+{{end}}{{end}}
+{{- range .Params}}
+// apiParams{{.Type}} заполняет {{.Type}} из запроса и проверяет по тегам apivalidator
+func apiParams{{.Type}}(r *http.Request) ({{.Type}}, error) {
+	var params {{.Type}}
+{{range .Fields}}
+	// {{.Field}}
+{{- if eq .Type "int"}}
+	if raw := r.FormValue({{printf "%q" .Name}}); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return params, apiBadRequest({{printf "%q" (print .Name " must be int")}})
+		}
+		params.{{.Field}} = v
+	}
+{{- else}}
+	params.{{.Field}} = r.FormValue({{printf "%q" .Name}})
+{{- end}}
+{{- if .Default}}
+	if params.{{.Field}} == {{.Zero}} {
+		params.{{.Field}} = {{.Literal .Default}}
+	}
+{{- end}}
+{{- if .Required}}
+	if params.{{.Field}} == {{.Zero}} {
+		return params, apiBadRequest({{printf "%q" (print .Name " must me not empty")}})
+	}
+{{- end}}
+{{- if .Enum}}{{$p := .}}
+	switch params.{{.Field}} {
+	case {{range $i, $v := .Enum}}{{if $i}}, {{end}}{{$p.Literal $v}}{{end}}:
+	default:
+		return params, apiBadRequest({{printf "%q" (print .Name " must be one of " .EnumText)}})
+	}
+{{- end}}
+{{- if .HasMin}}
+	if {{.Length}} < {{.Min}} {
+		return params, apiBadRequest({{printf "%q" (print .LengthName " must be >= " .Min)}})
+	}
+{{- end}}
+{{- if .HasMax}}
+	if {{.Length}} > {{.Max}} {
+		return params, apiBadRequest({{printf "%q" (print .LengthName " must be <= " .Max)}})
+	}
+{{- end}}
+{{end}}
+	return params, nil
+}
+{{end}}`))
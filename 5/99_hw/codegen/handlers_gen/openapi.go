@@ -0,0 +1,373 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// описание OpenAPI 3, только то, что нужно для apigen
+
+type openAPI struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       openAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type components struct {
+	Schemas         map[string]*schema         `json:"schemas"`
+	SecuritySchemes map[string]*securityScheme `json:"securitySchemes,omitempty"`
+}
+
+type securityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in"`
+	Name string `json:"name"`
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Parameters  []*parameter          `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Responses   map[string]*response  `json:"responses"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+const (
+	formContent  = "application/x-www-form-urlencoded"
+	jsonContent  = "application/json"
+	authScheme   = "auth"
+	errorSchema  = "Error"
+	schemaPrefix = "#/components/schemas/"
+)
+
+// writeOpenAPI пишет по документу на каждую структуру с api: у разных структур могут совпадать url
+func writeOpenAPI(file *apiFile, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, r := range file.Recvs {
+		doc, err := openAPIDoc(file, r)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, r.Name+".openapi.json"), append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func openAPIDoc(file *apiFile, r *apiRecv) (*openAPI, error) {
+	doc := &openAPI{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: r.Name, Version: "1.0.0"},
+		Paths:   make(map[string]map[string]*operation),
+		Components: components{Schemas: map[string]*schema{
+			errorSchema: {
+				Type:       "object",
+				Properties: map[string]*schema{"error": {Type: "string"}},
+				Required:   []string{"error"},
+			},
+		}},
+	}
+	schemas := &schemaBuilder{file: file, defs: doc.Components.Schemas}
+
+	for _, m := range r.Methods {
+		result, err := schemas.of(m.result)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", r.Name, m.Name, err)
+		}
+
+		// без метода в метке годится любой, описываем GET и POST
+		methods := []string{m.Method}
+		if m.Method == "" {
+			methods = []string{"GET", "POST"}
+		}
+
+		ops := make(map[string]*operation)
+		for _, method := range methods {
+			op := &operation{
+				OperationID: m.Name,
+				Responses:   responses(m, result),
+			}
+			if len(methods) > 1 && method != methods[0] {
+				op.OperationID = m.Name + method[:1] + strings.ToLower(method[1:])
+			}
+
+			if method == "GET" {
+				for _, p := range m.Params.Fields {
+					op.Parameters = append(op.Parameters, &parameter{Name: p.Name, In: "query", Required: p.Required, Schema: paramSchema(p)})
+				}
+			} else {
+				form := &schema{Type: "object", Properties: make(map[string]*schema)}
+				for _, p := range m.Params.Fields {
+					form.Properties[p.Name] = paramSchema(p)
+					if p.Required {
+						form.Required = append(form.Required, p.Name)
+					}
+				}
+				op.RequestBody = &requestBody{Required: len(form.Required) != 0, Content: map[string]mediaType{formContent: {Schema: form}}}
+			}
+
+			if m.Auth {
+				op.Security = []map[string][]string{{authScheme: {}}}
+				doc.Components.SecuritySchemes = map[string]*securityScheme{
+					authScheme: {Type: "apiKey", In: "header", Name: "X-Auth"},
+				}
+			}
+			ops[strings.ToLower(method)] = op
+		}
+		doc.Paths[m.URL] = ops
+	}
+
+	return doc, nil
+}
+
+// responses - ответы, которые может дать сгенерированный обработчик
+func responses(m *apiMethod, result *schema) map[string]*response {
+	errorResponse := func(description string) *response {
+		return &response{
+			Description: description,
+			Content:     map[string]mediaType{jsonContent: {Schema: &schema{Ref: schemaPrefix + errorSchema}}},
+		}
+	}
+
+	resp := map[string]*response{
+		"200": {
+			Description: "ok",
+			Content: map[string]mediaType{jsonContent: {Schema: &schema{
+				Type: "object",
+				Properties: map[string]*schema{
+					"error":    {Type: "string"},
+					"response": result,
+				},
+				Required: []string{"error", "response"},
+			}}},
+		},
+		"default": errorResponse("ApiError from the method with its status, any other error - 500"),
+	}
+	if len(m.Params.Fields) != 0 {
+		resp["400"] = errorResponse("invalid params")
+	}
+	if m.Auth {
+		resp["403"] = errorResponse("unauthorized")
+	}
+	if m.Method != "" {
+		resp["406"] = errorResponse("bad method")
+	}
+	return resp
+}
+
+// paramSchema переводит правила apivalidator в ограничения схемы
+func paramSchema(p *apiParam) *schema {
+	s := &schema{Type: "string"}
+	if p.Type == "int" {
+		s.Type = "integer"
+	}
+
+	value := func(v string) interface{} {
+		if p.Type == "int" {
+			n, _ := strconv.Atoi(v)
+			return n
+		}
+		return v
+	}
+	for _, v := range p.Enum {
+		s.Enum = append(s.Enum, value(v))
+	}
+	if p.Default != "" {
+		s.Default = value(p.Default)
+	}
+
+	min, max := p.Min, p.Max
+	switch {
+	case p.Type == "int":
+		if p.HasMin {
+			s.Minimum = &min
+		}
+		if p.HasMax {
+			s.Maximum = &max
+		}
+	default:
+		// required у строки - это непустое значение
+		if p.HasMin {
+			s.MinLength = &min
+		} else if p.Required {
+			one := 1
+			s.MinLength = &one
+		}
+		if p.HasMax {
+			s.MaxLength = &max
+		}
+	}
+	return s
+}
+
+// schemaBuilder описывает типы результатов, структуры из файла попадают в components
+type schemaBuilder struct {
+	file *apiFile
+	defs map[string]*schema
+}
+
+func (b *schemaBuilder) of(e ast.Expr) (*schema, error) {
+	switch t := e.(type) {
+	case *ast.StarExpr:
+		return b.of(t.X)
+	case *ast.ArrayType:
+		items, err := b.of(t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "array", Items: items}, nil
+	case *ast.MapType:
+		if key, ok := t.Key.(*ast.Ident); !ok || key.Name != "string" {
+			return nil, fmt.Errorf("map keys must be strings in %s", exprString(e))
+		}
+		values, err := b.of(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "object", AdditionalProperties: values}, nil
+	case *ast.InterfaceType:
+		return &schema{}, nil
+	case *ast.StructType:
+		return b.object(t)
+	case *ast.Ident:
+		if s, ok := basicSchema(t.Name); ok {
+			return s, nil
+		}
+		ts, ok := b.file.types[t.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %s", t.Name)
+		}
+		if _, ok := b.defs[t.Name]; !ok {
+			// заглушка до разбора полей - на случай типа, который ссылается сам на себя
+			b.defs[t.Name] = &schema{}
+			s, err := b.of(ts.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", t.Name, err)
+			}
+			*b.defs[t.Name] = *s
+		}
+		return &schema{Ref: schemaPrefix + t.Name}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", exprString(e))
+}
+
+func (b *schemaBuilder) object(st *ast.StructType) (*schema, error) {
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			return nil, fmt.Errorf("embedded %s is not supported", exprString(field.Type))
+		}
+
+		for _, name := range field.Names {
+			key, omitempty, ok := jsonName(name.Name, field.Tag)
+			if !ok {
+				continue
+			}
+			fs, err := b.of(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			s.Properties[key] = fs
+			if !omitempty {
+				s.Required = append(s.Required, key)
+			}
+		}
+	}
+	return s, nil
+}
+
+// jsonName - ключ поля в json, ok = false для неэкспортируемых и json:"-"
+func jsonName(field string, tag *ast.BasicLit) (name string, omitempty, ok bool) {
+	if !ast.IsExported(field) {
+		return "", false, false
+	}
+	name = field
+	if tag == nil {
+		return name, false, true
+	}
+
+	value := reflect.StructTag(tag.Value[1 : len(tag.Value)-1]).Get("json")
+	if value == "-" {
+		return "", false, false
+	}
+	key, opts, _ := strings.Cut(value, ",")
+	if key != "" {
+		name = key
+	}
+	return name, strings.Contains(","+opts+",", ",omitempty,"), true
+}
+
+func basicSchema(name string) (*schema, bool) {
+	switch name {
+	case "string":
+		return &schema{Type: "string"}, true
+	case "bool":
+		return &schema{Type: "boolean"}, true
+	case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32":
+		return &schema{Type: "integer"}, true
+	case "int64", "uint64":
+		return &schema{Type: "integer", Format: "int64"}, true
+	case "float32":
+		return &schema{Type: "number", Format: "float"}, true
+	case "float64":
+		return &schema{Type: "number", Format: "double"}, true
+	}
+	return nil, false
+}
@@ -2,3 +2,556 @@ package main
 
 // тут вы пишете код
 // обращаю ваше внимание - в этом задании запрещены глобальные переменные
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultLimit  = 5
+	defaultOffset = 0
+)
+
+// columnKind - во что сканируем колонку и какие значения в неё принимаем
+type columnKind int
+
+const (
+	kindString columnKind = iota
+	kindInt
+	kindFloat
+)
+
+type column struct {
+	Name          string
+	Kind          columnKind
+	Nullable      bool
+	Primary       bool
+	AutoIncrement bool
+	HasDefault    bool
+	// Ref - на что ссылается внешний ключ, nil если колонка не внешний ключ
+	Ref *reference
+}
+
+type reference struct {
+	Table  string
+	Column string
+}
+
+type table struct {
+	Name    string
+	Columns []*column
+	byName  map[string]*column
+	// PK - единственная колонка первичного ключа, nil если ключа нет или он составной
+	PK *column
+}
+
+// DbExplorer - CRUD по всем таблицам базы. Схема читается один раз в NewDbExplorer,
+// в запросы попадают только имена из неё, значения всегда идут плейсхолдерами
+type DbExplorer struct {
+	db     *sql.DB
+	d      dialect
+	tables map[string]*table
+	names  []string
+}
+
+// NewDbExplorer определяет диалект по драйверу db: MySQL, PostgreSQL или SQLite
+func NewDbExplorer(db *sql.DB) (http.Handler, error) {
+	return newDbExplorer(db, dialectOf(db))
+}
+
+func newDbExplorer(db *sql.DB, d dialect) (*DbExplorer, error) {
+	names, err := d.Tables(db)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list tables: %w", err)
+	}
+	sort.Strings(names)
+
+	e := &DbExplorer{db: db, d: d, tables: make(map[string]*table, len(names)), names: names}
+	for _, name := range names {
+		cols, err := d.Columns(db, name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot describe table %s: %w", name, err)
+		}
+		e.tables[name] = newTable(name, cols)
+	}
+
+	keys, err := d.ForeignKeys(db)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read foreign keys: %w", err)
+	}
+	e.addReferences(keys)
+	return e, nil
+}
+
+func newTable(name string, cols []*column) *table {
+	t := &table{Name: name, Columns: cols, byName: make(map[string]*column, len(cols))}
+	var primary []*column
+	for _, col := range cols {
+		t.byName[col.Name] = col
+		if col.Primary {
+			primary = append(primary, col)
+		}
+	}
+	if len(primary) == 1 {
+		t.PK = primary[0]
+	}
+	return t
+}
+
+// addReferences отмечает внешние ключи из одной колонки.
+// Составные ключи и ссылки на таблицы вне схемы пропускаются: раскрыть их по одному значению нельзя
+func (e *DbExplorer) addReferences(keys []foreignKey) {
+	type key struct{ table, name string }
+	parts := make(map[key]int)
+	for _, fk := range keys {
+		parts[key{fk.Table, fk.Name}]++
+	}
+
+	for _, fk := range keys {
+		t, ok := e.tables[fk.Table]
+		if !ok || parts[key{fk.Table, fk.Name}] != 1 {
+			continue
+		}
+		target, ok := e.tables[fk.Ref.Table]
+		if !ok {
+			continue
+		}
+		ref := fk.Ref
+		if ref.Column == "" && target.PK != nil {
+			ref.Column = target.PK.Name
+		}
+		if target.byName[ref.Column] == nil {
+			continue
+		}
+		if col := t.byName[fk.Column]; col != nil {
+			col.Ref = &ref
+		}
+	}
+}
+
+// ServeHTTP разбирает пути /, /$table и /$table/$id
+func (e *DbExplorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "bad method")
+			return
+		}
+		writeResponse(w, map[string]interface{}{"tables": e.names})
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) > 2 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	t, ok := e.tables[parts[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown table")
+		return
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			e.list(w, r, t)
+		case http.MethodPut:
+			e.create(w, r, t)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "bad method")
+		}
+		return
+	}
+
+	if t.PK == nil {
+		writeError(w, http.StatusNotFound, "table has no primary key")
+		return
+	}
+	id, err := t.PK.parse(parts[1])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "record not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		e.get(w, r, t, id)
+	case http.MethodPost:
+		e.update(w, r, t, id)
+	case http.MethodDelete:
+		e.delete(w, t, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "bad method")
+	}
+}
+
+func (e *DbExplorer) list(w http.ResponseWriter, r *http.Request, t *table) {
+	q, err := t.parseQuery(e.d, r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	from := " FROM " + e.d.Quote(t.Name) + q.whereClause()
+	// у COUNT те же аргументы фильтров, limit и offset добавляются после них
+	filterArgs := q.args
+
+	query := "SELECT " + t.columnList(e.d) + from
+	if len(q.order) != 0 {
+		query += " ORDER BY " + strings.Join(q.order, ", ")
+	}
+	query += " LIMIT " + q.add(q.limit) + " OFFSET " + q.add(q.offset)
+
+	records, err := e.selectRecords(t, query, q.args...)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if err := e.expand(records, q.expand); err != nil {
+		internalError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{"records": records}
+	if q.total {
+		var total int64
+		err := e.db.QueryRow("SELECT COUNT(*)"+from, filterArgs...).Scan(&total)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		response["total"] = total
+	}
+	writeResponse(w, response)
+}
+
+func (e *DbExplorer) get(w http.ResponseWriter, r *http.Request, t *table, id interface{}) {
+	expand, err := t.parseExpand(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := "SELECT " + t.columnList(e.d) + " FROM " + e.d.Quote(t.Name) + " WHERE " + e.d.Quote(t.PK.Name) + " = " + e.d.Placeholder(1)
+	record, err := t.scan(e.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "record not found")
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if err := e.expand([]map[string]interface{}{record}, expand); err != nil {
+		internalError(w, err)
+		return
+	}
+	writeResponse(w, map[string]interface{}{"record": record})
+}
+
+// selectRecords читает все строки запроса и закрывает их до возврата,
+// чтобы следующий запрос в том же обработчике не занимал второе соединение
+func (e *DbExplorer) selectRecords(t *table, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []map[string]interface{}{}
+	for rows.Next() {
+		record, err := t.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// expand заменяет значения внешних ключей записями, на которые они ссылаются,
+// по одному запросу на колонку. Ключ без записи остаётся как есть
+func (e *DbExplorer) expand(records []map[string]interface{}, cols []*column) error {
+	for _, col := range cols {
+		ref := e.tables[col.Ref.Table]
+
+		p := &params{d: e.d}
+		var marks []string
+		seen := make(map[string]bool)
+		for _, record := range records {
+			v := record[col.Name]
+			if v == nil || seen[fmt.Sprint(v)] {
+				continue
+			}
+			seen[fmt.Sprint(v)] = true
+			marks = append(marks, p.add(v))
+		}
+		if len(marks) == 0 {
+			continue
+		}
+
+		query := "SELECT " + ref.columnList(e.d) + " FROM " + e.d.Quote(ref.Name) + " WHERE " + e.d.Quote(col.Ref.Column) + " IN (" + strings.Join(marks, ", ") + ")"
+		related, err := e.selectRecords(ref, query, p.args...)
+		if err != nil {
+			return err
+		}
+		byKey := make(map[string]map[string]interface{}, len(related))
+		for _, rel := range related {
+			byKey[fmt.Sprint(rel[col.Ref.Column])] = rel
+		}
+		for _, record := range records {
+			if rel, ok := byKey[fmt.Sprint(record[col.Name])]; ok && record[col.Name] != nil {
+				record[col.Name] = rel
+			}
+		}
+	}
+	return nil
+}
+
+func (e *DbExplorer) create(w http.ResponseWriter, r *http.Request, t *table) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	p := &params{d: e.d}
+	var names, marks []string
+	var id interface{}
+	for _, col := range t.Columns {
+		if col.AutoIncrement {
+			continue
+		}
+		raw, ok := body[col.Name]
+		if !ok {
+			// NOT NULL без default сервер сам не заполнит
+			if col.Nullable || col.HasDefault {
+				continue
+			}
+			raw = col.zero()
+		}
+		value, err := col.validate(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if col == t.PK {
+			id = value
+		}
+		names = append(names, e.d.Quote(col.Name))
+		marks = append(marks, p.add(value))
+	}
+
+	newID, err := e.d.Insert(e.db, t, names, marks, p.args)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if t.PK == nil {
+		writeResponse(w, map[string]interface{}{})
+		return
+	}
+	if t.PK.AutoIncrement {
+		id = newID
+	}
+	writeResponse(w, map[string]interface{}{t.PK.Name: id})
+}
+
+func (e *DbExplorer) update(w http.ResponseWriter, r *http.Request, t *table, id interface{}) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	// поля по порядку колонок, чтобы запрос не зависел от порядка ключей в json
+	p := &params{d: e.d}
+	var sets []string
+	for _, col := range t.Columns {
+		raw, ok := body[col.Name]
+		if !ok {
+			continue
+		}
+		if col.Primary {
+			writeError(w, http.StatusBadRequest, invalidField(col.Name).Error())
+			return
+		}
+		value, err := col.validate(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		sets = append(sets, e.d.Quote(col.Name)+" = "+p.add(value))
+	}
+	if len(sets) == 0 {
+		writeResponse(w, map[string]interface{}{"updated": 0})
+		return
+	}
+
+	query := "UPDATE " + e.d.Quote(t.Name) + " SET " + strings.Join(sets, ", ") + " WHERE " + e.d.Quote(t.PK.Name) + " = " + p.add(id)
+	res, err := e.db.Exec(query, p.args...)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeResponse(w, map[string]interface{}{"updated": affected})
+}
+
+func (e *DbExplorer) delete(w http.ResponseWriter, t *table, id interface{}) {
+	res, err := e.db.Exec("DELETE FROM "+e.d.Quote(t.Name)+" WHERE "+e.d.Quote(t.PK.Name)+" = "+e.d.Placeholder(1), id)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeResponse(w, map[string]interface{}{"deleted": affected})
+}
+
+func (t *table) columnList(d dialect) string {
+	names := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		names[i] = d.Quote(col.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// scan читает строку в map по схеме таблицы, NULL превращается в nil
+func (t *table) scan(row interface{ Scan(...interface{}) error }) (map[string]interface{}, error) {
+	dest := make([]interface{}, len(t.Columns))
+	for i, col := range t.Columns {
+		switch col.Kind {
+		case kindInt:
+			dest[i] = new(sql.NullInt64)
+		case kindFloat:
+			dest[i] = new(sql.NullFloat64)
+		default:
+			dest[i] = new(sql.NullString)
+		}
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	record := make(map[string]interface{}, len(t.Columns))
+	for i, col := range t.Columns {
+		var value interface{}
+		switch v := dest[i].(type) {
+		case *sql.NullInt64:
+			if v.Valid {
+				value = v.Int64
+			}
+		case *sql.NullFloat64:
+			if v.Valid {
+				value = v.Float64
+			}
+		case *sql.NullString:
+			if v.Valid {
+				value = v.String
+			}
+		}
+		record[col.Name] = value
+	}
+	return record, nil
+}
+
+func invalidField(name string) error {
+	return fmt.Errorf("field %s have invalid type", name)
+}
+
+// validate проверяет значение из json (с UseNumber) и приводит его к типу колонки
+func (c *column) validate(raw interface{}) (interface{}, error) {
+	switch v := raw.(type) {
+	case nil:
+		if c.Nullable {
+			return nil, nil
+		}
+	case string:
+		if c.Kind == kindString {
+			return v, nil
+		}
+	case json.Number:
+		switch c.Kind {
+		case kindInt:
+			if n, err := v.Int64(); err == nil {
+				return n, nil
+			}
+		case kindFloat:
+			if f, err := v.Float64(); err == nil {
+				return f, nil
+			}
+		}
+	}
+	return nil, invalidField(c.Name)
+}
+
+// parse разбирает значение ключа из url
+func (c *column) parse(raw string) (interface{}, error) {
+	switch c.Kind {
+	case kindInt:
+		return strconv.ParseInt(raw, 10, 64)
+	case kindFloat:
+		return strconv.ParseFloat(raw, 64)
+	}
+	return raw, nil
+}
+
+func (c *column) zero() interface{} {
+	if c.Kind == kindString {
+		return ""
+	}
+	return json.Number("0")
+}
+
+// paramInt - число из параметра запроса, при мусоре или значении меньше min - def
+func paramInt(raw string, def, min int) int {
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < min {
+		return def
+	}
+	return n
+}
+
+func readBody(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	var body map[string]interface{}
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json body")
+		return nil, false
+	}
+	return body, true
+}
+
+func writeResponse(w http.ResponseWriter, response interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"response": response})
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]interface{}{"error": msg})
+}
+
+func internalError(w http.ResponseWriter, err error) {
+	log.Println("db_explorer:", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
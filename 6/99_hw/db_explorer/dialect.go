@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+// dialect - всё, чем базы различаются для explorer: чтение схемы, кавычки у имён,
+// плейсхолдеры и получение ключа только что вставленной записи.
+// Остальной SQL (SELECT/UPDATE/DELETE, LIMIT/OFFSET, LIKE, IN) общий
+type dialect interface {
+	// Tables - таблицы текущей базы (схемы)
+	Tables(db *sql.DB) ([]string, error)
+	// Columns - колонки таблицы в порядке объявления
+	Columns(db *sql.DB, table string) ([]*column, error)
+	// ForeignKeys - внешние ключи текущей базы, у составного ключа несколько строк с одним Name
+	ForeignKeys(db *sql.DB) ([]foreignKey, error)
+	// Quote берёт имя таблицы или колонки в кавычки
+	Quote(name string) string
+	// Placeholder - плейсхолдер n-го аргумента запроса, n с 1
+	Placeholder(n int) string
+	// Insert выполняет INSERT и возвращает значение автоинкрементного ключа, если он у таблицы есть
+	Insert(db *sql.DB, t *table, names, marks []string, args []interface{}) (int64, error)
+}
+
+// foreignKey - одна колонка внешнего ключа. Ref.Column пустой, если ключ ссылается
+// на первичный ключ таблицы без явного имени колонки (так умеет SQLite)
+type foreignKey struct {
+	Table  string
+	Name   string
+	Column string
+	Ref    reference
+}
+
+// dialectOf выбирает диалект по пакету драйвера. Всё незнакомое (например, sqlmock в тестах)
+// считается MySQL - под него написано задание
+func dialectOf(db *sql.DB) dialect {
+	driver := reflect.TypeOf(db.Driver())
+	for driver.Kind() == reflect.Ptr {
+		driver = driver.Elem()
+	}
+	pkg := driver.PkgPath()
+	switch {
+	case strings.Contains(pkg, "sqlite"):
+		return sqliteDialect{}
+	case strings.HasSuffix(pkg, "/pq"), strings.Contains(pkg, "pgx"), strings.Contains(pkg, "postgres"):
+		return postgresDialect{}
+	}
+	return mysqlDialect{}
+}
+
+// params собирает аргументы запроса и выдаёт под каждый плейсхолдер диалекта
+type params struct {
+	d    dialect
+	args []interface{}
+}
+
+func (p *params) add(v interface{}) string {
+	p.args = append(p.args, v)
+	return p.d.Placeholder(len(p.args))
+}
+
+// insertSQL - INSERT с колонками или, если их нет, с empty - у каждой базы свой способ вставить запись из одних default
+func insertSQL(d dialect, t *table, names, marks []string, empty string) string {
+	query := "INSERT INTO " + d.Quote(t.Name)
+	if len(names) == 0 {
+		return query + " " + empty
+	}
+	return query + " (" + strings.Join(names, ", ") + ") VALUES (" + strings.Join(marks, ", ") + ")"
+}
+
+// quoteWith удваивает кавычку внутри имени
+func quoteWith(q, name string) string {
+	return q + strings.ReplaceAll(name, q, q+q) + q
+}
+
+// queryStrings - первая колонка всех строк запроса
+func queryStrings(db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// queryForeignKeys читает внешние ключи из запроса с колонками
+// table, constraint, column, referenced table, referenced column
+func queryForeignKeys(db *sql.DB, query string) ([]foreignKey, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []foreignKey
+	for rows.Next() {
+		var fk foreignKey
+		var refColumn sql.NullString
+		if err := rows.Scan(&fk.Table, &fk.Name, &fk.Column, &fk.Ref.Table, &refColumn); err != nil {
+			return nil, err
+		}
+		fk.Ref.Column = refColumn.String
+		keys = append(keys, fk)
+	}
+	return keys, rows.Err()
+}
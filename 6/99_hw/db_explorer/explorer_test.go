@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var (
+	showColumns       = []string{"Field", "Type", "Collation", "Null", "Key", "Default", "Extra", "Privileges", "Comment"}
+	foreignKeys       = "SELECT TABLE_NAME, CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL"
+	foreignKeyColumns = []string{"TABLE_NAME", "CONSTRAINT_NAME", "COLUMN_NAME", "REFERENCED_TABLE_NAME", "REFERENCED_COLUMN_NAME"}
+)
+
+// newMockExplorer поднимает explorer над sqlmock со схемой одной таблицы books,
+// у которой ключ называется isbn и не автоинкрементный
+func newMockExplorer(t *testing.T) (http.Handler, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectQuery("SHOW TABLES").WillReturnRows(sqlmock.NewRows([]string{"Tables_in_test"}).AddRow("books"))
+	mock.ExpectQuery("SHOW FULL COLUMNS FROM `books`").WillReturnRows(sqlmock.NewRows(showColumns).
+		AddRow("isbn", "varchar(13)", "utf8_general_ci", "NO", "PRI", nil, "", "", "").
+		AddRow("title", "varchar(255)", "utf8_general_ci", "NO", "", nil, "", "", "").
+		AddRow("pages", "int(11) unsigned", nil, "YES", "", nil, "", "", "").
+		AddRow("price", "decimal(10,2)", nil, "NO", "", "0.00", "", "", ""))
+	mock.ExpectQuery(foreignKeys).WillReturnRows(sqlmock.NewRows(foreignKeyColumns))
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return handler, mock
+}
+
+func do(t *testing.T, handler http.Handler, method, url string, body interface{}) (int, interface{}) {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, url, bytes.NewReader(data)))
+
+	var result interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("%s %s: cant unpack json %q: %v", method, url, rec.Body.String(), err)
+	}
+	return rec.Code, result
+}
+
+func TestMockSchema(t *testing.T) {
+	handler, mock := newMockExplorer(t)
+	books := "SELECT `isbn`, `title`, `pages`, `price` FROM `books`"
+
+	// мусор в limit/offset - значения по умолчанию, а не кусок запроса
+	mock.ExpectQuery(books+" ORDER BY `isbn` LIMIT ? OFFSET ?").WithArgs(5, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"isbn", "title", "pages", "price"}).
+			AddRow("9785970600368", "Go", 300, 10.5).
+			AddRow("9780134190440", "The Go Programming Language", nil, 0))
+	status, result := do(t, handler, http.MethodGet, "/books?limit=1'&offset=-1", nil)
+	expected := map[string]interface{}{"response": map[string]interface{}{"records": []interface{}{
+		map[string]interface{}{"isbn": "9785970600368", "title": "Go", "pages": 300.0, "price": 10.5},
+		map[string]interface{}{"isbn": "9780134190440", "title": "The Go Programming Language", "pages": nil, "price": 0.0},
+	}}}
+	if status != http.StatusOK || !reflect.DeepEqual(result, expected) {
+		t.Errorf("list: got %d %#v", status, result)
+	}
+
+	// ключ не числовой, уходит строкой
+	mock.ExpectQuery(books + " WHERE `isbn` = ?").WithArgs("1' OR '1'='1").
+		WillReturnRows(sqlmock.NewRows([]string{"isbn", "title", "pages", "price"}))
+	status, result = do(t, handler, http.MethodGet, "/books/1'%20OR%20'1'='1", nil)
+	if status != http.StatusNotFound || !reflect.DeepEqual(result, map[string]interface{}{"error": "record not found"}) {
+		t.Errorf("get: got %d %#v", status, result)
+	}
+
+	// title не пришёл - NOT NULL без default заполняется пустым, price с default не передаётся
+	mock.ExpectExec("INSERT INTO `books` (`isbn`, `title`, `pages`) VALUES (?, ?, ?)").
+		WithArgs("9785970600368", "", int64(300)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	status, result = do(t, handler, http.MethodPut, "/books/", map[string]interface{}{"isbn": "9785970600368", "pages": 300, "unknown": 1})
+	if status != http.StatusOK || !reflect.DeepEqual(result, map[string]interface{}{"response": map[string]interface{}{"isbn": "9785970600368"}}) {
+		t.Errorf("create: got %d %#v", status, result)
+	}
+
+	mock.ExpectExec("UPDATE `books` SET `pages` = ?, `price` = ? WHERE `isbn` = ?").
+		WithArgs(nil, 12.5, "9785970600368").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	status, result = do(t, handler, http.MethodPost, "/books/9785970600368", map[string]interface{}{"price": 12.5, "pages": nil})
+	if status != http.StatusOK || !reflect.DeepEqual(result, map[string]interface{}{"response": map[string]interface{}{"updated": 1.0}}) {
+		t.Errorf("update: got %d %#v", status, result)
+	}
+
+	mock.ExpectExec("DELETE FROM `books` WHERE `isbn` = ?").WithArgs("9785970600368").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	status, result = do(t, handler, http.MethodDelete, "/books/9785970600368", nil)
+	if status != http.StatusOK || !reflect.DeepEqual(result, map[string]interface{}{"response": map[string]interface{}{"deleted": 1.0}}) {
+		t.Errorf("delete: got %d %#v", status, result)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unexpected queries: %v", err)
+	}
+}
+
+func TestMockValidation(t *testing.T) {
+	handler, mock := newMockExplorer(t)
+
+	cases := []struct {
+		method string
+		url    string
+		body   map[string]interface{}
+		field  string
+	}{
+		{http.MethodPost, "/books/1", map[string]interface{}{"isbn": "1"}, "isbn"},
+		{http.MethodPost, "/books/1", map[string]interface{}{"title": nil}, "title"},
+		{http.MethodPost, "/books/1", map[string]interface{}{"title": 42}, "title"},
+		{http.MethodPost, "/books/1", map[string]interface{}{"pages": "300"}, "pages"},
+		{http.MethodPost, "/books/1", map[string]interface{}{"pages": 1.5}, "pages"},
+		{http.MethodPost, "/books/1", map[string]interface{}{"price": true}, "price"},
+		{http.MethodPut, "/books", map[string]interface{}{"isbn": 1}, "isbn"},
+	}
+	for _, c := range cases {
+		status, result := do(t, handler, c.method, c.url, c.body)
+		expected := map[string]interface{}{"error": "field " + c.field + " have invalid type"}
+		if status != http.StatusBadRequest || !reflect.DeepEqual(result, expected) {
+			t.Errorf("%s %v: got %d %#v", c.method, c.body, status, result)
+		}
+	}
+
+	for _, url := range []string{"/nope", "/nope/1"} {
+		status, result := do(t, handler, http.MethodGet, url, nil)
+		if status != http.StatusNotFound || !reflect.DeepEqual(result, map[string]interface{}{"error": "unknown table"}) {
+			t.Errorf("%s: got %d %#v", url, status, result)
+		}
+	}
+
+	status, result := do(t, handler, http.MethodGet, "/", nil)
+	if status != http.StatusOK || !reflect.DeepEqual(result, map[string]interface{}{"response": map[string]interface{}{"tables": []interface{}{"books"}}}) {
+		t.Errorf("tables: got %d %#v", status, result)
+	}
+
+	// ни одного запроса к базе после чтения схемы
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unexpected queries: %v", err)
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	kinds := map[string]columnKind{
+		"int(11)":          kindInt,
+		"bigint unsigned":  kindInt,
+		"double":           kindFloat,
+		"decimal(10,2)":    kindFloat,
+		"varchar(255)":     kindString,
+		"text":             kindString,
+		"point":            kindString,
+		"enum('a','b')":    kindString,
+		"TINYINT(1)":       kindInt,
+		"datetime":         kindString,
+		"float unsigned":   kindFloat,
+		"mediumint(8)":     kindInt,
+		"char(36)":         kindString,
+		"json":             kindString,
+		"smallint(5) zero": kindInt,
+	}
+	for sqlType, kind := range kinds {
+		if got := kindOf(sqlType); got != kind {
+			t.Errorf("%s: got %d, expected %d", sqlType, got, kind)
+		}
+	}
+	if q := (mysqlDialect{}).Quote("a`b"); q != "`a``b`" {
+		t.Errorf("bad quoting %s", q)
+	}
+}
+
+func TestMockQuery(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SHOW TABLES").WillReturnRows(sqlmock.NewRows([]string{"Tables_in_test"}).AddRow("posts").AddRow("authors"))
+	mock.ExpectQuery("SHOW FULL COLUMNS FROM `authors`").WillReturnRows(sqlmock.NewRows(showColumns).
+		AddRow("author_id", "int(11)", nil, "NO", "PRI", nil, "auto_increment", "", "").
+		AddRow("name", "varchar(255)", "utf8_general_ci", "NO", "", nil, "", "", ""))
+	mock.ExpectQuery("SHOW FULL COLUMNS FROM `posts`").WillReturnRows(sqlmock.NewRows(showColumns).
+		AddRow("id", "int(11)", nil, "NO", "PRI", nil, "auto_increment", "", "").
+		AddRow("title", "varchar(255)", "utf8_general_ci", "NO", "", nil, "", "", "").
+		AddRow("rating", "double", nil, "YES", "", nil, "", "", "").
+		AddRow("author_id", "int(11)", nil, "YES", "MUL", nil, "", "", ""))
+	// составной ключ не раскрывается
+	mock.ExpectQuery(foreignKeys).WillReturnRows(sqlmock.NewRows(foreignKeyColumns).
+		AddRow("posts", "posts_author", "author_id", "authors", "author_id").
+		AddRow("posts", "posts_pair", "id", "authors", "author_id").
+		AddRow("posts", "posts_pair", "title", "authors", "name"))
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	posts := "SELECT `id`, `title`, `rating`, `author_id` FROM `posts`"
+	postColumns := []string{"id", "title", "rating", "author_id"}
+
+	where := " WHERE `author_id` IN (?, ?) AND `rating` >= ? AND `rating` < ? AND `title` LIKE ? AND `title` IS NOT NULL"
+	mock.ExpectQuery(posts+where+" ORDER BY `rating` DESC, `title`, `id` LIMIT ? OFFSET ?").
+		WithArgs(int64(1), int64(2), 2.5, 5.0, "%go%", 2, 1).
+		WillReturnRows(sqlmock.NewRows(postColumns).
+			AddRow(3, "go tips", 4.5, 2).
+			AddRow(1, "go basics", 3, 1).
+			AddRow(4, "go again", 3, 2))
+	mock.ExpectQuery("SELECT `author_id`, `name` FROM `authors` WHERE `author_id` IN (?, ?)").
+		WithArgs(int64(2), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"author_id", "name"}).AddRow(1, "rvasily").AddRow(2, "gopher"))
+	mock.ExpectQuery("SELECT COUNT(*) FROM `posts`"+where).
+		WithArgs(int64(1), int64(2), 2.5, 5.0, "%go%").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(7))
+
+	query := "/posts?limit=2&offset=1&total=true&sort=-rating,title&expand=author_id" +
+		"&title__like=%25go%25&rating__gte=2.5&rating__lt=5&author_id__in=1,2&title__null=false"
+	status, result := do(t, handler, http.MethodGet, query, nil)
+	gopher := map[string]interface{}{"author_id": 2.0, "name": "gopher"}
+	expected := map[string]interface{}{"response": map[string]interface{}{
+		"total": 7.0,
+		"records": []interface{}{
+			map[string]interface{}{"id": 3.0, "title": "go tips", "rating": 4.5, "author_id": gopher},
+			map[string]interface{}{"id": 1.0, "title": "go basics", "rating": 3.0, "author_id": map[string]interface{}{"author_id": 1.0, "name": "rvasily"}},
+			map[string]interface{}{"id": 4.0, "title": "go again", "rating": 3.0, "author_id": gopher},
+		},
+	}}
+	if status != http.StatusOK || !reflect.DeepEqual(result, expected) {
+		t.Errorf("list: got %d %#v", status, result)
+	}
+
+	// пустой внешний ключ не раскрывается и не даёт лишнего запроса
+	mock.ExpectQuery(posts + " WHERE `id` = ?").WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows(postColumns).AddRow(5, "draft", nil, nil))
+	status, result = do(t, handler, http.MethodGet, "/posts/5?expand=author_id", nil)
+	expected = map[string]interface{}{"response": map[string]interface{}{
+		"record": map[string]interface{}{"id": 5.0, "title": "draft", "rating": nil, "author_id": nil},
+	}}
+	if status != http.StatusOK || !reflect.DeepEqual(result, expected) {
+		t.Errorf("get: got %d %#v", status, result)
+	}
+
+	errorCases := map[string]string{
+		"/posts?nope__eq=1":             "unknown field nope",
+		"/posts?rating__gte=high":       "invalid value for rating__gte",
+		"/posts?id__in=1,x":             "invalid value for id__in",
+		"/posts?title__null=maybe":      "invalid value for title__null",
+		"/posts?title__regexp=.*":       "unknown filter title__regexp",
+		"/posts?sort=-nope":             "unknown field nope",
+		"/posts?expand=title":           "field title can not be expanded",
+		"/posts?expand=id":              "field id can not be expanded",
+		"/posts/1?expand=author_id,x":   "field x can not be expanded",
+		"/authors?sort=name%3BDROP%20x": "unknown field name;DROP x",
+		"/posts?title%60__eq=1":         "unknown field title`",
+		"/posts?author_id__ne=1%20OR1":  "invalid value for author_id__ne",
+	}
+	for url, msg := range errorCases {
+		status, result := do(t, handler, http.MethodGet, url, nil)
+		if status != http.StatusBadRequest || !reflect.DeepEqual(result, map[string]interface{}{"error": msg}) {
+			t.Errorf("%s: got %d %#v", url, status, result)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unexpected queries: %v", err)
+	}
+}
+
+func TestMockPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM information_schema.tables").WillReturnRows(sqlmock.NewRows([]string{"table_name"}).AddRow("items"))
+	mock.ExpectQuery("FROM information_schema.columns").WithArgs("items").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name", "data_type", "is_nullable", "column_default", "is_identity", "exists"}).
+			AddRow("id", "integer", "NO", "nextval('items_id_seq'::regclass)", "NO", true).
+			AddRow("title", "character varying", "NO", nil, "NO", false).
+			AddRow("score", "double precision", "YES", nil, "NO", false))
+	mock.ExpectQuery("constraint_type = 'FOREIGN KEY'").WillReturnRows(sqlmock.NewRows(foreignKeyColumns))
+
+	e, err := newDbExplorer(db, postgresDialect{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pk := e.tables["items"].PK; pk == nil || !pk.AutoIncrement || e.tables["items"].byName["score"].Kind != kindFloat {
+		t.Fatalf("unexpected schema %#v", e.tables["items"])
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id", "title", "score" FROM "items" WHERE "score" >= $1 AND "title" LIKE $2 ORDER BY "id" LIMIT $3 OFFSET $4`)).
+		WithArgs(1.5, "a%", 5, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "score"}).AddRow(1, "a", 2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM "items" WHERE "score" >= $1 AND "title" LIKE $2`)).
+		WithArgs(1.5, "a%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	status, result := do(t, e, http.MethodGet, "/items?score__gte=1.5&title__like=a%25&total=1", nil)
+	if status != http.StatusOK {
+		t.Errorf("list: got %d %#v", status, result)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "items" ("title") VALUES ($1) RETURNING "id"`)).
+		WithArgs("new").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	status, result = do(t, e, http.MethodPut, "/items", map[string]interface{}{"title": "new"})
+	if status != http.StatusOK || !reflect.DeepEqual(result, map[string]interface{}{"response": map[string]interface{}{"id": 7.0}}) {
+		t.Errorf("create: got %d %#v", status, result)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "items" SET "title" = $1, "score" = $2 WHERE "id" = $3`)).
+		WithArgs("x", nil, int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	status, result = do(t, e, http.MethodPost, "/items/7", map[string]interface{}{"score": nil, "title": "x"})
+	if status != http.StatusOK {
+		t.Errorf("update: got %d %#v", status, result)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unexpected queries: %v", err)
+	}
+}
+
+func TestDialectQuoting(t *testing.T) {
+	cases := []struct {
+		d      dialect
+		quoted string
+		second string
+	}{
+		{mysqlDialect{}, "`a``b\"`", "?"},
+		{postgresDialect{}, `"a` + "`" + `b"""`, "$2"},
+		{sqliteDialect{}, `"a` + "`" + `b"""`, "?"},
+	}
+	for _, c := range cases {
+		if q := c.d.Quote("a`b\""); q != c.quoted {
+			t.Errorf("%T: got %s, expected %s", c.d, q, c.quoted)
+		}
+		if p := c.d.Placeholder(2); p != c.second {
+			t.Errorf("%T: got %s, expected %s", c.d, p, c.second)
+		}
+	}
+
+	kinds := map[string]columnKind{
+		"INTEGER":          kindInt,
+		"BIGINT":           kindInt,
+		"VARCHAR(255)":     kindString,
+		"TEXT":             kindString,
+		"BLOB":             kindString,
+		"":                 kindString,
+		"REAL":             kindFloat,
+		"DOUBLE PRECISION": kindFloat,
+		"DECIMAL(10,2)":    kindFloat,
+		"DATETIME":         kindString,
+	}
+	for declared, kind := range kinds {
+		if got := sqliteKind(declared); got != kind {
+			t.Errorf("sqlite %q: got %d, expected %d", declared, got, kind)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+module db_explorer
+
+go 1.18
+
+require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/go-sql-driver/mysql v1.8.1
+	github.com/mattn/go-sqlite3 v1.14.19
+)
+
+require filippo.io/edwards25519 v1.1.0 // indirect
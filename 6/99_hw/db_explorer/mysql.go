@@ -0,0 +1,92 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Tables(db *sql.DB) ([]string, error) {
+	return queryStrings(db, "SHOW TABLES")
+}
+
+func (d mysqlDialect) Columns(db *sql.DB, table string) ([]*column, error) {
+	rows, err := db.Query("SHOW FULL COLUMNS FROM " + d.Quote(table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// набор колонок у SHOW FULL COLUMNS зависит от версии сервера, поэтому берём нужные по имени
+	header, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]sql.NullString, len(header))
+	dest := make([]interface{}, len(header))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	field := func(name string) sql.NullString {
+		for i, h := range header {
+			if strings.EqualFold(h, name) {
+				return values[i]
+			}
+		}
+		return sql.NullString{}
+	}
+
+	var cols []*column
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		cols = append(cols, &column{
+			Name:          field("Field").String,
+			Kind:          kindOf(field("Type").String),
+			Nullable:      field("Null").String == "YES",
+			Primary:       field("Key").String == "PRI",
+			AutoIncrement: strings.Contains(strings.ToLower(field("Extra").String), "auto_increment"),
+			HasDefault:    field("Default").Valid,
+		})
+	}
+	return cols, rows.Err()
+}
+
+func (mysqlDialect) ForeignKeys(db *sql.DB) ([]foreignKey, error) {
+	return queryForeignKeys(db, `SELECT TABLE_NAME, CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL`)
+}
+
+func (mysqlDialect) Quote(name string) string {
+	return quoteWith("`", name)
+}
+
+func (mysqlDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (d mysqlDialect) Insert(db *sql.DB, t *table, names, marks []string, args []interface{}) (int64, error) {
+	res, err := db.Exec(insertSQL(d, t, names, marks, "() VALUES ()"), args...)
+	if err != nil || t.PK == nil || !t.PK.AutoIncrement {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// kindOf переводит тип вида "int(11) unsigned", "varchar(255)" или "double precision" в columnKind
+func kindOf(sqlType string) columnKind {
+	base := strings.ToLower(sqlType)
+	if i := strings.IndexAny(base, "( "); i >= 0 {
+		base = base[:i]
+	}
+	switch base {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		return kindInt
+	case "float", "double", "real", "decimal", "numeric":
+		return kindFloat
+	}
+	return kindString
+}
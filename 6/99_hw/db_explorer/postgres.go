@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// postgresDialect смотрит только в current_schema(), обычно это public
+type postgresDialect struct{}
+
+func (postgresDialect) Tables(db *sql.DB) ([]string, error) {
+	return queryStrings(db, `SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+		ORDER BY table_name`)
+}
+
+func (postgresDialect) Columns(db *sql.DB, table string) ([]*column, error) {
+	rows, err := db.Query(`SELECT c.column_name, c.data_type, c.is_nullable, c.column_default, c.is_identity,
+			EXISTS (
+				SELECT 1 FROM information_schema.table_constraints tc
+				JOIN information_schema.key_column_usage k
+					ON k.constraint_schema = tc.constraint_schema AND k.constraint_name = tc.constraint_name
+					AND k.table_name = tc.table_name
+				WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema
+					AND tc.table_name = c.table_name AND k.column_name = c.column_name
+			)
+		FROM information_schema.columns c
+		WHERE c.table_schema = current_schema() AND c.table_name = $1
+		ORDER BY c.ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []*column
+	for rows.Next() {
+		var name, dataType, nullable, identity string
+		var def sql.NullString
+		col := &column{}
+		if err := rows.Scan(&name, &dataType, &nullable, &def, &identity, &col.Primary); err != nil {
+			return nil, err
+		}
+		col.Name = name
+		col.Kind = kindOf(dataType)
+		col.Nullable = nullable == "YES"
+		// serial - это default nextval(...), identity default не показывает
+		col.AutoIncrement = identity == "YES" || strings.HasPrefix(def.String, "nextval(")
+		col.HasDefault = def.Valid || col.AutoIncrement
+		cols = append(cols, col)
+	}
+	return cols, rows.Err()
+}
+
+func (postgresDialect) ForeignKeys(db *sql.DB) ([]foreignKey, error) {
+	return queryForeignKeys(db, `SELECT tc.table_name, tc.constraint_name, k.column_name, r.table_name, r.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage k
+			ON k.constraint_schema = tc.constraint_schema AND k.constraint_name = tc.constraint_name
+			AND k.table_name = tc.table_name
+		JOIN information_schema.constraint_column_usage r
+			ON r.constraint_schema = tc.constraint_schema AND r.constraint_name = tc.constraint_name
+		WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema()`)
+}
+
+func (postgresDialect) Quote(name string) string {
+	return quoteWith(`"`, name)
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// Insert забирает ключ через RETURNING: LastInsertId драйверы postgres не поддерживают
+func (d postgresDialect) Insert(db *sql.DB, t *table, names, marks []string, args []interface{}) (int64, error) {
+	query := insertSQL(d, t, names, marks, "DEFAULT VALUES")
+	if t.PK == nil || !t.PK.AutoIncrement {
+		_, err := db.Exec(query, args...)
+		return 0, err
+	}
+	var id int64
+	err := db.QueryRow(query+" RETURNING "+d.Quote(t.PK.Name), args...).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// listQuery - разобранные параметры GET /$table
+type listQuery struct {
+	params
+	where  []string
+	order  []string
+	limit  int
+	offset int
+	total  bool
+	expand []*column
+}
+
+func (q *listQuery) whereClause() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.where, " AND ")
+}
+
+// parseQuery разбирает параметры списка:
+//
+//	?title__like=%sql%&id__in=1,2&updated__null=true - фильтры, объединяются через AND
+//	?sort=-updated,title - сортировка, минус - по убыванию
+//	?expand=user_id - подставить вместо ключа запись, на которую он ссылается
+//	?total=1 - добавить в ответ число записей под фильтром без limit/offset
+//
+// Кривые limit/offset молча заменяются значениями по умолчанию, а всё, что ссылается на схему
+// (колонки, операции, значения фильтров) - ошибка запроса
+func (t *table) parseQuery(d dialect, values url.Values) (*listQuery, error) {
+	q := &listQuery{
+		params: params{d: d},
+		limit:  paramInt(values.Get("limit"), defaultLimit, 1),
+		offset: paramInt(values.Get("offset"), defaultOffset, 0),
+	}
+	q.total, _ = strconv.ParseBool(values.Get("total"))
+
+	// ключи по порядку, чтобы один и тот же url давал один и тот же запрос
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		i := strings.LastIndex(key, "__")
+		if i < 0 {
+			continue
+		}
+		col, ok := t.byName[key[:i]]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %s", key[:i])
+		}
+		for _, raw := range values[key] {
+			if err := q.filter(col, key[i+2:], raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range splitList(values["sort"]) {
+		dir := ""
+		if strings.HasPrefix(name, "-") {
+			name, dir = name[1:], " DESC"
+		}
+		if _, ok := t.byName[name]; !ok {
+			return nil, fmt.Errorf("unknown field %s", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			q.order = append(q.order, d.Quote(name)+dir)
+		}
+	}
+	// ключ в конце делает порядок однозначным, иначе limit/offset могут терять записи
+	if t.PK != nil && !seen[t.PK.Name] {
+		q.order = append(q.order, d.Quote(t.PK.Name))
+	}
+
+	expand, err := t.parseExpand(values)
+	if err != nil {
+		return nil, err
+	}
+	q.expand = expand
+	return q, nil
+}
+
+// parseExpand - колонки из ?expand=, у каждой должен быть внешний ключ
+func (t *table) parseExpand(values url.Values) ([]*column, error) {
+	var cols []*column
+	for _, name := range splitList(values["expand"]) {
+		col, ok := t.byName[name]
+		if !ok || col.Ref == nil {
+			return nil, fmt.Errorf("field %s can not be expanded", name)
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+func (q *listQuery) filter(col *column, op, raw string) error {
+	field := q.d.Quote(col.Name)
+	switch op {
+	case "null":
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s__null", col.Name)
+		}
+		if isNull {
+			q.where = append(q.where, field+" IS NULL")
+		} else {
+			q.where = append(q.where, field+" IS NOT NULL")
+		}
+	case "in":
+		var marks []string
+		for _, item := range strings.Split(raw, ",") {
+			value, err := col.parse(item)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s__in", col.Name)
+			}
+			marks = append(marks, q.add(value))
+		}
+		q.where = append(q.where, field+" IN ("+strings.Join(marks, ", ")+")")
+	case "like":
+		// шаблон - всегда строка, даже для числовых колонок
+		q.where = append(q.where, field+" LIKE "+q.add(raw))
+	default:
+		sqlOp := compareOp(op)
+		if sqlOp == "" {
+			return fmt.Errorf("unknown filter %s__%s", col.Name, op)
+		}
+		value, err := col.parse(raw)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s__%s", col.Name, op)
+		}
+		q.where = append(q.where, field+" "+sqlOp+" "+q.add(value))
+	}
+	return nil
+}
+
+// compareOp - операция сравнения для фильтра вида ?price__gte=10, "" для неизвестной
+func compareOp(op string) string {
+	switch op {
+	case "eq":
+		return "="
+	case "ne":
+		return "<>"
+	case "lt":
+		return "<"
+	case "lte":
+		return "<="
+	case "gt":
+		return ">"
+	case "gte":
+		return ">="
+	}
+	return ""
+}
+
+// splitList склеивает повторы параметра и значения через запятую: ?sort=a,b&sort=c
+func splitList(values []string) []string {
+	var items []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
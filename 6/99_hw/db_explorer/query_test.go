@@ -0,0 +1,177 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// PrepareQueryApis - данные из PrepareTestApis, у items появляется владелец из users
+func PrepareQueryApis(db *sql.DB) {
+	PrepareTestApis(db)
+
+	qs := []string{
+		`ALTER TABLE items
+  ADD COLUMN user_id int(11) DEFAULT NULL,
+  ADD CONSTRAINT items_user_id FOREIGN KEY (user_id) REFERENCES users (user_id);`,
+		`UPDATE items SET user_id = 1 WHERE id = 1;`,
+	}
+	for _, q := range qs {
+		_, err := db.Exec(q)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestQueryApis(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Skipf("mysql is not available: %v", err)
+	}
+
+	PrepareQueryApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	runCases(t, ts, db, queryCases())
+}
+
+// queryCases - фильтры, сортировка и раскрытие ключей поверх данных PrepareQueryApis
+// или PrepareSQLiteApis
+func queryCases() []Case {
+	rvasily := CR{
+		"user_id":  1,
+		"login":    "rvasily",
+		"password": "love",
+		"email":    "rvasily@example.com",
+		"info":     "none",
+		"updated":  nil,
+	}
+	item1 := CR{
+		"id":          1,
+		"title":       "database/sql",
+		"description": "Рассказать про базы данных",
+		"updated":     "rvasily",
+		"user_id":     1,
+	}
+	item2 := CR{
+		"id":          2,
+		"title":       "memcache",
+		"description": "Рассказать про мемкеш с примером использования",
+		"updated":     nil,
+		"user_id":     nil,
+	}
+
+	return []Case{
+		Case{
+			Path:   "/items",
+			Query:  "title__like=%25cache%25",
+			Result: CR{"response": CR{"records": []CR{item2}}},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "id__gte=1&id__lt=2",
+			Result: CR{"response": CR{"records": []CR{item1}}},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "updated__null=true",
+			Result: CR{"response": CR{"records": []CR{item2}}},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "id__in=1,2,3&title__ne=memcache",
+			Result: CR{"response": CR{"records": []CR{item1}}},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "sort=-id",
+			Result: CR{"response": CR{"records": []CR{item2, item1}}},
+		},
+		// NULL в MySQL и SQLite при сортировке идёт раньше любого значения
+		Case{
+			Path:   "/items",
+			Query:  "sort=updated,-title",
+			Result: CR{"response": CR{"records": []CR{item2, item1}}},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "total=1&limit=1&offset=1",
+			Result: CR{"response": CR{"records": []CR{item2}, "total": 2}},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "total=1&title__like=nothing",
+			Result: CR{"response": CR{"records": []CR{}, "total": 0}},
+		},
+		Case{
+			Path:  "/items",
+			Query: "expand=user_id",
+			Result: CR{"response": CR{"records": []CR{
+				CR{
+					"id":          1,
+					"title":       "database/sql",
+					"description": "Рассказать про базы данных",
+					"updated":     "rvasily",
+					"user_id":     rvasily,
+				},
+				item2,
+			}}},
+		},
+		Case{
+			Path:  "/items/1",
+			Query: "expand=user_id",
+			Result: CR{"response": CR{"record": CR{
+				"id":          1,
+				"title":       "database/sql",
+				"description": "Рассказать про базы данных",
+				"updated":     "rvasily",
+				"user_id":     rvasily,
+			}}},
+		},
+
+		// ошибки
+		Case{
+			Path:   "/items",
+			Query:  "nope__eq=1",
+			Status: http.StatusBadRequest,
+			Result: CR{"error": "unknown field nope"},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "id__gte=1'",
+			Status: http.StatusBadRequest,
+			Result: CR{"error": "invalid value for id__gte"},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "sort=title%20DESC",
+			Status: http.StatusBadRequest,
+			Result: CR{"error": "unknown field title DESC"},
+		},
+		Case{
+			Path:   "/users",
+			Query:  "expand=login",
+			Status: http.StatusBadRequest,
+			Result: CR{"error": "field login can not be expanded"},
+		},
+		// в like не бывает инъекций, только шаблон
+		Case{
+			Path:   "/users",
+			Query:  "login__like=%25'%20OR%20'1'='1",
+			Result: CR{"response": CR{"records": []CR{}}},
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+)
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Tables(db *sql.DB) ([]string, error) {
+	return queryStrings(db, `SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY name`)
+}
+
+func (sqliteDialect) Columns(db *sql.DB, table string) ([]*column, error) {
+	rows, err := db.Query(`SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []*column
+	var rowid *column
+	var primary int
+	for rows.Next() {
+		var declared string
+		var notNull bool
+		var def sql.NullString
+		var pk int
+		col := &column{}
+		if err := rows.Scan(&col.Name, &declared, &notNull, &def, &pk); err != nil {
+			return nil, err
+		}
+		col.Kind = sqliteKind(declared)
+		col.Nullable = !notNull
+		col.HasDefault = def.Valid
+		if pk > 0 {
+			col.Primary = true
+			primary++
+			if strings.EqualFold(declared, "INTEGER") {
+				rowid = col
+			}
+		}
+		cols = append(cols, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// только единственный INTEGER PRIMARY KEY становится rowid и заполняется сам
+	if rowid != nil && primary == 1 {
+		rowid.AutoIncrement = true
+		rowid.HasDefault = true
+	}
+	return cols, nil
+}
+
+func (sqliteDialect) ForeignKeys(db *sql.DB) ([]foreignKey, error) {
+	return queryForeignKeys(db, `SELECT m.name, f.id, f."from", f."table", f."to"
+		FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) f
+		WHERE m.type = 'table'`)
+}
+
+func (sqliteDialect) Quote(name string) string {
+	return quoteWith(`"`, name)
+}
+
+func (sqliteDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (d sqliteDialect) Insert(db *sql.DB, t *table, names, marks []string, args []interface{}) (int64, error) {
+	res, err := db.Exec(insertSQL(d, t, names, marks, "DEFAULT VALUES"), args...)
+	if err != nil || t.PK == nil || !t.PK.AutoIncrement {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// sqliteKind - тип колонки по правилам affinity SQLite: объявить можно что угодно,
+// важны подстроки в названии типа
+func sqliteKind(declared string) columnKind {
+	t := strings.ToUpper(declared)
+	switch {
+	case strings.Contains(t, "INT"):
+		return kindInt
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return kindString
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"),
+		strings.Contains(t, "DEC"), strings.Contains(t, "NUM"):
+		return kindFloat
+	}
+	return kindString
+}
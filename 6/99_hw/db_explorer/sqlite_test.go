@@ -0,0 +1,282 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// PrepareSQLiteApis - те же items и users, что в PrepareQueryApis, но в файле SQLite
+func PrepareSQLiteApis(db *sql.DB) {
+	qs := []string{
+		`CREATE TABLE users (
+  user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  login varchar(255) NOT NULL,
+  password varchar(255) NOT NULL,
+  email varchar(255) NOT NULL,
+  info text NOT NULL,
+  updated varchar(255) DEFAULT NULL
+);`,
+
+		`INSERT INTO users (user_id, login, password, email, info, updated) VALUES
+(1,	'rvasily',	'love',	'rvasily@example.com',	'none',	NULL);`,
+
+		`CREATE TABLE items (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title varchar(255) NOT NULL,
+  description text NOT NULL,
+  updated varchar(255) DEFAULT NULL,
+  user_id int(11) DEFAULT NULL REFERENCES users
+);`,
+
+		`INSERT INTO items (id, title, description, updated, user_id) VALUES
+(1,	'database/sql',	'Рассказать про базы данных',	'rvasily',	1),
+(2,	'memcache',	'Рассказать про мемкеш с примером использования',	NULL,	NULL);`,
+	}
+
+	for _, q := range qs {
+		_, err := db.Exec(q)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// newSQLiteServer поднимает explorer над новым файлом базы во временной папке теста
+func newSQLiteServer(t *testing.T) (*httptest.Server, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "explorer.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	PrepareSQLiteApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return ts, db
+}
+
+func TestSQLiteApis(t *testing.T) {
+	ts, db := newSQLiteServer(t)
+
+	cases := []Case{
+		Case{
+			Path: "/",
+			Result: CR{
+				"response": CR{
+					"tables": []string{"items", "users"},
+				},
+			},
+		},
+		Case{
+			Path:   "/unknown_table",
+			Status: http.StatusNotFound,
+			Result: CR{"error": "unknown table"},
+		},
+		Case{
+			Path:  "/items",
+			Query: "limit=1&offset=1",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"id":          2,
+							"title":       "memcache",
+							"description": "Рассказать про мемкеш с примером использования",
+							"updated":     nil,
+							"user_id":     nil,
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/items/100500",
+			Status: http.StatusNotFound,
+			Result: CR{"error": "record not found"},
+		},
+		Case{
+			Path:   "/items/",
+			Method: http.MethodPut,
+			Body: CR{
+				"id":          42, // auto increment primary key игнорируется при вставке
+				"title":       "db_crud",
+				"description": "",
+			},
+			Result: CR{"response": CR{"id": 3}},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodPost,
+			Body: CR{
+				"description": "Написать программу db_crud",
+				"updated":     "autotests",
+			},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodPost,
+			Body:   CR{"updated": nil},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path: "/items/3",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":          3,
+						"title":       "db_crud",
+						"description": "Написать программу db_crud",
+						"updated":     nil,
+						"user_id":     nil,
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body:   CR{"id": 4},
+			Result: CR{"error": "field id have invalid type"},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body:   CR{"title": nil},
+			Result: CR{"error": "field title have invalid type"},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body:   CR{"user_id": "1"},
+			Result: CR{"error": "field user_id have invalid type"},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodDelete,
+			Result: CR{"response": CR{"deleted": 1}},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodDelete,
+			Result: CR{"response": CR{"deleted": 0}},
+		},
+		// не забываем про sql-инъекции
+		Case{
+			Path:   "/users/",
+			Method: http.MethodPut,
+			Body: CR{
+				"user_id":    2,
+				"login":      "qwerty'",
+				"password":   "love\"",
+				"unkn_field": "love",
+			},
+			Result: CR{"response": CR{"user_id": 2}},
+		},
+		Case{
+			Path:  "/users",
+			Query: "limit=1'&offset=1\"",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"user_id":  1,
+							"login":    "rvasily",
+							"password": "love",
+							"email":    "rvasily@example.com",
+							"info":     "none",
+							"updated":  nil,
+						},
+						CR{
+							"user_id":  2,
+							"login":    "qwerty'",
+							"password": "love\"",
+							"email":    "",
+							"info":     "",
+							"updated":  nil,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestSQLiteQueryApis(t *testing.T) {
+	ts, db := newSQLiteServer(t)
+	runCases(t, ts, db, queryCases())
+}
+
+func TestSQLiteSchema(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "schema.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	qs := []string{
+		`CREATE TABLE "odd ""name""" (code TEXT PRIMARY KEY, price NUMERIC NOT NULL DEFAULT 0, weight DOUBLE, raw BLOB)`,
+		`CREATE TABLE pairs (a INTEGER, b INTEGER, PRIMARY KEY (a, b))`,
+		`CREATE TABLE refs (id INTEGER PRIMARY KEY, code TEXT REFERENCES "odd ""name""" (code), a INTEGER, b INTEGER,
+			FOREIGN KEY (a, b) REFERENCES pairs (a, b))`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	e, err := newDbExplorer(db, dialectOf(db))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := e.d.(sqliteDialect); !ok {
+		t.Fatalf("expected sqlite dialect, got %T", e.d)
+	}
+
+	odd := e.tables[`odd "name"`]
+	if odd == nil || odd.PK == nil || odd.PK.Name != "code" || odd.PK.AutoIncrement {
+		t.Fatalf("unexpected table %#v", odd)
+	}
+	price := odd.byName["price"]
+	if price.Kind != kindFloat || price.Nullable || !price.HasDefault {
+		t.Errorf("unexpected price column %#v", price)
+	}
+	if odd.byName["weight"].Kind != kindFloat || odd.byName["raw"].Kind != kindString {
+		t.Errorf("unexpected kinds %#v", odd.Columns)
+	}
+
+	// составной ключ - ни PK, ни автоинкремента, ни раскрытия
+	if pairs := e.tables["pairs"]; pairs.PK != nil || pairs.byName["a"].AutoIncrement {
+		t.Errorf("unexpected table %#v", pairs)
+	}
+	refs := e.tables["refs"]
+	if !refs.PK.AutoIncrement {
+		t.Errorf("INTEGER PRIMARY KEY must be auto increment")
+	}
+	if ref := refs.byName["code"].Ref; ref == nil || *ref != (reference{Table: `odd "name"`, Column: "code"}) {
+		t.Errorf("unexpected reference %#v", ref)
+	}
+	if refs.byName["a"].Ref != nil || refs.byName["b"].Ref != nil {
+		t.Errorf("composite foreign key must not be expanded")
+	}
+}
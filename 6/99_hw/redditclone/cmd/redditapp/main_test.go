@@ -254,6 +254,9 @@ func cleanupDBs(a *Application) {
 		id int(11) unsigned NOT NULL AUTO_INCREMENT,
 		password  VARBINARY(100) NOT NULL,
 		username VARCHAR(50) NOT NULL,
+		bio VARCHAR(500) NOT NULL DEFAULT '',
+		avatar_url VARCHAR(2048) NOT NULL DEFAULT '',
+		created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		PRIMARY KEY (id)
 	) ENGINE=INNODB DEFAULT CHARSET=utf8;`}
 
@@ -307,7 +310,7 @@ func startServer() {
 		MongoDBName:             "redditclone_db",
 		MongoPostsCollection:    "posts",
 		MongoCommentsCollection: "comments",
-		MySQLConnectionString:   "root:qwer1234@tcp(localhost:3306)/redditclone",
+		MySQLConnectionString:   "root:qwer1234@tcp(localhost:3306)/redditclone?parseTime=true",
 		RedisOptions: &redis.Options{
 			Addr:     "localhost:6379",
 			Password: "redis",
@@ -317,6 +320,8 @@ func startServer() {
 
 		PrivateKeyLocation: "../../key.rsa",
 		PublicKeyLocation:  "../../key.rsa.pub",
+
+		ValidateAPI: true,
 	}
 
 	cleanupDBs(a)
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"redditclone/pkg/handlers"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRoutesDocumented(t *testing.T) {
+	spec := handlers.OpenAPISpec()
+	openAPIHandler, err := handlers.NewOpenAPIHandler(spec)
+	if err != nil {
+		t.Fatalf("can't render spec: %v", err)
+	}
+
+	r := newRouter(&handlers.UserHandler{}, &handlers.PostHandler{}, &handlers.CommentHandler{}, openAPIHandler)
+
+	registered := map[string]bool{}
+	err = r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, "/api/") {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			// subrouter prefix
+			return nil
+		}
+
+		for _, m := range methods {
+			registered[m+" "+path] = true
+			if spec.Operation(path, m) == nil {
+				t.Errorf("route %s %s is not described in openapi spec", m, path)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for path, item := range spec.Paths {
+		for method := range *item {
+			if !registered[strings.ToUpper(method)+" "+path] {
+				t.Errorf("spec describes %s %s, but it is not registered", method, path)
+			}
+		}
+	}
+}
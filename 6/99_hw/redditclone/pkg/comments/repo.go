@@ -1,6 +1,14 @@
 package comments
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"redditclone/pkg/user"
+	"strconv"
+	"sync"
+)
+
+var ErrNotFound = errors.New("comment not found")
 
 type MemoryCommentsRepo struct {
 	lastID uint64
@@ -12,49 +20,82 @@ func NewRepo() *MemoryCommentsRepo {
 	return &MemoryCommentsRepo{data: make([]*Comment, 0, 10), mu: &sync.Mutex{}}
 }
 
-func (repo *MemoryCommentsRepo) GetAll() ([]*Comment, error) {
-	return repo.data, nil
+func (repo *MemoryCommentsRepo) GetAll(ctx context.Context) ([]*Comment, error) {
+	return repo.getComments(func(c *Comment) bool { return true })
 }
 
-func (repo *MemoryCommentsRepo) GetByID(id uint64) (*Comment, error) {
-	return nil, nil
-}
+func (repo *MemoryCommentsRepo) GetByID(ctx context.Context, id interface{}) (*Comment, error) {
+	res, err := repo.getComments(func(c *Comment) bool { return c.ID == id })
+	if err != nil {
+		return nil, err
+	}
 
-func (repo *MemoryCommentsRepo) GetByPostID(id interface{}) ([]*Comment, error) {
-	res := make([]*Comment, 0)
-	for _, c := range repo.data {
-		if c.PostID == id {
-			res = append(res, c)
-		}
+	if len(res) == 0 {
+		return nil, ErrNotFound
 	}
 
-	return res, nil
+	return res[0], nil
+}
+
+func (repo *MemoryCommentsRepo) GetByPostID(ctx context.Context, id interface{}) ([]*Comment, error) {
+	return repo.getComments(func(c *Comment) bool { return c.PostID == id })
 }
 
-func (repo *MemoryCommentsRepo) Add(comment *Comment) (uint64, error) {
+func (repo *MemoryCommentsRepo) GetByAuthorID(ctx context.Context, authorID int64) ([]*Comment, error) {
+	return repo.getComments(func(c *Comment) bool { return c.AuthorID == authorID })
+}
+
+func (repo *MemoryCommentsRepo) Add(ctx context.Context, comment *Comment) (interface{}, error) {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
 	repo.lastID++
-	id := repo.lastID
-	comment.ID = id
-	repo.data = append(repo.data, comment)
-	return id, nil
+	comment.ID = repo.lastID
+	c := *comment
+	repo.data = append(repo.data, &c)
+	return comment.ID, nil
 }
 
-func (repo *MemoryCommentsRepo) Update(comment *Comment) (bool, error) {
+func (repo *MemoryCommentsRepo) Delete(ctx context.Context, id interface{}) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for i, c := range repo.data {
+		if c.ID == id {
+			repo.data = append(repo.data[:i], repo.data[i+1:]...)
+			return true, nil
+		}
+	}
+
 	return false, nil
 }
 
-func (repo *MemoryCommentsRepo) Delete(commentID uint64) (bool, error) {
-	// repo.mu.Lock()
-	// defer repo.mu.Unlock()
-	// for i, p := range repo.data {
-	// 	if p.AuthorID == postID && p.ID == commentID {
-	// 		repo.data[i] = repo.data[len(repo.data)-1]
-	// 		repo.data = repo.data[:len(repo.data)-1]
-	// 		return true, nil
-	// 	}
-	// }
+func (repo *MemoryCommentsRepo) AnonymizeAuthor(ctx context.Context, authorID int64) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	var n int64
+	for _, c := range repo.data {
+		if c.AuthorID == authorID {
+			c.AuthorID = user.DeletedID
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (repo *MemoryCommentsRepo) ParseID(in string) (interface{}, error) {
+	return strconv.ParseUint(in, 10, 0)
+}
 
-	return false, nil
+func (repo *MemoryCommentsRepo) getComments(filter func(*Comment) bool) ([]*Comment, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	res := make([]*Comment, 0)
+	for _, c := range repo.data {
+		if filter(c) {
+			copied := *c
+			res = append(res, &copied)
+		}
+	}
+
+	return res, nil
 }
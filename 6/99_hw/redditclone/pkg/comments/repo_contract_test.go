@@ -0,0 +1,47 @@
+package comments_test
+
+import (
+	"context"
+	"os"
+	"redditclone/pkg/comments"
+	"redditclone/pkg/handlers"
+	"redditclone/pkg/repotest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMemoryCommentsRepoContract(t *testing.T) {
+	repotest.RunCommentsRepo(t, func(t *testing.T) handlers.CommentsRepo {
+		return comments.NewRepo()
+	})
+}
+
+// TestMongoCommentsRepoContract needs a running mongo, e.g.
+// REDDITCLONE_TEST_MONGO=mongodb://localhost:27017 go test ./pkg/comments/
+func TestMongoCommentsRepoContract(t *testing.T) {
+	uri := os.Getenv("REDDITCLONE_TEST_MONGO")
+	if uri == "" {
+		t.Skip("REDDITCLONE_TEST_MONGO is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("can't connect to mongo: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database("redditclone_test")
+	repotest.RunCommentsRepo(t, func(t *testing.T) handlers.CommentsRepo {
+		err := db.Collection("comments").Drop(context.Background())
+		if err != nil {
+			t.Fatalf("can't drop comments collection: %v", err)
+		}
+
+		return comments.NewCommentsRepoMongo(db)
+	})
+}
@@ -3,10 +3,12 @@ package comments
 import (
 	"context"
 	"redditclone/pkg/common"
+	"redditclone/pkg/user"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type CommentRepoMongo struct {
@@ -18,13 +20,21 @@ func NewCommentsRepoMongo(db *mongo.Database) *CommentRepoMongo {
 }
 
 func (repo *CommentRepoMongo) GetByPostID(ctx context.Context, id interface{}) ([]*Comment, error) {
-	cur, err := repo.collection.Find(ctx, bson.M{"postID": id})
-	defer cur.Close(ctx)
+	return repo.getByField(ctx, bson.M{"postID": id})
+}
+
+func (repo *CommentRepoMongo) GetByAuthorID(ctx context.Context, authorID int64) ([]*Comment, error) {
+	return repo.getByField(ctx, bson.M{"authorID": authorID})
+}
 
+func (repo *CommentRepoMongo) getByField(ctx context.Context, filter bson.M) ([]*Comment, error) {
+	cur, err := repo.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created", Value: 1}}))
 	if err != nil {
 		return nil, err
 	}
 
+	defer cur.Close(ctx)
+
 	var comments []*Comment
 	err = cur.All(ctx, &comments)
 	if err != nil {
@@ -35,8 +45,17 @@ func (repo *CommentRepoMongo) GetByPostID(ctx context.Context, id interface{}) (
 }
 
 func (repo *CommentRepoMongo) GetByID(ctx context.Context, id interface{}) (*Comment, error) {
-	// not implemented
-	return nil, nil
+	res := repo.collection.FindOne(ctx, bson.M{"_id": id})
+	comment := &Comment{}
+	err := res.Decode(comment)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
 }
 
 func (repo *CommentRepoMongo) Add(ctx context.Context, comment *Comment) (interface{}, error) {
@@ -61,6 +80,19 @@ func (repo *CommentRepoMongo) Delete(ctx context.Context, id interface{}) (bool,
 	return true, nil
 }
 
+// AnonymizeAuthor hands comments of a deleted account over to user.DeletedID
+func (repo *CommentRepoMongo) AnonymizeAuthor(ctx context.Context, authorID int64) (int64, error) {
+	res, err := repo.collection.UpdateMany(ctx, bson.M{"authorID": authorID},
+		bson.D{
+			{Key: "$set", Value: bson.D{{Key: "authorID", Value: user.DeletedID}}},
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.GetModifiedCount(), nil
+}
+
 func (repo *CommentRepoMongo) ParseID(in string) (interface{}, error) {
 	return primitive.ObjectIDFromHex(in)
 }
@@ -3,6 +3,7 @@ package comments
 import (
 	"context"
 	"redditclone/pkg/common"
+	"redditclone/pkg/user"
 	"reflect"
 	"testing"
 	"time"
@@ -42,6 +43,14 @@ var cases = []Case{
 		},
 		expectedResult: expectedComments,
 	},
+	{
+		name: "GetByAuthorIDHappyCase",
+		cond: bson.M{"authorID": int64(1)},
+		f: func(ctx context.Context, r *CommentRepoMongo) (interface{}, error) {
+			return r.GetByAuthorID(ctx, 1)
+		},
+		expectedResult: expectedComments,
+	},
 	{
 		name: "InsertCommentHappyCase",
 		cond: bson.M{"postID": id},
@@ -74,7 +83,7 @@ func TestGetByPostID(t *testing.T) {
 
 		// GetByPostID
 
-		mockCollection.EXPECT().Find(ctx, gomock.Eq(expectedFilter)).Return(mockCursor, nil)
+		mockCollection.EXPECT().Find(ctx, gomock.Eq(expectedFilter), gomock.Any()).Return(mockCursor, nil)
 		mockCursor.EXPECT().All(ctx, gomock.AssignableToTypeOf(&expectedComments)).
 			SetArg(1, expectedComments).Return(nil)
 		mockCursor.EXPECT().Close(ctx).Return(nil)
@@ -101,3 +110,24 @@ func TestGetByPostID(t *testing.T) {
 		}
 	}
 }
+
+func TestAnonymizeAuthor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCollection := common.NewMockCollectionHelper(ctrl)
+	mockUpdateResult := common.NewMockUpdateResultHelper(ctrl)
+	repo := &CommentRepoMongo{collection: mockCollection}
+
+	ctx := context.Background()
+	filter := bson.M{"authorID": int64(1)}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "authorID", Value: user.DeletedID}}}}
+	mockCollection.EXPECT().UpdateMany(ctx, gomock.Eq(filter), gomock.Eq(update)).Return(mockUpdateResult, nil)
+	mockUpdateResult.EXPECT().GetModifiedCount().Return(int64(3))
+
+	n, err := repo.AnonymizeAuthor(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected %v, but was %v", 3, n)
+	}
+}
@@ -19,6 +19,8 @@ type CollectionHelper interface {
 		opts ...*options.InsertOneOptions) (InsertOneResultHelper, error)
 	UpdateOne(ctx context.Context, filter interface{}, update interface{},
 		opts ...*options.UpdateOptions) (UpdateResultHelper, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{},
+		opts ...*options.UpdateOptions) (UpdateResultHelper, error)
 	DeleteOne(ctx context.Context, filter interface{},
 		opts ...*options.DeleteOptions) (DeleteResultHelper, error)
 }
@@ -37,6 +39,7 @@ type InsertOneResultHelper interface {
 }
 
 type UpdateResultHelper interface {
+	GetMatchedCount() int64
 	GetModifiedCount() int64
 }
 
@@ -106,6 +109,12 @@ func (mc *MongoCollection) UpdateOne(ctx context.Context, filter interface{}, up
 	return &MongoUpdateResult{res: res}, err
 }
 
+func (mc *MongoCollection) UpdateMany(ctx context.Context, filter interface{}, update interface{},
+	opts ...*options.UpdateOptions) (UpdateResultHelper, error) {
+	res, err := mc.Collection.UpdateMany(ctx, filter, update, opts...)
+	return &MongoUpdateResult{res: res}, err
+}
+
 func (mc *MongoCollection) DeleteOne(ctx context.Context, filter interface{},
 	opts ...*options.DeleteOptions) (DeleteResultHelper, error) {
 	res, err := mc.Collection.DeleteOne(ctx, filter, opts...)
@@ -124,6 +133,10 @@ type MongoUpdateResult struct {
 	res *mongo.UpdateResult
 }
 
+func (r *MongoUpdateResult) GetMatchedCount() int64 {
+	return r.res.MatchedCount
+}
+
 func (r *MongoUpdateResult) GetModifiedCount() int64 {
 	return r.res.ModifiedCount
 }
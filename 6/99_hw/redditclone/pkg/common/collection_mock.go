@@ -132,6 +132,26 @@ func (mr *MockCollectionHelperMockRecorder) UpdateOne(ctx, filter, update interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOne", reflect.TypeOf((*MockCollectionHelper)(nil).UpdateOne), varargs...)
 }
 
+// UpdateMany mocks base method
+func (m *MockCollectionHelper) UpdateMany(ctx context.Context, filter, update interface{}, opts ...*options.UpdateOptions) (UpdateResultHelper, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, filter, update}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateMany", varargs...)
+	ret0, _ := ret[0].(UpdateResultHelper)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateMany indicates an expected call of UpdateMany
+func (mr *MockCollectionHelperMockRecorder) UpdateMany(ctx, filter, update interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, filter, update}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMany", reflect.TypeOf((*MockCollectionHelper)(nil).UpdateMany), varargs...)
+}
+
 // DeleteOne mocks base method
 func (m *MockCollectionHelper) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (DeleteResultHelper, error) {
 	m.ctrl.T.Helper()
@@ -300,6 +320,20 @@ func (m *MockUpdateResultHelper) EXPECT() *MockUpdateResultHelperMockRecorder {
 	return m.recorder
 }
 
+// GetMatchedCount mocks base method
+func (m *MockUpdateResultHelper) GetMatchedCount() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMatchedCount")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// GetMatchedCount indicates an expected call of GetMatchedCount
+func (mr *MockUpdateResultHelperMockRecorder) GetMatchedCount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMatchedCount", reflect.TypeOf((*MockUpdateResultHelper)(nil).GetMatchedCount))
+}
+
 // GetModifiedCount mocks base method
 func (m *MockUpdateResultHelper) GetModifiedCount() int64 {
 	m.ctrl.T.Helper()
@@ -27,8 +27,10 @@ type AddCommentRequest struct {
 type CommentsRepo interface {
 	GetByPostID(context.Context, interface{}) ([]*comments.Comment, error)
 	GetByID(context.Context, interface{}) (*comments.Comment, error)
+	GetByAuthorID(context.Context, int64) ([]*comments.Comment, error)
 	Add(context.Context, *comments.Comment) (interface{}, error)
 	Delete(context.Context, interface{}) (bool, error)
+	AnonymizeAuthor(context.Context, int64) (int64, error)
 
 	ParseID(in string) (interface{}, error)
 }
@@ -108,3 +108,33 @@ func (mr *MockCommentsRepoMockRecorder) ParseID(in interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseID", reflect.TypeOf((*MockCommentsRepo)(nil).ParseID), in)
 }
+
+// GetByAuthorID mocks base method
+func (m *MockCommentsRepo) GetByAuthorID(arg0 context.Context, arg1 int64) ([]*comments.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByAuthorID", arg0, arg1)
+	ret0, _ := ret[0].([]*comments.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByAuthorID indicates an expected call of GetByAuthorID
+func (mr *MockCommentsRepoMockRecorder) GetByAuthorID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByAuthorID", reflect.TypeOf((*MockCommentsRepo)(nil).GetByAuthorID), arg0, arg1)
+}
+
+// AnonymizeAuthor mocks base method
+func (m *MockCommentsRepo) AnonymizeAuthor(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnonymizeAuthor", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AnonymizeAuthor indicates an expected call of AnonymizeAuthor
+func (mr *MockCommentsRepoMockRecorder) AnonymizeAuthor(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnonymizeAuthor", reflect.TypeOf((*MockCommentsRepo)(nil).AnonymizeAuthor), arg0, arg1)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"redditclone/pkg/comments"
 	"redditclone/pkg/posts"
+	"redditclone/pkg/user"
 	"strconv"
 	"time"
 
@@ -63,7 +64,17 @@ func getPostData(p *posts.Post, ur UsersRepo, cr CommentsRepo) (*PostResponse, e
 		return nil, err
 	}
 
-	return MapToPostResponse(p, author.ID, author.Username, comments, ur)
+	a := authorOf(author)
+	return MapToPostResponse(p, a.ID, a.Username, comments, ur)
+}
+
+// authorOf maps users missing in the repo, i.e. deleted accounts, to an anonymous author
+func authorOf(u *user.User) *Author {
+	if u == nil {
+		return &Author{Username: user.DeletedUsername, ID: user.DeletedID}
+	}
+
+	return &Author{Username: u.Username, ID: u.ID}
 }
 
 func MapToPostResponse(post *posts.Post, authorID int64, authorName string, comments []*comments.Comment, usersRepo UsersRepo) (*PostResponse, error) {
@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"net/http"
+	"redditclone/pkg/openapi"
+	"strconv"
+)
+
+// OpenAPIHandler serves the API description, the document is rendered once
+type OpenAPIHandler struct {
+	Spec *openapi.Document
+	body []byte
+}
+
+func NewOpenAPIHandler(spec *openapi.Document) (*OpenAPIHandler, error) {
+	body, err := spec.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAPIHandler{Spec: spec, body: body}, nil
+}
+
+func (h *OpenAPIHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.body)
+}
+
+// OpenAPISpec describes every /api route registered in cmd/redditapp.
+// Schemas follow the json tags of request and response structs of this package
+// and the rules of their validate methods.
+func OpenAPISpec() *openapi.Document {
+	return &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info:    &openapi.Info{Title: "redditclone", Version: "1.0.0"},
+		Components: &openapi.Components{
+			Schemas: specSchemas(),
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		Paths: map[string]*openapi.PathItem{
+			"/api/login": {
+				"post": {
+					OperationID: "login",
+					RequestBody: jsonBody("AuthReq"),
+					Responses: responses(
+						http.StatusOK, "AuthResponse",
+						http.StatusBadRequest, "Response",
+						http.StatusUnauthorized, "Response",
+						http.StatusUnprocessableEntity, "ErrorsResponse",
+					),
+				},
+			},
+			"/api/register": {
+				"post": {
+					OperationID: "register",
+					RequestBody: jsonBody("AuthReq"),
+					Responses: responses(
+						http.StatusCreated, "AuthResponse",
+						http.StatusBadRequest, "Response",
+						http.StatusUnprocessableEntity, "ErrorsResponse",
+					),
+				},
+			},
+			"/api/posts/": {
+				"get": {
+					OperationID: "getPosts",
+					Responses:   responses(http.StatusOK, "PostsList"),
+				},
+			},
+			"/api/posts": {
+				"post": {
+					OperationID: "createPost",
+					RequestBody: jsonBody("CreatePostReq"),
+					Security:    bearer(),
+					Responses: responses(
+						http.StatusCreated, "PostResponse",
+						http.StatusBadRequest, "Response",
+						http.StatusUnauthorized, "Response",
+						http.StatusUnprocessableEntity, "ErrorsResponse",
+					),
+				},
+			},
+			"/api/posts/{category}": {
+				"get": {
+					OperationID: "getPostsByCategory",
+					Parameters:  pathParams("category"),
+					Responses:   responses(http.StatusOK, "PostsList"),
+				},
+			},
+			"/api/post/{id}": {
+				"get": {
+					OperationID: "getPost",
+					Parameters:  pathParams("id"),
+					Responses: responses(
+						http.StatusOK, "PostResponse",
+						http.StatusBadRequest, "Response",
+						http.StatusNotFound, "Response",
+					),
+				},
+				"delete": {
+					OperationID: "deletePost",
+					Parameters:  pathParams("id"),
+					Responses: responses(
+						http.StatusOK, "Response",
+						http.StatusBadRequest, "Response",
+						http.StatusNotFound, "Response",
+					),
+				},
+			},
+			"/api/user/{username}": {
+				"get": {
+					OperationID: "getUserPosts",
+					Summary:     "Posts of the user, the route the frontend uses for the user page",
+					Parameters:  pathParams("username"),
+					Responses: responses(
+						http.StatusOK, "PostsList",
+						http.StatusNotFound, "Response",
+					),
+				},
+				"delete": {
+					OperationID: "deleteAccount",
+					Parameters:  pathParams("username"),
+					RequestBody: jsonBody("DeleteAccountReq"),
+					Security:    bearer(),
+					Responses:   accountResponses(http.StatusOK, "Response"),
+				},
+			},
+			"/api/user/{username}/profile": {
+				"get": {
+					OperationID: "getUser",
+					Summary:     "Profile of the user with their posts and comments",
+					Parameters:  pathParams("username"),
+					Responses: responses(
+						http.StatusOK, "UserResponse",
+						http.StatusNotFound, "Response",
+					),
+				},
+				"put": {
+					OperationID: "updateProfile",
+					Parameters:  pathParams("username"),
+					RequestBody: jsonBody("UpdateProfileReq"),
+					Security:    bearer(),
+					Responses:   accountResponses(http.StatusOK, "Profile"),
+				},
+			},
+			"/api/user/{username}/password": {
+				"post": {
+					OperationID: "changePassword",
+					Parameters:  pathParams("username"),
+					RequestBody: jsonBody("ChangePasswordReq"),
+					Security:    bearer(),
+					Responses:   accountResponses(http.StatusOK, "AuthResponse"),
+				},
+			},
+			"/api/post/{post_id}/upvote":   voteItem("upvote"),
+			"/api/post/{post_id}/downvote": voteItem("downvote"),
+			"/api/post/{post_id}/unvote":   voteItem("unvote"),
+			"/api/post/{post_id}": {
+				"post": {
+					OperationID: "addComment",
+					Parameters:  pathParams("post_id"),
+					RequestBody: jsonBody("AddCommentRequest"),
+					Security:    bearer(),
+					Responses: responses(
+						http.StatusCreated, "PostResponse",
+						http.StatusBadRequest, "Response",
+						http.StatusUnauthorized, "Response",
+					),
+				},
+			},
+			"/api/post/{post_id}/{comment_id}": {
+				"delete": {
+					OperationID: "deleteComment",
+					Parameters:  pathParams("post_id", "comment_id"),
+					Responses: responses(
+						http.StatusOK, "PostResponse",
+						http.StatusBadRequest, "Response",
+						http.StatusNotFound, "Response",
+					),
+				},
+			},
+			"/api/openapi.json": {
+				"get": {
+					OperationID: "getOpenAPI",
+					Responses: map[string]*openapi.Response{
+						"200": {Description: "this document", Content: openapi.JSONContent(&openapi.Schema{Type: "object"})},
+					},
+				},
+			},
+		},
+	}
+}
+
+func specSchemas() map[string]*openapi.Schema {
+	str := func() *openapi.Schema { return &openapi.Schema{Type: "string"} }
+	integer := func() *openapi.Schema { return &openapi.Schema{Type: "integer"} }
+
+	return map[string]*openapi.Schema{
+		"Response": {
+			Type:       "object",
+			Required:   []string{"message"},
+			Properties: map[string]*openapi.Schema{"message": str()},
+		},
+		"CustomError": {
+			Type:     "object",
+			Required: []string{"location", "param", "msg"},
+			Properties: map[string]*openapi.Schema{
+				"location": str(),
+				"param":    str(),
+				"value":    str(),
+				"msg":      str(),
+			},
+		},
+		"ErrorsResponse": {
+			Type:       "object",
+			Required:   []string{"errors"},
+			Properties: map[string]*openapi.Schema{"errors": {Type: "array", Items: openapi.Ref("CustomError")}},
+		},
+		"AuthReq": {
+			Type:     "object",
+			Required: []string{"username", "password"},
+			Properties: map[string]*openapi.Schema{
+				"username": {Type: "string", MinLength: openapi.Int(1), MaxLength: openapi.Int(32), Pattern: "^[a-zA-Z0-9_-]+$"},
+				"password": {Type: "string", MinLength: openapi.Int(8), MaxLength: openapi.Int(72)},
+			},
+		},
+		"AuthResponse": {
+			Type:       "object",
+			Required:   []string{"token"},
+			Properties: map[string]*openapi.Schema{"token": str()},
+		},
+		"CreatePostReq": {
+			Type:     "object",
+			Required: []string{"category", "type", "title"},
+			Properties: map[string]*openapi.Schema{
+				"category": {Type: "string", MinLength: openapi.Int(1)},
+				"type":     {Type: "string", Enum: []interface{}{"text", "link"}},
+				"title":    {Type: "string", MinLength: openapi.Int(1), MaxLength: openapi.Int(100)},
+				"url":      {Type: "string", Format: "uri"},
+				"text":     {Type: "string", MinLength: openapi.Int(4)},
+			},
+		},
+		"AddCommentRequest": {
+			Type:       "object",
+			Required:   []string{"comment"},
+			Properties: map[string]*openapi.Schema{"comment": str()},
+		},
+		"Author": {
+			Type:     "object",
+			Required: []string{"username", "id"},
+			Properties: map[string]*openapi.Schema{
+				"username": str(),
+				"id":       integer(),
+			},
+		},
+		"Vote": {
+			Type:     "object",
+			Required: []string{"user", "vote"},
+			Properties: map[string]*openapi.Schema{
+				"user": integer(),
+				"vote": {Type: "integer", Enum: []interface{}{-1, 1}},
+			},
+		},
+		"CommentResponse": {
+			Type:     "object",
+			Required: []string{"created", "author", "body", "id"},
+			Properties: map[string]*openapi.Schema{
+				"created": {Type: "string", Format: "date-time"},
+				"author":  openapi.Ref("Author"),
+				"body":    str(),
+				"id":      str(),
+			},
+		},
+		"PostResponse": {
+			Type: "object",
+			Required: []string{"score", "views", "type", "title", "author", "category",
+				"votes", "comments", "created", "upvotePercentage", "id"},
+			Properties: map[string]*openapi.Schema{
+				"score":            integer(),
+				"views":            {Type: "integer", Minimum: openapi.Float(0)},
+				"type":             {Type: "string", Enum: []interface{}{"text", "link"}},
+				"title":            str(),
+				"author":           openapi.Ref("Author"),
+				"category":         str(),
+				"url":              {Type: "string", Format: "uri"},
+				"text":             str(),
+				"votes":            {Type: "array", Items: openapi.Ref("Vote")},
+				"comments":         {Type: "array", Items: openapi.Ref("CommentResponse")},
+				"created":          {Type: "string", Format: "date-time"},
+				"upvotePercentage": {Type: "integer", Minimum: openapi.Float(0), Maximum: openapi.Float(100)},
+				"id":               str(),
+			},
+		},
+		"PostsList": {Type: "array", Items: openapi.Ref("PostResponse")},
+		"Profile": {
+			Type:     "object",
+			Required: []string{"id", "username", "bio", "avatarUrl", "created", "karma"},
+			Properties: map[string]*openapi.Schema{
+				"id":        integer(),
+				"username":  str(),
+				"bio":       str(),
+				"avatarUrl": str(),
+				"created":   {Type: "string", Format: "date-time"},
+				"karma":     integer(),
+			},
+		},
+		"UserComment": {
+			Type:     "object",
+			Required: []string{"created", "body", "id", "postId"},
+			Properties: map[string]*openapi.Schema{
+				"created": {Type: "string", Format: "date-time"},
+				"body":    str(),
+				"id":      str(),
+				"postId":  str(),
+			},
+		},
+		"UserResponse": {
+			Type:     "object",
+			Required: []string{"profile", "posts", "comments"},
+			Properties: map[string]*openapi.Schema{
+				"profile":  openapi.Ref("Profile"),
+				"posts":    openapi.Ref("PostsList"),
+				"comments": {Type: "array", Items: openapi.Ref("UserComment")},
+			},
+		},
+		"UpdateProfileReq": {
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"bio": {Type: "string", MaxLength: openapi.Int(500)},
+				// empty string removes the avatar, so no uri format here
+				"avatarUrl": {Type: "string", MaxLength: openapi.Int(2048)},
+			},
+		},
+		"ChangePasswordReq": {
+			Type:     "object",
+			Required: []string{"oldPassword", "newPassword"},
+			Properties: map[string]*openapi.Schema{
+				"oldPassword": str(),
+				"newPassword": {Type: "string", MinLength: openapi.Int(8), MaxLength: openapi.Int(72)},
+			},
+		},
+		"DeleteAccountReq": {
+			Type:       "object",
+			Required:   []string{"password"},
+			Properties: map[string]*openapi.Schema{"password": str()},
+		},
+	}
+}
+
+func voteItem(action string) *openapi.PathItem {
+	return &openapi.PathItem{
+		"get": {
+			OperationID: action,
+			Parameters:  pathParams("post_id"),
+			Security:    bearer(),
+			Responses: responses(
+				http.StatusOK, "PostResponse",
+				http.StatusBadRequest, "Response",
+				http.StatusUnauthorized, "Response",
+				http.StatusNotFound, "Response",
+			),
+		},
+	}
+}
+
+// accountResponses adds failures shared by routes changing an account of the session owner
+func accountResponses(status int, schema string) map[string]*openapi.Response {
+	return responses(
+		status, schema,
+		http.StatusBadRequest, "Response",
+		http.StatusUnauthorized, "Response",
+		http.StatusForbidden, "Response",
+		http.StatusNotFound, "Response",
+		http.StatusUnprocessableEntity, "ErrorsResponse",
+	)
+}
+
+func jsonBody(schema string) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: openapi.JSONContent(openapi.Ref(schema))}
+}
+
+func pathParams(names ...string) []*openapi.Parameter {
+	res := make([]*openapi.Parameter, 0, len(names))
+	for _, n := range names {
+		res = append(res, &openapi.Parameter{Name: n, In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}})
+	}
+
+	return res
+}
+
+func bearer() []map[string][]string {
+	return []map[string][]string{{"bearer": {}}}
+}
+
+// responses takes pairs of status and schema name,
+// every operation may also fail with an empty 500
+func responses(pairs ...interface{}) map[string]*openapi.Response {
+	res := map[string]*openapi.Response{
+		strconv.Itoa(http.StatusInternalServerError): {Description: http.StatusText(http.StatusInternalServerError)},
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		status := pairs[i].(int)
+		res[strconv.Itoa(status)] = &openapi.Response{
+			Description: http.StatusText(status),
+			Content:     openapi.JSONContent(openapi.Ref(pairs[i+1].(string))),
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"redditclone/pkg/openapi"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+// checkSchema marshals v the way handlers do and validates it against the named schema
+func checkSchema(t *testing.T, spec *openapi.Document, schema string, v interface{}) {
+	t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("can't marshal %s: %v", schema, err)
+	}
+
+	var value interface{}
+	if err = json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("can't unmarshal %s: %v", schema, err)
+	}
+
+	for _, e := range spec.Validate(openapi.Ref(schema), value) {
+		t.Errorf("%s does not match spec: %v", schema, e)
+	}
+}
+
+func TestResponsesMatchSpec(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usersRepoMock := NewMockUsersRepo(ctrl)
+	for _, u := range testUserData {
+		usersRepoMock.EXPECT().GetByID(u.ID).Return(u, nil).AnyTimes()
+	}
+
+	spec := OpenAPISpec()
+	for _, p := range testPostData {
+		resp, err := MapToPostResponse(p, p.AuthorID, "test", testCommentData, usersRepoMock)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		checkSchema(t, spec, "PostResponse", resp)
+	}
+
+	checkSchema(t, spec, "PostsList", []*PostResponse{})
+	checkSchema(t, spec, "Response", &Response{Message: "success"})
+	checkSchema(t, spec, "AuthResponse", &AuthResponse{Token: "token"})
+
+	profile := mapToProfile(testUserData[0], testPostData)
+	checkSchema(t, spec, "Profile", profile)
+	c := testCommentData[0]
+	checkSchema(t, spec, "UserResponse", &UserResponse{
+		Profile:  profile,
+		Posts:    []*PostResponse{},
+		Comments: []*UserCommentResponse{{Created: c.Created, Body: c.Body, ID: c.ID, PostID: c.PostID}},
+	})
+
+	username, password := "", "short"
+	errs := (&AuthReq{Username: &username, Password: &password}).validate()
+	checkSchema(t, spec, "ErrorsResponse", &ErrorsResponse{Errors: errs})
+}
+
+func TestRequestsMatchSpec(t *testing.T) {
+	spec := OpenAPISpec()
+
+	username, password := "test_user", "test_password"
+	checkSchema(t, spec, "AuthReq", &AuthReq{Username: &username, Password: &password})
+	checkSchema(t, spec, "AddCommentRequest", &AddCommentRequest{Comment: "comment"})
+
+	bio, avatar, newPassword := "gopher", "", "new_password"
+	checkSchema(t, spec, "UpdateProfileReq", &UpdateProfileReq{Bio: &bio, AvatarURL: &avatar})
+	checkSchema(t, spec, "ChangePasswordReq", &ChangePasswordReq{OldPassword: &password, NewPassword: &newPassword})
+	checkSchema(t, spec, "DeleteAccountReq", &DeleteAccountReq{Password: &password})
+
+	category, title, url := "news", "title", "https://news.mail.ru/"
+	postType := newPost.Type
+	// CreatePostReq has no json tags, frontend sends lowercase keys
+	checkSchema(t, spec, "CreatePostReq", map[string]*string{
+		"category": &category,
+		"type":     (*string)(&postType),
+		"title":    &title,
+		"url":      &url,
+	})
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	h, err := NewOpenAPIHandler(OpenAPISpec())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	h.Get(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %v but was %v", http.StatusOK, w.Code)
+	}
+
+	doc := &openapi.Document{}
+	if err = json.Unmarshal(w.Body.Bytes(), doc); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if doc.OpenAPI != openapi.Version || doc.Operation("/api/posts", http.MethodPost) == nil {
+		t.Errorf("unexpected document: %s", w.Body.String())
+	}
+}
@@ -35,6 +35,7 @@ type PostsRepo interface {
 	Upvote(context.Context, interface{}, int64) (*posts.Post, error)
 	DownVote(context.Context, interface{}, int64) (*posts.Post, error)
 	Unvote(context.Context, interface{}, int64) (*posts.Post, error)
+	AnonymizeAuthor(context.Context, int64) (int64, error)
 
 	ParseID(string) (interface{}, error)
 }
@@ -82,7 +83,7 @@ func mapToCommentsResponse(comments []*comments.Comment, usersRepo UsersRepo) ([
 		if err != nil {
 			return nil, err
 		}
-		mapped := &CommentResponse{Created: c.Created, Author: &Author{Username: author.Username, ID: author.ID}, Body: c.Body, ID: c.ID}
+		mapped := &CommentResponse{Created: c.Created, Author: authorOf(author), Body: c.Body, ID: c.ID}
 		result = append(result, mapped)
 	}
 
@@ -184,6 +185,10 @@ func (h *PostHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	post, err := h.PostsRepo.GetByID(ctx, id)
+	if err == posts.ErrNotFound {
+		WriteResponse(w, "post not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		h.Logger.Error(err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
@@ -329,9 +334,19 @@ func (h *PostHandler) GetByUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user == nil {
+		WriteResponse(w, "user not found", http.StatusNotFound)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	posts, err := h.PostsRepo.GetByAuthorID(ctx, user.ID)
+	if err != nil {
+		h.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
 	postsWithData, err := h.getPostsWithData(posts)
 	if err != nil {
@@ -438,7 +453,10 @@ func (h *PostHandler) vote(w http.ResponseWriter, r *http.Request,
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	post, err := voteRepo(ctx, id, sess.User.ID)
-
+	if err == posts.ErrNotFound {
+		WriteResponse(w, "post not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		h.Logger.Error(err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
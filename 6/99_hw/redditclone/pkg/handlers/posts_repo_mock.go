@@ -183,3 +183,18 @@ func (mr *MockPostsRepoMockRecorder) ParseID(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseID", reflect.TypeOf((*MockPostsRepo)(nil).ParseID), arg0)
 }
+
+// AnonymizeAuthor mocks base method
+func (m *MockPostsRepo) AnonymizeAuthor(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnonymizeAuthor", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AnonymizeAuthor indicates an expected call of AnonymizeAuthor
+func (mr *MockPostsRepoMockRecorder) AnonymizeAuthor(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnonymizeAuthor", reflect.TypeOf((*MockPostsRepo)(nil).AnonymizeAuthor), arg0, arg1)
+}
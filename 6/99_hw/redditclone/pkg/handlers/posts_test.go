@@ -500,3 +500,34 @@ func PostsTestEquals(t *testing.T, p1 *PostResponse, p2 *PostResponse) {
 		t.Errorf("test fail, expected: %v, but was: %v", p2, p1)
 	}
 }
+
+func TestPostNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostsRepo(ctrl)
+	h := &PostHandler{
+		PostsRepo: postsRepoMock,
+		Logger:    zap.NewNop().Sugar(),
+	}
+
+	id := primitive.NewObjectID()
+	postsRepoMock.EXPECT().ParseID(id.Hex()).Return(id, nil).Times(2)
+	postsRepoMock.EXPECT().GetByID(gomock.Any(), id).Return(nil, posts.ErrNotFound)
+	postsRepoMock.EXPECT().Upvote(gomock.Any(), id, userIDs[0]).Return(nil, posts.ErrNotFound)
+
+	w := httptest.NewRecorder()
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"id": id.Hex()})
+	h.GetByID(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GetByID: expected %v but was %v", http.StatusNotFound, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"post_id": id.Hex()})
+	r = r.WithContext(context.WithValue(r.Context(), session.SessionKey, &session.Session{User: &session.User{ID: userIDs[0]}}))
+	h.Upvote(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Upvote: expected %v but was %v", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/session"
+	"redditclone/pkg/user"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type ProfileResponse struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	Bio       string    `json:"bio"`
+	AvatarURL string    `json:"avatarUrl"`
+	Created   time.Time `json:"created"`
+	Karma     int       `json:"karma"`
+}
+
+type UserCommentResponse struct {
+	Created time.Time   `json:"created"`
+	Body    string      `json:"body"`
+	ID      interface{} `json:"id"`
+	PostID  interface{} `json:"postId"`
+}
+
+type UserResponse struct {
+	Profile  *ProfileResponse       `json:"profile"`
+	Posts    []*PostResponse        `json:"posts"`
+	Comments []*UserCommentResponse `json:"comments"`
+}
+
+type UpdateProfileReq struct {
+	Bio       *string `json:"bio"`
+	AvatarURL *string `json:"avatarUrl"`
+}
+
+type ChangePasswordReq struct {
+	OldPassword *string `json:"oldPassword"`
+	NewPassword *string `json:"newPassword"`
+}
+
+type DeleteAccountReq struct {
+	Password *string `json:"password"`
+}
+
+func (r *UpdateProfileReq) validate() []*CustomError {
+	var bioErr, avatarErr *CustomError
+	if r.Bio != nil {
+		bio := &Validator{value: r.Bio, location: "body", field: "bio"}
+		bioErr = bio.MaxLength(500)
+	}
+
+	// empty url removes the avatar
+	if r.AvatarURL != nil && *r.AvatarURL != "" {
+		avatar := &Validator{value: r.AvatarURL, location: "body", field: "avatarUrl"}
+		avatarErr = func() *CustomError {
+			err := avatar.MaxLength(2048)
+			if err != nil {
+				return err
+			}
+			return avatar.URL()
+		}()
+	}
+
+	return mergeErrors(bioErr, avatarErr)
+}
+
+func (r *ChangePasswordReq) validate() []*CustomError {
+	old := &Validator{value: r.OldPassword, location: "body", field: "oldPassword"}
+	pwd := &Validator{value: r.NewPassword, location: "body", field: "newPassword"}
+	pwdErr := func() *CustomError {
+		err := pwd.Required()
+		if err != nil {
+			return err
+		}
+		err = pwd.MinLength(8)
+		if err != nil {
+			return err
+		}
+		return pwd.MaxLength(72)
+	}()
+
+	return mergeErrors(old.Required(), pwdErr)
+}
+
+func (r *DeleteAccountReq) validate() []*CustomError {
+	pwd := &Validator{value: r.Password, location: "body", field: "password"}
+	return mergeErrors(pwd.Required())
+}
+
+// Profile returns the profile of the user with their posts and comments
+func (u *UserHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	usr, err := u.Repo.GetByUsername(mux.Vars(r)["username"])
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if usr == nil {
+		WriteResponse(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	userPosts, err := u.PostsRepo.GetByAuthorID(ctx, usr.ID)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	postsResp := make([]*PostResponse, 0, len(userPosts))
+	for _, p := range userPosts {
+		postResp, err := getPostData(p, u.Repo, u.CommentsRepo)
+		if err != nil {
+			u.Logger.Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		postsResp = append(postsResp, postResp)
+	}
+
+	userComments, err := u.CommentsRepo.GetByAuthorID(ctx, usr.ID)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	commentsResp := make([]*UserCommentResponse, 0, len(userComments))
+	for _, c := range userComments {
+		commentsResp = append(commentsResp, &UserCommentResponse{Created: c.Created, Body: c.Body, ID: c.ID, PostID: c.PostID})
+	}
+
+	resp := &UserResponse{Profile: mapToProfile(usr, userPosts), Posts: postsResp, Comments: commentsResp}
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBytes)
+}
+
+func (u *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	usr, ok := u.accountOwner(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateProfileReq
+	if !u.readBody(w, r, &req) {
+		return
+	}
+
+	validationErrors := req.validate()
+	if len(validationErrors) > 0 {
+		writeErrorsResponse(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if req.Bio != nil {
+		usr.Bio = *req.Bio
+	}
+	if req.AvatarURL != nil {
+		usr.AvatarURL = *req.AvatarURL
+	}
+
+	if !u.update(w, usr) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	userPosts, err := u.PostsRepo.GetByAuthorID(ctx, usr.ID)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	respBytes, err := json.Marshal(mapToProfile(usr, userPosts))
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBytes)
+}
+
+// ChangePassword logs out every session of the user and answers with a token of a new one
+func (u *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	usr, ok := u.accountOwner(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordReq
+	if !u.readBody(w, r, &req) {
+		return
+	}
+
+	validationErrors := req.validate()
+	if len(validationErrors) > 0 {
+		writeErrorsResponse(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !checkPass(usr.Password, *req.OldPassword) {
+		validationError := &CustomError{Location: "body", Param: "oldPassword", Msg: "is invalid"}
+		writeErrorsResponse(w, []*CustomError{validationError}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	salt := make([]byte, 8)
+	rand.Read(salt)
+	usr.Password = HashPass(salt, *req.NewPassword)
+
+	if !u.update(w, usr) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := u.Sm.DestroyAll(ctx, &session.User{ID: usr.ID, Username: usr.Username})
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	u.writeAuthResponse(w, usr, http.StatusOK)
+}
+
+// DeleteAccount removes the user, their posts and comments stay with user.DeletedID as the author
+func (u *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	usr, ok := u.accountOwner(w, r)
+	if !ok {
+		return
+	}
+
+	var req DeleteAccountReq
+	if !u.readBody(w, r, &req) {
+		return
+	}
+
+	validationErrors := req.validate()
+	if len(validationErrors) > 0 {
+		writeErrorsResponse(w, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !checkPass(usr.Password, *req.Password) {
+		validationError := &CustomError{Location: "body", Param: "password", Msg: "is invalid"}
+		writeErrorsResponse(w, []*CustomError{validationError}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// the content goes first and the user last: after a failure the account is still there
+	// and the deletion can be repeated, nothing is left pointing at a removed id
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := u.PostsRepo.AnonymizeAuthor(ctx, usr.ID)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	_, err = u.CommentsRepo.AnonymizeAuthor(ctx, usr.ID)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	_, err = u.Repo.Delete(usr.ID)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = u.Sm.DestroyAll(ctx, &session.User{ID: usr.ID, Username: usr.Username})
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	WriteResponse(w, "success", http.StatusOK)
+}
+
+// accountOwner loads the user from the path and checks that the session belongs to them
+func (u *UserHandler) accountOwner(w http.ResponseWriter, r *http.Request) (*user.User, bool) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+
+	usr, err := u.Repo.GetByUsername(mux.Vars(r)["username"])
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if usr == nil {
+		WriteResponse(w, "user not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	if usr.ID != sess.User.ID {
+		WriteResponse(w, "forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	return usr, true
+}
+
+func (u *UserHandler) readBody(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	err = json.Unmarshal(body, req)
+	if err != nil {
+		WriteResponse(w, "bad request", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func (u *UserHandler) update(w http.ResponseWriter, usr *user.User) bool {
+	ok, err := u.Repo.Update(usr)
+	if err != nil {
+		u.Logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	// deleted in the meantime
+	if !ok {
+		WriteResponse(w, "user not found", http.StatusNotFound)
+		return false
+	}
+
+	return true
+}
+
+// mapToProfile counts karma as the sum of votes on the user's posts, self upvotes excluded
+func mapToProfile(usr *user.User, userPosts []*posts.Post) *ProfileResponse {
+	karma := 0
+	for _, p := range userPosts {
+		for voter, v := range p.Votes {
+			if voter != usr.ID {
+				karma += int(v)
+			}
+		}
+	}
+
+	return &ProfileResponse{
+		ID:        usr.ID,
+		Username:  usr.Username,
+		Bio:       usr.Bio,
+		AvatarURL: usr.AvatarURL,
+		Created:   usr.Created,
+		Karma:     karma,
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"redditclone/pkg/comments"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/session"
+	"redditclone/pkg/user"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type accountMocks struct {
+	users    *MockUsersRepo
+	posts    *MockPostsRepo
+	comments *MockCommentsRepo
+	sm       *session.MockSessionManager
+}
+
+type accountCase struct {
+	name           string
+	sessionUserID  int64
+	body           string
+	execHandler    func(h *UserHandler, w http.ResponseWriter, r *http.Request)
+	setup          func(m *accountMocks, owner *user.User)
+	expectedStatus int
+	expectedBody   string
+}
+
+var accountCases = []accountCase{
+	{
+		name:          "UpdateProfileHappyCase",
+		sessionUserID: userIDs[0],
+		body:          `{"bio":"gopher","avatarUrl":"https://mail.ru/avatar.png"}`,
+		execHandler: func(h *UserHandler, w http.ResponseWriter, r *http.Request) {
+			h.UpdateProfile(w, r)
+		},
+		setup: func(m *accountMocks, owner *user.User) {
+			m.users.EXPECT().Update(gomock.Any()).DoAndReturn(func(u *user.User) (bool, error) {
+				if u.Bio != "gopher" || u.AvatarURL != "https://mail.ru/avatar.png" {
+					return false, nil
+				}
+				return true, nil
+			})
+			m.posts.EXPECT().GetByAuthorID(gomock.Any(), owner.ID).Return([]*posts.Post{}, nil)
+		},
+		expectedStatus: http.StatusOK,
+		expectedBody:   `{"id":1,"username":"test1","bio":"gopher","avatarUrl":"https://mail.ru/avatar.png","created":"0001-01-01T00:00:00Z","karma":0}`,
+	},
+	{
+		name:          "UpdateProfileForbiddenCase",
+		sessionUserID: userIDs[1],
+		body:          `{"bio":"gopher"}`,
+		execHandler: func(h *UserHandler, w http.ResponseWriter, r *http.Request) {
+			h.UpdateProfile(w, r)
+		},
+		setup:          func(m *accountMocks, owner *user.User) {},
+		expectedStatus: http.StatusForbidden,
+		expectedBody:   `{"message":"forbidden"}`,
+	},
+	{
+		name:          "UpdateProfileInvalidAvatarCase",
+		sessionUserID: userIDs[0],
+		body:          `{"avatarUrl":"avatar"}`,
+		execHandler: func(h *UserHandler, w http.ResponseWriter, r *http.Request) {
+			h.UpdateProfile(w, r)
+		},
+		setup:          func(m *accountMocks, owner *user.User) {},
+		expectedStatus: http.StatusUnprocessableEntity,
+		expectedBody:   `{"errors":[{"location":"body","param":"avatarUrl","value":"avatar","msg":"is invalid"}]}`,
+	},
+	{
+		name:          "ChangePasswordHappyCase",
+		sessionUserID: userIDs[0],
+		body:          `{"oldPassword":"secret_password","newPassword":"new_password"}`,
+		execHandler: func(h *UserHandler, w http.ResponseWriter, r *http.Request) {
+			h.ChangePassword(w, r)
+		},
+		setup: func(m *accountMocks, owner *user.User) {
+			sessUser := &session.User{ID: owner.ID, Username: owner.Username}
+			gomock.InOrder(
+				m.users.EXPECT().Update(gomock.Any()).DoAndReturn(func(u *user.User) (bool, error) {
+					return checkPass(u.Password, "new_password"), nil
+				}),
+				m.sm.EXPECT().DestroyAll(gomock.Any(), sessUser).Return(nil),
+				m.sm.EXPECT().Create(gomock.Any(), gomock.Any(), sessUser, gomock.Any(), gomock.Any()).Return(token, nil),
+			)
+		},
+		expectedStatus: http.StatusOK,
+		expectedBody:   `{"token":"test_token"}`,
+	},
+	{
+		name:          "ChangePasswordWrongOldPasswordCase",
+		sessionUserID: userIDs[0],
+		body:          `{"oldPassword":"wrong_password","newPassword":"new_password"}`,
+		execHandler: func(h *UserHandler, w http.ResponseWriter, r *http.Request) {
+			h.ChangePassword(w, r)
+		},
+		setup:          func(m *accountMocks, owner *user.User) {},
+		expectedStatus: http.StatusUnprocessableEntity,
+		expectedBody:   `{"errors":[{"location":"body","param":"oldPassword","value":"","msg":"is invalid"}]}`,
+	},
+	{
+		name:          "ChangePasswordShortCase",
+		sessionUserID: userIDs[0],
+		body:          `{"oldPassword":"secret_password","newPassword":"short"}`,
+		execHandler: func(h *UserHandler, w http.ResponseWriter, r *http.Request) {
+			h.ChangePassword(w, r)
+		},
+		setup:          func(m *accountMocks, owner *user.User) {},
+		expectedStatus: http.StatusUnprocessableEntity,
+		expectedBody:   `{"errors":[{"location":"body","param":"newPassword","value":"short","msg":"must be at least 8 characters long"}]}`,
+	},
+	{
+		name:          "DeleteAccountHappyCase",
+		sessionUserID: userIDs[0],
+		body:          `{"password":"secret_password"}`,
+		execHandler: func(h *UserHandler, w http.ResponseWriter, r *http.Request) {
+			h.DeleteAccount(w, r)
+		},
+		setup: func(m *accountMocks, owner *user.User) {
+			gomock.InOrder(
+				m.posts.EXPECT().AnonymizeAuthor(gomock.Any(), owner.ID).Return(int64(2), nil),
+				m.comments.EXPECT().AnonymizeAuthor(gomock.Any(), owner.ID).Return(int64(1), nil),
+				m.users.EXPECT().Delete(owner.ID).Return(true, nil),
+				m.sm.EXPECT().DestroyAll(gomock.Any(), &session.User{ID: owner.ID, Username: owner.Username}).Return(nil),
+			)
+		},
+		expectedStatus: http.StatusOK,
+		expectedBody:   `{"message":"success"}`,
+	},
+	{
+		// the account is kept when its content can't be anonymised
+		name:          "DeleteAccountAnonymizeErrorCase",
+		sessionUserID: userIDs[0],
+		body:          `{"password":"secret_password"}`,
+		execHandler: func(h *UserHandler, w http.ResponseWriter, r *http.Request) {
+			h.DeleteAccount(w, r)
+		},
+		setup: func(m *accountMocks, owner *user.User) {
+			m.posts.EXPECT().AnonymizeAuthor(gomock.Any(), owner.ID).Return(int64(1), nil)
+			m.comments.EXPECT().AnonymizeAuthor(gomock.Any(), owner.ID).Return(int64(0), errors.New("mongo is down"))
+		},
+		expectedStatus: http.StatusInternalServerError,
+		expectedBody:   ``,
+	},
+	{
+		name:          "DeleteAccountWrongPasswordCase",
+		sessionUserID: userIDs[0],
+		body:          `{"password":"wrong_password"}`,
+		execHandler: func(h *UserHandler, w http.ResponseWriter, r *http.Request) {
+			h.DeleteAccount(w, r)
+		},
+		setup:          func(m *accountMocks, owner *user.User) {},
+		expectedStatus: http.StatusUnprocessableEntity,
+		expectedBody:   `{"errors":[{"location":"body","param":"password","value":"","msg":"is invalid"}]}`,
+	},
+}
+
+func TestAccountChanges(t *testing.T) {
+	for _, tc := range accountCases {
+		ctrl := gomock.NewController(t)
+		m := &accountMocks{
+			users:    NewMockUsersRepo(ctrl),
+			posts:    NewMockPostsRepo(ctrl),
+			comments: NewMockCommentsRepo(ctrl),
+			sm:       session.NewMockSessionManager(ctrl),
+		}
+		h := &UserHandler{Sm: m.sm, Repo: m.users, PostsRepo: m.posts, CommentsRepo: m.comments, Logger: zap.NewNop().Sugar()}
+
+		owner := &user.User{ID: userIDs[0], Username: testUserData[0].Username, Password: passwordDB}
+		m.users.EXPECT().GetByUsername(owner.Username).Return(owner, nil)
+		tc.setup(m, owner)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tc.body))
+		r = mux.SetURLVars(r, map[string]string{"username": owner.Username})
+		r = r.WithContext(context.WithValue(r.Context(), session.SessionKey, &session.Session{User: &session.User{ID: tc.sessionUserID}}))
+
+		tc.execHandler(h, w, r)
+
+		if w.Code != tc.expectedStatus {
+			t.Errorf("%s: expected status %v, but was %v", tc.name, tc.expectedStatus, w.Code)
+		}
+		if w.Body.String() != tc.expectedBody {
+			t.Errorf("%s: expected body %s, but was %s", tc.name, tc.expectedBody, w.Body.String())
+		}
+
+		ctrl.Finish()
+	}
+}
+
+func TestProfile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usersRepoMock := NewMockUsersRepo(ctrl)
+	postsRepoMock := NewMockPostsRepo(ctrl)
+	commentsRepoMock := NewMockCommentsRepo(ctrl)
+	h := &UserHandler{Repo: usersRepoMock, PostsRepo: postsRepoMock, CommentsRepo: commentsRepoMock, Logger: zap.NewNop().Sugar()}
+
+	owner := testUserData[0]
+	// the post has the author's upvote and two upvotes of others
+	userPost := testPostData[0]
+	userComments := []*comments.Comment{testCommentData[0], testCommentData[1]}
+
+	usersRepoMock.EXPECT().GetByUsername(owner.Username).Return(owner, nil)
+	usersRepoMock.EXPECT().GetByUsername("unknown").Return(nil, nil)
+	usersRepoMock.EXPECT().GetByID(owner.ID).Return(owner, nil).AnyTimes()
+	postsRepoMock.EXPECT().GetByAuthorID(gomock.Any(), owner.ID).Return([]*posts.Post{userPost}, nil)
+	commentsRepoMock.EXPECT().GetByPostID(gomock.Any(), userPost.ID).Return([]*comments.Comment{}, nil)
+	commentsRepoMock.EXPECT().GetByAuthorID(gomock.Any(), owner.ID).Return(userComments, nil)
+
+	w := httptest.NewRecorder()
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"username": owner.Username})
+	h.Profile(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %v, but was %v", http.StatusOK, w.Code)
+	}
+
+	resp := &UserResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Profile.Username != owner.Username || resp.Profile.Karma != 2 {
+		t.Errorf("expected %v with karma 2, but was %+v", owner.Username, resp.Profile)
+	}
+	if len(resp.Posts) != 1 || resp.Posts[0].Title != userPost.Title {
+		t.Errorf("expected posts [%v], but was %+v", userPost.Title, resp.Posts)
+	}
+	if len(resp.Comments) != 2 || resp.Comments[1].Body != userComments[1].Body {
+		t.Errorf("expected 2 comments, but was %+v", resp.Comments)
+	}
+
+	w = httptest.NewRecorder()
+	r = mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"username": "unknown"})
+	h.Profile(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %v, but was %v", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestDeletedAuthor(t *testing.T) {
+	a := authorOf(nil)
+	if a.ID != user.DeletedID || a.Username != user.DeletedUsername {
+		t.Errorf("expected deleted author, but was %+v", a)
+	}
+}
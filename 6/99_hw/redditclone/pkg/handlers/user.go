@@ -19,15 +19,19 @@ import (
 )
 
 type UserHandler struct {
-	Sm     session.SessionManager
-	Repo   UsersRepo
-	Logger *zap.SugaredLogger
+	Sm           session.SessionManager
+	Repo         UsersRepo
+	PostsRepo    PostsRepo
+	CommentsRepo CommentsRepo
+	Logger       *zap.SugaredLogger
 }
 
 type UsersRepo interface {
 	GetByID(id int64) (*user.User, error)
 	GetByUsername(username string) (*user.User, error)
 	Add(user *user.User) (int64, error)
+	Update(user *user.User) (bool, error)
+	Delete(id int64) (bool, error)
 }
 
 type AuthReq struct {
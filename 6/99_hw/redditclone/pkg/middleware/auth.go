@@ -24,10 +24,7 @@ var authRoutes = map[string]string{
 
 func Auth(logger *zap.SugaredLogger, sm session.SessionManager, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		m, ok := authRoutes[r.URL.Path]
-
-		if (!ok || m != r.Method) && !strings.HasSuffix(r.URL.Path, "vote") &&
-			(!strings.HasPrefix(r.URL.Path, "/api/post/") || r.Method != http.MethodPost) {
+		if !requiresAuth(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -50,3 +47,21 @@ func Auth(logger *zap.SugaredLogger, sm session.SessionManager, next http.Handle
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+func requiresAuth(r *http.Request) bool {
+	if m, ok := authRoutes[r.URL.Path]; ok && m == r.Method {
+		return true
+	}
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "vote"):
+		return true
+	case strings.HasPrefix(r.URL.Path, "/api/post/") && r.Method == http.MethodPost:
+		return true
+	// account changes, profiles are public
+	case strings.HasPrefix(r.URL.Path, "/api/user/") && r.Method != http.MethodGet:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"redditclone/pkg/handlers"
+	"redditclone/pkg/openapi"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// ValidateAPI checks requests and responses of documented routes against the spec.
+// It is meant for tests: it must be registered with mux Router.Use to see the matched route,
+// buffers every response and answers 500 when a handler breaks the contract.
+func ValidateAPI(logger *zap.SugaredLogger, spec *openapi.Document, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		op := spec.Operation(path, r.Method)
+		if op == nil {
+			logger.Errorf("openapi: %s %s is not documented", r.Method, path)
+			handlers.WriteResponse(w, fmt.Sprintf("%s %s is not documented", r.Method, path), http.StatusInternalServerError)
+			return
+		}
+
+		if op.RequestBody != nil {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				logger.Error(err.Error())
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			if errs := validateBody(spec, op.RequestBody.Content, body); len(errs) > 0 {
+				writeSpecErrors(w, errs, http.StatusUnprocessableEntity)
+				return
+			}
+		}
+
+		rec := &responseRecorder{header: w.Header(), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if msg := validateResponse(spec, op, rec); msg != "" {
+			logger.Errorf("openapi: %s %s: %s", r.Method, path, msg)
+			handlers.WriteResponse(w, "response does not match openapi spec: "+msg, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(rec.status)
+		w.Write(rec.body.Bytes())
+	})
+}
+
+func validateResponse(spec *openapi.Document, op *openapi.Operation, rec *responseRecorder) string {
+	resp, ok := op.Responses[strconv.Itoa(rec.status)]
+	if !ok {
+		return fmt.Sprintf("status %d is not documented", rec.status)
+	}
+
+	// handlers answer errors with a bare status
+	if rec.body.Len() == 0 {
+		return ""
+	}
+
+	if resp.Content == nil {
+		return fmt.Sprintf("status %d should have no body", rec.status)
+	}
+
+	errs := validateBody(spec, resp.Content, rec.body.Bytes())
+	if len(errs) > 0 {
+		return errs[0].Error()
+	}
+
+	return ""
+}
+
+func validateBody(spec *openapi.Document, content map[string]*openapi.MediaType, body []byte) []*openapi.ValidationError {
+	media, ok := content["application/json"]
+	if !ok {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []*openapi.ValidationError{{Msg: "is not valid json"}}
+	}
+
+	return spec.Validate(media.Schema, value)
+}
+
+// writeSpecErrors answers in the same format as handlers' validation
+func writeSpecErrors(w http.ResponseWriter, errs []*openapi.ValidationError, status int) {
+	res := make([]*handlers.CustomError, 0, len(errs))
+	for _, e := range errs {
+		value := ""
+		if str, ok := e.Value.(string); ok {
+			value = str
+		} else if e.Value != nil {
+			value = fmt.Sprint(e.Value)
+		}
+
+		res = append(res, &handlers.CustomError{Location: "body", Param: e.Path, Value: value, Msg: e.Msg})
+	}
+
+	body, _ := json.Marshal(&handlers.ErrorsResponse{Errors: res})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	// the first status wins, as with a real connection
+	if rec.wroteHeader {
+		return
+	}
+
+	rec.status = status
+	rec.wroteHeader = true
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"redditclone/pkg/handlers"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type validateAPICase struct {
+	name     string
+	method   string
+	path     string
+	body     string
+	status   int
+	expected string
+}
+
+var validateAPICases = []validateAPICase{
+	{
+		name:     "ValidRequest",
+		method:   http.MethodPost,
+		path:     "/api/login",
+		body:     `{"username": "test_user", "password": "test_password"}`,
+		status:   http.StatusOK,
+		expected: `{"token":"token"}`,
+	},
+	{
+		name:     "InvalidRequest",
+		method:   http.MethodPost,
+		path:     "/api/login",
+		body:     `{"username": "test user"}`,
+		status:   http.StatusUnprocessableEntity,
+		expected: `{"errors":[{"location":"body","param":"password","value":"","msg":"is required"},{"location":"body","param":"username","value":"test user","msg":"contains invalid characters"}]}`,
+	},
+	{
+		name:   "InvalidResponse",
+		method: http.MethodGet,
+		path:   "/api/posts/",
+		status: http.StatusInternalServerError,
+	},
+	{
+		name:   "UndocumentedStatus",
+		method: http.MethodGet,
+		path:   "/api/post/1",
+		status: http.StatusInternalServerError,
+	},
+	{
+		name:   "EmptyErrorResponse",
+		method: http.MethodGet,
+		path:   "/api/user/test",
+		status: http.StatusInternalServerError,
+	},
+}
+
+func TestValidateAPI(t *testing.T) {
+	spec := handlers.OpenAPISpec()
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api/").Subrouter()
+	api.Use(func(next http.Handler) http.Handler {
+		return ValidateAPI(zap.NewNop().Sugar(), spec, next)
+	})
+
+	api.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"token":"token"}`))
+	}).Methods(http.MethodPost)
+	// posts list without required fields
+	api.HandleFunc("/posts/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"title": "title"}]`))
+	}).Methods(http.MethodGet)
+	api.HandleFunc("/post/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}).Methods(http.MethodGet)
+	api.HandleFunc("/user/{username}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}).Methods(http.MethodGet)
+
+	for i, c := range validateAPICases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.method, c.path, bytes.NewBufferString(c.body)))
+
+		if w.Code != c.status {
+			t.Errorf("test case %d %s: expected status %v, but was %v, body %s", i, c.name, c.status, w.Code, w.Body.String())
+			continue
+		}
+
+		if c.expected != "" && w.Body.String() != c.expected {
+			t.Errorf("test case %d %s: expected body %s, but was %s", i, c.name, c.expected, w.Body.String())
+		}
+
+		if c.status == http.StatusInternalServerError && w.Body.Len() > 0 {
+			var resp handlers.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Message == "" {
+				t.Errorf("test case %d %s: expected message, but was %s", i, c.name, w.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError points to the first broken rule of a value,
+// messages are the same as the ones handlers' Validator produces
+type ValidationError struct {
+	Path  string
+	Value interface{}
+	Msg   string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Msg
+	}
+
+	return e.Path + " " + e.Msg
+}
+
+var (
+	regexpsMu sync.Mutex
+	regexps   = map[string]*regexp.Regexp{}
+)
+
+// Validate checks a value decoded by encoding/json into interface{} against the schema.
+// Every field is reported once, at its first failed rule.
+func (d *Document) Validate(s *Schema, value interface{}) []*ValidationError {
+	return d.validate(s, value, "")
+}
+
+func (d *Document) validate(s *Schema, value interface{}, path string) []*ValidationError {
+	s = d.Resolve(s)
+	if s == nil {
+		return nil
+	}
+
+	fail := func(msg string) []*ValidationError {
+		return []*ValidationError{{Path: path, Value: value, Msg: msg}}
+	}
+
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+
+		return fail("cannot be null")
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		return fail(fmt.Sprintf("must be one of %v", s.Enum))
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fail("must be an object")
+		}
+
+		return d.validateObject(s, obj, path)
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fail("must be an array")
+		}
+
+		var errs []*ValidationError
+		for i, item := range arr {
+			errs = append(errs, d.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+
+		return errs
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+
+		if msg := validateString(s, str); msg != "" {
+			return fail(msg)
+		}
+	case "integer", "number":
+		num, ok := value.(float64)
+		if !ok {
+			return fail("must be a number")
+		}
+
+		if s.Type == "integer" && num != math.Trunc(num) {
+			return fail("must be an integer")
+		}
+		if s.Minimum != nil && num < *s.Minimum {
+			return fail(fmt.Sprintf("must be at least %v", *s.Minimum))
+		}
+		if s.Maximum != nil && num > *s.Maximum {
+			return fail(fmt.Sprintf("must be at most %v", *s.Maximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("must be a boolean")
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) validateObject(s *Schema, obj map[string]interface{}, path string) []*ValidationError {
+	var errs []*ValidationError
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			errs = append(errs, &ValidationError{Path: join(path, name), Msg: "is required"})
+		}
+	}
+
+	// sorted to keep error order stable between runs
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v, ok := obj[name]
+		if !ok {
+			continue
+		}
+
+		errs = append(errs, d.validate(s.Properties[name], v, join(path, name))...)
+	}
+
+	return errs
+}
+
+func validateString(s *Schema, str string) string {
+	length := utf8.RuneCountInString(str)
+	if s.MinLength != nil && length < *s.MinLength {
+		if *s.MinLength == 1 {
+			return "cannot be blank"
+		}
+
+		return fmt.Sprintf("must be at least %d characters long", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return fmt.Sprintf("must be at most %d characters long", *s.MaxLength)
+	}
+
+	if s.Pattern != "" {
+		r, err := compile(s.Pattern)
+		if err != nil {
+			return fmt.Sprintf("has invalid pattern in schema: %v", err)
+		}
+		if !r.MatchString(str) {
+			return "contains invalid characters"
+		}
+	}
+
+	switch s.Format {
+	case "uri":
+		if _, err := url.ParseRequestURI(str); err != nil {
+			return "is invalid"
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return "is invalid"
+		}
+	}
+
+	return ""
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	regexpsMu.Lock()
+	defer regexpsMu.Unlock()
+	if r, ok := regexps[pattern]; ok {
+		return r, nil
+	}
+
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	regexps[pattern] = r
+	return r, nil
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		// json numbers are float64, enums are declared with go ints
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
@@ -0,0 +1,121 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var testDoc = &Document{
+	Components: &Components{
+		Schemas: map[string]*Schema{
+			"Author": {
+				Type:     "object",
+				Required: []string{"username", "id"},
+				Properties: map[string]*Schema{
+					"username": {Type: "string", MinLength: Int(1), MaxLength: Int(5), Pattern: "^[a-z]+$"},
+					"id":       {Type: "integer", Minimum: Float(1)},
+				},
+			},
+			"Post": {
+				Type:     "object",
+				Required: []string{"author"},
+				Properties: map[string]*Schema{
+					"author":  Ref("Author"),
+					"type":    {Type: "string", Enum: []interface{}{"text", "link"}},
+					"url":     {Type: "string", Format: "uri"},
+					"created": {Type: "string", Format: "date-time"},
+					"votes":   {Type: "array", Items: &Schema{Type: "integer", Enum: []interface{}{-1, 1}}},
+				},
+			},
+		},
+	},
+}
+
+type validateCase struct {
+	name     string
+	body     string
+	expected []string
+}
+
+var validateCases = []validateCase{
+	{
+		name: "Valid",
+		body: `{"author": {"username": "vasya", "id": 1}, "type": "link", "url": "https://mail.ru/",
+			"created": "2021-02-16T21:45:02.420486+03:00", "votes": [1, -1]}`,
+		expected: nil,
+	},
+	{
+		name:     "MissingRequired",
+		body:     `{"author": {"username": "vasya"}}`,
+		expected: []string{"author.id is required"},
+	},
+	{
+		name:     "WrongTypes",
+		body:     `{"author": {"username": 1, "id": "1"}, "votes": {}}`,
+		expected: []string{"author.id must be a number", "author.username must be a string", "votes must be an array"},
+	},
+	{
+		name:     "StringRules",
+		body:     `{"author": {"username": "", "id": 1}, "url": "mail.ru", "created": "yesterday"}`,
+		expected: []string{"author.username cannot be blank", "created is invalid", "url is invalid"},
+	},
+	{
+		name:     "LengthAndPattern",
+		body:     `{"author": {"username": "vasily", "id": 1}}`,
+		expected: []string{"author.username must be at most 5 characters long"},
+	},
+	{
+		name:     "Pattern",
+		body:     `{"author": {"username": "Vas", "id": 1}}`,
+		expected: []string{"author.username contains invalid characters"},
+	},
+	{
+		name:     "NumberRules",
+		body:     `{"author": {"username": "vas", "id": 0.5}, "votes": [1, 0]}`,
+		expected: []string{"author.id must be an integer", "votes[1] must be one of [-1 1]"},
+	},
+	{
+		name:     "Enum",
+		body:     `{"author": {"username": "vas", "id": 1}, "type": "image"}`,
+		expected: []string{"type must be one of [text link]"},
+	},
+	{
+		name:     "Null",
+		body:     `{"author": null}`,
+		expected: []string{"author cannot be null"},
+	},
+}
+
+func TestValidate(t *testing.T) {
+	for i, c := range validateCases {
+		var value interface{}
+		if err := json.Unmarshal([]byte(c.body), &value); err != nil {
+			t.Fatalf("test case %d %s: bad json: %v", i, c.name, err)
+		}
+
+		var res []string
+		for _, e := range testDoc.Validate(Ref("Post"), value) {
+			res = append(res, e.Error())
+		}
+
+		if !reflect.DeepEqual(res, c.expected) {
+			t.Errorf("test case %d %s failed, expected %v, but was %v", i, c.name, c.expected, res)
+		}
+	}
+}
+
+func TestOperation(t *testing.T) {
+	op := &Operation{OperationID: "getPost"}
+	doc := &Document{Paths: map[string]*PathItem{"/api/post/{id}": {"get": op}}}
+
+	if doc.Operation("/api/post/{id}", "GET") != op {
+		t.Errorf("expected operation for GET /api/post/{id}")
+	}
+	if doc.Operation("/api/post/{id}", "DELETE") != nil {
+		t.Errorf("expected no operation for DELETE /api/post/{id}")
+	}
+	if doc.Operation("/api/posts", "GET") != nil {
+		t.Errorf("expected no operation for GET /api/posts")
+	}
+}
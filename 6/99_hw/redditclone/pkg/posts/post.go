@@ -6,9 +6,10 @@ import (
 
 type PostType string
 
+// the values are what the frontend sends and checks, "text" or "link"
 const (
 	Text PostType = "text"
-	Link          = ""
+	Link PostType = "link"
 )
 
 type PostCategory string
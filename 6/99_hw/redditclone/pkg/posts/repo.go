@@ -1,10 +1,15 @@
 package posts
 
 import (
+	"context"
+	"errors"
+	"redditclone/pkg/user"
 	"strconv"
 	"sync"
 )
 
+var ErrNotFound = errors.New("post not found")
+
 type MemoryPostsRepo struct {
 	mu     *sync.Mutex
 	lastID uint64
@@ -15,69 +20,64 @@ func NewRepo() *MemoryPostsRepo {
 	return &MemoryPostsRepo{data: make([]*Post, 0, 10), mu: &sync.Mutex{}}
 }
 
-// TODO pass context
-func (repo *MemoryPostsRepo) GetAll() ([]*Post, error) {
-	return repo.data, nil
+func (repo *MemoryPostsRepo) GetAll(ctx context.Context) ([]*Post, error) {
+	return repo.getPosts(func(p *Post) bool { return true })
 }
 
-func (repo *MemoryPostsRepo) GetByCategory(category string) ([]*Post, error) {
+func (repo *MemoryPostsRepo) GetByCategory(ctx context.Context, category string) ([]*Post, error) {
 	return repo.getPosts(func(p *Post) bool { return p.Category == PostCategory(category) })
 }
 
-func (repo *MemoryPostsRepo) GetByAuthorID(authorID int64) ([]*Post, error) {
+func (repo *MemoryPostsRepo) GetByAuthorID(ctx context.Context, authorID interface{}) ([]*Post, error) {
 	return repo.getPosts(func(p *Post) bool { return p.AuthorID == authorID })
 }
 
-func (repo *MemoryPostsRepo) GetByID(id uint64) (*Post, error) {
-	res, err := repo.getPosts(func(p *Post) bool { return p.ID == id })
-	if err != nil {
-		return nil, err
-	}
-
-	if len(res) > 0 {
-		repo.mu.Lock()
-		defer repo.mu.Unlock()
-		res[0].Views++
-		return res[0], nil
+func (repo *MemoryPostsRepo) GetByID(ctx context.Context, id interface{}) (*Post, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	p := repo.find(id)
+	if p == nil {
+		return nil, ErrNotFound
 	}
 
-	return nil, nil
+	p.Views++
+	return copyPost(p), nil
 }
 
-func (repo *MemoryPostsRepo) Add(post *Post) (uint64, error) {
+func (repo *MemoryPostsRepo) Add(ctx context.Context, post *Post) (interface{}, error) {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
 	repo.lastID++
 	post.ID = repo.lastID
-	repo.data = append(repo.data, post)
-	return post.ID.(uint64), nil
+	post.Votes = map[int64]VoteValue{post.AuthorID: Upvote}
+	repo.data = append(repo.data, copyPost(post))
+	return post.ID, nil
 }
 
-func (repo *MemoryPostsRepo) Update(post *Post) (bool, error) {
+func (repo *MemoryPostsRepo) Update(ctx context.Context, post *Post) (bool, error) {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
-	for i, p := range repo.data {
-		if p.ID == post.ID {
-			repo.data[i].AuthorID = post.ID.(int64)
-			repo.data[i].Category = post.Category
-			repo.data[i].Text = post.Text
-			repo.data[i].Title = post.Title
-			repo.data[i].Type = post.Type
-			repo.data[i].Views = post.Views
-			return true, nil
-		}
+	p := repo.find(post.ID)
+	if p == nil {
+		return false, nil
 	}
 
-	return false, nil
+	p.Category = post.Category
+	p.Text = post.Text
+	p.URL = post.URL
+	p.Title = post.Title
+	p.Type = post.Type
+	p.Views = post.Views
+	return true, nil
 }
 
-func (repo *MemoryPostsRepo) Delete(id uint64) (bool, error) {
+func (repo *MemoryPostsRepo) Delete(ctx context.Context, id interface{}) (bool, error) {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
 	for i, p := range repo.data {
 		if p.ID == id {
-			repo.data[i] = repo.data[len(repo.data)-1]
-			repo.data = repo.data[:len(repo.data)-1]
+			// keep insertion order, GetAll relies on it
+			repo.data = append(repo.data[:i], repo.data[i+1:]...)
 			return true, nil
 		}
 	}
@@ -85,78 +85,84 @@ func (repo *MemoryPostsRepo) Delete(id uint64) (bool, error) {
 	return false, nil
 }
 
-func (repo *MemoryPostsRepo) Upvote(postID, userID uint64) (*Post, error) {
+func (repo *MemoryPostsRepo) AnonymizeAuthor(ctx context.Context, authorID int64) (int64, error) {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
+	var n int64
 	for _, p := range repo.data {
-		if p.ID == postID {
-			return vote(p, userID, func(a map[uint64]VoteValue, b uint64) { a[b] = 1 })
+		if p.AuthorID == authorID {
+			p.AuthorID = user.DeletedID
+			n++
 		}
 	}
 
-	return nil, nil
+	return n, nil
+}
+
+func (repo *MemoryPostsRepo) Upvote(ctx context.Context, postID interface{}, userID int64) (*Post, error) {
+	return repo.vote(postID, userID, Upvote)
+}
+
+func (repo *MemoryPostsRepo) DownVote(ctx context.Context, postID interface{}, userID int64) (*Post, error) {
+	return repo.vote(postID, userID, Downvote)
+}
+
+func (repo *MemoryPostsRepo) Unvote(ctx context.Context, postID interface{}, userID int64) (*Post, error) {
+	return repo.vote(postID, userID, Unvote)
+}
+
+func (repo *MemoryPostsRepo) ParseID(in string) (interface{}, error) {
+	return strconv.ParseUint(in, 10, 0)
 }
 
-func (repo *MemoryPostsRepo) DownVote(postID, userID uint64) (*Post, error) {
+func (repo *MemoryPostsRepo) vote(postID interface{}, userID int64, v VoteValue) (*Post, error) {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
-	for _, p := range repo.data {
-		if p.ID == postID {
-			return vote(p, userID, func(a map[uint64]VoteValue, b uint64) { a[b] = -1 })
-		}
+	p := repo.find(postID)
+	if p == nil {
+		return nil, ErrNotFound
 	}
 
-	return nil, nil
+	if v == Unvote {
+		delete(p.Votes, userID)
+	} else {
+		p.Votes[userID] = v
+	}
+
+	return copyPost(p), nil
 }
 
-func (repo *MemoryPostsRepo) Unvote(postID, userID uint64) (*Post, error) {
-	repo.mu.Lock()
-	defer repo.mu.Unlock()
+// find must be called with repo.mu held
+func (repo *MemoryPostsRepo) find(id interface{}) *Post {
 	for _, p := range repo.data {
-		if p.ID == postID {
-			return vote(p, userID, func(a map[uint64]VoteValue, b uint64) { delete(a, b) })
+		if p.ID == id {
+			return p
 		}
 	}
 
-	return nil, nil
+	return nil
 }
 
 func (repo *MemoryPostsRepo) getPosts(filter func(*Post) bool) ([]*Post, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
 	res := make([]*Post, 0, 10)
 	for _, p := range repo.data {
 		if filter(p) {
-			res = append(res, p)
+			res = append(res, copyPost(p))
 		}
 	}
 
 	return res, nil
 }
 
-func vote(p *Post, userID uint64, vote func(map[uint64]VoteValue, uint64)) (*Post, error) {
-	return nil, nil
-	// userVote := make(map[uint64]VoteValue)
-	// err := json.Unmarshal(p.Votes, &userVote)
-	// if err != nil {
-	// 	return nil, err
-	// }
-
-	// vote(userVote, userID)
-
-	// res := &Votes{Votes: make([]*Vote, 0, len(userVote))}
-
-	// for u, v := range userVote {
-	// 	res.Votes = append(res.Votes, &Vote{User: u, Vote: v})
-	// }
-
-	// newVotes, err := json.Marshal(userVote)
-	// if err != nil {
-	// 	return nil, err
-	// }
-
-	// p.Votes = newVotes
-	// return p, nil
-}
+// copyPost detaches stored posts from callers, so nobody mutates the repo without the lock
+func copyPost(p *Post) *Post {
+	res := *p
+	res.Votes = make(map[int64]VoteValue, len(p.Votes))
+	for u, v := range p.Votes {
+		res.Votes[u] = v
+	}
 
-func (repo *MemoryPostsRepo) ParseID(in string) (interface{}, error) {
-	return strconv.ParseUint(in, 10, 0)
+	return &res
 }
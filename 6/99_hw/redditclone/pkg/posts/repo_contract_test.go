@@ -0,0 +1,45 @@
+package posts_test
+
+import (
+	"context"
+	"os"
+	"redditclone/pkg/handlers"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/repotest"
+	"testing"
+	"time"
+)
+
+func TestMemoryPostsRepoContract(t *testing.T) {
+	repotest.RunPostsRepo(t, func(t *testing.T) handlers.PostsRepo {
+		return posts.NewRepo()
+	})
+}
+
+// TestMongoPostsRepoContract needs a running mongo, e.g.
+// REDDITCLONE_TEST_MONGO=mongodb://localhost:27017 go test ./pkg/posts/
+// the posts collection of redditclone_test is dropped before every subtest
+func TestMongoPostsRepoContract(t *testing.T) {
+	uri := os.Getenv("REDDITCLONE_TEST_MONGO")
+	if uri == "" {
+		t.Skip("REDDITCLONE_TEST_MONGO is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := posts.NewMongoClient(ctx, uri)
+	if err != nil {
+		t.Fatalf("can't connect to mongo: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database("redditclone_test")
+	repotest.RunPostsRepo(t, func(t *testing.T) handlers.PostsRepo {
+		err := db.Collection("posts").Drop(context.Background())
+		if err != nil {
+			t.Fatalf("can't drop posts collection: %v", err)
+		}
+
+		return posts.NewPostsRepoMongo(db)
+	})
+}
@@ -2,8 +2,8 @@ package posts
 
 import (
 	"context"
-	"fmt"
 	"redditclone/pkg/common"
+	"redditclone/pkg/user"
 	"strconv"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -20,8 +20,8 @@ func NewMongoClient(ctx context.Context, uri string) (*mongo.Client, error) {
 	return mongo.Connect(ctx, options.Client().ApplyURI(uri))
 }
 
-func NewPostsRepoMongo(client *mongo.Client) *PostsRepoMongo {
-	return &PostsRepoMongo{collection: &common.MongoCollection{Collection: client.Database("redditclone_db").Collection("posts")}}
+func NewPostsRepoMongo(db *mongo.Database) *PostsRepoMongo {
+	return &PostsRepoMongo{collection: &common.MongoCollection{Collection: db.Collection("posts")}}
 }
 
 func (r *PostsRepoMongo) GetAll(ctx context.Context) ([]*Post, error) {
@@ -39,11 +39,14 @@ func (r *PostsRepoMongo) GetByAuthorID(ctx context.Context, authorID interface{}
 func (r *PostsRepoMongo) GetByID(ctx context.Context, id interface{}) (*Post, error) {
 	res := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id},
 		bson.D{
-			{"$inc", bson.D{{"views", 1}}},
+			{Key: "$inc", Value: bson.D{{Key: "views", Value: 1}}},
 		})
 
 	post := &Post{}
 	err := res.Decode(post)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +78,19 @@ func (r *PostsRepoMongo) Delete(ctx context.Context, id interface{}) (bool, erro
 	return true, nil
 }
 
+// AnonymizeAuthor hands posts of a deleted account over to user.DeletedID, votes are kept
+func (r *PostsRepoMongo) AnonymizeAuthor(ctx context.Context, authorID int64) (int64, error) {
+	res, err := r.collection.UpdateMany(ctx, bson.M{"authorID": authorID},
+		bson.D{
+			{Key: "$set", Value: bson.D{{Key: "authorID", Value: user.DeletedID}}},
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.GetModifiedCount(), nil
+}
+
 func (r *PostsRepoMongo) Upvote(ctx context.Context, postID interface{}, userID int64) (*Post, error) {
 	return r.vote(ctx, postID, userID, Upvote)
 }
@@ -92,45 +108,37 @@ func (r *PostsRepoMongo) ParseID(in string) (interface{}, error) {
 }
 
 func (r *PostsRepoMongo) vote(ctx context.Context, postID interface{}, userID int64, v VoteValue) (*Post, error) {
-
-	// if v == Unvote {
-	// 	delete(p.Votes, userID)
-	// } else {
-	// 	p.Votes[userID] = v
-	// }
-
-	// updateRes, err := r.collection.UpdateOne(ctx, bson.M{"_id": postID},
-	// 	bson.D{
-	// 		{"$set", bson.D{{"votes", p.Votes}}},
-	// 	})
-	// if err != nil {
-	// 	return nil, err
-	// }
+	// only the voter's key is touched, so concurrent votes of other users are kept
+	voteKey := "votes." + strconv.FormatInt(userID, 10)
 
 	var updateRes common.UpdateResultHelper
 	var err error
 	if v == Unvote {
 		updateRes, err = r.collection.UpdateOne(ctx, bson.M{"_id": postID},
 			bson.D{
-				{"$unset", bson.D{{"votes", strconv.FormatInt(userID, 10)}}},
+				{Key: "$unset", Value: bson.D{{Key: voteKey, Value: ""}}},
 			})
 	} else {
 		updateRes, err = r.collection.UpdateOne(ctx, bson.M{"_id": postID},
 			bson.D{
-				{"$set", bson.D{{"votes", bson.M{strconv.FormatInt(userID, 10): v}}}},
+				{Key: "$set", Value: bson.D{{Key: voteKey, Value: v}}},
 			})
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	if updateRes.GetModifiedCount() == 0 {
-		return nil, fmt.Errorf("can't update post")
+	// repeated vote with the same value modifies nothing, but it is not an error
+	if updateRes.GetMatchedCount() == 0 {
+		return nil, ErrNotFound
 	}
 
 	res := r.collection.FindOne(ctx, bson.M{"_id": postID})
 	p := &Post{}
 	err = res.Decode(p)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +147,7 @@ func (r *PostsRepoMongo) vote(ctx context.Context, postID interface{}, userID in
 }
 
 func (r *PostsRepoMongo) getByField(ctx context.Context, filter bson.M) ([]*Post, error) {
-	cur, err := r.collection.Find(ctx, filter)
+	cur, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created", Value: 1}}))
 	if err != nil {
 		return nil, err
 	}
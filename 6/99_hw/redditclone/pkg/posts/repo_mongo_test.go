@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"redditclone/pkg/common"
+	"redditclone/pkg/user"
 	"reflect"
 	"strconv"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type getByFieldCase struct {
@@ -85,7 +87,7 @@ func TestGetByField(t *testing.T) {
 
 		expectedFilter := c.cond
 
-		mockCollection.EXPECT().Find(ctx, gomock.Eq(expectedFilter)).Return(mockCursor, c.cursorErr)
+		mockCollection.EXPECT().Find(ctx, gomock.Eq(expectedFilter), gomock.Any()).Return(mockCursor, c.cursorErr)
 		mockCursor.EXPECT().All(ctx, gomock.AssignableToTypeOf(&expectedPosts)).
 			SetArg(1, expectedPosts).Return(c.findErr)
 		mockCursor.EXPECT().Close(ctx).Return(nil)
@@ -177,12 +179,12 @@ func TestVotes(t *testing.T) {
 
 		bsonM := bson.M{"_id": postID}
 
+		voteKey := "votes." + strconv.FormatInt(userID, 10)
 		unsetBsonD := bson.D{
-			{"$unset", bson.D{{"votes", strconv.FormatInt(userID, 10)}}},
+			{"$unset", bson.D{{voteKey, ""}}},
 		}
-		m := bson.M{strconv.FormatInt(userID, 10): c.expected[userID]}
 		setBsonD := bson.D{
-			{"$set", bson.D{{"votes", m}}},
+			{"$set", bson.D{{voteKey, c.expected[userID]}}},
 		}
 
 		if c.name == "UnvoteHappyCase" {
@@ -199,7 +201,7 @@ func TestVotes(t *testing.T) {
 		mockFindOneResult.EXPECT().Decode(gomock.AssignableToTypeOf(expectedPost)).
 			SetArg(0, *expectedPost).Return(c.findOneErr)
 
-		mockUpdateResult.EXPECT().GetModifiedCount().Return(int64(1))
+		mockUpdateResult.EXPECT().GetMatchedCount().Return(int64(1))
 
 		res, err := c.vote(testRepo, ctx, postID, userID)
 
@@ -247,6 +249,28 @@ func TestGetByID(t *testing.T) {
 	}
 }
 
+func TestGetByIDNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCollection := common.NewMockCollectionHelper(ctrl)
+	mockSingleCollection := common.NewMockSingleResultHelper(ctrl)
+
+	repo := &PostsRepoMongo{collection: mockCollection}
+	ctx := context.Background()
+
+	mockCollection.EXPECT().
+		FindOneAndUpdate(ctx, gomock.Any(), gomock.Any()).
+		Return(mockSingleCollection)
+	mockSingleCollection.EXPECT().Decode(gomock.Any()).Return(mongo.ErrNoDocuments)
+
+	res, err := repo.GetByID(ctx, primitive.NewObjectID())
+	if err != ErrNotFound {
+		t.Errorf("test fail, expected error: %v, but was %v", ErrNotFound, err)
+	}
+	if res != nil {
+		t.Errorf("test fail, expected nil but was %v", res)
+	}
+}
+
 func TestAdd(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockCollection := common.NewMockCollectionHelper(ctrl)
@@ -299,6 +323,32 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestAnonymizeAuthor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCollection := common.NewMockCollectionHelper(ctrl)
+	mockUpdateResult := common.NewMockUpdateResultHelper(ctrl)
+	repo := &PostsRepoMongo{collection: mockCollection}
+
+	ctx := context.Background()
+	filter := bson.M{"authorID": int64(256)}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "authorID", Value: user.DeletedID}}}}
+
+	updateErr := errors.New("error while calling collection.updateMany")
+	mockCollection.EXPECT().UpdateMany(ctx, gomock.Eq(filter), gomock.Eq(update)).Return(mockUpdateResult, nil)
+	mockCollection.EXPECT().UpdateMany(ctx, gomock.Eq(filter), gomock.Eq(update)).Return(nil, updateErr)
+	mockUpdateResult.EXPECT().GetModifiedCount().Return(int64(2))
+
+	n, err := repo.AnonymizeAuthor(ctx, 256)
+	if err != nil || n != 2 {
+		t.Errorf("test fail, expected 2, nil but was %v, %v", n, err)
+	}
+
+	_, err = repo.AnonymizeAuthor(ctx, 256)
+	if err != updateErr {
+		t.Errorf("test fail, expected error: %v, but was %v", updateErr, err)
+	}
+}
+
 func TestParseID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockCollection := common.NewMockCollectionHelper(ctrl)
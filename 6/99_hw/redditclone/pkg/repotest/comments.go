@@ -0,0 +1,188 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"redditclone/pkg/comments"
+	"redditclone/pkg/handlers"
+	"redditclone/pkg/user"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// RunCommentsRepo checks that the repo created by newRepo behaves like handlers expect:
+// comments of a post are ordered by creation time, oldest first,
+// and unknown ids give comments.ErrNotFound.
+func RunCommentsRepo(t *testing.T, newRepo func(t *testing.T) handlers.CommentsRepo) {
+	t.Run("AddAndGetByID", func(t *testing.T) { testCommentsAddAndGetByID(t, newRepo(t)) })
+	t.Run("NotFound", func(t *testing.T) { testCommentsNotFound(t, newRepo(t)) })
+	t.Run("GetByPostID", func(t *testing.T) { testCommentsGetByPostID(t, newRepo(t)) })
+	t.Run("AnonymizeAuthor", func(t *testing.T) { testCommentsAnonymizeAuthor(t, newRepo(t)) })
+	t.Run("ConcurrentAdd", func(t *testing.T) { testCommentsConcurrentAdd(t, newRepo(t)) })
+}
+
+// post ids are opaque for comments repos, plain strings work for every backend
+const (
+	testPostA = "post_a"
+	testPostB = "post_b"
+)
+
+func newTestComment(i int, postID interface{}) *comments.Comment {
+	return &comments.Comment{
+		Created:  postsBaseTime.Add(time.Duration(i) * time.Minute),
+		AuthorID: int64(i),
+		Body:     fmt.Sprintf("comment %d", i),
+		PostID:   postID,
+	}
+}
+
+func addComment(t *testing.T, repo handlers.CommentsRepo, c *comments.Comment) interface{} {
+	t.Helper()
+	id, err := repo.Add(context.Background(), c)
+	if err != nil {
+		t.Fatalf("unexpected error while adding comment: %v", err)
+	}
+
+	return roundTripID(t, repo, id)
+}
+
+func commentBodies(list []*comments.Comment) []string {
+	res := make([]string, 0, len(list))
+	for _, c := range list {
+		res = append(res, c.Body)
+	}
+
+	return res
+}
+
+func testCommentsAddAndGetByID(t *testing.T, repo handlers.CommentsRepo) {
+	id := addComment(t, repo, newTestComment(1, testPostA))
+
+	c, err := repo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(c.ID, id) {
+		t.Errorf("expected id %v, but was %v", id, c.ID)
+	}
+	if c.Body != "comment 1" || c.AuthorID != 1 || c.PostID != testPostA {
+		t.Errorf("stored comment differs from added one: %+v", c)
+	}
+}
+
+func testCommentsNotFound(t *testing.T, repo handlers.CommentsRepo) {
+	ctx := context.Background()
+	id := addComment(t, repo, newTestComment(1, testPostA))
+
+	ok, err := repo.Delete(ctx, id)
+	if err != nil || !ok {
+		t.Fatalf("expected true, nil, but was %v, %v", ok, err)
+	}
+
+	ok, err = repo.Delete(ctx, id)
+	if err != nil || ok {
+		t.Errorf("second delete: expected false, nil, but was %v, %v", ok, err)
+	}
+
+	c, err := repo.GetByID(ctx, id)
+	if !errors.Is(err, comments.ErrNotFound) {
+		t.Errorf("expected %v, but was %v", comments.ErrNotFound, err)
+	}
+	if c != nil {
+		t.Errorf("expected nil comment, but was %+v", c)
+	}
+
+	list, err := repo.GetByPostID(ctx, testPostA)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("expected no comments, but was %v", commentBodies(list))
+	}
+}
+
+func testCommentsGetByPostID(t *testing.T, repo handlers.CommentsRepo) {
+	addComment(t, repo, newTestComment(1, testPostA))
+	addComment(t, repo, newTestComment(2, testPostB))
+	addComment(t, repo, newTestComment(3, testPostA))
+
+	list, err := repo.GetByPostID(context.Background(), testPostA)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"comment 1", "comment 3"}
+	if bodies := commentBodies(list); !reflect.DeepEqual(bodies, expected) {
+		t.Errorf("expected %v, but was %v", expected, bodies)
+	}
+}
+
+func testCommentsAnonymizeAuthor(t *testing.T, repo handlers.CommentsRepo) {
+	ctx := context.Background()
+	for i, c := range []*comments.Comment{newTestComment(1, testPostA), newTestComment(2, testPostB), newTestComment(3, testPostA)} {
+		// comments 1 and 2 are written by author 7
+		if i < 2 {
+			c.AuthorID = 7
+		}
+		addComment(t, repo, c)
+	}
+
+	list, err := repo.GetByAuthorID(ctx, 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := commentBodies(list); !reflect.DeepEqual(got, []string{"comment 1", "comment 2"}) {
+		t.Errorf("expected comments of author 7, but was %v", got)
+	}
+
+	n, err := repo.AnonymizeAuthor(ctx, 7)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2, nil, but was %v, %v", n, err)
+	}
+
+	list, err = repo.GetByAuthorID(ctx, 7)
+	if err != nil || len(list) != 0 {
+		t.Errorf("expected no comments of author 7, but was %v, %v", commentBodies(list), err)
+	}
+
+	list, err = repo.GetByAuthorID(ctx, user.DeletedID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := commentBodies(list); !reflect.DeepEqual(got, []string{"comment 1", "comment 2"}) {
+		t.Errorf("expected anonymized comments, but was %v", got)
+	}
+}
+
+func testCommentsConcurrentAdd(t *testing.T, repo handlers.CommentsRepo) {
+	ctx := context.Background()
+	ids := make([]interface{}, concurrency)
+	errs := make([]error, concurrency)
+	parallel(concurrency, func(i int) {
+		ids[i], errs[i] = repo.Add(ctx, newTestComment(i, testPostA))
+	})
+
+	seen := make(map[string]bool, concurrency)
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		key := formatID(ids[i])
+		if seen[key] {
+			t.Errorf("id %v returned twice", key)
+		}
+		seen[key] = true
+	}
+
+	list, err := repo.GetByPostID(ctx, testPostA)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != concurrency {
+		t.Errorf("expected %d comments, but was %d", concurrency, len(list))
+	}
+}
@@ -0,0 +1,331 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"redditclone/pkg/handlers"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/user"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// RunPostsRepo checks that the repo created by newRepo behaves like handlers expect:
+//   - Add upvotes the post by its author and returns an id accepted by ParseID
+//   - GetByID increments views, unknown ids give posts.ErrNotFound
+//   - lists are ordered by creation time, oldest first
+//   - votes of one user never touch votes of the others, repeating a vote is not an error
+//   - AnonymizeAuthor moves posts to user.DeletedID and keeps their votes
+func RunPostsRepo(t *testing.T, newRepo func(t *testing.T) handlers.PostsRepo) {
+	t.Run("AddAndGetByID", func(t *testing.T) { testPostsAddAndGetByID(t, newRepo(t)) })
+	t.Run("NotFound", func(t *testing.T) { testPostsNotFound(t, newRepo(t)) })
+	t.Run("Ordering", func(t *testing.T) { testPostsOrdering(t, newRepo(t)) })
+	t.Run("Delete", func(t *testing.T) { testPostsDelete(t, newRepo(t)) })
+	t.Run("Voting", func(t *testing.T) { testPostsVoting(t, newRepo(t)) })
+	t.Run("AnonymizeAuthor", func(t *testing.T) { testPostsAnonymizeAuthor(t, newRepo(t)) })
+	t.Run("ConcurrentAdd", func(t *testing.T) { testPostsConcurrentAdd(t, newRepo(t)) })
+	t.Run("ConcurrentVotesAndViews", func(t *testing.T) { testPostsConcurrentVotesAndViews(t, newRepo(t)) })
+}
+
+var postsBaseTime = time.Date(2021, time.February, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestPost(i int, authorID int64, category posts.PostCategory) *posts.Post {
+	return &posts.Post{
+		Type:     posts.Text,
+		Title:    fmt.Sprintf("post %d", i),
+		AuthorID: authorID,
+		Category: category,
+		Text:     "some text",
+		Created:  postsBaseTime.Add(time.Duration(i) * time.Minute),
+	}
+}
+
+func addPost(t *testing.T, repo handlers.PostsRepo, p *posts.Post) interface{} {
+	t.Helper()
+	id, err := repo.Add(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error while adding post: %v", err)
+	}
+
+	return roundTripID(t, repo, id)
+}
+
+func postTitles(list []*posts.Post) []string {
+	res := make([]string, 0, len(list))
+	for _, p := range list {
+		res = append(res, p.Title)
+	}
+
+	return res
+}
+
+func testPostsAddAndGetByID(t *testing.T, repo handlers.PostsRepo) {
+	ctx := context.Background()
+	id := addPost(t, repo, newTestPost(1, 7, posts.Music))
+
+	p, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(p.ID, id) {
+		t.Errorf("expected id %v, but was %v", id, p.ID)
+	}
+	if p.Title != "post 1" || p.AuthorID != 7 || p.Category != posts.Music || p.Text != "some text" {
+		t.Errorf("stored post differs from added one: %+v", p)
+	}
+	if !reflect.DeepEqual(p.Votes, map[int64]posts.VoteValue{7: posts.Upvote}) {
+		t.Errorf("added post should be upvoted by author, but votes were %v", p.Votes)
+	}
+	if p.Views != 1 {
+		t.Errorf("expected 1 view, but was %v", p.Views)
+	}
+
+	p, err = repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Views != 2 {
+		t.Errorf("expected 2 views, but was %v", p.Views)
+	}
+}
+
+func testPostsNotFound(t *testing.T, repo handlers.PostsRepo) {
+	ctx := context.Background()
+	id := addPost(t, repo, newTestPost(1, 7, posts.Music))
+	if _, err := repo.Delete(ctx, id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p, err := repo.GetByID(ctx, id)
+	if !errors.Is(err, posts.ErrNotFound) {
+		t.Errorf("expected %v, but was %v", posts.ErrNotFound, err)
+	}
+	if p != nil {
+		t.Errorf("expected nil post, but was %+v", p)
+	}
+
+	_, err = repo.Upvote(ctx, id, 8)
+	if !errors.Is(err, posts.ErrNotFound) {
+		t.Errorf("upvote: expected %v, but was %v", posts.ErrNotFound, err)
+	}
+	_, err = repo.Unvote(ctx, id, 8)
+	if !errors.Is(err, posts.ErrNotFound) {
+		t.Errorf("unvote: expected %v, but was %v", posts.ErrNotFound, err)
+	}
+
+	list, err := repo.GetByAuthorID(ctx, int64(100500))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("expected no posts, but was %v", postTitles(list))
+	}
+}
+
+func testPostsOrdering(t *testing.T, repo handlers.PostsRepo) {
+	ctx := context.Background()
+	addPost(t, repo, newTestPost(1, 1, posts.Music))
+	addPost(t, repo, newTestPost(2, 2, posts.Funny))
+	addPost(t, repo, newTestPost(3, 1, posts.Funny))
+	addPost(t, repo, newTestPost(4, 2, posts.Music))
+
+	cases := []struct {
+		name     string
+		get      func() ([]*posts.Post, error)
+		expected []string
+	}{
+		{
+			name:     "GetAll",
+			get:      func() ([]*posts.Post, error) { return repo.GetAll(ctx) },
+			expected: []string{"post 1", "post 2", "post 3", "post 4"},
+		},
+		{
+			name:     "GetByCategory",
+			get:      func() ([]*posts.Post, error) { return repo.GetByCategory(ctx, posts.Funny) },
+			expected: []string{"post 2", "post 3"},
+		},
+		{
+			name:     "GetByAuthorID",
+			get:      func() ([]*posts.Post, error) { return repo.GetByAuthorID(ctx, int64(2)) },
+			expected: []string{"post 2", "post 4"},
+		},
+	}
+
+	for _, c := range cases {
+		list, err := c.get()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.name, err)
+		}
+
+		if titles := postTitles(list); !reflect.DeepEqual(titles, c.expected) {
+			t.Errorf("%s: expected %v, but was %v", c.name, c.expected, titles)
+		}
+	}
+}
+
+func testPostsDelete(t *testing.T, repo handlers.PostsRepo) {
+	ctx := context.Background()
+	id := addPost(t, repo, newTestPost(1, 1, posts.Music))
+	addPost(t, repo, newTestPost(2, 1, posts.Music))
+	addPost(t, repo, newTestPost(3, 1, posts.Music))
+
+	ok, err := repo.Delete(ctx, id)
+	if err != nil || !ok {
+		t.Fatalf("expected true, nil, but was %v, %v", ok, err)
+	}
+
+	ok, err = repo.Delete(ctx, id)
+	if err != nil || ok {
+		t.Fatalf("second delete: expected false, nil, but was %v, %v", ok, err)
+	}
+
+	list, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if titles := postTitles(list); !reflect.DeepEqual(titles, []string{"post 2", "post 3"}) {
+		t.Errorf("delete should keep the order of other posts, but was %v", titles)
+	}
+}
+
+func testPostsAnonymizeAuthor(t *testing.T, repo handlers.PostsRepo) {
+	ctx := context.Background()
+	id := addPost(t, repo, newTestPost(1, 7, posts.Music))
+	addPost(t, repo, newTestPost(2, 7, posts.News))
+	addPost(t, repo, newTestPost(3, 8, posts.Music))
+	if _, err := repo.Upvote(ctx, id, 8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n, err := repo.AnonymizeAuthor(ctx, 7)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2, nil, but was %v, %v", n, err)
+	}
+
+	list, err := repo.GetByAuthorID(ctx, int64(7))
+	if err != nil || len(list) != 0 {
+		t.Errorf("expected no posts of author 7, but was %v, %v", postTitles(list), err)
+	}
+
+	list, err = repo.GetByAuthorID(ctx, user.DeletedID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if titles := postTitles(list); !reflect.DeepEqual(titles, []string{"post 1", "post 2"}) {
+		t.Errorf("expected anonymized posts, but was %v", titles)
+	}
+
+	p, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(p.Votes, map[int64]posts.VoteValue{7: posts.Upvote, 8: posts.Upvote}) {
+		t.Errorf("votes should be kept, but was %v", p.Votes)
+	}
+}
+
+func testPostsVoting(t *testing.T, repo handlers.PostsRepo) {
+	ctx := context.Background()
+	id := addPost(t, repo, newTestPost(1, 1, posts.Music))
+
+	steps := []struct {
+		name     string
+		vote     func(context.Context, interface{}, int64) (*posts.Post, error)
+		userID   int64
+		expected map[int64]posts.VoteValue
+	}{
+		{"Upvote", repo.Upvote, 2, map[int64]posts.VoteValue{1: posts.Upvote, 2: posts.Upvote}},
+		{"Downvote", repo.DownVote, 3, map[int64]posts.VoteValue{1: posts.Upvote, 2: posts.Upvote, 3: posts.Downvote}},
+		{"UpvoteAgain", repo.Upvote, 2, map[int64]posts.VoteValue{1: posts.Upvote, 2: posts.Upvote, 3: posts.Downvote}},
+		{"ChangeVote", repo.Upvote, 3, map[int64]posts.VoteValue{1: posts.Upvote, 2: posts.Upvote, 3: posts.Upvote}},
+		{"Unvote", repo.Unvote, 2, map[int64]posts.VoteValue{1: posts.Upvote, 3: posts.Upvote}},
+		{"UnvoteAuthor", repo.Unvote, 1, map[int64]posts.VoteValue{3: posts.Upvote}},
+	}
+
+	for _, s := range steps {
+		p, err := s.vote(ctx, id, s.userID)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", s.name, err)
+		}
+		if !reflect.DeepEqual(p.Votes, s.expected) {
+			t.Errorf("%s: expected %v, but was %v", s.name, s.expected, p.Votes)
+		}
+	}
+}
+
+func testPostsConcurrentAdd(t *testing.T, repo handlers.PostsRepo) {
+	ctx := context.Background()
+	ids := make([]interface{}, concurrency)
+	errs := make([]error, concurrency)
+	parallel(concurrency, func(i int) {
+		ids[i], errs[i] = repo.Add(ctx, newTestPost(i, int64(i), posts.News))
+	})
+
+	seen := make(map[string]bool, concurrency)
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		key := formatID(ids[i])
+		if seen[key] {
+			t.Errorf("id %v returned twice", key)
+		}
+		seen[key] = true
+	}
+
+	list, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != concurrency {
+		t.Errorf("expected %d posts, but was %d", concurrency, len(list))
+	}
+}
+
+func testPostsConcurrentVotesAndViews(t *testing.T, repo handlers.PostsRepo) {
+	ctx := context.Background()
+	id := addPost(t, repo, newTestPost(1, 1, posts.Music))
+
+	errs := make([]error, concurrency)
+	parallel(concurrency, func(i int) {
+		userID := int64(100 + i)
+		if i%2 == 0 {
+			_, errs[i] = repo.Upvote(ctx, id, userID)
+		} else {
+			_, errs[i] = repo.DownVote(ctx, id, userID)
+		}
+		if errs[i] == nil {
+			_, errs[i] = repo.GetByID(ctx, id)
+		}
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expected := map[int64]posts.VoteValue{1: posts.Upvote}
+	for i := 0; i < concurrency; i++ {
+		if i%2 == 0 {
+			expected[int64(100+i)] = posts.Upvote
+		} else {
+			expected[int64(100+i)] = posts.Downvote
+		}
+	}
+
+	p, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(p.Votes, expected) {
+		t.Errorf("lost votes, expected %v, but was %v", expected, p.Votes)
+	}
+	if p.Views != concurrency+1 {
+		t.Errorf("lost views, expected %d, but was %d", concurrency+1, p.Views)
+	}
+}
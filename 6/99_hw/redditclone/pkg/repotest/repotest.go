@@ -0,0 +1,59 @@
+// Package repotest holds the contract every repository implementation
+// has to satisfy to be used by the handlers.
+//
+// Each backend runs the same suite from its own tests:
+//
+//	repotest.RunPostsRepo(t, func(t *testing.T) handlers.PostsRepo {
+//		return posts.NewRepo()
+//	})
+//
+// The factory is called once per subtest and must return an empty repository.
+package repotest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// concurrency is the number of goroutines used by the concurrent subtests
+const concurrency = 20
+
+// idParser is implemented by posts and comments repos
+type idParser interface {
+	ParseID(string) (interface{}, error)
+}
+
+// formatID writes an id the way handlers expose it in JSON and URLs:
+// mongo ObjectIDs as hex, fmt.Sprint would give ObjectID("...")
+func formatID(id interface{}) string {
+	if h, ok := id.(interface{ Hex() string }); ok {
+		return h.Hex()
+	}
+	return fmt.Sprint(id)
+}
+
+// roundTripID turns an id returned by Add into the form the repo expects back,
+// the same way handlers do with ids taken from the URL
+func roundTripID(t *testing.T, repo idParser, id interface{}) interface{} {
+	t.Helper()
+	parsed, err := repo.ParseID(formatID(id))
+	if err != nil {
+		t.Fatalf("can't parse id %v returned by Add: %v", id, err)
+	}
+
+	return parsed
+}
+
+// parallel runs f concurrently for i in [0, n) and waits for all of them
+func parallel(n int, f func(i int)) {
+	wg := &sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,24 @@
+package repotest
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFormatID(t *testing.T) {
+	oid := primitive.NewObjectID()
+	cases := []struct {
+		id       interface{}
+		expected string
+	}{
+		{oid, oid.Hex()},
+		{oid.Hex(), oid.Hex()},
+		{uint64(42), "42"},
+	}
+	for _, c := range cases {
+		if got := formatID(c.id); got != c.expected {
+			t.Errorf("formatID(%#v): expected %q, got %q", c.id, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package repotest
+
+import (
+	"bytes"
+	"fmt"
+	"redditclone/pkg/handlers"
+	"redditclone/pkg/user"
+	"testing"
+)
+
+// RunUsersRepo checks that the repo created by newRepo behaves like handlers expect.
+// Unknown users are reported as nil, nil: Login and Register rely on it.
+// Update never changes the username and the created date.
+func RunUsersRepo(t *testing.T, newRepo func(t *testing.T) handlers.UsersRepo) {
+	t.Run("AddAndGet", func(t *testing.T) { testUsersAddAndGet(t, newRepo(t)) })
+	t.Run("NotFound", func(t *testing.T) { testUsersNotFound(t, newRepo(t)) })
+	t.Run("UpdateAndDelete", func(t *testing.T) { testUsersUpdateAndDelete(t, newRepo(t)) })
+	t.Run("ConcurrentAdd", func(t *testing.T) { testUsersConcurrentAdd(t, newRepo(t)) })
+}
+
+func testUsersAddAndGet(t *testing.T, repo handlers.UsersRepo) {
+	u := &user.User{Username: "vectoreal", Password: []byte("secretPASSW0rd")}
+	id, err := repo.Add(u)
+	if err != nil {
+		t.Fatalf("unexpected error while adding user: %v", err)
+	}
+
+	byID, err := repo.GetByID(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byName, err := repo.GetByUsername(u.Username)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, got := range []*user.User{byID, byName} {
+		if got == nil {
+			t.Fatalf("expected user, but was nil")
+		}
+		if got.ID != id || got.Username != u.Username || !bytes.Equal(got.Password, u.Password) {
+			t.Errorf("expected %v, but was %v", &user.User{ID: id, Username: u.Username, Password: u.Password}, got)
+		}
+	}
+}
+
+func testUsersNotFound(t *testing.T, repo handlers.UsersRepo) {
+	id, err := repo.Add(&user.User{Username: "exists", Password: []byte("secretPASSW0rd")})
+	if err != nil {
+		t.Fatalf("unexpected error while adding user: %v", err)
+	}
+
+	u, err := repo.GetByID(id + 100500)
+	if u != nil || err != nil {
+		t.Errorf("GetByID: expected nil, nil, but was %v, %v", u, err)
+	}
+
+	u, err = repo.GetByUsername("nobody")
+	if u != nil || err != nil {
+		t.Errorf("GetByUsername: expected nil, nil, but was %v, %v", u, err)
+	}
+}
+
+func testUsersUpdateAndDelete(t *testing.T, repo handlers.UsersRepo) {
+	id, err := repo.Add(&user.User{Username: "vectoreal", Password: []byte("secretPASSW0rd")})
+	if err != nil {
+		t.Fatalf("unexpected error while adding user: %v", err)
+	}
+
+	added, err := repo.GetByID(id)
+	if err != nil || added == nil {
+		t.Fatalf("expected user, but was %v, %v", added, err)
+	}
+	if added.Created.IsZero() {
+		t.Errorf("expected created date to be set")
+	}
+
+	changed := &user.User{ID: id, Username: "renamed", Password: []byte("newPASSW0rd"), Bio: "gopher", AvatarURL: "https://mail.ru/avatar.png"}
+	for i := 0; i < 2; i++ {
+		// the second update saves the same values
+		ok, err := repo.Update(changed)
+		if err != nil || !ok {
+			t.Fatalf("update #%d: expected true, nil, but was %v, %v", i, ok, err)
+		}
+	}
+
+	got, err := repo.GetByID(id)
+	if err != nil || got == nil {
+		t.Fatalf("expected user, but was %v, %v", got, err)
+	}
+	if got.Username != "vectoreal" || !got.Created.Equal(added.Created) {
+		t.Errorf("username and created date should stay the same, but was %v", got)
+	}
+	if !bytes.Equal(got.Password, changed.Password) || got.Bio != changed.Bio || got.AvatarURL != changed.AvatarURL {
+		t.Errorf("expected %v, but was %v", changed, got)
+	}
+
+	ok, err := repo.Delete(id)
+	if err != nil || !ok {
+		t.Fatalf("expected true, nil, but was %v, %v", ok, err)
+	}
+
+	got, err = repo.GetByID(id)
+	if got != nil || err != nil {
+		t.Errorf("expected nil, nil after delete, but was %v, %v", got, err)
+	}
+
+	ok, err = repo.Delete(id)
+	if err != nil || ok {
+		t.Errorf("expected false, nil for deleted user, but was %v, %v", ok, err)
+	}
+
+	ok, err = repo.Update(changed)
+	if err != nil || ok {
+		t.Errorf("expected false, nil for update of deleted user, but was %v, %v", ok, err)
+	}
+}
+
+func testUsersConcurrentAdd(t *testing.T, repo handlers.UsersRepo) {
+	ids := make([]int64, concurrency)
+	errs := make([]error, concurrency)
+	parallel(concurrency, func(i int) {
+		ids[i], errs[i] = repo.Add(&user.User{Username: fmt.Sprintf("user_%d", i), Password: []byte("secretPASSW0rd")})
+	})
+
+	seen := make(map[int64]bool, concurrency)
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if seen[ids[i]] {
+			t.Errorf("id %v returned twice", ids[i])
+		}
+		seen[ids[i]] = true
+
+		u, err := repo.GetByID(ids[i])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if u == nil || u.Username != fmt.Sprintf("user_%d", i) {
+			t.Errorf("expected user_%d for id %d, but was %v", i, ids[i], u)
+		}
+	}
+}
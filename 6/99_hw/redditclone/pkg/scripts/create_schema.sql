@@ -2,5 +2,8 @@ CREATE TABLE IF NOT EXISTS `users` (
     `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
     `password`  VARBINARY(100) NOT NULL,
     `username` VARCHAR(50) NOT NULL,
+    `bio` VARCHAR(500) NOT NULL DEFAULT '',
+    `avatar_url` VARCHAR(2048) NOT NULL DEFAULT '',
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`)
 ) ENGINE=INNODB DEFAULT CHARSET=utf8;
\ No newline at end of file
@@ -84,12 +84,14 @@ func (sm *SessionManagerRedis) Destroy(ctx context.Context, w http.ResponseWrite
 }
 
 func (sm *SessionManagerRedis) DestroyAll(ctx context.Context, user *User) error {
-	sessionIDs, err := sm.rdb.SMembers(ctx, strconv.FormatInt(user.ID, 10)).Result()
+	userKey := strconv.FormatInt(user.ID, 10)
+	sessionIDs, err := sm.rdb.SMembers(ctx, userKey).Result()
 	if err != nil {
 		return err
 	}
 
-	err = sm.rdb.Del(ctx, sessionIDs...).Err()
+	// the set goes too, so Del always gets at least one key
+	err = sm.rdb.Del(ctx, append(sessionIDs, userKey)...).Err()
 	if err != nil {
 		return err
 	}
@@ -114,7 +114,24 @@ func TestDestroyAll(t *testing.T) {
 	sm := NewSessionManagerRedis(mock, jwtMock)
 
 	mock.On("SMembers", ctx, strconv.FormatInt(u.ID, 10)).Return(redis.NewStringSliceResult([]string{sessID}, nil))
-	mock.On("Del", ctx, []string{sessID}).Return(redis.NewIntResult(1, nil))
+	mock.On("Del", ctx, []string{sessID, strconv.FormatInt(u.ID, 10)}).Return(redis.NewIntResult(2, nil))
+
+	err := sm.DestroyAll(ctx, u)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err.Error())
+	}
+}
+
+func TestDestroyAllWithoutSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	jwtMock := NewMockSessionManager(ctrl)
+	ctx := context.Background()
+	mock := redismock.NewMock()
+	sm := NewSessionManagerRedis(mock, jwtMock)
+
+	mock.On("SMembers", ctx, strconv.FormatInt(u.ID, 10)).Return(redis.NewStringSliceResult([]string{}, nil))
+	mock.On("Del", ctx, []string{strconv.FormatInt(u.ID, 10)}).Return(redis.NewIntResult(0, nil))
 
 	err := sm.DestroyAll(ctx, u)
 
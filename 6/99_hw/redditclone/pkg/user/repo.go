@@ -1,6 +1,9 @@
 package user
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
 type MemoryUsersRepo struct {
 	lastID int64
@@ -13,27 +16,23 @@ func NewRepo() *MemoryUsersRepo {
 }
 
 func (repo *MemoryUsersRepo) GetAll() ([]*User, error) {
-	return repo.data, nil
-}
-
-func (repo *MemoryUsersRepo) GetByID(id int64) (*User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	res := make([]*User, 0, len(repo.data))
 	for _, u := range repo.data {
-		if u.ID == id {
-			return u, nil
-		}
+		res = append(res, copyUser(u))
 	}
 
-	return nil, nil
+	return res, nil
 }
 
-func (repo *MemoryUsersRepo) GetByUsername(username string) (*User, error) {
-	for _, u := range repo.data {
-		if u.Username == username {
-			return u, nil
-		}
-	}
+// GetByID returns nil, nil for unknown users, the same as UserRepoSQL
+func (repo *MemoryUsersRepo) GetByID(id int64) (*User, error) {
+	return repo.getUser(func(u *User) bool { return u.ID == id })
+}
 
-	return nil, nil
+func (repo *MemoryUsersRepo) GetByUsername(username string) (*User, error) {
+	return repo.getUser(func(u *User) bool { return u.Username == username })
 }
 
 func (repo *MemoryUsersRepo) Add(user *User) (int64, error) {
@@ -41,14 +40,57 @@ func (repo *MemoryUsersRepo) Add(user *User) (int64, error) {
 	defer repo.mu.Unlock()
 	repo.lastID++
 	user.ID = repo.lastID
-	repo.data = append(repo.data, user)
+	if user.Created.IsZero() {
+		user.Created = time.Now()
+	}
+	repo.data = append(repo.data, copyUser(user))
 	return repo.lastID, nil
 }
 
 func (repo *MemoryUsersRepo) Update(user *User) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for i, u := range repo.data {
+		if u.ID == user.ID {
+			// username and created date never change, as in UserRepoSQL
+			updated := copyUser(user)
+			updated.Username = u.Username
+			updated.Created = u.Created
+			repo.data[i] = updated
+			return true, nil
+		}
+	}
+
 	return false, nil
 }
 
-func (repo *MemoryUsersRepo) Delete(user *User) (bool, error) {
+func (repo *MemoryUsersRepo) Delete(id int64) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for i, u := range repo.data {
+		if u.ID == id {
+			repo.data = append(repo.data[:i], repo.data[i+1:]...)
+			return true, nil
+		}
+	}
+
 	return false, nil
 }
+
+func (repo *MemoryUsersRepo) getUser(match func(*User) bool) (*User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, u := range repo.data {
+		if match(u) {
+			return copyUser(u), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func copyUser(u *User) *User {
+	res := *u
+	res.Password = append([]byte(nil), u.Password...)
+	return &res
+}
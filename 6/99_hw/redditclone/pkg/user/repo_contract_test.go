@@ -0,0 +1,51 @@
+package user_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"redditclone/pkg/handlers"
+	"redditclone/pkg/repotest"
+	"redditclone/pkg/user"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func TestMemoryUsersRepoContract(t *testing.T) {
+	repotest.RunUsersRepo(t, func(t *testing.T) handlers.UsersRepo {
+		return user.NewRepo()
+	})
+}
+
+// TestSQLUsersRepoContract needs a running mysql, e.g.
+// REDDITCLONE_TEST_MYSQL="root:qwer1234@tcp(localhost:3306)/redditclone_test" go test ./pkg/user/
+// the users table is truncated before every subtest
+func TestSQLUsersRepoContract(t *testing.T) {
+	dsn := os.Getenv("REDDITCLONE_TEST_MYSQL")
+	if dsn == "" {
+		t.Skip("REDDITCLONE_TEST_MYSQL is not set")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("can't open mysql: %v", err)
+	}
+	defer db.Close()
+
+	schema, err := ioutil.ReadFile("../scripts/create_schema.sql")
+	if err != nil {
+		t.Fatalf("can't read schema: %v", err)
+	}
+	if _, err = db.Exec(string(schema)); err != nil {
+		t.Fatalf("can't create schema: %v", err)
+	}
+
+	repotest.RunUsersRepo(t, func(t *testing.T) handlers.UsersRepo {
+		if _, err := db.Exec("TRUNCATE TABLE users"); err != nil {
+			t.Fatalf("can't truncate users: %v", err)
+		}
+
+		return user.NewUserRepoSQL(db)
+	})
+}
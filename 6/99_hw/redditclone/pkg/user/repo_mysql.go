@@ -2,8 +2,12 @@ package user
 
 import (
 	"database/sql"
+	"time"
 )
 
+// the dsn needs parseTime=true to scan `created`
+const userColumns = "`id`, `username`, `password`, `bio`, `avatar_url`, `created`"
+
 type UserRepoSQL struct {
 	db *sql.DB
 }
@@ -13,27 +17,65 @@ func NewUserRepoSQL(db *sql.DB) *UserRepoSQL {
 }
 
 func (repo *UserRepoSQL) GetByID(id int64) (*User, error) {
-	query := "SELECT `id`, `username`, `password` FROM users WHERE id = ?"
-	r := repo.db.QueryRow(query, id)
+	query := "SELECT " + userColumns + " FROM users WHERE id = ?"
+	return scanUser(repo.db.QueryRow(query, id))
+}
 
-	u := User{}
-	err := r.Scan(&u.ID, &u.Username, &u.Password)
-	if err == sql.ErrNoRows {
-		return nil, nil
+func (repo *UserRepoSQL) GetByUsername(username string) (*User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE username = ?"
+	return scanUser(repo.db.QueryRow(query, username))
+}
+
+func (repo *UserRepoSQL) Add(user *User) (int64, error) {
+	if user.Created.IsZero() {
+		user.Created = time.Now()
 	}
+
+	query := "INSERT INTO users (`username`, `password`, `bio`, `avatar_url`, `created`) VALUES (?, ?, ?, ?, ?)"
+	r, err := repo.db.Exec(query, user.Username, user.Password, user.Bio, user.AvatarURL, user.Created)
+
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	return &u, nil
+	lastID, err := r.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return lastID, nil
 }
 
-func (repo *UserRepoSQL) GetByUsername(username string) (*User, error) {
-	query := "SELECT `id`, `username`, `password` FROM users WHERE username = ?"
-	r := repo.db.QueryRow(query, username)
+// Update saves password and profile fields, username and created date never change
+func (repo *UserRepoSQL) Update(user *User) (bool, error) {
+	query := "UPDATE users SET `password` = ?, `bio` = ?, `avatar_url` = ? WHERE id = ?"
+	r, err := repo.db.Exec(query, user.Password, user.Bio, user.AvatarURL, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := affected(r)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	// mysql does not count rows updated with the same values
+	u, err := repo.GetByID(user.ID)
+	return u != nil, err
+}
+
+func (repo *UserRepoSQL) Delete(id int64) (bool, error) {
+	r, err := repo.db.Exec("DELETE FROM users WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+
+	return affected(r)
+}
 
+func scanUser(r *sql.Row) (*User, error) {
 	u := User{}
-	err := r.Scan(&u.ID, &u.Username, &u.Password)
+	err := r.Scan(&u.ID, &u.Username, &u.Password, &u.Bio, &u.AvatarURL, &u.Created)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -44,18 +86,11 @@ func (repo *UserRepoSQL) GetByUsername(username string) (*User, error) {
 	return &u, nil
 }
 
-func (repo *UserRepoSQL) Add(user *User) (int64, error) {
-	query := "INSERT INTO users (`username`, `password`) VALUES (?, ?)"
-	r, err := repo.db.Exec(query, user.Username, user.Password)
-
-	if err != nil {
-		return 0, err
-	}
-
-	lastID, err := r.LastInsertId()
+func affected(r sql.Result) (bool, error) {
+	n, err := r.RowsAffected()
 	if err != nil {
-		return 0, err
+		return false, err
 	}
 
-	return lastID, nil
+	return n > 0, nil
 }
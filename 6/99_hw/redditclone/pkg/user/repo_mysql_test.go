@@ -5,6 +5,7 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
@@ -15,7 +16,8 @@ type getByFieldTestCase struct {
 }
 
 var id = int64(25)
-var u = &User{ID: id, Username: "vectoreal", Password: []byte("secretPASSW0rd")}
+var created = time.Date(2021, time.February, 16, 21, 45, 2, 0, time.UTC)
+var u = &User{ID: id, Username: "vectoreal", Password: []byte("secretPASSW0rd"), Bio: "gopher", AvatarURL: "https://mail.ru/avatar.png", Created: created}
 
 var cases = []getByFieldTestCase{
 	{
@@ -43,11 +45,11 @@ func TestGetByID(t *testing.T) {
 
 		repo := NewUserRepoSQL(db)
 
-		rows := sqlmock.NewRows([]string{"id", "username", "password"}).
-			AddRow(id, u.Username, u.Password)
+		rows := sqlmock.NewRows([]string{"id", "username", "password", "bio", "avatar_url", "created"}).
+			AddRow(id, u.Username, u.Password, u.Bio, u.AvatarURL, u.Created)
 
 		mock.
-			ExpectQuery("SELECT `id`, `username`, `password` FROM users WHERE").
+			ExpectQuery("SELECT `id`, `username`, `password`, `bio`, `avatar_url`, `created` FROM users WHERE").
 			WithArgs(tc.param).
 			WillReturnRows(rows)
 
@@ -62,7 +64,7 @@ func TestGetByID(t *testing.T) {
 
 		// error
 		mock.
-			ExpectQuery("SELECT `id`, `username`, `password` FROM users WHERE").
+			ExpectQuery("SELECT `id`, `username`, `password`, `bio`, `avatar_url`, `created` FROM users WHERE").
 			WithArgs(tc.param).
 			WillReturnError(errors.New("db_error"))
 
@@ -78,7 +80,7 @@ func TestGetByID(t *testing.T) {
 
 		// no rows
 		mock.
-			ExpectQuery("SELECT `id`, `username`, `password` FROM users WHERE").
+			ExpectQuery("SELECT `id`, `username`, `password`, `bio`, `avatar_url`, `created` FROM users WHERE").
 			WithArgs(tc.param).
 			WillReturnError(sql.ErrNoRows)
 
@@ -100,7 +102,7 @@ func TestAdd(t *testing.T) {
 	repo := NewUserRepoSQL(db)
 	mock.
 		ExpectExec("INSERT INTO users").
-		WithArgs(u.Username, u.Password).
+		WithArgs(u.Username, u.Password, u.Bio, u.AvatarURL, u.Created).
 		WillReturnResult(sqlmock.NewResult(u.ID, int64(1)))
 
 	id, err := repo.Add(u)
@@ -114,7 +116,7 @@ func TestAdd(t *testing.T) {
 	// error
 	mock.
 		ExpectExec("INSERT INTO users").
-		WithArgs(u.Username, u.Password).
+		WithArgs(u.Username, u.Password, u.Bio, u.AvatarURL, u.Created).
 		WillReturnError(errors.New("db_error"))
 
 	_, err = repo.Add(u)
@@ -128,7 +130,7 @@ func TestAdd(t *testing.T) {
 
 	mock.
 		ExpectExec("INSERT INTO users").
-		WithArgs(u.Username, u.Password).
+		WithArgs(u.Username, u.Password, u.Bio, u.AvatarURL, u.Created).
 		WillReturnResult(sqlmock.NewErrorResult(errors.New("db_error")))
 
 	_, err = repo.Add(u)
@@ -139,3 +141,85 @@ func TestAdd(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err.Error())
 	}
 }
+
+func TestUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepoSQL(db)
+	mock.
+		ExpectExec("UPDATE users SET").
+		WithArgs(u.Password, u.Bio, u.AvatarURL, u.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ok, err := repo.Update(u)
+	if err != nil || !ok {
+		t.Fatalf("expected true, nil but was %v, %v", ok, err)
+	}
+
+	// same values, mysql reports no affected rows
+	mock.
+		ExpectExec("UPDATE users SET").
+		WithArgs(u.Password, u.Bio, u.AvatarURL, u.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery("SELECT (.+) FROM users WHERE").
+		WithArgs(u.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "bio", "avatar_url", "created"}).
+			AddRow(id, u.Username, u.Password, u.Bio, u.AvatarURL, u.Created))
+
+	ok, err = repo.Update(u)
+	if err != nil || !ok {
+		t.Fatalf("expected true, nil but was %v, %v", ok, err)
+	}
+
+	// unknown user
+	mock.
+		ExpectExec("UPDATE users SET").
+		WithArgs(u.Password, u.Bio, u.AvatarURL, u.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery("SELECT (.+) FROM users WHERE").
+		WithArgs(u.ID).
+		WillReturnError(sql.ErrNoRows)
+
+	ok, err = repo.Update(u)
+	if err != nil || ok {
+		t.Fatalf("expected false, nil but was %v, %v", ok, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepoSQL(db)
+	mock.
+		ExpectExec("DELETE FROM users WHERE").
+		WithArgs(u.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("DELETE FROM users WHERE").
+		WithArgs(u.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ok, err := repo.Delete(u.ID)
+	if err != nil || !ok {
+		t.Fatalf("expected true, nil but was %v, %v", ok, err)
+	}
+
+	ok, err = repo.Delete(u.ID)
+	if err != nil || ok {
+		t.Fatalf("expected false, nil but was %v, %v", ok, err)
+	}
+}
@@ -1,7 +1,18 @@
 package user
 
+import "time"
+
+// DeletedID replaces the author of posts and comments of a deleted account
+const (
+	DeletedID       int64 = 0
+	DeletedUsername       = "[deleted]"
+)
+
 type User struct {
-	Username string
-	Password []byte
-	ID       int64
+	Username  string
+	Password  []byte
+	ID        int64
+	Bio       string
+	AvatarURL string
+	Created   time.Time
 }
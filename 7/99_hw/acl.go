@@ -0,0 +1,501 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	aclAllow = "allow"
+	aclDeny  = "deny"
+
+	// anyConsumer in a rule matches every consumer that sent the ACL field
+	anyConsumer = "*"
+	groupPrefix = "@"
+
+	// a quota counts calls of every consumer, every method or every consumer and method apart
+	scopeConsumer       = "consumer"
+	scopeMethod         = "method"
+	scopeConsumerMethod = "consumer_method"
+)
+
+// aclConfig is the extended ACL format:
+//
+//	{
+//		"groups": {"biz": ["biz_user", "biz_admin"]},
+//		"rules": [
+//			{"effect": "allow", "consumers": ["@biz"], "methods": ["/main.Biz/*"]},
+//			{"effect": "deny", "consumers": ["biz_user"], "methods": ["/main.Biz/Test"]}
+//		],
+//		"quotas": [
+//			{"consumers": ["@biz"], "methods": ["/main.Biz/*"], "rate": 10, "burst": 20, "daily": 10000}
+//		]
+//	}
+//
+// The old format {"consumer": ["/main.Biz/*"]} is still accepted as a list of allow rules.
+type aclConfig struct {
+	Groups map[string][]string `json:"groups"`
+	Rules  []*aclRuleConfig    `json:"rules"`
+	Quotas []*aclQuotaConfig   `json:"quotas"`
+}
+
+type aclRuleConfig struct {
+	Effect    string   `json:"effect"`
+	Consumers []string `json:"consumers"`
+	Methods   []string `json:"methods"`
+}
+
+// aclQuotaConfig limits the calls allowed by the rules, every matching quota must pass
+type aclQuotaConfig struct {
+	Consumers []string `json:"consumers"`
+	Methods   []string `json:"methods"`
+	Scope     string   `json:"scope"` // scopeConsumer if empty
+	Rate      float64  `json:"rate"`  // requests per second, 0 is no limit
+	Burst     int      `json:"burst"` // ceil(rate) if empty
+	Daily     int64    `json:"daily"` // calls per UTC day, 0 is no limit
+}
+
+// ACLError lists every problem found in an ACL, so all of them can be fixed at once
+type ACLError struct {
+	Problems []string
+}
+
+func (e *ACLError) Error() string {
+	return "invalid acl: " + strings.Join(e.Problems, "; ")
+}
+
+// aclTarget is the consumers and method patterns of a rule or a quota
+type aclTarget struct {
+	consumers map[string]bool
+	methods   []string
+}
+
+func (t *aclTarget) matches(consumer, method string) bool {
+	if !t.consumers[consumer] && !t.consumers[anyConsumer] {
+		return false
+	}
+
+	for _, m := range t.methods {
+		// patterns are validated on load
+		if ok, _ := path.Match(m, method); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+type aclRule struct {
+	allow bool
+	aclTarget
+}
+
+type aclQuota struct {
+	// id keeps the counters of an unchanged quota across reloads
+	id    string
+	scope string
+	rate  float64
+	burst int
+	daily int64
+	aclTarget
+}
+
+// aclPolicy is an immutable compiled ACL, a deny rule wins over any allow rule
+type aclPolicy struct {
+	groups   map[string][]string
+	rules    []*aclRule
+	quotas   []*aclQuota
+	known    map[string]bool
+	source   string
+	loadedAt time.Time
+}
+
+func parseACL(data []byte, source string) (*aclPolicy, error) {
+	cfg, err := decodeACL(data)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &aclPolicy{
+		groups:   cfg.Groups,
+		known:    make(map[string]bool),
+		source:   source,
+		loadedAt: time.Now(),
+	}
+	if p.groups == nil {
+		p.groups = make(map[string][]string)
+	}
+
+	var problems []string
+	for name, members := range p.groups {
+		if name == "" || strings.HasPrefix(name, groupPrefix) || name == anyConsumer {
+			problems = append(problems, fmt.Sprintf("group %q: invalid name", name))
+		}
+		for _, m := range members {
+			if m == "" || strings.HasPrefix(m, groupPrefix) || m == anyConsumer {
+				problems = append(problems, fmt.Sprintf("group %q: invalid consumer %q, groups can't be nested", name, m))
+			}
+			p.known[m] = true
+		}
+	}
+
+	for i, rc := range cfg.Rules {
+		rule, ruleProblems := p.compileRule(rc)
+		for _, pr := range ruleProblems {
+			problems = append(problems, fmt.Sprintf("rule #%d: %s", i+1, pr))
+		}
+		p.rules = append(p.rules, rule)
+	}
+
+	for i, qc := range cfg.Quotas {
+		quota, quotaProblems := p.compileQuota(i, qc)
+		for _, pr := range quotaProblems {
+			problems = append(problems, fmt.Sprintf("quota #%d: %s", i+1, pr))
+		}
+		p.quotas = append(p.quotas, quota)
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, &ACLError{Problems: problems}
+	}
+
+	return p, nil
+}
+
+func decodeACL(data []byte) (*aclConfig, error) {
+	if isLegacyACL(data) {
+		var legacy map[string][]string
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, fmt.Errorf("legacy acl: %v", err)
+		}
+
+		// sorted to keep rules order stable in Admin.Policy
+		consumers := make([]string, 0, len(legacy))
+		for c := range legacy {
+			consumers = append(consumers, c)
+		}
+		sort.Strings(consumers)
+
+		cfg := &aclConfig{}
+		for _, c := range consumers {
+			cfg.Rules = append(cfg.Rules, &aclRuleConfig{Effect: aclAllow, Consumers: []string{c}, Methods: legacy[c]})
+		}
+
+		return cfg, nil
+	}
+
+	cfg := &aclConfig{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// isLegacyACL tells the format by the first value: the new one starts with an object
+// or a list of objects, the old one with a list of methods. An empty list or a value
+// of neither kind is judged by its key, so errors are reported against the right format
+func isLegacyACL(data []byte) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return false
+	}
+	key, err := dec.Token()
+	if err != nil {
+		return false
+	}
+	name, ok := key.(string)
+	if !ok {
+		return false
+	}
+
+	tok, err := dec.Token()
+	if err == nil && tok == json.Delim('[') {
+		tok, err = dec.Token()
+		if _, ok := tok.(string); err == nil && ok {
+			return true
+		}
+	}
+	if err == nil && tok == json.Delim('{') {
+		return false
+	}
+
+	switch name {
+	case "groups", "rules", "quotas":
+		return false
+	}
+	return true
+}
+
+func (p *aclPolicy) compileRule(rc *aclRuleConfig) (*aclRule, []string) {
+	var problems []string
+	rule := &aclRule{}
+
+	switch rc.Effect {
+	case aclAllow:
+		rule.allow = true
+	case aclDeny:
+	default:
+		problems = append(problems, fmt.Sprintf("effect must be %q or %q, got %q", aclAllow, aclDeny, rc.Effect))
+	}
+
+	target, targetProblems := p.compileTarget(rc.Consumers, rc.Methods)
+	rule.aclTarget = *target
+
+	return rule, append(problems, targetProblems...)
+}
+
+func (p *aclPolicy) compileQuota(i int, qc *aclQuotaConfig) (*aclQuota, []string) {
+	var problems []string
+
+	// the position is a part of the id, so two equal quotas are counted apart
+	raw, _ := json.Marshal(qc)
+	quota := &aclQuota{id: fmt.Sprintf("%d:%s", i, raw), scope: qc.Scope, rate: qc.Rate, burst: qc.Burst, daily: qc.Daily}
+
+	switch quota.scope {
+	case "":
+		quota.scope = scopeConsumer
+	case scopeConsumer, scopeMethod, scopeConsumerMethod:
+	default:
+		problems = append(problems, fmt.Sprintf("scope must be %q, %q or %q, got %q",
+			scopeConsumer, scopeMethod, scopeConsumerMethod, quota.scope))
+	}
+
+	if quota.rate < 0 || quota.burst < 0 || quota.daily < 0 {
+		problems = append(problems, "rate, burst and daily can't be negative")
+	}
+	if quota.rate == 0 && quota.daily == 0 {
+		problems = append(problems, "no rate and no daily limit")
+	}
+	if quota.rate == 0 && quota.burst > 0 {
+		problems = append(problems, "burst without rate")
+	}
+	if quota.rate > 0 && quota.burst == 0 {
+		quota.burst = int(math.Ceil(quota.rate))
+	}
+
+	target, targetProblems := p.compileTarget(qc.Consumers, qc.Methods)
+	quota.aclTarget = *target
+
+	return quota, append(problems, targetProblems...)
+}
+
+func (p *aclPolicy) compileTarget(consumers, methods []string) (*aclTarget, []string) {
+	var problems []string
+	target := &aclTarget{consumers: make(map[string]bool)}
+
+	if len(consumers) == 0 {
+		problems = append(problems, "no consumers")
+	}
+	for _, c := range consumers {
+		switch {
+		case c == "":
+			problems = append(problems, "empty consumer")
+		case strings.HasPrefix(c, groupPrefix):
+			members, ok := p.groups[strings.TrimPrefix(c, groupPrefix)]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("unknown group %q", c))
+			}
+			for _, m := range members {
+				target.consumers[m] = true
+			}
+		default:
+			target.consumers[c] = true
+			p.known[c] = true
+		}
+	}
+
+	if len(methods) == 0 {
+		problems = append(problems, "no methods")
+	}
+	for _, m := range methods {
+		if !strings.HasPrefix(m, "/") {
+			problems = append(problems, fmt.Sprintf("method %q must start with /", m))
+			continue
+		}
+		if _, err := path.Match(m, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("method %q: %v", m, err))
+			continue
+		}
+		target.methods = append(target.methods, m)
+	}
+
+	return target, problems
+}
+
+func (p *aclPolicy) check(consumer, method string) error {
+	if len(consumer) == 0 {
+		return grpc.Errorf(codes.Unauthenticated, "no ACL field")
+	}
+
+	allowed := false
+	for _, r := range p.rules {
+		if !r.matches(consumer, method) {
+			continue
+		}
+		if !r.allow {
+			return grpc.Errorf(codes.Unauthenticated, "method %s is denied for consumer %s", method, consumer)
+		}
+		allowed = true
+	}
+
+	if allowed {
+		return nil
+	}
+
+	if !p.known[consumer] {
+		return grpc.Errorf(codes.Unauthenticated, "no auth for consumer %s", consumer)
+	}
+
+	return grpc.Errorf(codes.Unauthenticated, "no auth")
+}
+
+// ACL holds the current policy and swaps it on reload, calls in flight keep the old one
+type ACL struct {
+	mu        *sync.RWMutex
+	policy    *aclPolicy
+	version   int64
+	reloadErr error
+	limiter   *limiter
+}
+
+func NewACL(data []byte, source string) (*ACL, error) {
+	p, err := parseACL(data, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ACL{mu: &sync.RWMutex{}, policy: p, version: 1, limiter: newLimiter(time.Now)}, nil
+}
+
+// SetClock replaces time.Now for the quotas, it must be called before the first Limit
+func (a *ACL) SetClock(now func() time.Time) {
+	a.limiter.now = now
+}
+
+func (a *ACL) Check(consumer, method string) error {
+	a.mu.RLock()
+	p := a.policy
+	a.mu.RUnlock()
+	return p.check(consumer, method)
+}
+
+// Limit takes a call from every quota matching the consumer and method,
+// a call over any of them is refused with ResourceExhausted and taken from none
+func (a *ACL) Limit(consumer, method string) error {
+	a.mu.RLock()
+	p := a.policy
+	a.mu.RUnlock()
+	return a.limiter.take(p.quotas, consumer, method)
+}
+
+// Reload replaces the policy, an invalid one is reported and the current policy stays
+func (a *ACL) Reload(data []byte, source string) error {
+	p, err := parseACL(data, source)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reloadErr = err
+	if err != nil {
+		return err
+	}
+
+	a.policy = p
+	a.version++
+	a.limiter.retain(p.quotas)
+	return nil
+}
+
+// Watch polls the file and reloads the policy when its content changes, until ctx is done
+func (a *ACL) Watch(ctx context.Context, filename string, interval time.Duration) {
+	last, _ := ioutil.ReadFile(filename)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				a.mu.Lock()
+				a.reloadErr = err
+				a.mu.Unlock()
+				continue
+			}
+			if bytes.Equal(data, last) {
+				continue
+			}
+
+			last = data
+			if err := a.Reload(data, filename); err != nil {
+				log.Printf("acl reload from %s failed, keeping the previous policy: %v\n", filename, err)
+			}
+		}
+	}
+}
+
+// Describe returns the effective policy with groups expanded
+func (a *ACL) Describe() *Policy {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	p := a.policy
+
+	res := &Policy{
+		Version:  a.version,
+		LoadedAt: p.loadedAt.Unix(),
+		Source:   p.source,
+	}
+	if a.reloadErr != nil {
+		res.LastReloadError = a.reloadErr.Error()
+	}
+
+	names := make([]string, 0, len(p.groups))
+	for name := range p.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		res.Groups = append(res.Groups, &ConsumerGroup{Name: name, Consumers: p.groups[name]})
+	}
+
+	for _, r := range p.rules {
+		rule := &ACLRule{Effect: aclDeny, Methods: r.methods}
+		if r.allow {
+			rule.Effect = aclAllow
+		}
+		for c := range r.consumers {
+			rule.Consumers = append(rule.Consumers, c)
+		}
+		sort.Strings(rule.Consumers)
+		res.Rules = append(res.Rules, rule)
+	}
+
+	for _, q := range p.quotas {
+		quota := &QuotaRule{Methods: q.methods, Scope: q.scope, Rate: q.rate, Burst: int64(q.burst), Daily: q.daily}
+		for c := range q.consumers {
+			quota.Consumers = append(quota.Consumers, c)
+		}
+		sort.Strings(quota.Consumers)
+		res.Quotas = append(res.Quotas, quota)
+	}
+
+	return res
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const extendedACLData = `{
+	"groups": {"biz": ["biz_user", "biz_admin"]},
+	"rules": [
+		{"effect": "allow", "consumers": ["@biz"], "methods": ["/main.Biz/*"]},
+		{"effect": "deny", "consumers": ["biz_user"], "methods": ["/main.Biz/Test"]},
+		{"effect": "allow", "consumers": ["*"], "methods": ["/main.Admin/Policy"]}
+	]
+}`
+
+func TestACLValidation(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		problems []string
+	}{
+		{
+			name: "UnknownEffect",
+			data: `{"rules": [{"effect": "permit", "consumers": ["a"], "methods": ["/main.Biz/Check"]}]}`,
+			problems: []string{
+				`rule #1: effect must be "allow" or "deny", got "permit"`,
+			},
+		},
+		{
+			name: "EmptyRule",
+			data: `{"rules": [{"effect": "deny"}]}`,
+			problems: []string{
+				"rule #1: no consumers",
+				"rule #1: no methods",
+			},
+		},
+		{
+			name: "UnknownGroup",
+			data: `{"rules": [{"effect": "allow", "consumers": ["@ops"], "methods": ["/main.Biz/*"]}]}`,
+			problems: []string{
+				`rule #1: unknown group "@ops"`,
+			},
+		},
+		{
+			name: "NestedGroup",
+			data: `{"groups": {"a": ["@b"], "b": ["c"]}, "rules": []}`,
+			problems: []string{
+				`group "a": invalid consumer "@b", groups can't be nested`,
+			},
+		},
+		{
+			name: "BadMethods",
+			data: `{"rules": [{"effect": "allow", "consumers": ["a"], "methods": ["main.Biz/Check", "/main.Biz/[*"]}]}`,
+			problems: []string{
+				`rule #1: method "/main.Biz/[*": syntax error in pattern`,
+				`rule #1: method "main.Biz/Check" must start with /`,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		_, err := NewACL([]byte(tc.data), "test")
+		aclErr, ok := err.(*ACLError)
+		if !ok {
+			t.Errorf("%s: expected *ACLError, got %v", tc.name, err)
+			continue
+		}
+		if strings.Join(aclErr.Problems, "\n") != strings.Join(tc.problems, "\n") {
+			t.Errorf("%s: expected problems %q, got %q", tc.name, tc.problems, aclErr.Problems)
+		}
+	}
+
+	if _, err := NewACL([]byte(`{"rules": [], "owners": {}}`), "test"); err == nil {
+		t.Errorf("expected error on unknown field, got nil")
+	}
+
+	// a broken old-format ACL is reported as such, not as an unknown field of the new one
+	_, err := NewACL([]byte(`{"logger": ["/main.Admin/Logging", 1]}`), "test")
+	if err == nil || !strings.Contains(err.Error(), "legacy acl") {
+		t.Errorf("expected legacy acl error, got %v", err)
+	}
+}
+
+func TestACLCheck(t *testing.T) {
+	acl, err := NewACL([]byte(extendedACLData), "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		consumer string
+		method   string
+		allowed  bool
+	}{
+		{"biz_user", "/main.Biz/Check", true},
+		{"biz_user", "/main.Biz/Test", false},
+		{"biz_admin", "/main.Biz/Test", true},
+		{"biz_admin", "/main.Admin/Logging", false},
+		{"logger", "/main.Admin/Policy", true},
+		{"logger", "/main.Biz/Check", false},
+		{"", "/main.Admin/Policy", false},
+	}
+
+	for _, tc := range cases {
+		err := acl.Check(tc.consumer, tc.method)
+		if tc.allowed && err != nil {
+			t.Errorf("%s %s: expected access, got %v", tc.consumer, tc.method, err)
+		}
+		if !tc.allowed && status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s %s: expected Unauthenticated, got %v", tc.consumer, tc.method, err)
+		}
+	}
+}
+
+func TestACLReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acl")
+	if err != nil {
+		t.Fatalf("cant create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "acl.json")
+	if err := ioutil.WriteFile(filename, []byte(extendedACLData), 0644); err != nil {
+		t.Fatalf("cant write acl: %v", err)
+	}
+
+	wait(1)
+	ctx, finish := context.WithCancel(context.Background())
+	err = StartMyMicroservice(ctx, listenAddr, "", WithACLFile(filename, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	if _, err := biz.Test(getConsumerCtx("biz_user"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated before reload, got %v", err)
+	}
+
+	allowAll := `{"biz_user": ["/main.Biz/*", "/main.Admin/Policy"]}`
+	if err := ioutil.WriteFile(filename, []byte(allowAll), 0644); err != nil {
+		t.Fatalf("cant write acl: %v", err)
+	}
+	wait(5)
+
+	if _, err := biz.Test(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("expected access after reload, got %v", err)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte(`{"biz_user": "/main.Biz/*"}`), 0644); err != nil {
+		t.Fatalf("cant write acl: %v", err)
+	}
+	wait(5)
+
+	policy, err := adm.Policy(getConsumerCtx("biz_user"), &Nothing{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy.Version != 2 || policy.Source != filename {
+		t.Errorf("expected version 2 from %s, got %d from %s", filename, policy.Version, policy.Source)
+	}
+	if policy.LastReloadError == "" {
+		t.Errorf("expected the broken reload to be reported")
+	}
+	if len(policy.Rules) != 1 || policy.Rules[0].Effect != aclAllow || policy.Rules[0].Consumers[0] != "biz_user" {
+		t.Errorf("expected the previous policy to stay, got %+v", policy.Rules)
+	}
+}
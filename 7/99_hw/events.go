@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultEventBuffer = 1024
+	defaultSegmentSize = 4 << 20
+	defaultMaxSegments = 16
+
+	// maxEventsPerQuery caps Admin.Events, a narrower range gives the rest
+	maxEventsPerQuery = 1000
+
+	segmentExt = ".seg"
+)
+
+// seqEvent is an event with its number in the store, a replay and live events are joined by it.
+// Events read from segments of previous runs have seq 0
+type seqEvent struct {
+	seq uint64
+	*Event
+}
+
+// EventStore keeps the last events in a ring buffer and, if a segment log is set, all of them on disk.
+// Readers take a snapshot under mu and read the files without it, so a long replay never holds up Append
+type EventStore struct {
+	mu   *sync.Mutex
+	ring []seqEvent
+	next int
+	full bool
+	seq  uint64
+	log  *segmentLog
+}
+
+// NewEventStore creates a store for bufferSize events, with an empty dir nothing is written to disk
+func NewEventStore(bufferSize int, dir string) (*EventStore, error) {
+	if bufferSize <= 0 {
+		return nil, fmt.Errorf("event buffer size must be positive, got %d", bufferSize)
+	}
+
+	s := &EventStore{mu: &sync.Mutex{}, ring: make([]seqEvent, bufferSize)}
+	if dir != "" {
+		l, err := openSegmentLog(dir, defaultSegmentSize, defaultMaxSegments)
+		if err != nil {
+			return nil, err
+		}
+		s.log = l
+	}
+
+	return s, nil
+}
+
+// Append stores the event and returns its seq, the numbers go up by one from 1
+func (s *EventStore) Append(e *Event) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	s.ring[s.next] = seqEvent{seq: s.seq, Event: e}
+	s.next = (s.next + 1) % len(s.ring)
+	if s.next == 0 {
+		s.full = true
+	}
+
+	if s.log != nil {
+		// the audit log must not fail the request itself
+		if err := s.log.append(s.seq, e); err != nil {
+			log.Printf("can't write event to %s: %v\n", s.log.dir, err)
+		}
+	}
+
+	return s.seq
+}
+
+// Find returns events matching q from the oldest, at most maxEventsPerQuery of them
+func (s *EventStore) Find(q *EventQuery) (*EventList, error) {
+	if q.GetMethod() != "" {
+		if _, err := path.Match(q.GetMethod(), ""); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "bad method pattern %q: %v", q.GetMethod(), err)
+		}
+	}
+
+	limit := int(q.GetLimit())
+	if limit <= 0 || limit > maxEventsPerQuery {
+		limit = maxEventsPerQuery
+	}
+
+	res := &EventList{}
+	_, err := s.scan(func(se seqEvent) bool {
+		e := se.Event
+		if !matchEvent(q, e) {
+			return true
+		}
+		if len(res.Events) == limit {
+			res.Truncated = true
+			return false
+		}
+		res.Events = append(res.Events, e)
+		return true
+	})
+
+	return res, err
+}
+
+// Since returns events not older than the unix timestamp
+func (s *EventStore) Since(from int64) ([]*Event, error) {
+	res, _, err := s.replay(from)
+	return res, err
+}
+
+// replay is Since that also returns the seq of the last stored event at the time of the snapshot,
+// everything up to it is in the result if it passes the timestamp
+func (s *EventStore) replay(from int64) ([]*Event, uint64, error) {
+	var res []*Event
+	upto, err := s.scan(func(se seqEvent) bool {
+		if se.GetTimestamp() >= from {
+			res = append(res, se.Event)
+		}
+		return true
+	})
+
+	return res, upto, err
+}
+
+// scan walks the events from the oldest until fn returns false and returns the seq of the last one.
+// The segment log has a longer history than the ring, so it's preferred.
+// Only the snapshot is taken under mu, the files are read without it
+func (s *EventStore) scan(fn func(seqEvent) bool) (uint64, error) {
+	s.mu.Lock()
+	upto := s.seq
+	if s.log != nil {
+		segments := s.log.snapshot()
+		s.mu.Unlock()
+		return upto, scanSegments(segments, fn)
+	}
+
+	start, n := 0, s.next
+	if s.full {
+		start, n = s.next, len(s.ring)
+	}
+	events := make([]seqEvent, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, s.ring[(start+i)%len(s.ring)])
+	}
+	s.mu.Unlock()
+
+	for _, e := range events {
+		if !fn(e) {
+			break
+		}
+	}
+
+	return upto, nil
+}
+
+func (s *EventStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return nil
+	}
+
+	return s.log.close()
+}
+
+func matchEvent(q *EventQuery, e *Event) bool {
+	if q.GetFrom() != 0 && e.GetTimestamp() < q.GetFrom() {
+		return false
+	}
+	if q.GetTo() != 0 && e.GetTimestamp() > q.GetTo() {
+		return false
+	}
+	if q.GetConsumer() != "" && e.GetConsumer() != q.GetConsumer() {
+		return false
+	}
+	if q.GetMethod() != "" {
+		if ok, _ := path.Match(q.GetMethod(), e.GetMethod()); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// segmentLog writes length-prefixed events to numbered files in dir,
+// a file is closed after maxSize bytes and only the last maxSegments files are kept.
+// Every start opens a new segment, so a record torn by a crash is never followed by others.
+type segmentLog struct {
+	dir         string
+	maxSize     int64
+	maxSegments int
+	segments    []uint64
+	// firstSeq - seq of the first event of the segments written by this run
+	firstSeq map[uint64]uint64
+	file     *os.File
+	w        *bufio.Writer
+	size     int64
+}
+
+// segmentRef is a segment as it was at a snapshot: limit bytes of it were already flushed,
+// a negative limit means the segment is complete
+type segmentRef struct {
+	path     string
+	firstSeq uint64
+	limit    int64
+}
+
+func openSegmentLog(dir string, maxSize int64, maxSegments int) (*segmentLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &segmentLog{dir: dir, maxSize: maxSize, maxSegments: maxSegments, firstSeq: make(map[uint64]uint64)}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), segmentExt) {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(f.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		l.segments = append(l.segments, n)
+	}
+	sort.Slice(l.segments, func(i, j int) bool { return l.segments[i] < l.segments[j] })
+
+	if err := l.rotate(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *segmentLog) segmentPath(n uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%016d%s", n, segmentExt))
+}
+
+func (l *segmentLog) rotate() error {
+	if err := l.close(); err != nil {
+		return err
+	}
+
+	var n uint64 = 1
+	if len(l.segments) > 0 {
+		n = l.segments[len(l.segments)-1] + 1
+	}
+
+	f, err := os.OpenFile(l.segmentPath(n), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	l.file, l.w, l.size = f, bufio.NewWriter(f), 0
+	l.segments = append(l.segments, n)
+
+	for len(l.segments) > l.maxSegments {
+		if err := os.Remove(l.segmentPath(l.segments[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		delete(l.firstSeq, l.segments[0])
+		l.segments = l.segments[1:]
+	}
+
+	return nil
+}
+
+func (l *segmentLog) append(seq uint64, e *Event) error {
+	if l.size >= l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	if l.size == 0 {
+		l.firstSeq[l.segments[len(l.segments)-1]] = seq
+	}
+
+	data, err := proto.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(prefix[:], uint64(len(data)))
+	if _, err := l.w.Write(prefix[:n]); err != nil {
+		return err
+	}
+	if _, err := l.w.Write(data); err != nil {
+		return err
+	}
+	l.size += int64(n + len(data))
+
+	// flushed every time to make the event visible to scan and to survive a crash
+	return l.w.Flush()
+}
+
+// snapshot lists the segments to read, the open one only up to what is flushed now
+func (l *segmentLog) snapshot() []segmentRef {
+	refs := make([]segmentRef, 0, len(l.segments))
+	for i, n := range l.segments {
+		ref := segmentRef{path: l.segmentPath(n), firstSeq: l.firstSeq[n], limit: -1}
+		if i == len(l.segments)-1 && l.file != nil {
+			ref.limit = l.size
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// scanSegments reads the segments of a snapshot, a segment removed by rotation meanwhile is skipped
+func scanSegments(refs []segmentRef, fn func(seqEvent) bool) error {
+	for _, ref := range refs {
+		more, err := scanSegment(ref, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func scanSegment(ref segmentRef, fn func(seqEvent) bool) (bool, error) {
+	f, err := os.Open(ref.path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	var in io.Reader = f
+	if ref.limit >= 0 {
+		in = io.LimitReader(f, ref.limit)
+	}
+	r := bufio.NewReader(in)
+	seq := ref.firstSeq
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			// torn tail of a segment written before a crash
+			return true, nil
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return true, nil
+		}
+
+		e := &Event{}
+		if err := proto.Unmarshal(data, e); err != nil {
+			return false, fmt.Errorf("corrupted segment %s: %v", ref.path, err)
+		}
+		if !fn(seqEvent{seq: seq, Event: e}) {
+			return false, nil
+		}
+		if seq != 0 {
+			seq++
+		}
+	}
+}
+
+func (l *segmentLog) close() error {
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.w.Flush()
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	l.file, l.w = nil, nil
+
+	return err
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const eventsACLData = `{
+	"logger":    ["/main.Admin/Logging", "/main.Admin/Events"],
+	"biz_user":  ["/main.Biz/Check", "/main.Biz/Add"]
+}`
+
+func testEvent(ts int64, consumer, method string) *Event {
+	return &Event{Timestamp: ts, Consumer: consumer, Method: method}
+}
+
+// shortEvents drops proto internals to compare with reflect.DeepEqual, as in TestLogging
+func shortEvents(events []*Event) []*Event {
+	res := make([]*Event, 0, len(events))
+	for _, e := range events {
+		res = append(res, testEvent(e.Timestamp, e.Consumer, e.Method))
+	}
+	return res
+}
+
+func TestEventStoreRing(t *testing.T) {
+	store, err := NewEventStore(3, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := int64(1); i <= 5; i++ {
+		store.Append(testEvent(i, "biz_user", "/main.Biz/Check"))
+	}
+
+	events, err := store.Since(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []*Event{
+		testEvent(3, "biz_user", "/main.Biz/Check"),
+		testEvent(4, "biz_user", "/main.Biz/Check"),
+		testEvent(5, "biz_user", "/main.Biz/Check"),
+	}
+	if !reflect.DeepEqual(shortEvents(events), expected) {
+		t.Errorf("expected %+v, got %+v", expected, events)
+	}
+
+	if _, err := NewEventStore(0, ""); err == nil {
+		t.Errorf("expected error on empty buffer, got nil")
+	}
+}
+
+func TestEventStoreFind(t *testing.T) {
+	store, err := NewEventStore(10, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Append(testEvent(10, "biz_user", "/main.Biz/Check"))
+	store.Append(testEvent(11, "biz_admin", "/main.Biz/Test"))
+	store.Append(testEvent(12, "biz_user", "/main.Biz/Add"))
+	store.Append(testEvent(13, "logger", "/main.Admin/Logging"))
+
+	cases := []struct {
+		name      string
+		query     *EventQuery
+		expected  []int64
+		truncated bool
+	}{
+		{"All", &EventQuery{}, []int64{10, 11, 12, 13}, false},
+		{"TimeRange", &EventQuery{From: 11, To: 12}, []int64{11, 12}, false},
+		{"Consumer", &EventQuery{Consumer: "biz_user"}, []int64{10, 12}, false},
+		{"MethodGlob", &EventQuery{Method: "/main.Biz/*"}, []int64{10, 11, 12}, false},
+		{"Limit", &EventQuery{Method: "/main.Biz/*", Limit: 2}, []int64{10, 11}, true},
+	}
+
+	for _, tc := range cases {
+		res, err := store.Find(tc.query)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		var got []int64
+		for _, e := range res.Events {
+			got = append(got, e.Timestamp)
+		}
+		if !reflect.DeepEqual(got, tc.expected) || res.Truncated != tc.truncated {
+			t.Errorf("%s: expected %v (truncated %v), got %v (truncated %v)", tc.name, tc.expected, tc.truncated, got, res.Truncated)
+		}
+	}
+
+	if _, err := store.Find(&EventQuery{Method: "/main.Biz/[*"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument on bad pattern, got %v", err)
+	}
+}
+
+func TestSegmentLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatalf("cant create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// every event takes its own segment, only the last 3 stay
+	l, err := openSegmentLog(dir, 1, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := int64(1); i <= 5; i++ {
+		if err := l.append(uint64(i), testEvent(i, "biz_user", "/main.Biz/Check")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := l.close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a crash in the middle of a record
+	last := l.segmentPath(l.segments[len(l.segments)-1])
+	f, err := os.OpenFile(last, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Write([]byte{42, 1})
+	f.Close()
+
+	l, err = openSegmentLog(dir, 1<<20, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.close()
+	if err := l.append(1, testEvent(6, "biz_admin", "/main.Biz/Test")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the previous run's events have no seq
+	var got []int64
+	var seqs []uint64
+	err = scanSegments(l.snapshot(), func(e seqEvent) bool {
+		got = append(got, e.Timestamp)
+		seqs = append(seqs, e.seq)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, []int64{4, 5, 6}) || !reflect.DeepEqual(seqs, []uint64{0, 0, 1}) {
+		t.Errorf("expected events [4 5 6] with seqs [0 0 1], got %v with %v", got, seqs)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(files) != 3 {
+		t.Errorf("expected 3 segments, got %v", files)
+	}
+}
+
+func TestPubSubSlowSubscriber(t *testing.T) {
+	ps := NewPubSub(nil)
+	slow, _ := ps.Subscribe("slow")
+	fast, _ := ps.Subscribe("fast")
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer+1; i++ {
+			ps.Publish(testEvent(int64(i), "biz_user", "/main.Biz/Check"))
+			<-fast
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("publish is blocked by a slow subscriber")
+	}
+
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("expected %d buffered events before the drop, got %d", subscriberBuffer, n)
+	}
+}
+
+func TestPubSubReplayDedupe(t *testing.T) {
+	store, err := NewEventStore(1000, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ps := NewPubSub(store)
+
+	const total = 500
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		for i := int64(1); i <= total; i++ {
+			ps.Publish(testEvent(i, "biz_user", "/main.Biz/Check"))
+			if i == total/2 {
+				close(started)
+			}
+		}
+		close(done)
+	}()
+
+	// subscribe while events are being published
+	<-started
+	history, upto, evch, err := ps.SubscribeSince("replay", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-done
+
+	var got []int64
+	for _, e := range history {
+		got = append(got, e.Timestamp)
+	}
+	for len(evch) > 0 {
+		e := <-evch
+		if e.seq > upto {
+			got = append(got, e.Timestamp)
+		}
+	}
+
+	if len(got) != total {
+		t.Fatalf("expected %d events, got %d", total, len(got))
+	}
+	for i, ts := range got {
+		if ts != int64(i+1) {
+			t.Fatalf("event #%d: expected %d, got %d", i, i+1, ts)
+		}
+	}
+}
+
+func TestLoggingReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatalf("cant create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	wait(1)
+	ctx, finish := context.WithCancel(context.Background())
+	err = StartMyMicroservice(ctx, listenAddr, eventsACLData, WithEventLog(dir))
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	started := time.Now().Unix()
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Add(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Test(getConsumerCtx("biz_user"), &Nothing{})
+
+	res, err := adm.Events(getConsumerCtx("logger"), &EventQuery{From: started, Consumer: "biz_user"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var methods []string
+	for _, e := range res.Events {
+		methods = append(methods, e.Method)
+	}
+	// the denied call is not an event
+	if !reflect.DeepEqual(methods, []string{"/main.Biz/Check", "/main.Biz/Add"}) {
+		t.Errorf("expected Check and Add, got %v", methods)
+	}
+
+	streamCtx, cancel := context.WithCancel(getConsumerCtx("logger"))
+	defer cancel()
+	streamCtx = metadata.AppendToOutgoingContext(streamCtx, replayFromKey, strconv.FormatInt(started, 10))
+	logStream, err := adm.Logging(streamCtx, &Nothing{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+
+	expected := []string{
+		"biz_user /main.Biz/Check",
+		"biz_user /main.Biz/Add",
+		"logger /main.Admin/Events",
+		"logger /main.Admin/Logging",
+		"biz_user /main.Biz/Check",
+	}
+	for i, exp := range expected {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		if got := evt.Consumer + " " + evt.Method; got != exp {
+			t.Errorf("event #%d: expected %s, got %s", i, exp, got)
+		}
+	}
+
+	badCtx := metadata.AppendToOutgoingContext(getConsumerCtx("logger"), replayFromKey, "yesterday")
+	badStream, err := adm.Logging(badCtx, &Nothing{})
+	if err == nil {
+		_, err = badStream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument on bad replay-from, got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// bucket counts the calls of one quota key: a token bucket for the rate and a counter for the day
+type bucket struct {
+	tokens float64
+	last   time.Time
+	day    string
+	used   int64
+}
+
+func (b *bucket) refill(q *aclQuota, now time.Time, day string) {
+	if b.day != day {
+		b.day = day
+		b.used = 0
+	}
+
+	if q.rate > 0 && now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * q.rate
+		if b.tokens > float64(q.burst) {
+			b.tokens = float64(q.burst)
+		}
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+}
+
+type limiter struct {
+	mu  *sync.Mutex
+	now func() time.Time
+	// quota id -> scope key -> bucket
+	buckets map[string]map[string]*bucket
+}
+
+func newLimiter(now func() time.Time) *limiter {
+	return &limiter{mu: &sync.Mutex{}, now: now, buckets: make(map[string]map[string]*bucket)}
+}
+
+func (l *limiter) take(quotas []*aclQuota, consumer, method string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	day := now.UTC().Format("2006-01-02")
+
+	var taken []*bucket
+	for _, q := range quotas {
+		if !q.matches(consumer, method) {
+			continue
+		}
+
+		b := l.bucket(q, quotaKey(q.scope, consumer, method), now)
+		b.refill(q, now, day)
+		if q.daily > 0 && b.used >= q.daily {
+			return grpc.Errorf(codes.ResourceExhausted, "daily quota of %d calls is exceeded for %s", q.daily, quotaTarget(q.scope, consumer, method))
+		}
+		if q.rate > 0 && b.tokens < 1 {
+			return grpc.Errorf(codes.ResourceExhausted, "rate limit of %g rps is exceeded for %s", q.rate, quotaTarget(q.scope, consumer, method))
+		}
+		taken = append(taken, b)
+	}
+
+	for _, b := range taken {
+		b.tokens--
+		b.used++
+	}
+
+	return nil
+}
+
+func (l *limiter) bucket(q *aclQuota, key string, now time.Time) *bucket {
+	byKey, ok := l.buckets[q.id]
+	if !ok {
+		byKey = make(map[string]*bucket)
+		l.buckets[q.id] = byKey
+	}
+
+	b, ok := byKey[key]
+	if !ok {
+		b = &bucket{tokens: float64(q.burst), last: now}
+		byKey[key] = b
+	}
+
+	return b
+}
+
+// retain drops the counters of quotas removed by a reload
+func (l *limiter) retain(quotas []*aclQuota) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ids := make(map[string]bool, len(quotas))
+	for _, q := range quotas {
+		ids[q.id] = true
+	}
+	for id := range l.buckets {
+		if !ids[id] {
+			delete(l.buckets, id)
+		}
+	}
+}
+
+func quotaKey(scope, consumer, method string) string {
+	switch scope {
+	case scopeMethod:
+		return method
+	case scopeConsumerMethod:
+		return consumer + " " + method
+	default:
+		return consumer
+	}
+}
+
+func quotaTarget(scope, consumer, method string) string {
+	switch scope {
+	case scopeMethod:
+		return "method " + method
+	case scopeConsumerMethod:
+		return "consumer " + consumer + " on method " + method
+	default:
+		return "consumer " + consumer
+	}
+}
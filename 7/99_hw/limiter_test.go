@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClock is moved by hand, the quotas see no real time
+type fakeClock struct {
+	mu  *sync.Mutex
+	now time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{mu: &sync.Mutex{}, now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+const quotaACLData = `{
+	"groups": {"biz": ["biz_user", "biz_admin"]},
+	"rules": [
+		{"effect": "allow", "consumers": ["@biz"], "methods": ["/main.Biz/*"]},
+		{"effect": "allow", "consumers": ["stat"], "methods": ["/main.Admin/Statistics"]}
+	],
+	"quotas": [
+		{"consumers": ["biz_user"], "methods": ["/main.Biz/*"], "rate": 1, "burst": 2},
+		{"consumers": ["@biz"], "methods": ["/main.Biz/Test"], "scope": "method", "daily": 3}
+	]
+}`
+
+func newQuotaACL(t *testing.T, clock *fakeClock) *ACL {
+	acl, err := NewACL([]byte(quotaACLData), "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	acl.SetClock(clock.Now)
+	return acl
+}
+
+func TestLimiterRate(t *testing.T) {
+	clock := newFakeClock(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	acl := newQuotaACL(t, clock)
+
+	steps := []struct {
+		wait    time.Duration
+		allowed bool
+	}{
+		{0, true},
+		{0, true}, // the burst
+		{0, false},
+		{500 * time.Millisecond, false},
+		{500 * time.Millisecond, true}, // a token per second
+		{0, false},
+		{10 * time.Second, true}, // no more than the burst is saved
+		{0, true},
+		{0, false},
+	}
+
+	for i, step := range steps {
+		clock.Add(step.wait)
+		err := acl.Limit("biz_user", "/main.Biz/Check")
+		if step.allowed && err != nil {
+			t.Errorf("step #%d: expected call, got %v", i, err)
+		}
+		if !step.allowed && status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("step #%d: expected ResourceExhausted, got %v", i, err)
+		}
+	}
+
+	// biz_admin has no rate limit
+	for i := 0; i < 10; i++ {
+		if err := acl.Limit("biz_admin", "/main.Biz/Check"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestLimiterDaily(t *testing.T) {
+	clock := newFakeClock(time.Date(2020, 5, 1, 23, 0, 0, 0, time.UTC))
+	acl := newQuotaACL(t, clock)
+
+	// the daily quota is per method, so both consumers share it
+	for _, consumer := range []string{"biz_admin", "biz_admin", "biz_user"} {
+		if err := acl.Limit(consumer, "/main.Biz/Test"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	err := acl.Limit("biz_admin", "/main.Biz/Test")
+	if status.Code(err) != codes.ResourceExhausted || !strings.Contains(err.Error(), "method /main.Biz/Test") {
+		t.Fatalf("expected daily quota error, got %v", err)
+	}
+
+	// the refused call takes nothing from the rate quota of biz_user
+	clock.Add(2 * time.Second)
+	if err := acl.Limit("biz_user", "/main.Biz/Test"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected daily quota error, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := acl.Limit("biz_user", "/main.Biz/Check"); err != nil {
+			t.Fatalf("expected the whole burst, got %v", err)
+		}
+	}
+
+	clock.Add(time.Hour)
+	if err := acl.Limit("biz_admin", "/main.Biz/Test"); err != nil {
+		t.Fatalf("expected a new day, got %v", err)
+	}
+}
+
+func TestLimiterReload(t *testing.T) {
+	clock := newFakeClock(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	acl := newQuotaACL(t, clock)
+
+	acl.Limit("biz_user", "/main.Biz/Check")
+	acl.Limit("biz_user", "/main.Biz/Check")
+
+	// the same quotas keep their counters
+	if err := acl.Reload([]byte(quotaACLData), "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := acl.Limit("biz_user", "/main.Biz/Check"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted after reload, got %v", err)
+	}
+
+	withoutQuotas := `{"biz_user": ["/main.Biz/*"]}`
+	if err := acl.Reload([]byte(withoutQuotas), "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := acl.Limit("biz_user", "/main.Biz/Check"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(acl.limiter.buckets) != 0 {
+		t.Errorf("expected counters of removed quotas to be dropped, got %v", acl.limiter.buckets)
+	}
+}
+
+func TestQuotaValidation(t *testing.T) {
+	data := `{"quotas": [
+		{"consumers": ["a"], "methods": ["/main.Biz/*"], "scope": "host", "daily": 1},
+		{"consumers": ["a"], "methods": ["/main.Biz/*"], "burst": 5},
+		{"consumers": ["a"], "methods": ["/main.Biz/*"], "rate": -1}
+	]}`
+	expected := []string{
+		"quota #1: scope must be \"consumer\", \"method\" or \"consumer_method\", got \"host\"",
+		"quota #2: burst without rate",
+		"quota #2: no rate and no daily limit",
+		"quota #3: rate, burst and daily can't be negative",
+	}
+
+	_, err := NewACL([]byte(data), "test")
+	aclErr, ok := err.(*ACLError)
+	if !ok {
+		t.Fatalf("expected *ACLError, got %v", err)
+	}
+	if strings.Join(aclErr.Problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected problems %q, got %q", expected, aclErr.Problems)
+	}
+}
+
+func TestQuotaStat(t *testing.T) {
+	clock := newFakeClock(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, quotaACLData, WithClock(clock.Now))
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(2)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	statStream, err := adm.Statistics(getConsumerCtx("stat"), &StatInterval{IntervalSeconds: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Add(getConsumerCtx("biz_user"), &Nothing{})
+	_, err = biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+
+	stat, err := statStream.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stat.ThrottledByConsumer["biz_user"] != 1 || stat.ByConsumer["biz_user"] != 2 {
+		t.Errorf("expected 2 calls and 1 throttled, got %+v", stat)
+	}
+	if m := stat.Methods["/main.Biz/Check"]; m == nil || m.ErrorsByCode[codes.ResourceExhausted.String()] != 1 {
+		t.Errorf("expected a throttled Check call, got %+v", m)
+	}
+}
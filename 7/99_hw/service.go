@@ -2,12 +2,12 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
+	"net/http"
 	"reflect"
-	"regexp"
+	"strconv"
 	"sync"
 	"time"
 
@@ -21,31 +21,125 @@ import (
 	"google.golang.org/protobuf/runtime/protoimpl"
 )
 
-func StartMyMicroservice(ctx context.Context, listenAddr, ACLData string) error {
-	var ACLDataParsed map[string][]string
-	err := json.Unmarshal([]byte(ACLData), &ACLDataParsed)
+const (
+	// replayFromKey is the Logging metadata with a unix timestamp to replay the events from
+	replayFromKey = "replay-from"
+
+	subscriberBuffer = 256
+)
+
+// Option configures StartMyMicroservice
+type Option func(*options)
+
+type options struct {
+	aclFile        string
+	reloadInterval time.Duration
+	eventBuffer    int
+	eventLogDir    string
+	metricsAddr    string
+	now            func() time.Time
+}
+
+// WithACLFile reads the ACL from the file instead of ACLData
+// and reloads it when the file changes, without restarting the server
+func WithACLFile(filename string, reloadInterval time.Duration) Option {
+	return func(o *options) {
+		o.aclFile = filename
+		o.reloadInterval = reloadInterval
+	}
+}
+
+// WithEventBuffer sets how many recent events are kept in memory for Admin.Events and replay
+func WithEventBuffer(size int) Option {
+	return func(o *options) {
+		o.eventBuffer = size
+	}
+}
+
+// WithEventLog also writes events to segment files in dir, so the history survives restarts
+func WithEventLog(dir string) Option {
+	return func(o *options) {
+		o.eventLogDir = dir
+	}
+}
+
+// WithMetricsAddr serves the statistics in the Prometheus text format at http://addr/metrics
+func WithMetricsAddr(addr string) Option {
+	return func(o *options) {
+		o.metricsAddr = addr
+	}
+}
+
+// WithClock replaces time.Now for the quotas
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+func StartMyMicroservice(ctx context.Context, listenAddr, ACLData string, opts ...Option) error {
+	o := &options{reloadInterval: time.Second, eventBuffer: defaultEventBuffer}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	data, source := []byte(ACLData), "inline"
+	if o.aclFile != "" {
+		var err error
+		data, err = ioutil.ReadFile(o.aclFile)
+		if err != nil {
+			return err
+		}
+		source = o.aclFile
+	}
 
+	acl, err := NewACL(data, source)
 	if err != nil {
 		return err
 	}
+	if o.now != nil {
+		acl.SetClock(o.now)
+	}
 
-	ACLDataRegexp := compileRegexps(ACLDataParsed)
-	server := grpc.NewServer(grpc.UnaryInterceptor(unaryInterceptor),
-		grpc.StreamInterceptor(streamInterceptor))
+	store, err := NewEventStore(o.eventBuffer, o.eventLogDir)
+	if err != nil {
+		return err
+	}
 
-	logEvents := NewPubSub()
-	statEvents := NewPubSub()
+	ms := &microservice{acl: acl, logEv: NewPubSub(store), stats: NewStatsCollector()}
+	server := grpc.NewServer(grpc.UnaryInterceptor(ms.unaryInterceptor),
+		grpc.StreamInterceptor(ms.streamInterceptor))
 
-	RegisterAdminServer(server, NewAdminServer(ctx, ACLDataRegexp, logEvents, statEvents))
-	RegisterBizServer(server, NewBizServer(ACLDataRegexp, logEvents, statEvents))
+	RegisterAdminServer(server, NewAdminServer(ctx, acl, ms.logEv, ms.stats))
+	RegisterBizServer(server, NewBizServer())
 
 	errorsCh := make(chan error)
 
 	lis, err := net.Listen("tcp", listenAddr)
 	if err != nil {
+		store.Close()
 		return err
 	}
 
+	var metricsServer *http.Server
+	if o.metricsAddr != "" {
+		metricsLis, err := net.Listen("tcp", o.metricsAddr)
+		if err != nil {
+			lis.Close()
+			store.Close()
+			return err
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/metrics", ms.metricsHandler)
+		metricsServer = &http.Server{Handler: mux}
+		go metricsServer.Serve(metricsLis)
+	}
+
+	if o.aclFile != "" {
+		go acl.Watch(ctx, o.aclFile, o.reloadInterval)
+	}
+
 	go func(l net.Listener, s *grpc.Server) {
 		err := s.Serve(l)
 		if err != nil {
@@ -59,6 +153,12 @@ func StartMyMicroservice(ctx context.Context, listenAddr, ACLData string) error
 			select {
 			case <-ctx.Done():
 				server.Stop()
+				if metricsServer != nil {
+					metricsServer.Close()
+				}
+				if err := store.Close(); err != nil {
+					log.Printf("can't close event log: %v\n", err)
+				}
 				break LOOP
 			case err := <-errorsCh:
 				server.Stop()
@@ -71,84 +171,118 @@ func StartMyMicroservice(ctx context.Context, listenAddr, ACLData string) error
 	return nil
 }
 
-type ServerWithACLData interface {
-	GetACLData() map[string][]*regexp.Regexp
+// microservice keeps what the interceptors share, the task forbids globals
+type microservice struct {
+	acl   *ACL
+	logEv *PubSub
+	stats *StatsCollector
 }
 
 type AdminServerImpl struct {
-	ACLData map[string][]*regexp.Regexp
-	LogEv   *PubSub
-	StatEv  *PubSub
-}
-
-func (as *AdminServerImpl) GetACLData() map[string][]*regexp.Regexp {
-	return as.ACLData
+	ACL   *ACL
+	LogEv *PubSub
+	Stats *StatsCollector
 }
 
 func NewAdminServer(ctx context.Context,
-	ACLData map[string][]*regexp.Regexp,
+	acl *ACL,
 	logevs *PubSub,
-	statevs *PubSub) *AdminServerImpl {
-	return &AdminServerImpl{ACLData: ACLData, LogEv: logevs, StatEv: statevs}
+	stats *StatsCollector) *AdminServerImpl {
+	return &AdminServerImpl{ACL: acl, LogEv: logevs, Stats: stats}
 }
 
 func (as *AdminServerImpl) Logging(_ *Nothing, inStream Admin_LoggingServer) error {
-	evch, err := as.LogEv.Subscribe(inStream)
+	from, err := replayFrom(inStream.Context())
+	if err != nil {
+		return err
+	}
+
+	history, upto, evch, err := as.LogEv.SubscribeSince(inStream, from)
 	defer as.LogEv.Unsubscribe(inStream)
 
 	if err != nil {
 		return err
 	}
 
-	for e := range evch {
+	for _, e := range history {
 		if err := inStream.Send(e); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	for {
+		select {
+		case <-inStream.Context().Done():
+			return nil
+		case e, ok := <-evch:
+			if !ok {
+				return errSlowSubscriber()
+			}
+			// already sent with the history
+			if e.seq <= upto {
+				continue
+			}
+			if err := inStream.Send(e.Event); err != nil {
+				return err
+			}
+		}
+	}
 }
 
-func (as *AdminServerImpl) Statistics(i *StatInterval, inStream Admin_StatisticsServer) error {
-	events, err := as.StatEv.Subscribe(inStream)
-	defer as.StatEv.Unsubscribe(inStream)
+// Events looks up past events, see EventQuery
+func (as *AdminServerImpl) Events(_ context.Context, q *EventQuery) (*EventList, error) {
+	return as.LogEv.Find(q)
+}
 
-	if err != nil {
-		return err
+// replayFrom reads the replay-from metadata, 0 means live events only
+func replayFrom(ctx context.Context) (int64, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(replayFromKey)
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	from, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil || from <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "%s must be a unix timestamp, got %q", replayFromKey, values[0])
+	}
+
+	return from, nil
+}
+
+func (as *AdminServerImpl) Statistics(i *StatInterval, inStream Admin_StatisticsServer) error {
+	if i.GetIntervalSeconds() == 0 {
+		return status.Error(codes.InvalidArgument, "interval_seconds must be positive")
 	}
 
-	stats := NewStat()
+	as.Stats.Subscribe(inStream)
+	defer as.Stats.Unsubscribe(inStream)
+
 	ticker := time.NewTicker(time.Duration(i.GetIntervalSeconds()) * time.Second)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case e := <-events:
-			stats.ByConsumer[e.Consumer]++
-			stats.ByMethod[e.Method]++
+		case <-inStream.Context().Done():
+			return nil
 		case <-ticker.C:
-			err := inStream.Send(stats)
+			err := inStream.Send(as.Stats.Flush(inStream))
 			if err != nil {
 				return err
 			}
-			stats = NewStat()
 		}
 	}
 }
 
-type BizServerImpl struct {
-	ACLData map[string][]*regexp.Regexp
-	LogEv   *PubSub
-	StatEv  *PubSub
+// Policy shows the ACL in effect and the error of the last failed reload
+func (as *AdminServerImpl) Policy(context.Context, *Nothing) (*Policy, error) {
+	return as.ACL.Describe(), nil
 }
 
-func (bs *BizServerImpl) GetACLData() map[string][]*regexp.Regexp {
-	return bs.ACLData
-}
+type BizServerImpl struct{}
 
-func NewBizServer(ACLData map[string][]*regexp.Regexp,
-	logevs *PubSub,
-	statevs *PubSub) *BizServerImpl {
-	return &BizServerImpl{ACLData: ACLData, LogEv: logevs, StatEv: statevs}
+func NewBizServer() *BizServerImpl {
+	return &BizServerImpl{}
 }
 
 func (bs *BizServerImpl) Check(context.Context, *Nothing) (*Nothing, error) {
@@ -163,23 +297,55 @@ func (bs *BizServerImpl) Test(context.Context, *Nothing) (*Nothing, error) {
 	return new(Nothing), nil
 }
 
+// PubSub fans events out to subscribers without waiting for them:
+// a subscriber whose buffer is full is dropped and its channel is closed.
+// mu guards only subs and is never held across the store I/O,
+// publishing keeps the events in order under its own lock
 type PubSub struct {
-	subs map[interface{}](chan *Event)
-	mu   *sync.Mutex
+	subs      map[interface{}](chan seqEvent)
+	mu        *sync.Mutex
+	publishMu *sync.Mutex
+	store     *EventStore
 }
 
-func NewPubSub() *PubSub {
+// NewPubSub creates a PubSub, with a store every published event is saved in it
+func NewPubSub(store *EventStore) *PubSub {
 	return &PubSub{
-		subs: make(map[interface{}](chan *Event)),
-		mu:   &sync.Mutex{}}
+		subs:      make(map[interface{}](chan seqEvent)),
+		mu:        &sync.Mutex{},
+		publishMu: &sync.Mutex{},
+		store:     store}
+}
+
+func (ps *PubSub) Subscribe(stream interface{}) (chan seqEvent, error) {
+	_, _, evch, err := ps.SubscribeSince(stream, 0)
+	return evch, err
 }
 
-func (ps *PubSub) Subscribe(stream interface{}) (chan *Event, error) {
+// SubscribeSince also returns the stored events starting from the unix timestamp
+// and the seq they go up to. The subscriber is registered before the history is read,
+// so nothing published in between is lost: the channel events with seq up to it
+// are already in the history and must be skipped
+func (ps *PubSub) SubscribeSince(stream interface{}, from int64) ([]*Event, uint64, chan seqEvent, error) {
+	if from > 0 && ps.store == nil {
+		return nil, 0, nil, status.Error(codes.FailedPrecondition, "events are not stored")
+	}
+
+	evch := make(chan seqEvent, subscriberBuffer)
 	ps.mu.Lock()
-	evch := make(chan *Event)
 	ps.subs[stream] = evch
 	ps.mu.Unlock()
-	return evch, nil
+
+	if from == 0 {
+		return nil, 0, evch, nil
+	}
+
+	history, upto, err := ps.store.replay(from)
+	if err != nil {
+		ps.Unsubscribe(stream)
+		return nil, 0, nil, err
+	}
+	return history, upto, evch, nil
 }
 
 func (ps *PubSub) Unsubscribe(stream interface{}) {
@@ -189,75 +355,84 @@ func (ps *PubSub) Unsubscribe(stream interface{}) {
 }
 
 func (ps *PubSub) Publish(e *Event) {
+	ps.publishMu.Lock()
+	defer ps.publishMu.Unlock()
+
+	se := seqEvent{Event: e}
+	if ps.store != nil {
+		se.seq = ps.store.Append(e)
+	}
+
 	ps.mu.Lock()
-	for _, ch := range ps.subs {
-		ch <- e
+	defer ps.mu.Unlock()
+
+	for stream, ch := range ps.subs {
+		select {
+		case ch <- se:
+		default:
+			close(ch)
+			delete(ps.subs, stream)
+		}
 	}
+}
 
-	ps.mu.Unlock()
+func (ps *PubSub) Find(q *EventQuery) (*EventList, error) {
+	if ps.store == nil {
+		return nil, status.Error(codes.FailedPrecondition, "events are not stored")
+	}
+
+	return ps.store.Find(q)
+}
+
+func errSlowSubscriber() error {
+	return status.Error(codes.ResourceExhausted, "subscriber is too slow, events were dropped")
 }
 
 func NewStat() *Stat {
 	return &Stat{Timestamp: time.Now().Unix(),
-		ByConsumer: make(map[string]uint64),
-		ByMethod:   make(map[string]uint64)}
+		ByConsumer:          make(map[string]uint64),
+		ByMethod:            make(map[string]uint64),
+		Methods:             make(map[string]*MethodStat),
+		InFlight:            make(map[string]int64),
+		ThrottledByConsumer: make(map[string]uint64)}
 }
 
-func compileRegexps(data map[string][]string) map[string][]*regexp.Regexp {
-	res := make(map[string][]*regexp.Regexp)
-	for consumer, routes := range data {
-		res[consumer] = make([]*regexp.Regexp, 0, len(routes))
-		for _, r := range routes {
-			regexp, err := regexp.Compile(r)
-			if err != nil {
-				fmt.Printf("error regexp compile, consumer %v route %v: %s\n", consumer, r, err.Error())
-				continue
-			}
-			res[consumer] = append(res[consumer], regexp)
-		}
-	}
-
-	return res
-}
-func unaryInterceptor(ctx context.Context,
+func (ms *microservice) unaryInterceptor(ctx context.Context,
 	req interface{},
 	info *grpc.UnaryServerInfo,
 	handler grpc.UnaryHandler) (interface{}, error) {
-	bs, ok := info.Server.(*BizServerImpl)
-	if !ok {
-		return new(Nothing), fmt.Errorf("wrong server, BizServerImpl required")
-	}
-
-	err := applyInterceptors(ctx, bs, bs.LogEv, bs.StatEv, info.FullMethod)
+	consumer, err := ms.authorize(ctx, info.FullMethod)
 	if err != nil {
 		return new(Nothing), err
 	}
 
-	return handler(ctx, req)
+	ms.stats.Start(consumer, info.FullMethod)
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	ms.stats.Finish(info.FullMethod, status.Code(err), time.Since(start))
+
+	return resp, err
 }
 
-func streamInterceptor(srv interface{},
+func (ms *microservice) streamInterceptor(srv interface{},
 	ss grpc.ServerStream,
 	info *grpc.StreamServerInfo,
 	handler grpc.StreamHandler) error {
-	as, ok := srv.(*AdminServerImpl)
-	if !ok {
-		return fmt.Errorf("wrong server, AdminServerImpl required")
-	}
-
-	err := applyInterceptors(ss.Context(), as, as.LogEv, as.StatEv, info.FullMethod)
+	consumer, err := ms.authorize(ss.Context(), info.FullMethod)
 	if err != nil {
 		return err
 	}
 
-	return handler(srv, ss)
+	ms.stats.Start(consumer, info.FullMethod)
+	start := time.Now()
+	err = handler(srv, ss)
+	ms.stats.Finish(info.FullMethod, status.Code(err), time.Since(start))
+
+	return err
 }
 
-func applyInterceptors(ctx context.Context,
-	srv interface{},
-	logEv *PubSub,
-	statEv *PubSub,
-	method string) error {
+// authorize checks the ACL and logs the call, a refused call only gets into the error counts
+func (ms *microservice) authorize(ctx context.Context, method string) (string, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	var consumer string
 	if ok && len(md.Get("consumer")) > 0 {
@@ -267,39 +442,26 @@ func applyInterceptors(ctx context.Context,
 	if p, ok := peer.FromContext(ctx); ok {
 		host = p.Addr.String()
 	}
-	err := authInterceptor(ctx, srv, consumer, method)
+	err := ms.acl.Check(consumer, method)
+	if err == nil {
+		err = ms.acl.Limit(consumer, method)
+	}
 	if err != nil {
-		return err
+		ms.stats.Reject(consumer, method, status.Code(err))
+		return "", err
 	}
 
 	e := &Event{Timestamp: time.Now().Unix(), Consumer: consumer, Method: method, Host: host}
-	logEv.Publish(e)
-	statEv.Publish(e)
+	ms.logEv.Publish(e)
 
-	return nil
+	return consumer, nil
 }
 
-func authInterceptor(ctx context.Context,
-	srv interface{},
-	consumer, method string) error {
-	if len(consumer) == 0 {
-		return grpc.Errorf(codes.Unauthenticated, "no ACL field")
-	}
-
-	if as, ok := srv.(ServerWithACLData); ok {
-		methods, ok := as.GetACLData()[consumer]
-		if !ok {
-			return grpc.Errorf(codes.Unauthenticated, fmt.Sprintf("no auth for consumer %s", consumer))
-		}
-
-		for _, mReg := range methods {
-			if mReg.MatchString(method) {
-				return nil
-			}
-		}
+func (ms *microservice) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := ms.stats.WritePrometheus(w); err != nil {
+		log.Printf("can't write metrics: %v\n", err)
 	}
-
-	return grpc.Errorf(codes.Unauthenticated, "no auth")
 }
 
 // SERVICE.PB.GO
@@ -311,38 +473,528 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// This is a compile-time assertion that a sufficiently up-to-date version
-// of the legacy proto package is being used.
-const _ = proto.ProtoPackageIsVersion4
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+type Event struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Timestamp int64  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Consumer  string `protobuf:"bytes,2,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	Method    string `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
+	Host      string `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Event) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Event) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *Event) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *Event) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+// завершённые за интервал вызовы метода, перцентили оцениваются по гистограмме
+type MethodStat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Calls        uint64            `protobuf:"varint,1,opt,name=calls,proto3" json:"calls,omitempty"`
+	ErrorsByCode map[string]uint64 `protobuf:"bytes,2,rep,name=errors_by_code,json=errorsByCode,proto3" json:"errors_by_code,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"` // codes.Code.String(), например Unauthenticated
+	P50Ms        float64           `protobuf:"fixed64,3,opt,name=p50_ms,json=p50Ms,proto3" json:"p50_ms,omitempty"`
+	P90Ms        float64           `protobuf:"fixed64,4,opt,name=p90_ms,json=p90Ms,proto3" json:"p90_ms,omitempty"`
+	P99Ms        float64           `protobuf:"fixed64,5,opt,name=p99_ms,json=p99Ms,proto3" json:"p99_ms,omitempty"`
+	MaxMs        float64           `protobuf:"fixed64,6,opt,name=max_ms,json=maxMs,proto3" json:"max_ms,omitempty"`
+}
+
+func (x *MethodStat) Reset() {
+	*x = MethodStat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MethodStat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MethodStat) ProtoMessage() {}
+
+func (x *MethodStat) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MethodStat.ProtoReflect.Descriptor instead.
+func (*MethodStat) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *MethodStat) GetCalls() uint64 {
+	if x != nil {
+		return x.Calls
+	}
+	return 0
+}
+
+func (x *MethodStat) GetErrorsByCode() map[string]uint64 {
+	if x != nil {
+		return x.ErrorsByCode
+	}
+	return nil
+}
+
+func (x *MethodStat) GetP50Ms() float64 {
+	if x != nil {
+		return x.P50Ms
+	}
+	return 0
+}
+
+func (x *MethodStat) GetP90Ms() float64 {
+	if x != nil {
+		return x.P90Ms
+	}
+	return 0
+}
+
+func (x *MethodStat) GetP99Ms() float64 {
+	if x != nil {
+		return x.P99Ms
+	}
+	return 0
+}
+
+func (x *MethodStat) GetMaxMs() float64 {
+	if x != nil {
+		return x.MaxMs
+	}
+	return 0
+}
+
+type Stat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Timestamp           int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	ByMethod            map[string]uint64      `protobuf:"bytes,2,rep,name=by_method,json=byMethod,proto3" json:"by_method,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"` // начатые вызовы
+	ByConsumer          map[string]uint64      `protobuf:"bytes,3,rep,name=by_consumer,json=byConsumer,proto3" json:"by_consumer,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Methods             map[string]*MethodStat `protobuf:"bytes,4,rep,name=methods,proto3" json:"methods,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	InFlight            map[string]int64       `protobuf:"bytes,5,rep,name=in_flight,json=inFlight,proto3" json:"in_flight,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`                                    // вызовы в работе на момент отправки
+	ThrottledByConsumer map[string]uint64      `protobuf:"bytes,6,rep,name=throttled_by_consumer,json=throttledByConsumer,proto3" json:"throttled_by_consumer,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"` // отказы по квотам
+}
+
+func (x *Stat) Reset() {
+	*x = Stat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Stat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stat) ProtoMessage() {}
+
+func (x *Stat) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stat.ProtoReflect.Descriptor instead.
+func (*Stat) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Stat) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Stat) GetByMethod() map[string]uint64 {
+	if x != nil {
+		return x.ByMethod
+	}
+	return nil
+}
+
+func (x *Stat) GetByConsumer() map[string]uint64 {
+	if x != nil {
+		return x.ByConsumer
+	}
+	return nil
+}
+
+func (x *Stat) GetMethods() map[string]*MethodStat {
+	if x != nil {
+		return x.Methods
+	}
+	return nil
+}
+
+func (x *Stat) GetInFlight() map[string]int64 {
+	if x != nil {
+		return x.InFlight
+	}
+	return nil
+}
+
+func (x *Stat) GetThrottledByConsumer() map[string]uint64 {
+	if x != nil {
+		return x.ThrottledByConsumer
+	}
+	return nil
+}
+
+type StatInterval struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IntervalSeconds uint64 `protobuf:"varint,1,opt,name=interval_seconds,json=intervalSeconds,proto3" json:"interval_seconds,omitempty"`
+}
+
+func (x *StatInterval) Reset() {
+	*x = StatInterval{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatInterval) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatInterval) ProtoMessage() {}
+
+func (x *StatInterval) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatInterval.ProtoReflect.Descriptor instead.
+func (*StatInterval) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StatInterval) GetIntervalSeconds() uint64 {
+	if x != nil {
+		return x.IntervalSeconds
+	}
+	return 0
+}
+
+type Nothing struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Dummy bool `protobuf:"varint,1,opt,name=dummy,proto3" json:"dummy,omitempty"`
+}
+
+func (x *Nothing) Reset() {
+	*x = Nothing{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Nothing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Nothing) ProtoMessage() {}
+
+func (x *Nothing) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Nothing.ProtoReflect.Descriptor instead.
+func (*Nothing) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Nothing) GetDummy() bool {
+	if x != nil {
+		return x.Dummy
+	}
+	return false
+}
+
+// правило ACL с уже раскрытыми группами консюмеров
+type ACLRule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Effect    string   `protobuf:"bytes,1,opt,name=effect,proto3" json:"effect,omitempty"`       // allow или deny
+	Consumers []string `protobuf:"bytes,2,rep,name=consumers,proto3" json:"consumers,omitempty"` // "*" - любой консюмер
+	Methods   []string `protobuf:"bytes,3,rep,name=methods,proto3" json:"methods,omitempty"`     // glob, например /main.Biz/*
+}
+
+func (x *ACLRule) Reset() {
+	*x = ACLRule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ACLRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ACLRule) ProtoMessage() {}
+
+func (x *ACLRule) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ACLRule.ProtoReflect.Descriptor instead.
+func (*ACLRule) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ACLRule) GetEffect() string {
+	if x != nil {
+		return x.Effect
+	}
+	return ""
+}
+
+func (x *ACLRule) GetConsumers() []string {
+	if x != nil {
+		return x.Consumers
+	}
+	return nil
+}
+
+func (x *ACLRule) GetMethods() []string {
+	if x != nil {
+		return x.Methods
+	}
+	return nil
+}
+
+// квота на вызовы, разрешённые правилами
+type QuotaRule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Consumers []string `protobuf:"bytes,1,rep,name=consumers,proto3" json:"consumers,omitempty"`
+	Methods   []string `protobuf:"bytes,2,rep,name=methods,proto3" json:"methods,omitempty"`
+	Scope     string   `protobuf:"bytes,3,opt,name=scope,proto3" json:"scope,omitempty"` // consumer, method или consumer_method
+	Rate      float64  `protobuf:"fixed64,4,opt,name=rate,proto3" json:"rate,omitempty"` // запросов в секунду
+	Burst     int64    `protobuf:"varint,5,opt,name=burst,proto3" json:"burst,omitempty"`
+	Daily     int64    `protobuf:"varint,6,opt,name=daily,proto3" json:"daily,omitempty"` // вызовов за сутки по UTC
+}
+
+func (x *QuotaRule) Reset() {
+	*x = QuotaRule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QuotaRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaRule) ProtoMessage() {}
+
+func (x *QuotaRule) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaRule.ProtoReflect.Descriptor instead.
+func (*QuotaRule) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *QuotaRule) GetConsumers() []string {
+	if x != nil {
+		return x.Consumers
+	}
+	return nil
+}
+
+func (x *QuotaRule) GetMethods() []string {
+	if x != nil {
+		return x.Methods
+	}
+	return nil
+}
+
+func (x *QuotaRule) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *QuotaRule) GetRate() float64 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
+
+func (x *QuotaRule) GetBurst() int64 {
+	if x != nil {
+		return x.Burst
+	}
+	return 0
+}
+
+func (x *QuotaRule) GetDaily() int64 {
+	if x != nil {
+		return x.Daily
+	}
+	return 0
+}
 
-type Event struct {
+type ConsumerGroup struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Timestamp int64  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Consumer  string `protobuf:"bytes,2,opt,name=consumer,proto3" json:"consumer,omitempty"`
-	Method    string `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
-	Host      string `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	Name      string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Consumers []string `protobuf:"bytes,2,rep,name=consumers,proto3" json:"consumers,omitempty"`
 }
 
-func (x *Event) Reset() {
-	*x = Event{}
+func (x *ConsumerGroup) Reset() {
+	*x = ConsumerGroup{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_service_proto_msgTypes[0]
+		mi := &file_service_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Event) String() string {
+func (x *ConsumerGroup) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Event) ProtoMessage() {}
+func (*ConsumerGroup) ProtoMessage() {}
 
-func (x *Event) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[0]
+func (x *ConsumerGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -353,66 +1005,56 @@ func (x *Event) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Event.ProtoReflect.Descriptor instead.
-func (*Event) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{0}
-}
-
-func (x *Event) GetTimestamp() int64 {
-	if x != nil {
-		return x.Timestamp
-	}
-	return 0
-}
-
-func (x *Event) GetConsumer() string {
-	if x != nil {
-		return x.Consumer
-	}
-	return ""
+// Deprecated: Use ConsumerGroup.ProtoReflect.Descriptor instead.
+func (*ConsumerGroup) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *Event) GetMethod() string {
+func (x *ConsumerGroup) GetName() string {
 	if x != nil {
-		return x.Method
+		return x.Name
 	}
 	return ""
 }
 
-func (x *Event) GetHost() string {
+func (x *ConsumerGroup) GetConsumers() []string {
 	if x != nil {
-		return x.Host
+		return x.Consumers
 	}
-	return ""
+	return nil
 }
 
-type Stat struct {
+type Policy struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Timestamp  int64             `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	ByMethod   map[string]uint64 `protobuf:"bytes,2,rep,name=by_method,json=byMethod,proto3" json:"by_method,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
-	ByConsumer map[string]uint64 `protobuf:"bytes,3,rep,name=by_consumer,json=byConsumer,proto3" json:"by_consumer,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Version         int64            `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"` // растёт с каждой успешной перезагрузкой
+	LoadedAt        int64            `protobuf:"varint,2,opt,name=loaded_at,json=loadedAt,proto3" json:"loaded_at,omitempty"`
+	Source          string           `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	Groups          []*ConsumerGroup `protobuf:"bytes,4,rep,name=groups,proto3" json:"groups,omitempty"`
+	Rules           []*ACLRule       `protobuf:"bytes,5,rep,name=rules,proto3" json:"rules,omitempty"`
+	LastReloadError string           `protobuf:"bytes,6,opt,name=last_reload_error,json=lastReloadError,proto3" json:"last_reload_error,omitempty"`
+	Quotas          []*QuotaRule     `protobuf:"bytes,7,rep,name=quotas,proto3" json:"quotas,omitempty"`
 }
 
-func (x *Stat) Reset() {
-	*x = Stat{}
+func (x *Policy) Reset() {
+	*x = Policy{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_service_proto_msgTypes[1]
+		mi := &file_service_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Stat) String() string {
+func (x *Policy) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Stat) ProtoMessage() {}
+func (*Policy) ProtoMessage() {}
 
-func (x *Stat) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[1]
+func (x *Policy) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -423,57 +1065,90 @@ func (x *Stat) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Stat.ProtoReflect.Descriptor instead.
-func (*Stat) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use Policy.ProtoReflect.Descriptor instead.
+func (*Policy) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *Stat) GetTimestamp() int64 {
+func (x *Policy) GetVersion() int64 {
 	if x != nil {
-		return x.Timestamp
+		return x.Version
 	}
 	return 0
 }
 
-func (x *Stat) GetByMethod() map[string]uint64 {
+func (x *Policy) GetLoadedAt() int64 {
 	if x != nil {
-		return x.ByMethod
+		return x.LoadedAt
+	}
+	return 0
+}
+
+func (x *Policy) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Policy) GetGroups() []*ConsumerGroup {
+	if x != nil {
+		return x.Groups
 	}
 	return nil
 }
 
-func (x *Stat) GetByConsumer() map[string]uint64 {
+func (x *Policy) GetRules() []*ACLRule {
 	if x != nil {
-		return x.ByConsumer
+		return x.Rules
 	}
 	return nil
 }
 
-type StatInterval struct {
+func (x *Policy) GetLastReloadError() string {
+	if x != nil {
+		return x.LastReloadError
+	}
+	return ""
+}
+
+func (x *Policy) GetQuotas() []*QuotaRule {
+	if x != nil {
+		return x.Quotas
+	}
+	return nil
+}
+
+// фильтр журнала, нулевые поля не ограничивают выборку
+type EventQuery struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	IntervalSeconds uint64 `protobuf:"varint,1,opt,name=interval_seconds,json=intervalSeconds,proto3" json:"interval_seconds,omitempty"`
+	From     int64  `protobuf:"varint,1,opt,name=from,proto3" json:"from,omitempty"` // unix-время, включительно
+	To       int64  `protobuf:"varint,2,opt,name=to,proto3" json:"to,omitempty"`     // unix-время, включительно
+	Consumer string `protobuf:"bytes,3,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	Method   string `protobuf:"bytes,4,opt,name=method,proto3" json:"method,omitempty"` // glob, например /main.Biz/*
+	Limit    uint32 `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`  // не больше 1000
 }
 
-func (x *StatInterval) Reset() {
-	*x = StatInterval{}
+func (x *EventQuery) Reset() {
+	*x = EventQuery{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_service_proto_msgTypes[2]
+		mi := &file_service_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *StatInterval) String() string {
+func (x *EventQuery) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StatInterval) ProtoMessage() {}
+func (*EventQuery) ProtoMessage() {}
 
-func (x *StatInterval) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[2]
+func (x *EventQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -484,43 +1159,72 @@ func (x *StatInterval) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StatInterval.ProtoReflect.Descriptor instead.
-func (*StatInterval) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use EventQuery.ProtoReflect.Descriptor instead.
+func (*EventQuery) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *StatInterval) GetIntervalSeconds() uint64 {
+func (x *EventQuery) GetFrom() int64 {
 	if x != nil {
-		return x.IntervalSeconds
+		return x.From
 	}
 	return 0
 }
 
-type Nothing struct {
+func (x *EventQuery) GetTo() int64 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+func (x *EventQuery) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *EventQuery) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *EventQuery) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type EventList struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Dummy bool `protobuf:"varint,1,opt,name=dummy,proto3" json:"dummy,omitempty"`
+	Events    []*Event `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Truncated bool     `protobuf:"varint,2,opt,name=truncated,proto3" json:"truncated,omitempty"` // есть ещё события, надо сузить интервал
 }
 
-func (x *Nothing) Reset() {
-	*x = Nothing{}
+func (x *EventList) Reset() {
+	*x = EventList{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_service_proto_msgTypes[3]
+		mi := &file_service_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Nothing) String() string {
+func (x *EventList) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Nothing) ProtoMessage() {}
+func (*EventList) ProtoMessage() {}
 
-func (x *Nothing) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[3]
+func (x *EventList) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -531,14 +1235,21 @@ func (x *Nothing) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Nothing.ProtoReflect.Descriptor instead.
-func (*Nothing) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use EventList.ProtoReflect.Descriptor instead.
+func (*EventList) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *Nothing) GetDummy() bool {
+func (x *EventList) GetEvents() []*Event {
 	if x != nil {
-		return x.Dummy
+		return x.Events
+	}
+	return nil
+}
+
+func (x *EventList) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
 	}
 	return false
 }
@@ -554,45 +1265,141 @@ var file_service_proto_rawDesc = []byte{
 	0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x68,
 	0x6f, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64,
 	0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
-	0x68, 0x6f, 0x73, 0x74, 0x22, 0x94, 0x02, 0x0a, 0x04, 0x53, 0x74, 0x61, 0x74, 0x12, 0x1c, 0x0a,
-	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
-	0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x35, 0x0a, 0x09, 0x62,
-	0x79, 0x5f, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18,
-	0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x2e, 0x42, 0x79, 0x4d, 0x65, 0x74,
-	0x68, 0x6f, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x62, 0x79, 0x4d, 0x65, 0x74, 0x68,
-	0x6f, 0x64, 0x12, 0x3b, 0x0a, 0x0b, 0x62, 0x79, 0x5f, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65,
-	0x72, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53,
-	0x74, 0x61, 0x74, 0x2e, 0x42, 0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x45, 0x6e,
-	0x74, 0x72, 0x79, 0x52, 0x0a, 0x62, 0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x1a,
-	0x3b, 0x0a, 0x0d, 0x42, 0x79, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79,
-	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
-	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3d, 0x0a, 0x0f,
-	0x42, 0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x68, 0x6f, 0x73, 0x74, 0x22, 0x89, 0x02, 0x0a, 0x0a, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x53,
+	0x74, 0x61, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x61, 0x6c, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x05, 0x63, 0x61, 0x6c, 0x6c, 0x73, 0x12, 0x48, 0x0a, 0x0e, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x73, 0x5f, 0x62, 0x79, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x22, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x53,
+	0x74, 0x61, 0x74, 0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x42, 0x79, 0x43, 0x6f, 0x64, 0x65,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0c, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x42, 0x79, 0x43,
+	0x6f, 0x64, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x70, 0x35, 0x30, 0x5f, 0x6d, 0x73, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x05, 0x70, 0x35, 0x30, 0x4d, 0x73, 0x12, 0x15, 0x0a, 0x06, 0x70, 0x39,
+	0x30, 0x5f, 0x6d, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x70, 0x39, 0x30, 0x4d,
+	0x73, 0x12, 0x15, 0x0a, 0x06, 0x70, 0x39, 0x39, 0x5f, 0x6d, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x05, 0x70, 0x39, 0x39, 0x4d, 0x73, 0x12, 0x15, 0x0a, 0x06, 0x6d, 0x61, 0x78, 0x5f,
+	0x6d, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x6d, 0x61, 0x78, 0x4d, 0x73, 0x1a,
+	0x3f, 0x0a, 0x11, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x42, 0x79, 0x43, 0x6f, 0x64, 0x65, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01,
+	0x22, 0xaa, 0x05, 0x0a, 0x04, 0x53, 0x74, 0x61, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x35, 0x0a, 0x09, 0x62, 0x79, 0x5f, 0x6d, 0x65,
+	0x74, 0x68, 0x6f, 0x64, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x61, 0x69,
+	0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x2e, 0x42, 0x79, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x62, 0x79, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x3b,
+	0x0a, 0x0b, 0x62, 0x79, 0x5f, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x18, 0x03, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x2e,
+	0x42, 0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x0a, 0x62, 0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x12, 0x31, 0x0a, 0x07, 0x6d,
+	0x65, 0x74, 0x68, 0x6f, 0x64, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6d,
+	0x61, 0x69, 0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x73, 0x12, 0x35,
+	0x0a, 0x09, 0x69, 0x6e, 0x5f, 0x66, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x18, 0x05, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x2e, 0x49, 0x6e,
+	0x46, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x69, 0x6e, 0x46,
+	0x6c, 0x69, 0x67, 0x68, 0x74, 0x12, 0x57, 0x0a, 0x15, 0x74, 0x68, 0x72, 0x6f, 0x74, 0x74, 0x6c,
+	0x65, 0x64, 0x5f, 0x62, 0x79, 0x5f, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x18, 0x06,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74,
+	0x2e, 0x54, 0x68, 0x72, 0x6f, 0x74, 0x74, 0x6c, 0x65, 0x64, 0x42, 0x79, 0x43, 0x6f, 0x6e, 0x73,
+	0x75, 0x6d, 0x65, 0x72, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x13, 0x74, 0x68, 0x72, 0x6f, 0x74,
+	0x74, 0x6c, 0x65, 0x64, 0x42, 0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x1a, 0x3b,
+	0x0a, 0x0d, 0x42, 0x79, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
 	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
 	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
-	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x39, 0x0a, 0x0c, 0x53,
-	0x74, 0x61, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x29, 0x0a, 0x10, 0x69,
-	0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x53,
-	0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x22, 0x1f, 0x0a, 0x07, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e,
-	0x67, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x75, 0x6d, 0x6d, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08,
-	0x52, 0x05, 0x64, 0x75, 0x6d, 0x6d, 0x79, 0x32, 0x64, 0x0a, 0x05, 0x41, 0x64, 0x6d, 0x69, 0x6e,
-	0x12, 0x29, 0x0a, 0x07, 0x4c, 0x6f, 0x67, 0x67, 0x69, 0x6e, 0x67, 0x12, 0x0d, 0x2e, 0x6d, 0x61,
-	0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x0b, 0x2e, 0x6d, 0x61, 0x69,
-	0x6e, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x30, 0x01, 0x12, 0x30, 0x0a, 0x0a, 0x53,
-	0x74, 0x61, 0x74, 0x69, 0x73, 0x74, 0x69, 0x63, 0x73, 0x12, 0x12, 0x2e, 0x6d, 0x61, 0x69, 0x6e,
-	0x2e, 0x53, 0x74, 0x61, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x1a, 0x0a, 0x2e,
-	0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x22, 0x00, 0x30, 0x01, 0x32, 0x7d, 0x0a,
-	0x03, 0x42, 0x69, 0x7a, 0x12, 0x27, 0x0a, 0x05, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x0d, 0x2e,
-	0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x0d, 0x2e, 0x6d,
-	0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x22, 0x00, 0x12, 0x25, 0x0a,
-	0x03, 0x41, 0x64, 0x64, 0x12, 0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68,
-	0x69, 0x6e, 0x67, 0x1a, 0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69,
-	0x6e, 0x67, 0x22, 0x00, 0x12, 0x26, 0x0a, 0x04, 0x54, 0x65, 0x73, 0x74, 0x12, 0x0d, 0x2e, 0x6d,
-	0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x0d, 0x2e, 0x6d, 0x61,
-	0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x22, 0x00, 0x42, 0x08, 0x5a, 0x06,
-	0x2e, 0x3b, 0x6d, 0x61, 0x69, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3d, 0x0a, 0x0f, 0x42,
+	0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x4c, 0x0a, 0x0c, 0x4d, 0x65,
+	0x74, 0x68, 0x6f, 0x64, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x26, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x6d, 0x61,
+	0x69, 0x6e, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x53, 0x74, 0x61, 0x74, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3b, 0x0a, 0x0d, 0x49, 0x6e, 0x46, 0x6c,
+	0x69, 0x67, 0x68, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x46, 0x0a, 0x18, 0x54, 0x68, 0x72, 0x6f, 0x74, 0x74, 0x6c,
+	0x65, 0x64, 0x42, 0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x39, 0x0a,
+	0x0c, 0x53, 0x74, 0x61, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x29, 0x0a,
+	0x10, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61,
+	0x6c, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x22, 0x1f, 0x0a, 0x07, 0x4e, 0x6f, 0x74, 0x68,
+	0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x75, 0x6d, 0x6d, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x05, 0x64, 0x75, 0x6d, 0x6d, 0x79, 0x22, 0x59, 0x0a, 0x07, 0x41, 0x43, 0x4c,
+	0x52, 0x75, 0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74, 0x12, 0x1c, 0x0a, 0x09,
+	0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x09, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65,
+	0x74, 0x68, 0x6f, 0x64, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x74,
+	0x68, 0x6f, 0x64, 0x73, 0x22, 0x99, 0x01, 0x0a, 0x09, 0x51, 0x75, 0x6f, 0x74, 0x61, 0x52, 0x75,
+	0x6c, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x73,
+	0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x07, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x63,
+	0x6f, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x72, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04,
+	0x72, 0x61, 0x74, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x75, 0x72, 0x73, 0x74, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x05, 0x62, 0x75, 0x72, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x61,
+	0x69, 0x6c, 0x79, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x64, 0x61, 0x69, 0x6c, 0x79,
+	0x22, 0x41, 0x0a, 0x0d, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x47, 0x72, 0x6f, 0x75,
+	0x70, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65,
+	0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d,
+	0x65, 0x72, 0x73, 0x22, 0xfe, 0x01, 0x0a, 0x06, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x18,
+	0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x6c, 0x6f, 0x61, 0x64,
+	0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x6c, 0x6f, 0x61,
+	0x64, 0x65, 0x64, 0x41, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x2b, 0x0a,
+	0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e,
+	0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x47, 0x72, 0x6f,
+	0x75, 0x70, 0x52, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x12, 0x23, 0x0a, 0x05, 0x72, 0x75,
+	0x6c, 0x65, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e,
+	0x2e, 0x41, 0x43, 0x4c, 0x52, 0x75, 0x6c, 0x65, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x12,
+	0x2a, 0x0a, 0x11, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x72, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x6c, 0x61, 0x73, 0x74,
+	0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x27, 0x0a, 0x06, 0x71,
+	0x75, 0x6f, 0x74, 0x61, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x6d, 0x61,
+	0x69, 0x6e, 0x2e, 0x51, 0x75, 0x6f, 0x74, 0x61, 0x52, 0x75, 0x6c, 0x65, 0x52, 0x06, 0x71, 0x75,
+	0x6f, 0x74, 0x61, 0x73, 0x22, 0x7a, 0x0a, 0x0a, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x51, 0x75, 0x65,
+	0x72, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d,
+	0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d,
+	0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69,
+	0x6d, 0x69, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74,
+	0x22, 0x4e, 0x0a, 0x09, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x23, 0x0a,
+	0x06, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e,
+	0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x06, 0x65, 0x76, 0x65, 0x6e,
+	0x74, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64,
+	0x32, 0xbc, 0x01, 0x0a, 0x05, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x12, 0x29, 0x0a, 0x07, 0x4c, 0x6f,
+	0x67, 0x67, 0x69, 0x6e, 0x67, 0x12, 0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74,
+	0x68, 0x69, 0x6e, 0x67, 0x1a, 0x0b, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x22, 0x00, 0x30, 0x01, 0x12, 0x30, 0x0a, 0x0a, 0x53, 0x74, 0x61, 0x74, 0x69, 0x73, 0x74,
+	0x69, 0x63, 0x73, 0x12, 0x12, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x49,
+	0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x1a, 0x0a, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53,
+	0x74, 0x61, 0x74, 0x22, 0x00, 0x30, 0x01, 0x12, 0x27, 0x0a, 0x06, 0x50, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x12, 0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67,
+	0x1a, 0x0c, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x22, 0x00,
+	0x12, 0x2d, 0x0a, 0x06, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x10, 0x2e, 0x6d, 0x61, 0x69,
+	0x6e, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x51, 0x75, 0x65, 0x72, 0x79, 0x1a, 0x0f, 0x2e, 0x6d,
+	0x61, 0x69, 0x6e, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x4c, 0x69, 0x73, 0x74, 0x22, 0x00, 0x32,
+	0x7d, 0x0a, 0x03, 0x42, 0x69, 0x7a, 0x12, 0x27, 0x0a, 0x05, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x12,
+	0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x0d,
+	0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x22, 0x00, 0x12,
+	0x25, 0x0a, 0x03, 0x41, 0x64, 0x64, 0x12, 0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f,
+	0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74,
+	0x68, 0x69, 0x6e, 0x67, 0x22, 0x00, 0x12, 0x26, 0x0a, 0x04, 0x54, 0x65, 0x73, 0x74, 0x12, 0x0d,
+	0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x0d, 0x2e,
+	0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x22, 0x00, 0x42, 0x08,
+	0x5a, 0x06, 0x2e, 0x3b, 0x6d, 0x61, 0x69, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -607,33 +1414,57 @@ func file_service_proto_rawDescGZIP() []byte {
 	return file_service_proto_rawDescData
 }
 
-var file_service_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_service_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_service_proto_goTypes = []interface{}{
-	(*Event)(nil),        // 0: main.Event
-	(*Stat)(nil),         // 1: main.Stat
-	(*StatInterval)(nil), // 2: main.StatInterval
-	(*Nothing)(nil),      // 3: main.Nothing
-	nil,                  // 4: main.Stat.ByMethodEntry
-	nil,                  // 5: main.Stat.ByConsumerEntry
+	(*Event)(nil),         // 0: main.Event
+	(*MethodStat)(nil),    // 1: main.MethodStat
+	(*Stat)(nil),          // 2: main.Stat
+	(*StatInterval)(nil),  // 3: main.StatInterval
+	(*Nothing)(nil),       // 4: main.Nothing
+	(*ACLRule)(nil),       // 5: main.ACLRule
+	(*QuotaRule)(nil),     // 6: main.QuotaRule
+	(*ConsumerGroup)(nil), // 7: main.ConsumerGroup
+	(*Policy)(nil),        // 8: main.Policy
+	(*EventQuery)(nil),    // 9: main.EventQuery
+	(*EventList)(nil),     // 10: main.EventList
+	nil,                   // 11: main.MethodStat.ErrorsByCodeEntry
+	nil,                   // 12: main.Stat.ByMethodEntry
+	nil,                   // 13: main.Stat.ByConsumerEntry
+	nil,                   // 14: main.Stat.MethodsEntry
+	nil,                   // 15: main.Stat.InFlightEntry
+	nil,                   // 16: main.Stat.ThrottledByConsumerEntry
 }
 var file_service_proto_depIdxs = []int32{
-	4, // 0: main.Stat.by_method:type_name -> main.Stat.ByMethodEntry
-	5, // 1: main.Stat.by_consumer:type_name -> main.Stat.ByConsumerEntry
-	3, // 2: main.Admin.Logging:input_type -> main.Nothing
-	2, // 3: main.Admin.Statistics:input_type -> main.StatInterval
-	3, // 4: main.Biz.Check:input_type -> main.Nothing
-	3, // 5: main.Biz.Add:input_type -> main.Nothing
-	3, // 6: main.Biz.Test:input_type -> main.Nothing
-	0, // 7: main.Admin.Logging:output_type -> main.Event
-	1, // 8: main.Admin.Statistics:output_type -> main.Stat
-	3, // 9: main.Biz.Check:output_type -> main.Nothing
-	3, // 10: main.Biz.Add:output_type -> main.Nothing
-	3, // 11: main.Biz.Test:output_type -> main.Nothing
-	7, // [7:12] is the sub-list for method output_type
-	2, // [2:7] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	11, // 0: main.MethodStat.errors_by_code:type_name -> main.MethodStat.ErrorsByCodeEntry
+	12, // 1: main.Stat.by_method:type_name -> main.Stat.ByMethodEntry
+	13, // 2: main.Stat.by_consumer:type_name -> main.Stat.ByConsumerEntry
+	14, // 3: main.Stat.methods:type_name -> main.Stat.MethodsEntry
+	15, // 4: main.Stat.in_flight:type_name -> main.Stat.InFlightEntry
+	16, // 5: main.Stat.throttled_by_consumer:type_name -> main.Stat.ThrottledByConsumerEntry
+	7,  // 6: main.Policy.groups:type_name -> main.ConsumerGroup
+	5,  // 7: main.Policy.rules:type_name -> main.ACLRule
+	6,  // 8: main.Policy.quotas:type_name -> main.QuotaRule
+	0,  // 9: main.EventList.events:type_name -> main.Event
+	1,  // 10: main.Stat.MethodsEntry.value:type_name -> main.MethodStat
+	4,  // 11: main.Admin.Logging:input_type -> main.Nothing
+	3,  // 12: main.Admin.Statistics:input_type -> main.StatInterval
+	4,  // 13: main.Admin.Policy:input_type -> main.Nothing
+	9,  // 14: main.Admin.Events:input_type -> main.EventQuery
+	4,  // 15: main.Biz.Check:input_type -> main.Nothing
+	4,  // 16: main.Biz.Add:input_type -> main.Nothing
+	4,  // 17: main.Biz.Test:input_type -> main.Nothing
+	0,  // 18: main.Admin.Logging:output_type -> main.Event
+	2,  // 19: main.Admin.Statistics:output_type -> main.Stat
+	8,  // 20: main.Admin.Policy:output_type -> main.Policy
+	10, // 21: main.Admin.Events:output_type -> main.EventList
+	4,  // 22: main.Biz.Check:output_type -> main.Nothing
+	4,  // 23: main.Biz.Add:output_type -> main.Nothing
+	4,  // 24: main.Biz.Test:output_type -> main.Nothing
+	18, // [18:25] is the sub-list for method output_type
+	11, // [11:18] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_service_proto_init() }
@@ -655,7 +1486,7 @@ func file_service_proto_init() {
 			}
 		}
 		file_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Stat); i {
+			switch v := v.(*MethodStat); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -667,7 +1498,7 @@ func file_service_proto_init() {
 			}
 		}
 		file_service_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*StatInterval); i {
+			switch v := v.(*Stat); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -679,6 +1510,18 @@ func file_service_proto_init() {
 			}
 		}
 		file_service_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatInterval); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Nothing); i {
 			case 0:
 				return &v.state
@@ -690,6 +1533,78 @@ func file_service_proto_init() {
 				return nil
 			}
 		}
+		file_service_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ACLRule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QuotaRule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConsumerGroup); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Policy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EventQuery); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EventList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -697,7 +1612,7 @@ func file_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_service_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
@@ -723,8 +1638,11 @@ const _ = grpc.SupportPackageIsVersion6
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type AdminClient interface {
+	// с метаданными replay-from (unix-время) сначала отдаёт события журнала начиная с этого момента
 	Logging(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (Admin_LoggingClient, error)
 	Statistics(ctx context.Context, in *StatInterval, opts ...grpc.CallOption) (Admin_StatisticsClient, error)
+	Policy(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Policy, error)
+	Events(ctx context.Context, in *EventQuery, opts ...grpc.CallOption) (*EventList, error)
 }
 
 type adminClient struct {
@@ -799,10 +1717,31 @@ func (x *adminStatisticsClient) Recv() (*Stat, error) {
 	return m, nil
 }
 
+func (c *adminClient) Policy(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Policy, error) {
+	out := new(Policy)
+	err := c.cc.Invoke(ctx, "/main.Admin/Policy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Events(ctx context.Context, in *EventQuery, opts ...grpc.CallOption) (*EventList, error) {
+	out := new(EventList)
+	err := c.cc.Invoke(ctx, "/main.Admin/Events", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AdminServer is the server API for Admin service.
 type AdminServer interface {
+	// с метаданными replay-from (unix-время) сначала отдаёт события журнала начиная с этого момента
 	Logging(*Nothing, Admin_LoggingServer) error
 	Statistics(*StatInterval, Admin_StatisticsServer) error
+	Policy(context.Context, *Nothing) (*Policy, error)
+	Events(context.Context, *EventQuery) (*EventList, error)
 }
 
 // UnimplementedAdminServer can be embedded to have forward compatible implementations.
@@ -815,6 +1754,12 @@ func (*UnimplementedAdminServer) Logging(*Nothing, Admin_LoggingServer) error {
 func (*UnimplementedAdminServer) Statistics(*StatInterval, Admin_StatisticsServer) error {
 	return status.Errorf(codes.Unimplemented, "method Statistics not implemented")
 }
+func (*UnimplementedAdminServer) Policy(context.Context, *Nothing) (*Policy, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Policy not implemented")
+}
+func (*UnimplementedAdminServer) Events(context.Context, *EventQuery) (*EventList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Events not implemented")
+}
 
 func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
 	s.RegisterService(&_Admin_serviceDesc, srv)
@@ -862,10 +1807,55 @@ func (x *adminStatisticsServer) Send(m *Stat) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Admin_Policy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Nothing)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Policy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Admin/Policy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Policy(ctx, req.(*Nothing))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Events_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EventQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Events(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Admin/Events",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Events(ctx, req.(*EventQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Admin_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "main.Admin",
 	HandlerType: (*AdminServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Policy",
+			Handler:    _Admin_Policy_Handler,
+		},
+		{
+			MethodName: "Events",
+			Handler:    _Admin_Events_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Logging",
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+const metricsPrefix = "hw7_"
+
+// latencyBounds are the histogram upper bounds in seconds,
+// the Prometheus defaults with finer steps below 5ms for the fast Biz calls
+func latencyBounds() []float64 {
+	return []float64{
+		0.0001, 0.00025, 0.0005, 0.001, 0.0025,
+		0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+	}
+}
+
+// histogram counts observations per bucket, the last bucket is +Inf
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+	max    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	i := sort.SearchFloat64s(bounds, v)
+	h.counts[i]++
+	h.count++
+	h.sum += v
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// quantile interpolates inside the bucket holding the q-th observation, never above max
+func (h *histogram) quantile(bounds []float64, q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := q * float64(h.count)
+	var seen uint64
+	for i, n := range h.counts {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+		if i == len(bounds) {
+			return h.max
+		}
+
+		lower := 0.0
+		if i > 0 {
+			lower = bounds[i-1]
+		}
+		v := lower + (bounds[i]-lower)*(rank-float64(seen))/float64(n)
+		if v > h.max {
+			v = h.max
+		}
+		return v
+	}
+
+	return h.max
+}
+
+type methodTotals struct {
+	codes   map[codes.Code]uint64
+	latency *histogram
+}
+
+type windowMethod struct {
+	calls   uint64
+	errors  map[string]uint64
+	latency *histogram
+}
+
+// statWindow collects one interval of a Statistics stream
+type statWindow struct {
+	byMethod   map[string]uint64
+	byConsumer map[string]uint64
+	methods    map[string]*windowMethod
+	throttled  map[string]uint64
+}
+
+func newStatWindow() *statWindow {
+	return &statWindow{
+		byMethod:   make(map[string]uint64),
+		byConsumer: make(map[string]uint64),
+		methods:    make(map[string]*windowMethod),
+		throttled:  make(map[string]uint64),
+	}
+}
+
+// StatsCollector keeps totals since the start for /metrics and a window per Statistics stream.
+// Windows are updated in place, so a slow stream never holds up the calls.
+type StatsCollector struct {
+	mu         *sync.Mutex
+	bounds     []float64
+	totals     map[string]*methodTotals
+	byConsumer map[string]uint64
+	inFlight   map[string]int64
+	throttled  map[string]uint64
+	windows    map[interface{}]*statWindow
+}
+
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{
+		mu:         &sync.Mutex{},
+		bounds:     latencyBounds(),
+		totals:     make(map[string]*methodTotals),
+		byConsumer: make(map[string]uint64),
+		inFlight:   make(map[string]int64),
+		throttled:  make(map[string]uint64),
+		windows:    make(map[interface{}]*statWindow),
+	}
+}
+
+// Start counts a call that passed the ACL and is about to run
+func (c *StatsCollector) Start(consumer, method string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.byConsumer[consumer]++
+	c.inFlight[method]++
+	for _, w := range c.windows {
+		w.byMethod[method]++
+		w.byConsumer[consumer]++
+	}
+}
+
+// Finish records a call started with Start
+func (c *StatsCollector) Finish(method string, code codes.Code, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight[method]--
+	if c.inFlight[method] == 0 {
+		delete(c.inFlight, method)
+	}
+	c.record(method, code, d)
+}
+
+// Reject records a call refused before the handler, it has no latency
+func (c *StatsCollector) Reject(consumer, method string, code codes.Code) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record(method, code, -1)
+	if code != codes.ResourceExhausted {
+		return
+	}
+	c.throttled[consumer]++
+	for _, w := range c.windows {
+		w.throttled[consumer]++
+	}
+}
+
+func (c *StatsCollector) record(method string, code codes.Code, d time.Duration) {
+	t, ok := c.totals[method]
+	if !ok {
+		t = &methodTotals{codes: make(map[codes.Code]uint64), latency: newHistogram(c.bounds)}
+		c.totals[method] = t
+	}
+	t.codes[code]++
+	if d >= 0 {
+		t.latency.observe(c.bounds, d.Seconds())
+	}
+
+	for _, w := range c.windows {
+		m, ok := w.methods[method]
+		if !ok {
+			m = &windowMethod{errors: make(map[string]uint64), latency: newHistogram(c.bounds)}
+			w.methods[method] = m
+		}
+		if code != codes.OK {
+			m.errors[code.String()]++
+		}
+		if d >= 0 {
+			m.calls++
+			m.latency.observe(c.bounds, d.Seconds())
+		}
+	}
+}
+
+func (c *StatsCollector) Subscribe(stream interface{}) {
+	c.mu.Lock()
+	c.windows[stream] = newStatWindow()
+	c.mu.Unlock()
+}
+
+func (c *StatsCollector) Unsubscribe(stream interface{}) {
+	c.mu.Lock()
+	delete(c.windows, stream)
+	c.mu.Unlock()
+}
+
+// Flush returns the window of the stream and starts a new one
+func (c *StatsCollector) Flush(stream interface{}) *Stat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stat := NewStat()
+	w, ok := c.windows[stream]
+	if !ok {
+		return stat
+	}
+	c.windows[stream] = newStatWindow()
+
+	stat.ByMethod = w.byMethod
+	stat.ByConsumer = w.byConsumer
+	stat.ThrottledByConsumer = w.throttled
+	for method, m := range w.methods {
+		stat.Methods[method] = &MethodStat{
+			Calls:        m.calls,
+			ErrorsByCode: m.errors,
+			P50Ms:        m.latency.quantile(c.bounds, 0.5) * 1000,
+			P90Ms:        m.latency.quantile(c.bounds, 0.9) * 1000,
+			P99Ms:        m.latency.quantile(c.bounds, 0.99) * 1000,
+			MaxMs:        m.latency.max * 1000,
+		}
+	}
+	for method, n := range c.inFlight {
+		stat.InFlight[method] = n
+	}
+
+	return stat
+}
+
+// WritePrometheus writes the totals in the Prometheus text format.
+// The text is rendered under the lock and written after it, so a slow scraper
+// never holds up the calls
+func (c *StatsCollector) WritePrometheus(out io.Writer) error {
+	buf := &bytes.Buffer{}
+	c.mu.Lock()
+	c.renderPrometheus(buf)
+	c.mu.Unlock()
+
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// renderPrometheus must be called under c.mu
+func (c *StatsCollector) renderPrometheus(w *bytes.Buffer) {
+	methods := make([]string, 0, len(c.totals))
+	for method := range c.totals {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	writeHeader(w, "requests_total", "counter", "Finished and rejected calls by method and gRPC code.")
+	for _, method := range methods {
+		t := c.totals[method]
+		codeList := make([]codes.Code, 0, len(t.codes))
+		for code := range t.codes {
+			codeList = append(codeList, code)
+		}
+		sort.Slice(codeList, func(i, j int) bool { return codeList[i] < codeList[j] })
+		for _, code := range codeList {
+			fmt.Fprintf(w, "%srequests_total{method=%q,code=%q} %d\n", metricsPrefix, method, code.String(), t.codes[code])
+		}
+	}
+
+	writeHeader(w, "request_duration_seconds", "histogram", "Duration of finished calls, streams included.")
+	for _, method := range methods {
+		h := c.totals[method].latency
+		if h.count == 0 {
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range c.bounds {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%srequest_duration_seconds_bucket{method=%q,le=%q} %d\n",
+				metricsPrefix, method, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "%srequest_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n", metricsPrefix, method, h.count)
+		fmt.Fprintf(w, "%srequest_duration_seconds_sum{method=%q} %s\n", metricsPrefix, method, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%srequest_duration_seconds_count{method=%q} %d\n", metricsPrefix, method, h.count)
+	}
+
+	writeHeader(w, "in_flight_requests", "gauge", "Calls being handled right now.")
+	inFlight := make([]string, 0, len(c.inFlight))
+	for method := range c.inFlight {
+		inFlight = append(inFlight, method)
+	}
+	sort.Strings(inFlight)
+	for _, method := range inFlight {
+		fmt.Fprintf(w, "%sin_flight_requests{method=%q} %d\n", metricsPrefix, method, c.inFlight[method])
+	}
+
+	writeHeader(w, "consumer_requests_total", "counter", "Started calls by consumer.")
+	consumers := make([]string, 0, len(c.byConsumer))
+	for consumer := range c.byConsumer {
+		consumers = append(consumers, consumer)
+	}
+	sort.Strings(consumers)
+	for _, consumer := range consumers {
+		fmt.Fprintf(w, "%sconsumer_requests_total{consumer=%q} %d\n", metricsPrefix, consumer, c.byConsumer[consumer])
+	}
+
+	writeHeader(w, "throttled_requests_total", "counter", "Calls refused by quotas by consumer.")
+	throttled := make([]string, 0, len(c.throttled))
+	for consumer := range c.throttled {
+		throttled = append(throttled, consumer)
+	}
+	sort.Strings(throttled)
+	for _, consumer := range throttled {
+		fmt.Fprintf(w, "%sthrottled_requests_total{consumer=%q} %d\n", metricsPrefix, consumer, c.throttled[consumer])
+	}
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, name, help, metricsPrefix, name, kind)
+}
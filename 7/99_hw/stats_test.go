@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// the previous test's server stops in the background after finish,
+// so this one listens on its own addresses
+const (
+	statsListenAddr = "127.0.0.1:8084"
+	metricsAddr     = "127.0.0.1:8083"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	bounds := []float64{1, 2, 4}
+	h := newHistogram(bounds)
+	for _, v := range []float64{0.5, 1.5, 1.5, 3, 10} {
+		h.observe(bounds, v)
+	}
+
+	cases := []struct {
+		q        float64
+		expected float64
+	}{
+		{0.2, 1},    // the 1st value is in (0, 1]
+		{0.5, 1.75}, // the 2.5th is in the middle of (1, 2] with 2 values
+		{0.8, 4},
+		{0.99, 10}, // +Inf bucket gives the max
+	}
+	for _, tc := range cases {
+		if got := h.quantile(bounds, tc.q); math.Abs(got-tc.expected) > 1e-9 {
+			t.Errorf("q%v: expected %v, got %v", tc.q, tc.expected, got)
+		}
+	}
+
+	if got := newHistogram(bounds).quantile(bounds, 0.5); got != 0 {
+		t.Errorf("expected 0 for empty histogram, got %v", got)
+	}
+}
+
+func TestStatsCollectorWindow(t *testing.T) {
+	c := NewStatsCollector()
+	c.Subscribe("stream")
+
+	c.Start("biz_user", "/main.Biz/Check")
+	c.Finish("/main.Biz/Check", codes.OK, 2*time.Millisecond)
+	c.Start("biz_user", "/main.Biz/Check")
+	c.Finish("/main.Biz/Check", codes.Internal, 4*time.Millisecond)
+	c.Reject("biz_user", "/main.Biz/Test", codes.Unauthenticated)
+	c.Start("logger", "/main.Admin/Logging")
+
+	stat := c.Flush("stream")
+	check := stat.Methods["/main.Biz/Check"]
+	if check == nil || check.Calls != 2 || check.ErrorsByCode["Internal"] != 1 || check.MaxMs != 4 {
+		t.Errorf("unexpected Check stat: %+v", check)
+	}
+	if check != nil && (check.P50Ms <= 0 || check.P50Ms > check.P99Ms || check.P99Ms > check.MaxMs) {
+		t.Errorf("percentiles out of order: %+v", check)
+	}
+	test := stat.Methods["/main.Biz/Test"]
+	if test == nil || test.Calls != 0 || test.ErrorsByCode["Unauthenticated"] != 1 {
+		t.Errorf("unexpected Test stat: %+v", test)
+	}
+	if stat.ByMethod["/main.Biz/Test"] != 0 || stat.ByConsumer["biz_user"] != 2 {
+		t.Errorf("rejected calls must not be counted as started: %+v", stat)
+	}
+	if stat.InFlight["/main.Admin/Logging"] != 1 || len(stat.InFlight) != 1 {
+		t.Errorf("expected 1 Logging in flight, got %v", stat.InFlight)
+	}
+
+	stat = c.Flush("stream")
+	if len(stat.ByMethod) != 0 || len(stat.Methods) != 0 || stat.InFlight["/main.Admin/Logging"] != 1 {
+		t.Errorf("expected an empty window with the gauge kept, got %+v", stat)
+	}
+}
+
+func TestStatLatency(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, statsListenAddr, ACLData, WithMetricsAddr(metricsAddr))
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(2)
+	}()
+
+	conn, err := grpc.Dial(statsListenAddr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("cant connect to grpc: %v", err)
+	}
+	defer conn.Close()
+
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	statStream, err := adm.Statistics(getConsumerCtx("stat"), &StatInterval{IntervalSeconds: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Add(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Test(getConsumerCtx("biz_user"), &Nothing{})
+
+	stat, err := statStream.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m := stat.Methods["/main.Biz/Check"]; m == nil || m.Calls != 1 || m.MaxMs <= 0 {
+		t.Errorf("expected a timed Check call, got %+v", m)
+	}
+	if m := stat.Methods["/main.Biz/Test"]; m == nil || m.ErrorsByCode[codes.Unauthenticated.String()] != 1 {
+		t.Errorf("expected a denied Test call, got %+v", m)
+	}
+	if stat.InFlight["/main.Admin/Statistics"] != 1 {
+		t.Errorf("expected the stream itself in flight, got %v", stat.InFlight)
+	}
+
+	resp, err := http.Get("http://" + metricsAddr + "/metrics")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	expected := []string{
+		`# TYPE hw7_requests_total counter`,
+		`hw7_requests_total{method="/main.Biz/Check",code="OK"} 1`,
+		`hw7_requests_total{method="/main.Biz/Test",code="Unauthenticated"} 1`,
+		`hw7_request_duration_seconds_bucket{method="/main.Biz/Add",le="+Inf"} 1`,
+		`hw7_request_duration_seconds_count{method="/main.Biz/Add"} 1`,
+		`hw7_in_flight_requests{method="/main.Admin/Statistics"} 1`,
+		`hw7_consumer_requests_total{consumer="biz_user"} 2`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("expected %q in metrics:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func unpack(t *testing.T, raw string) interface{} {
+	t.Helper()
+	var data interface{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		t.Fatalf("bad test json %s: %v", raw, err)
+	}
+	return data
+}
+
+type Numbers struct {
+	I8  int8
+	I16 int16
+	I32 int32
+	I64 int64
+	U   uint
+	U8  uint8
+	U16 uint16
+	U64 uint64
+	F32 float32
+	F64 float64
+}
+
+func TestNumbers(t *testing.T) {
+	result := new(Numbers)
+	err := i2s(unpack(t, `{"I8":-128,"I16":32767,"I32":-5,"I64":9007199254740992,"U":7,"U8":255,"U16":65535,"U64":42,"F32":1.5,"F64":-2.25}`), result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &Numbers{I8: -128, I16: 32767, I32: -5, I64: 1 << 53, U: 7, U8: 255, U16: 65535, U64: 42, F32: 1.5, F64: -2.25}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+
+	// числа, положенные в map из кода, а не из json, и json.Number
+	data := map[string]interface{}{"I8": int64(-1), "U8": uint32(200), "F32": 3, "I64": json.Number("-9223372036854775808")}
+	result = new(Numbers)
+	if err := i2s(data, result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.I8 != -1 || result.U8 != 200 || result.F32 != 3 || result.I64 != math.MinInt64 {
+		t.Errorf("unexpected result %#v", result)
+	}
+
+	cases := []struct {
+		data string
+		path string
+		err  error
+	}{
+		{`{"I8":128}`, "I8", ErrOverflow},
+		{`{"I16":-32769}`, "I16", ErrOverflow},
+		{`{"U8":256}`, "U8", ErrOverflow},
+		{`{"U":-1}`, "U", ErrOverflow},
+		{`{"I64":1e19}`, "I64", ErrOverflow},
+		{`{"F32":1e39}`, "F32", ErrOverflow},
+		{`{"I32":1.5}`, "I32", ErrTypeMismatch},
+		{`{"U16":"1"}`, "U16", ErrTypeMismatch},
+		{`{"F64":true}`, "F64", ErrTypeMismatch},
+	}
+	for _, c := range cases {
+		err := i2s(unpack(t, c.data), new(Numbers))
+		checkDecodeError(t, c.data, err, c.path, c.err)
+	}
+}
+
+func checkDecodeError(t *testing.T, name string, err error, path string, want error) {
+	t.Helper()
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Errorf("%s: expected DecodeError, got %v", name, err)
+		return
+	}
+	if de.Path != path {
+		t.Errorf("%s: got path %q, expected %q", name, de.Path, path)
+	}
+	if !errors.Is(err, want) {
+		t.Errorf("%s: got %v, expected %v", name, err, want)
+	}
+	if path != "" && !strings.HasPrefix(err.Error(), path+": ") {
+		t.Errorf("%s: message %q has no path", name, err)
+	}
+}
+
+type Address struct {
+	City   string `json:"city"`
+	Street string `json:"street,omitempty"`
+}
+
+type Base struct {
+	ID      int       `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type Meta struct {
+	Source string `json:"source"`
+}
+
+type User struct {
+	Base
+	*Meta
+	Name     string            `json:"name"`
+	Address  *Address          `json:"address"`
+	Tags     map[string]int    `json:"tags,omitempty"`
+	Scores   map[int][]float64 `json:"scores,omitempty"`
+	Extra    interface{}       `json:"extra,omitempty"`
+	Skipped  string            `json:"-"`
+	Nickname *string           `json:"nick,omitempty"`
+	Pair     [2]string         `json:"pair,omitempty"`
+}
+
+type Users struct {
+	Users []User
+}
+
+func TestStructs(t *testing.T) {
+	raw := `{"Users":[{
+		"id": 1, "created": "2019-03-04T05:06:07Z", "source": "import",
+		"name": "rvasily", "address": {"city": "Moscow"},
+		"tags": {"go": 1, "c": 2}, "scores": {"7": [1, 2.5]},
+		"extra": {"any": ["thing", null]}, "nick": "vasily", "pair": ["a"]
+	}, {
+		"id": 2, "created": "2020-01-01T00:00:00Z", "name": "anon", "address": null
+	}]}`
+
+	result := new(Users)
+	if err := i2s(unpack(t, raw), result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	nick := "vasily"
+	expected := &Users{Users: []User{{
+		Base:     Base{ID: 1, Created: time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC)},
+		Meta:     &Meta{Source: "import"},
+		Name:     "rvasily",
+		Address:  &Address{City: "Moscow"},
+		Tags:     map[string]int{"go": 1, "c": 2},
+		Scores:   map[int][]float64{7: {1, 2.5}},
+		Extra:    map[string]interface{}{"any": []interface{}{"thing", nil}},
+		Nickname: &nick,
+		Pair:     [2]string{"a", ""},
+	}, {
+		Base: Base{ID: 2, Created: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Name: "anon",
+	}}}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+}
+
+func TestFieldPathErrors(t *testing.T) {
+	cases := []struct {
+		data string
+		path string
+		err  error
+	}{
+		{`{"Users":[{}, {}, {"address": {"city": 5}}]}`, "Users[2].address.city", ErrTypeMismatch},
+		{`{"Users":[{"address": {"town": "Moscow"}}]}`, "Users[0].address.town", ErrUnknownField},
+		{`{"Users":[{"tags": {"go": "one"}}]}`, `Users[0].tags["go"]`, ErrTypeMismatch},
+		{`{"Users":[{"scores": {"x": []}}]}`, `Users[0].scores["x"]`, ErrTypeMismatch},
+		{`{"Users":[{"pair": ["a", "b", "c"]}]}`, "Users[0].pair", ErrOverflow},
+		{`{"Users":[{"Skipped": "x"}]}`, "Users[0].Skipped", ErrUnknownField},
+		{`{"Users":{}}`, "Users", ErrTypeMismatch},
+	}
+	for _, c := range cases {
+		err := i2s(unpack(t, c.data), new(Users))
+		checkDecodeError(t, c.data, err, c.path, c.err)
+	}
+
+	var de *DecodeError
+	err := i2s(unpack(t, `{"Users":[{"created": "yesterday"}]}`), new(Users))
+	if !errors.As(err, &de) || de.Path != "Users[0].created" {
+		t.Errorf("expected time error at Users[0].created, got %v", err)
+	}
+}
+
+type Named struct {
+	Name string
+}
+
+type Labeled struct {
+	Name string
+}
+
+type Inner struct {
+	Value int
+}
+
+type Wrap struct {
+	Inner
+	Value string
+}
+
+// Value из Wrap ближе, чем из Inner, а два Name на одной глубине не достаются никому
+type Conflict struct {
+	Named
+	Labeled
+	Wrap
+}
+
+func TestModes(t *testing.T) {
+	data := unpack(t, `{"city": "Moscow", "zip": 101000}`)
+
+	if err := i2s(data, new(Address)); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("strict mode: expected unknown field error, got %v", err)
+	}
+
+	lenient := Decoder{TagName: "json", Lenient: true}
+	result := new(Address)
+	if err := lenient.Decode(data, result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.City != "Moscow" {
+		t.Errorf("unexpected result %#v", result)
+	}
+
+	// Street - omitempty, без него можно, без city - нет
+	required := Decoder{TagName: "json", RequireFields: true}
+	if err := required.Decode(unpack(t, `{"city": "Moscow"}`), new(Address)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := required.Decode(unpack(t, `{"street": "Tverskaya"}`), new(Address))
+	checkDecodeError(t, "required", err, "city", ErrMissingField)
+
+	// свой тег, без тега - имена полей
+	type Custom struct {
+		Name string `db:"user_name"`
+		Age  int
+	}
+	custom := Decoder{TagName: "db"}
+	c := new(Custom)
+	if err := custom.Decode(unpack(t, `{"user_name": "rvasily", "Age": 30}`), c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Name != "rvasily" || c.Age != 30 {
+		t.Errorf("unexpected result %#v", c)
+	}
+
+	plain := Decoder{}
+	conflict := new(Conflict)
+	if err := plain.Decode(unpack(t, `{"Value": "x"}`), conflict); err != nil || conflict.Wrap.Value != "x" {
+		t.Errorf("shallow Value: %v %#v", err, conflict)
+	}
+	if err := plain.Decode(unpack(t, `{"Name": "x"}`), conflict); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("ambiguous Name: expected unknown field error, got %v", err)
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	// в interface{} с указателем пишем по указателю
+	simple := &Simple{}
+	var target interface{} = simple
+	if err := i2s(unpack(t, `{"ID": 1, "Username": "rvasily", "Active": true}`), &target); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if simple.Username != "rvasily" || target != simple {
+		t.Errorf("unexpected result %#v", target)
+	}
+
+	var stringer interface{ String() string }
+	err := i2s(unpack(t, `"x"`), &stringer)
+	checkDecodeError(t, "stringer", err, "", ErrTypeMismatch)
+
+	if err := i2s(nil, (*Simple)(nil)); err == nil {
+		t.Errorf("expected error for nil pointer")
+	}
+}
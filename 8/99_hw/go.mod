@@ -0,0 +1,3 @@
+module hw8
+
+go 1.18
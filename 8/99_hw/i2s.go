@@ -1,72 +1,555 @@
 package main
 
 import (
+	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 )
 
+var (
+	ErrTypeMismatch = errors.New("type mismatch")
+	ErrOverflow     = errors.New("value out of range")
+	ErrUnknownField = errors.New("unknown field")
+	ErrMissingField = errors.New("missing field")
+)
+
+// DecodeError - ошибка вместе с путём до значения, например Users[2].Address.City
+type DecodeError struct {
+	Path string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decoder заполняет значение из того, что получается при распаковке json в interface{}:
+// map[string]interface{}, []interface{}, float64, json.Number, string, bool и nil.
+// Числа любых типов приводятся к нужному с проверкой переполнения
+type Decoder struct {
+	// TagName - тег с именем ключа и опциями, например json:"name,omitempty".
+	// Без тега или с пустым TagName ключ совпадает с именем поля, "-" пропускает поле
+	TagName string
+	// Lenient - пропускать ключи, для которых нет поля, по умолчанию это ошибка
+	Lenient bool
+	// RequireFields - ключ должен быть у каждого поля, кроме помеченных omitempty
+	RequireFields bool
+}
+
+// i2s - interface to struct: строгий разбор, имена ключей берутся из тегов json
 func i2s(data interface{}, out interface{}) error {
+	d := Decoder{TagName: "json"}
+	return d.Decode(data, out)
+}
+
+// Decode пишет data в значение, на которое указывает out
+func (d *Decoder) Decode(data interface{}, out interface{}) error {
 	ptr := reflect.ValueOf(out)
 	if ptr.Kind() != reflect.Ptr {
 		return TypeMismatchError(reflect.Ptr, ptr.Kind())
 	}
-	ov := ptr.Elem()
-	if !ov.CanSet() {
-		return fmt.Errorf("cannot set the value of %s", ov)
+	if ptr.IsNil() {
+		return fmt.Errorf("cannot decode into nil %s", ptr.Type())
 	}
-	switch v := reflect.ValueOf(data); v.Kind() {
-	case reflect.Map:
-		if ov.Kind() != reflect.Struct {
-			return TypeMismatchError(reflect.Struct, ov.Kind())
+	return d.decode(data, ptr.Elem(), "")
+}
+
+func TypeMismatchError(ek reflect.Kind, fk reflect.Kind) error {
+	return fmt.Errorf("%w, expected %s but was %s", ErrTypeMismatch, ek, fk)
+}
+
+var (
+	numberType          = reflect.TypeOf(json.Number(""))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func fail(path string, err error) error {
+	return &DecodeError{Path: path, Err: err}
+}
+
+func mismatch(path string, v reflect.Value, ov reflect.Value) error {
+	return fail(path, fmt.Errorf("%w, cannot decode %s into %s", ErrTypeMismatch, v.Type(), ov.Type()))
+}
+
+func (d *Decoder) decode(data interface{}, ov reflect.Value, path string) error {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() == reflect.Ptr {
+		ov.Set(reflect.Zero(ov.Type()))
+		return nil
+	}
+
+	// готовое значение нужного типа кладём как есть, map и slice копируем, чтобы не делить их с data
+	if v.Type() == ov.Type() && v.Kind() != reflect.Map && v.Kind() != reflect.Slice {
+		ov.Set(v)
+		return nil
+	}
+
+	switch ov.Kind() {
+	case reflect.Ptr:
+		if ov.IsNil() {
+			ov.Set(reflect.New(ov.Type().Elem()))
 		}
-		for _, e := range v.MapKeys() {
-			field := ov.FieldByName(e.String())
-			if !field.IsValid() {
-				return fmt.Errorf("cannot find field %s", e.String())
-			}
-			if !field.CanAddr() {
-				return fmt.Errorf("field %s is not addressable", field)
-			}
+		return d.decode(v.Interface(), ov.Elem(), path)
+	case reflect.Interface:
+		return d.decodeInterface(v, ov, path)
+	}
 
-			if err := i2s(v.MapIndex(e).Interface(), field.Addr().Interface()); err != nil {
-				return err
-			}
+	if v.Kind() == reflect.String && v.Type() != numberType && ov.CanAddr() && ov.Addr().Type().Implements(textUnmarshalerType) {
+		if err := ov.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(v.String())); err != nil {
+			return fail(path, err)
+		}
+		return nil
+	}
+
+	switch ov.Kind() {
+	case reflect.Bool:
+		if v.Kind() != reflect.Bool {
+			return mismatch(path, v, ov)
+		}
+		ov.SetBool(v.Bool())
+	case reflect.String:
+		if v.Kind() != reflect.String {
+			return mismatch(path, v, ov)
+		}
+		ov.SetString(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt(v)
+		if err == nil && ov.OverflowInt(n) {
+			err = fmt.Errorf("%w: %v does not fit into %s", ErrOverflow, n, ov.Type())
+		}
+		if err != nil {
+			return d.numberError(path, v, ov, err)
+		}
+		ov.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := toUint(v)
+		if err == nil && ov.OverflowUint(n) {
+			err = fmt.Errorf("%w: %v does not fit into %s", ErrOverflow, n, ov.Type())
+		}
+		if err != nil {
+			return d.numberError(path, v, ov, err)
+		}
+		ov.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(v)
+		if err == nil && ov.OverflowFloat(f) {
+			err = fmt.Errorf("%w: %v does not fit into %s", ErrOverflow, f, ov.Type())
+		}
+		if err != nil {
+			return d.numberError(path, v, ov, err)
 		}
+		ov.SetFloat(f)
+	case reflect.Struct:
+		return d.decodeStruct(v, ov, path)
+	case reflect.Map:
+		return d.decodeMap(v, ov, path)
 	case reflect.Slice:
-		if ov.Kind() != reflect.Slice {
-			return TypeMismatchError(reflect.Slice, ov.Kind())
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return mismatch(path, v, ov)
+		}
+		slice := reflect.MakeSlice(ov.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := d.decode(v.Index(i).Interface(), slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
 		}
-		slice := reflect.MakeSlice(reflect.TypeOf(ov.Interface()), v.Len(), v.Len())
 		ov.Set(slice)
+	case reflect.Array:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return mismatch(path, v, ov)
+		}
+		if v.Len() > ov.Len() {
+			return fail(path, fmt.Errorf("%w: %d elements do not fit into %s", ErrOverflow, v.Len(), ov.Type()))
+		}
+		array := reflect.New(ov.Type()).Elem()
 		for i := 0; i < v.Len(); i++ {
-			if err := i2s(v.Index(i).Interface(), slice.Index(i).Addr().Interface()); err != nil {
+			if err := d.decode(v.Index(i).Interface(), array.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
 				return err
 			}
 		}
-	case reflect.Float64:
-		if ov.Kind() != reflect.Int {
-			return TypeMismatchError(reflect.Int, ov.Kind())
+		ov.Set(array)
+	default:
+		return fail(path, fmt.Errorf("unsupported type %s", ov.Type()))
+	}
+
+	return nil
+}
+
+// numberError: не число - это несовпадение типов, а не переполнение
+func (d *Decoder) numberError(path string, v, ov reflect.Value, err error) error {
+	if err == errNotNumber {
+		return mismatch(path, v, ov)
+	}
+	return fail(path, err)
+}
+
+var errNotNumber = errors.New("not a number")
+
+func toInt(v reflect.Value) (int64, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("%w: %v does not fit into int64", ErrOverflow, v.Uint())
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) {
+			return 0, fmt.Errorf("%w, %v is not an integer", ErrTypeMismatch, f)
 		}
+		if f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, fmt.Errorf("%w: %v does not fit into int64", ErrOverflow, f)
+		}
+		return int64(f), nil
+	case reflect.String:
+		if v.Type() == numberType {
+			n, err := strconv.ParseInt(v.String(), 10, 64)
+			if err != nil {
+				return 0, numberSyntax(v.String(), err)
+			}
+			return n, nil
+		}
+	}
+	return 0, errNotNumber
+}
 
+func toUint(v reflect.Value) (uint64, error) {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
 		f := v.Float()
-		ov.SetInt(int64(f))
+		if f != math.Trunc(f) {
+			return 0, fmt.Errorf("%w, %v is not an integer", ErrTypeMismatch, f)
+		}
+		if f < 0 || f >= math.MaxUint64 {
+			return 0, fmt.Errorf("%w: %v does not fit into uint64", ErrOverflow, f)
+		}
+		return uint64(f), nil
 	case reflect.String:
-		if ov.Kind() != reflect.String {
-			return TypeMismatchError(reflect.String, ov.Kind())
+		if v.Type() == numberType {
+			n, err := strconv.ParseUint(v.String(), 10, 64)
+			if err != nil {
+				return 0, numberSyntax(v.String(), err)
+			}
+			return n, nil
 		}
-		ov.SetString(v.String())
-	case reflect.Bool:
-		if ov.Kind() != reflect.Bool {
-			return TypeMismatchError(reflect.Bool, ov.Kind())
+	}
+
+	n, err := toInt(v)
+	if err == nil && n < 0 {
+		return 0, fmt.Errorf("%w: %v does not fit into uint64", ErrOverflow, n)
+	}
+	return uint64(n), err
+}
+
+func toFloat(v reflect.Value) (float64, error) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.String:
+		if v.Type() == numberType {
+			f, err := strconv.ParseFloat(v.String(), 64)
+			if err != nil {
+				return 0, numberSyntax(v.String(), err)
+			}
+			return f, nil
 		}
-		ov.SetBool(v.Bool())
-	default:
-		return fmt.Errorf("unhandled kind %s", v.Kind())
 	}
+	return 0, errNotNumber
+}
+
+func numberSyntax(s string, err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("%w: %s", ErrOverflow, s)
+	}
+	return fmt.Errorf("%w, %s is not a valid number here", ErrTypeMismatch, s)
+}
 
+func (d *Decoder) decodeInterface(v reflect.Value, ov reflect.Value, path string) error {
+	// в непустой interface{} с указателем пишем туда, куда он указывает, как encoding/json
+	if !ov.IsNil() && ov.Elem().Kind() == reflect.Ptr && !ov.Elem().IsNil() {
+		return d.decode(v.Interface(), ov.Elem().Elem(), path)
+	}
+	if !v.Type().AssignableTo(ov.Type()) {
+		return mismatch(path, v, ov)
+	}
+	ov.Set(v)
 	return nil
 }
 
-func TypeMismatchError(ek reflect.Kind, fk reflect.Kind) error {
-	return fmt.Errorf("type mismatch, expected %s but was %s", ek, fk)
+// keyName - строковый ключ map, в том числе лежащий в interface{}
+func keyName(k reflect.Value) (string, bool) {
+	if k.Kind() == reflect.Interface {
+		k = k.Elem()
+	}
+	if k.Kind() != reflect.String {
+		return "", false
+	}
+	return k.String(), true
+}
+
+func (d *Decoder) decodeStruct(v reflect.Value, ov reflect.Value, path string) error {
+	if v.Kind() != reflect.Map {
+		return mismatch(path, v, ov)
+	}
+
+	fields := d.fields(ov.Type())
+	names := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+	for _, k := range v.MapKeys() {
+		name, ok := keyName(k)
+		if !ok {
+			return mismatch(path, v, ov)
+		}
+		names = append(names, name)
+		values[name] = v.MapIndex(k)
+	}
+	// порядок ключей в map случайный, а ошибка должна быть всегда одна и та же
+	sort.Strings(names)
+
+	seen := make([]bool, len(fields.list))
+	for _, name := range names {
+		fieldPath := joinPath(path, name)
+		i, ok := fields.byName[name]
+		if !ok {
+			if d.Lenient {
+				continue
+			}
+			return fail(fieldPath, ErrUnknownField)
+		}
+		seen[i] = true
+
+		fv, err := fieldByIndex(ov, fields.list[i].index)
+		if err != nil {
+			return fail(fieldPath, err)
+		}
+		if err := d.decode(values[name].Interface(), fv, fieldPath); err != nil {
+			return err
+		}
+	}
+
+	if d.RequireFields {
+		for i, f := range fields.list {
+			if !seen[i] && !f.optional {
+				return fail(joinPath(path, f.name), ErrMissingField)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) decodeMap(v reflect.Value, ov reflect.Value, path string) error {
+	if v.Kind() != reflect.Map {
+		return mismatch(path, v, ov)
+	}
+
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+
+	m := reflect.MakeMapWithSize(ov.Type(), len(keys))
+	for _, k := range keys {
+		elemPath := fmt.Sprintf("%s[%v]", path, k.Interface())
+		if name, ok := keyName(k); ok {
+			elemPath = fmt.Sprintf("%s[%q]", path, name)
+		}
+
+		key := reflect.New(ov.Type().Key()).Elem()
+		if err := d.decodeKey(k, key, elemPath); err != nil {
+			return err
+		}
+		elem := reflect.New(ov.Type().Elem()).Elem()
+		if err := d.decode(v.MapIndex(k).Interface(), elem, elemPath); err != nil {
+			return err
+		}
+		m.SetMapIndex(key, elem)
+	}
+	ov.Set(m)
+	return nil
+}
+
+// decodeKey: в json ключи - всегда строки, поэтому строку в числовой ключ разбираем как число
+func (d *Decoder) decodeKey(k reflect.Value, key reflect.Value, path string) error {
+	name, ok := keyName(k)
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		if ok {
+			return d.decode(json.Number(name), key, path)
+		}
+	}
+	return d.decode(k.Interface(), key, path)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// fieldByIndex идёт по встроенным структурам, создавая встроенные указатели по пути
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+type field struct {
+	name     string
+	index    []int
+	optional bool
+}
+
+type structFields struct {
+	list   []field
+	byName map[string]int
+}
+
+type fieldsKey struct {
+	t   reflect.Type
+	tag string
+}
+
+var fieldCache sync.Map
+
+// fields - поля структуры по ключам, вместе с полями встроенных структур.
+// Как в encoding/json: из одноимённых побеждает менее вложенное, при равенстве - с тегом,
+// а если и так не решить - ключ не достаётся никому
+func (d *Decoder) fields(t reflect.Type) *structFields {
+	key := fieldsKey{t, d.TagName}
+	if f, ok := fieldCache.Load(key); ok {
+		return f.(*structFields)
+	}
+
+	type candidate struct {
+		field
+		depth  int
+		tagged bool
+	}
+	var all []candidate
+
+	var walk func(t reflect.Type, index []int, visited map[reflect.Type]bool)
+	walk = func(t reflect.Type, index []int, visited map[reflect.Type]bool) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			var tag string
+			if d.TagName != "" {
+				tag = sf.Tag.Get(d.TagName)
+			}
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			idx := append(index[:len(index):len(index)], i)
+
+			if sf.Anonymous && name == "" {
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					if !visited[ft] {
+						visited[ft] = true
+						walk(ft, idx, visited)
+						delete(visited, ft)
+					}
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+
+			c := candidate{field: field{name: name, index: idx}, depth: len(idx), tagged: name != ""}
+			if c.name == "" {
+				c.name = sf.Name
+			}
+			for _, opt := range strings.Split(opts, ",") {
+				if opt == "omitempty" {
+					c.optional = true
+				}
+			}
+			all = append(all, c)
+		}
+	}
+	walk(t, nil, map[reflect.Type]bool{t: true})
+
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].name != all[j].name {
+			return all[i].name < all[j].name
+		}
+		if all[i].depth != all[j].depth {
+			return all[i].depth < all[j].depth
+		}
+		return all[i].tagged && !all[j].tagged
+	})
+
+	sf := &structFields{byName: make(map[string]int)}
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j].name == all[i].name {
+			j++
+		}
+		best := all[i]
+		dominant := j == i+1 ||
+			all[i+1].depth > best.depth ||
+			best.tagged && !all[i+1].tagged
+		if dominant {
+			sf.list = append(sf.list, best.field)
+		}
+		i = j
+	}
+	// в порядке объявления, чтобы первым сообщалось о первом пропущенном поле
+	sort.Slice(sf.list, func(i, j int) bool {
+		a, b := sf.list[i].index, sf.list[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	for i, f := range sf.list {
+		sf.byName[f.name] = i
+	}
+
+	f, _ := fieldCache.LoadOrStore(key, sf)
+	return f.(*structFields)
 }